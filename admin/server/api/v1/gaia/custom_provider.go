@@ -0,0 +1,105 @@
+package gaia
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetCustomProviders 获取自定义提供商列表
+// @Tags ModelProvider
+// @Summary 获取自定义提供商列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=[]gaia.CustomProvider,msg=string} "获取成功"
+// @Router /gaia/model-provider/custom-providers [get]
+func (m *ModelProviderApi) GetCustomProviders(c *gin.Context) {
+	list, err := modelProviderService.GetCustomProviders()
+	if err != nil {
+		global.GVA_LOG.Error("获取自定义提供商列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// CreateCustomProvider 创建自定义提供商
+// @Tags ModelProvider
+// @Summary 创建自定义提供商（自建 OpenAI 兼容上游，如 vLLM / Ollama）
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.CustomProviderReq true "自定义提供商配置"
+// @Success 200 {object} response.Response{data=gaia.CustomProvider,msg=string} "创建成功"
+// @Router /gaia/model-provider/custom-providers [post]
+func (m *ModelProviderApi) CreateCustomProvider(c *gin.Context) {
+	var req gaiaReq.CustomProviderReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	provider, err := modelProviderService.CreateCustomProvider(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建自定义提供商失败", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(provider, "创建成功", c)
+}
+
+// UpdateCustomProvider 更新自定义提供商
+// @Tags ModelProvider
+// @Summary 更新自定义提供商（name 不可修改，api_key 留空表示不修改）
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "自定义提供商 ID"
+// @Param data body gaiaReq.CustomProviderReq true "自定义提供商配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/custom-providers/{id} [put]
+func (m *ModelProviderApi) UpdateCustomProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	var req gaiaReq.CustomProviderReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err = modelProviderService.UpdateCustomProvider(uint(id), req); err != nil {
+		global.GVA_LOG.Error("更新自定义提供商失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteCustomProvider 删除自定义提供商
+// @Tags ModelProvider
+// @Summary 删除自定义提供商（同时删除其启用状态与已选模型）
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "自定义提供商 ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /gaia/model-provider/custom-providers/{id} [delete]
+func (m *ModelProviderApi) DeleteCustomProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	if err = modelProviderService.DeleteCustomProvider(uint(id)); err != nil {
+		global.GVA_LOG.Error("删除自定义提供商失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
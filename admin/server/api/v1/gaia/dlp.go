@@ -0,0 +1,165 @@
+package gaia
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetDLPRules 获取DLP 规则列表（分页）
+// @Tags ModelProvider
+// @Summary 获取DLP 规则列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param action query string false "动作(redact/block/log)"
+// @Param keyword query string false "规则名称/匹配内容"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /gaia/model-provider/dlp-rules [get]
+func (m *ModelProviderApi) GetDLPRules(c *gin.Context) {
+	var req gaiaReq.GetDLPRulesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := modelProviderService.GetDLPRules(req)
+	if err != nil {
+		global.GVA_LOG.Error("获取DLP 规则列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
+
+// CreateDLPRule 创建DLP 规则
+// @Tags ModelProvider
+// @Summary 创建DLP 规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.DLPRuleReq true "DLP 规则配置"
+// @Success 200 {object} response.Response{data=gaia.DLPRule,msg=string} "创建成功"
+// @Router /gaia/model-provider/dlp-rules [post]
+func (m *ModelProviderApi) CreateDLPRule(c *gin.Context) {
+	var req gaiaReq.DLPRuleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	rule, err := modelProviderService.CreateDLPRule(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建DLP 规则失败", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(rule, "创建成功", c)
+}
+
+// UpdateDLPRule 更新DLP 规则
+// @Tags ModelProvider
+// @Summary 更新DLP 规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "规则 ID"
+// @Param data body gaiaReq.DLPRuleReq true "DLP 规则配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/dlp-rules/{id} [put]
+func (m *ModelProviderApi) UpdateDLPRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	var req gaiaReq.DLPRuleReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err = modelProviderService.UpdateDLPRule(uint(id), req); err != nil {
+		global.GVA_LOG.Error("更新DLP 规则失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteDLPRule 删除DLP 规则
+// @Tags ModelProvider
+// @Summary 删除DLP 规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "规则 ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /gaia/model-provider/dlp-rules/{id} [delete]
+func (m *ModelProviderApi) DeleteDLPRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	if err = modelProviderService.DeleteDLPRule(uint(id)); err != nil {
+		global.GVA_LOG.Error("删除DLP 规则失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetDLPAuditLogs 获取 DLP 命中审计列表（分页，按时间倒序）
+// @Tags ModelProvider
+// @Summary 获取 DLP 命中审计列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param rule_id query int false "规则 ID"
+// @Param account_id query string false "账号 ID"
+// @Param action query string false "动作(redact/block/log)"
+// @Param request_id query string false "请求 ID"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /gaia/model-provider/dlp-audits [get]
+func (m *ModelProviderApi) GetDLPAuditLogs(c *gin.Context) {
+	var req gaiaReq.GetDLPAuditLogsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := modelProviderService.GetDLPAuditLogs(req)
+	if err != nil {
+		global.GVA_LOG.Error("获取 DLP 审计列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
@@ -6,21 +6,25 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	gaiaModel "github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
 type ForwardProxyApi struct{}
 
-// ForwardProxy 转发代理入口：免 JWT，通过 forwarding token + ding_id 鉴权并计费
+// ForwardProxy 转发代理入口：免 JWT，通过网关 Key（sk-gaia-...）或 forwarding token + ding_id 鉴权并计费
 // @Tags ForwardProxy
-// @Summary GPT 转发代理（钉钉入口，无需 JWT）
+// @Summary GPT 转发代理（钉钉入口 / 网关 Key，无需 JWT）
+// @Param Authorization header string false "Bearer sk-gaia-...（网关 Key）"
 // @Param X-Forward-Token header string false "转发 Token"
 // @Param X-Ding-Id header string false "钉钉 ID"
 // @Param forward_token query string false "转发 Token（Header 优先）"
 // @Param ding_id query string false "钉钉 ID（Header 优先）"
-// @Param path path string true "上游路径"
+// @Param path path string true "上游路径（v1/realtime 携带 WebSocket 升级头时代理 Realtime 会话）"
 // @Router /gaia/forward/proxy/{path} [get,post,put,patch,delete]
 func (f *ForwardProxyApi) ForwardProxy(c *gin.Context) {
 	// 打印请求 Header，便于排查转发问题
@@ -30,6 +34,17 @@ func (f *ForwardProxyApi) ForwardProxy(c *gin.Context) {
 		zap.String("path", c.Request.URL.Path),
 	)
 
+	// 0. 网关虚拟 Key：Authorization: Bearer sk-gaia-... 或 X-Api-Key: sk-gaia-...（Anthropic SDK 默认头）
+	if gatewayKey := extractGatewayKey(c); gatewayKey != "" {
+		key, keyErr := modelProviderService.AuthenticateGatewayKey(gatewayKey, c.ClientIP())
+		if keyErr != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": gin.H{"message": keyErr.Error()}})
+			return
+		}
+		proxyWithAccountId(c, key.AccountId, 0, key)
+		return
+	}
+
 	// 1. 读取转发配置
 	integrate := systemIntegratedService.GetIntegratedConfig(gaiaModel.SystemIntegrationDingTalk)
 	configMap, err := systemIntegratedService.ParseDingTalkConfig(integrate.Config)
@@ -93,7 +108,24 @@ func (f *ForwardProxyApi) ForwardProxy(c *gin.Context) {
 	}
 
 	// 6. 复用与 Proxy 相同的转发逻辑（path/body/ProxyRequest）
-	proxyWithAccountId(c, accountId)
+	proxyWithAccountId(c, accountId, 0, nil)
+}
+
+// extractGatewayKey 从 Authorization / X-Api-Key 头中提取网关虚拟 Key，不是 sk-gaia- 前缀时返回空；
+// 浏览器 WebSocket 无法设置请求头，Realtime 会话也可通过子协议 openai-insecure-api-key.<key> 传入
+func extractGatewayKey(c *gin.Context) string {
+	for _, v := range []string{c.GetHeader("Authorization"), c.GetHeader("X-Api-Key")} {
+		v = strings.TrimSpace(strings.TrimPrefix(v, "Bearer "))
+		if serviceGaia.IsGatewayKey(v) {
+			return v
+		}
+	}
+	for _, p := range websocket.Subprotocols(c.Request) {
+		if v, ok := strings.CutPrefix(p, "openai-insecure-api-key."); ok && serviceGaia.IsGatewayKey(v) {
+			return v
+		}
+	}
+	return ""
 }
 
 // validateForwardToken 校验 token 是否在转发 Token 列表中（SHA256 比对）
@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetGatewayKeys 获取网关 Key 列表（分页）
+// @Tags ModelProvider
+// @Summary 获取网关 Key 列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param account_id query string false "绑定账号"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /gaia/model-provider/keys [get]
+func (m *ModelProviderApi) GetGatewayKeys(c *gin.Context) {
+	var req gaiaReq.GetGatewayKeysReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := modelProviderService.GetGatewayKeys(req)
+	if err != nil {
+		global.GVA_LOG.Error("获取网关 Key 列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
+
+// CreateGatewayKey 创建网关 Key（明文仅返回一次）
+// @Tags ModelProvider
+// @Summary 创建网关 Key
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.GatewayKeyCreateReq true "Key 配置"
+// @Success 200 {object} response.Response{data=gaiaResponse.GatewayKeyCreated,msg=string} "创建成功"
+// @Router /gaia/model-provider/keys [post]
+func (m *ModelProviderApi) CreateGatewayKey(c *gin.Context) {
+	var req gaiaReq.GatewayKeyCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	created, err := modelProviderService.CreateGatewayKey(req, utils.GetUserID(c), utils.GetUserUuid(c).String())
+	if err != nil {
+		global.GVA_LOG.Error("创建网关 Key 失败", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(created, "创建成功", c)
+}
+
+// UpdateGatewayKey 更新网关 Key
+// @Tags ModelProvider
+// @Summary 更新网关 Key
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "Key ID"
+// @Param data body gaiaReq.GatewayKeyUpdateReq true "Key 配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/keys/{id} [put]
+func (m *ModelProviderApi) UpdateGatewayKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	var req gaiaReq.GatewayKeyUpdateReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err = modelProviderService.UpdateGatewayKey(uint(id), req); err != nil {
+		global.GVA_LOG.Error("更新网关 Key 失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteGatewayKey 删除网关 Key
+// @Tags ModelProvider
+// @Summary 删除网关 Key
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "Key ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /gaia/model-provider/keys/{id} [delete]
+func (m *ModelProviderApi) DeleteGatewayKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	if err = modelProviderService.DeleteGatewayKey(uint(id)); err != nil {
+		global.GVA_LOG.Error("删除网关 Key 失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetModelAccessRules 获取模型访问规则列表（分页）
+// @Tags ModelProvider
+// @Summary 获取模型访问规则列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param scope query string false "作用范围(account/authority)"
+// @Param keyword query string false "规则名称/subject/模型"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /gaia/model-provider/access-rules [get]
+func (m *ModelProviderApi) GetModelAccessRules(c *gin.Context) {
+	var req gaiaReq.GetModelAccessRulesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := modelProviderService.GetModelAccessRules(req)
+	if err != nil {
+		global.GVA_LOG.Error("获取模型访问规则列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
+
+// CreateModelAccessRule 创建模型访问规则
+// @Tags ModelProvider
+// @Summary 创建模型访问规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.ModelAccessRuleReq true "访问规则配置"
+// @Success 200 {object} response.Response{data=gaia.ModelAccessRule,msg=string} "创建成功"
+// @Router /gaia/model-provider/access-rules [post]
+func (m *ModelProviderApi) CreateModelAccessRule(c *gin.Context) {
+	var req gaiaReq.ModelAccessRuleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	rule, err := modelProviderService.CreateModelAccessRule(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建模型访问规则失败", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(rule, "创建成功", c)
+}
+
+// UpdateModelAccessRule 更新模型访问规则
+// @Tags ModelProvider
+// @Summary 更新模型访问规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "规则 ID"
+// @Param data body gaiaReq.ModelAccessRuleReq true "访问规则配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/access-rules/{id} [put]
+func (m *ModelProviderApi) UpdateModelAccessRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	var req gaiaReq.ModelAccessRuleReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err = modelProviderService.UpdateModelAccessRule(uint(id), req); err != nil {
+		global.GVA_LOG.Error("更新模型访问规则失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteModelAccessRule 删除模型访问规则
+// @Tags ModelProvider
+// @Summary 删除模型访问规则
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "规则 ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /gaia/model-provider/access-rules/{id} [delete]
+func (m *ModelProviderApi) DeleteModelAccessRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	if err = modelProviderService.DeleteModelAccessRule(uint(id)); err != nil {
+		global.GVA_LOG.Error("删除模型访问规则失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
@@ -1,17 +1,22 @@
 package gaia
 
 import (
-	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaModel "github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid/v5"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -76,7 +81,8 @@ func (m *ModelProviderApi) UpdateProviderConfig(c *gin.Context) {
 // @Success 200 {object} gaiaResponse.OpenAIModelsResponse "获取成功"
 // @Router /gaia/models [get]
 func (m *ModelProviderApi) GetModels(c *gin.Context) {
-	models, err := modelProviderService.GetEnabledModels()
+	caller := gaiaReq.ProxyCaller{AccountId: utils.GetUserUuid(c).String(), AuthorityId: utils.GetUserAuthorityId(c)}
+	models, err := modelProviderService.GetEnabledModels(caller)
 	if err != nil {
 		global.GVA_LOG.Error("获取模型列表失败", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -86,7 +92,9 @@ func (m *ModelProviderApi) GetModels(c *gin.Context) {
 }
 
 // proxyWithAccountId 通用代理逻辑：按路径转发到上游并计费。
-func proxyWithAccountId(c *gin.Context, accountId string) {
+// key 为网关虚拟 Key（JWT / 转发 Token 调用时为 nil），非空时校验其路径/模型作用域与预算；
+// authorityId 为账号角色 ID（非 JWT 调用时为 0，限流需要时由服务层查询）。
+func proxyWithAccountId(c *gin.Context, accountId string, authorityId uint, key *gaiaModel.GatewayKey) {
 	path := c.Param("path")
 	if path == "" || path == "/" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "代理路径不能为空"}})
@@ -101,14 +109,17 @@ func proxyWithAccountId(c *gin.Context, accountId string) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "读取请求体失败"}})
 		return
 	}
+	// Realtime WebSocket：模型来自 query（?model=，Azure 可为 deployment）
+	realtime := websocket.IsWebSocketUpgrade(c.Request)
 	var bodyModel string
-	if len(body) > 0 {
-		var parseObj map[string]interface{}
-		if jsonErr := json.Unmarshal(body, &parseObj); jsonErr == nil {
-			if mv, ok := parseObj["model"].(string); ok {
-				bodyModel = mv
-			}
+	if realtime {
+		bodyModel = strings.TrimSpace(c.Query("model"))
+		if bodyModel == "" {
+			bodyModel = strings.TrimSpace(c.Query("deployment"))
 		}
+	} else {
+		// body 的 model 字段，其次 Gemini 原生路径 / Bedrock Converse 路径中的模型
+		bodyModel = modelProviderService.RequestModel(path, body)
 	}
 	global.GVA_LOG.Info("Gaia代理请求入参",
 		zap.String("account_id", accountId),
@@ -118,30 +129,115 @@ func proxyWithAccountId(c *gin.Context, accountId string) {
 		zap.String("body_model", bodyModel),
 	)
 
-	// 余额前置检查：余额耗尽时直接拦截，不继续请求上游
-	if quotaErr := modelProviderService.CheckAccountQuota(accountId); quotaErr != nil {
+	// 请求 ID：沿用客户端传入的 X-Request-Id，否则由网关生成，回写响应头并记入代理日志
+	requestId := strings.TrimSpace(c.GetHeader("X-Request-Id"))
+	if requestId == "" || len(requestId) > 64 {
+		requestId = uuid.Must(uuid.NewV4()).String()
+	}
+	c.Header("X-Request-Id", requestId)
+
+	caller := gaiaReq.ProxyCaller{AccountId: accountId, AuthorityId: authorityId, RequestId: requestId, ClientIP: c.ClientIP()}
+	if key != nil {
+		caller.KeyId = key.Id
+		if scopeErr := modelProviderService.CheckGatewayKeyScope(key, path, bodyModel); scopeErr != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": gin.H{"message": scopeErr.Error()}})
+			return
+		}
+	}
+
+	// 提示词 DLP：转发上游前按规则脱敏 / 拦截请求体中的敏感信息（身份证、手机号、密钥等），命中写审计；Realtime 帧在会话中逐帧检查
+	if body, err = modelProviderService.ApplyPromptPolicy(caller, path, reqHeader, body); err != nil {
+		writeProxyError(c, err)
+		return
+	}
+
+	// RPM/TPM 限流（模型取自 body 或 Gemini / Converse 路径）：超限返回 OpenAI 风格的 429，并附带 Retry-After 与 x-ratelimit-* 头
+	limit := modelProviderService.CheckRateLimit(&caller, bodyModel)
+	for k, v := range limit.Headers {
+		c.Header(k, v)
+	}
+	if !limit.Allowed {
+		c.Header("Retry-After", strconv.Itoa(limit.RetryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": gin.H{
+			"message": limit.Message, "type": limit.Type, "code": "rate_limit_exceeded"}})
+		return
+	}
+
+	// Realtime 会话时长不定，不做预占：握手前检查余额，会话中按 response.done 逐次扣费并定期检查余额
+	if realtime {
+		if err = modelProviderService.ProxyRealtime(c.Writer, c.Request, caller, path, reqHeader); err != nil {
+			global.GVA_LOG.Error("Realtime 代理失败", zap.String("account_id", accountId), zap.String("path", path), zap.Error(err))
+			writeProxyError(c, err)
+		}
+		return
+	}
+
+	// 额度预占：按最大可能花费预占，可用余额（扣除进行中请求的预占）不足时直接拦截，不继续请求上游；
+	// 成功扣费时预占随之结算，其余情况（上游失败、无用量）在请求结束后释放
+	if quotaErr := modelProviderService.ReserveQuota(&caller, path, body); quotaErr != nil {
 		c.JSON(http.StatusPaymentRequired, gin.H{"error": gin.H{"message": quotaErr.Error()}})
 		return
 	}
+	defer modelProviderService.ReleaseQuotaHold(caller)
 
-	if err = modelProviderService.ProxyRequest(
-		accountId, path, c.Request.Method, reqHeader, body, c.Writer); err != nil {
+	// 透传请求 context：客户端断开时取消上游请求
+	if err = modelProviderService.ProxyRequest(c.Request.Context(),
+		caller, path, c.Request.Method, reqHeader, body, c.Writer); err != nil {
 		global.GVA_LOG.Error("代理请求失败", zap.String("account_id", accountId), zap.String("path", path), zap.Error(err))
-		if !c.Writer.Written() {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{"message": err.Error()}})
-		}
+		writeProxyError(c, err)
 	}
 }
 
+// writeProxyError 将代理失败写回 OpenAI 风格的错误响应（响应已开始写出时不再写入）。
+func writeProxyError(c *gin.Context, err error) {
+	if c.Writer.Written() {
+		return
+	}
+	var denied *serviceGaia.ModelAccessDeniedError
+	var scopeErr *serviceGaia.GatewayKeyScopeError
+	if errors.As(err, &denied) || errors.As(err, &scopeErr) {
+		c.JSON(http.StatusForbidden, gin.H{"error": gin.H{
+			"message": err.Error(), "type": "permission_error", "code": "model_not_allowed"}})
+		return
+	}
+	var quotaErr *serviceGaia.QuotaInsufficientError
+	if errors.As(err, &quotaErr) {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": gin.H{
+			"message": err.Error(), "type": "insufficient_quota", "code": "insufficient_quota"}})
+		return
+	}
+	var blocked *serviceGaia.PromptBlockedError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"message": err.Error(), "type": "invalid_request_error", "code": "content_policy_violation"}})
+		return
+	}
+	var batchErr *serviceGaia.BatchRequestError
+	if errors.As(err, &batchErr) {
+		c.JSON(batchErr.Status, gin.H{"error": gin.H{"message": err.Error(), "type": "invalid_request_error"}})
+		return
+	}
+	// 上游熔断：快速失败，返回 OpenAI 风格的 503 与 Retry-After
+	var circuitOpen *serviceGaia.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		c.Header("Retry-After", strconv.Itoa(circuitOpen.RetryAfterSeconds()))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": gin.H{
+			"message": err.Error(), "type": "server_error", "code": "upstream_circuit_open"}})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{"message": err.Error()}})
+}
+
 // Proxy 通用中转 API：将 /gaia/proxy/* 的请求按路径转发到上游（需 JWT，account 来自当前登录用户）。
 // @Tags ModelProvider
 // @Summary 通用中转API（按路径转发）
 // @Security ApiKeyAuth
-// @Param path path string true "上游路径，如 v1/chat/completions、v1/messages"
+// @Description 携带 WebSocket 升级头访问 v1/realtime?model=... 时代理 OpenAI / Azure Realtime 会话
+// @Param path path string true "上游路径，如 v1/chat/completions、v1/messages、model/{modelId}/converse（AWS Bedrock）、v1/realtime（WebSocket）、v1/files 与 v1/batches（Batch API）"
 // @Router /gaia/proxy/*path [get,post,put,patch,delete]
 func (m *ModelProviderApi) Proxy(c *gin.Context) {
 	accountId := utils.GetUserUuid(c).String()
-	proxyWithAccountId(c, accountId)
+	proxyWithAccountId(c, accountId, utils.GetUserAuthorityId(c), nil)
 }
 
 // GetAvailableModels 获取提供商的可用模型
@@ -207,6 +303,65 @@ func (m *ModelProviderApi) TestProviderCredentials(c *gin.Context) {
 	response.OkWithData(result, c)
 }
 
+// GetProviderCredentials 获取提供商全部凭证及负载均衡状态
+// @Tags ModelProvider
+// @Summary 获取提供商凭证负载均衡状态
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param provider_name query string true "提供商名称"
+// @Success 200 {object} response.Response{data=gaiaResponse.ProviderCredentialsResponse,msg=string} "获取成功"
+// @Router /gaia/model-provider/credentials [get]
+func (m *ModelProviderApi) GetProviderCredentials(c *gin.Context) {
+	providerName := c.Query("provider_name")
+	if providerName == "" {
+		response.FailWithMessage("参数错误:provider_name不能为空", c)
+		return
+	}
+	status, err := modelProviderService.GetProviderCredentialStatus(providerName)
+	if err != nil {
+		global.GVA_LOG.Error("获取提供商凭证状态失败", zap.String("provider", providerName), zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithData(status, c)
+}
+
+// GetCircuitBreakers 获取上游熔断器状态
+// @Tags ModelProvider
+// @Summary 获取上游熔断器状态（提供商级与凭证级，仅本实例）
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=[]gaiaResponse.CircuitBreakerStatus,msg=string} "获取成功"
+// @Router /gaia/model-provider/circuit-breakers [get]
+func (m *ModelProviderApi) GetCircuitBreakers(c *gin.Context) {
+	response.OkWithData(modelProviderService.GetCircuitBreakers(), c)
+}
+
+// UpdateProviderBalance 设置提供商多凭证负载均衡策略与权重
+// @Tags ModelProvider
+// @Summary 设置提供商负载均衡策略
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.ProviderBalanceReq true "负载均衡配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/credentials/balance [post]
+func (m *ModelProviderApi) UpdateProviderBalance(c *gin.Context) {
+	var req gaiaReq.ProviderBalanceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err := modelProviderService.UpdateProviderBalance(req); err != nil {
+		global.GVA_LOG.Error("设置提供商负载均衡失败", zap.String("provider", req.ProviderName), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
 // GetProxyLogs 获取代理日志（分页）
 // @Tags ModelProvider
 // @Summary 获取代理日志
@@ -215,6 +370,13 @@ func (m *ModelProviderApi) TestProviderCredentials(c *gin.Context) {
 // @Produce application/json
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
+// @Param user_id query string false "账号ID"
+// @Param provider query string false "提供商"
+// @Param model query string false "模型名（模糊匹配）"
+// @Param status query string false "状态(success/error/cancelled)"
+// @Param keyword query string false "错误信息关键字"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
 // @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
 // @Router /gaia/model-provider/logs [get]
 func (m *ModelProviderApi) GetProxyLogs(c *gin.Context) {
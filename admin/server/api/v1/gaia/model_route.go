@@ -0,0 +1,124 @@
+package gaia
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetModelRoutes 获取模型路由列表（分页）
+// @Tags ModelProvider
+// @Summary 获取模型路由列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param keyword query string false "规则名称/pattern"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /gaia/model-provider/routes [get]
+func (m *ModelProviderApi) GetModelRoutes(c *gin.Context) {
+	var req gaiaReq.GetModelRoutesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+	list, total, err := modelProviderService.GetModelRoutes(req)
+	if err != nil {
+		global.GVA_LOG.Error("获取模型路由列表失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
+
+// CreateModelRoute 创建模型路由
+// @Tags ModelProvider
+// @Summary 创建模型路由
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.ModelRouteReq true "路由配置"
+// @Success 200 {object} response.Response{data=gaia.ModelRoute,msg=string} "创建成功"
+// @Router /gaia/model-provider/routes [post]
+func (m *ModelProviderApi) CreateModelRoute(c *gin.Context) {
+	var req gaiaReq.ModelRouteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	route, err := modelProviderService.CreateModelRoute(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建模型路由失败", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(route, "创建成功", c)
+}
+
+// UpdateModelRoute 更新模型路由
+// @Tags ModelProvider
+// @Summary 更新模型路由
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "路由 ID"
+// @Param data body gaiaReq.ModelRouteReq true "路由配置"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /gaia/model-provider/routes/{id} [put]
+func (m *ModelProviderApi) UpdateModelRoute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	var req gaiaReq.ModelRouteReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	if err = modelProviderService.UpdateModelRoute(uint(id), req); err != nil {
+		global.GVA_LOG.Error("更新模型路由失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteModelRoute 删除模型路由
+// @Tags ModelProvider
+// @Summary 删除模型路由
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id path int true "路由 ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /gaia/model-provider/routes/{id} [delete]
+func (m *ModelProviderApi) DeleteModelRoute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.FailWithMessage("参数错误:id 非法", c)
+		return
+	}
+	if err = modelProviderService.DeleteModelRoute(uint(id)); err != nil {
+		global.GVA_LOG.Error("删除模型路由失败", zap.Uint64("id", id), zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
@@ -0,0 +1,80 @@
+package gaia
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetProxyLogStats 代理日志统计（汇总与分组）
+// @Tags ModelProvider
+// @Summary 代理日志统计
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param group_by query string false "分组维度(user/model/provider/day)，空为仅汇总"
+// @Param user_id query string false "账号ID"
+// @Param provider query string false "提供商"
+// @Param model query string false "模型名（模糊匹配）"
+// @Param status query string false "状态(success/error/cancelled)"
+// @Param keyword query string false "错误信息关键字"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Success 200 {object} response.Response{data=gaiaResponse.ProxyLogStats,msg=string} "获取成功"
+// @Router /gaia/model-provider/logs/stats [get]
+func (m *ModelProviderApi) GetProxyLogStats(c *gin.Context) {
+	var req gaiaReq.GetProxyLogStatsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	stats, err := modelProviderService.GetProxyLogStats(req)
+	if err != nil {
+		global.GVA_LOG.Error("代理日志统计失败", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(stats, "获取成功", c)
+}
+
+// ExportProxyLogs 按筛选条件导出代理日志
+// @Tags ModelProvider
+// @Summary 导出代理日志
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/octet-stream
+// @Param format query string false "导出格式(csv/xlsx)，默认 xlsx"
+// @Param user_id query string false "账号ID"
+// @Param provider query string false "提供商"
+// @Param model query string false "模型名（模糊匹配）"
+// @Param status query string false "状态(success/error/cancelled)"
+// @Param keyword query string false "错误信息关键字"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Router /gaia/model-provider/logs/export [get]
+func (m *ModelProviderApi) ExportProxyLogs(c *gin.Context) {
+	var req gaiaReq.ExportProxyLogsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误:"+err.Error(), c)
+		return
+	}
+	file, name, err := modelProviderService.ExportProxyLogs(req)
+	if err != nil {
+		global.GVA_LOG.Error("导出代理日志失败", zap.Error(err))
+		response.FailWithMessage("导出失败:"+err.Error(), c)
+		return
+	}
+	contentType := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	if req.Format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name)))
+	c.Header("success", "true")
+	c.Data(http.StatusOK, contentType, file.Bytes())
+}
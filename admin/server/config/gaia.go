@@ -1,9 +1,44 @@
 package config
 
 type Gaia struct {
-	Url                 string `mapstructure:"url" json:"url" yaml:"url"`
-	LoginMaxErrorLimit  int    `mapstructure:"login_max_error_limit" json:"login_max_error_limit" yaml:"login_max_error_limit"`
-	SuperAdminAccountId string `mapstructure:"SUPER_ADMIN_ACCOUNT_ID" json:"SUPER_ADMIN_ACCOUNT_ID" yaml:"SUPER_ADMIN_ACCOUNT_ID"` // 超级管理员账号
-	SuperAdminTenantId  string `mapstructure:"SUPER_ADMIN_TENANT_ID" json:"SUPER_ADMIN_TENANT_ID" yaml:"SUPER_ADMIN_TENANT_ID"`    // 系统默认工作区
-	StoragePath         string `mapstructure:"storage-path" json:"storage-path" yaml:"storage-path"`                              // Dify storage 目录路径，用于读取私钥
+	Url                 string      `mapstructure:"url" json:"url" yaml:"url"`
+	LoginMaxErrorLimit  int         `mapstructure:"login_max_error_limit" json:"login_max_error_limit" yaml:"login_max_error_limit"`
+	SuperAdminAccountId string      `mapstructure:"SUPER_ADMIN_ACCOUNT_ID" json:"SUPER_ADMIN_ACCOUNT_ID" yaml:"SUPER_ADMIN_ACCOUNT_ID"` // 超级管理员账号
+	SuperAdminTenantId  string      `mapstructure:"SUPER_ADMIN_TENANT_ID" json:"SUPER_ADMIN_TENANT_ID" yaml:"SUPER_ADMIN_TENANT_ID"`    // 系统默认工作区
+	StoragePath         string      `mapstructure:"storage-path" json:"storage-path" yaml:"storage-path"`                               // Dify storage 目录路径，用于读取私钥
+	Gateway             GaiaGateway `mapstructure:"gateway" json:"gateway" yaml:"gateway"`                                              // 模型网关配置
+	MetricsToken        string      `mapstructure:"metrics-token" json:"metrics-token" yaml:"metrics-token"`                            // /metrics 访问令牌（Authorization: Bearer），空则仅允许本机回环地址访问
+}
+
+// GaiaGateway 模型网关（/gaia/proxy、/gaia/forward/proxy）相关配置
+type GaiaGateway struct {
+	FailoverRetries int                `mapstructure:"failover-retries" json:"failover-retries" yaml:"failover-retries"` // 上游 5xx/429/连接失败时最多转移到其他提供商的次数，0 为不转移
+	Cache           GaiaGatewayCache   `mapstructure:"cache" json:"cache" yaml:"cache"`                                  // 精确匹配响应缓存
+	CircuitBreaker  GaiaCircuitBreaker `mapstructure:"circuit-breaker" json:"circuit-breaker" yaml:"circuit-breaker"`    // 上游熔断
+	Realtime        GaiaRealtime       `mapstructure:"realtime" json:"realtime" yaml:"realtime"`                         // Realtime WebSocket 会话
+}
+
+// GaiaRealtime Realtime WebSocket 会话（/v1/realtime）：按 response.done 逐次扣费，会话进行中定期检查余额，余额耗尽时断开
+type GaiaRealtime struct {
+	BalanceCheckInterval int `mapstructure:"balance-check-interval" json:"balance-check-interval" yaml:"balance-check-interval"` // 余额检查间隔（秒），默认 30
+	MaxDuration          int `mapstructure:"max-duration" json:"max-duration" yaml:"max-duration"`                               // 单个会话最长时长（秒），0 为不限
+}
+
+// GaiaCircuitBreaker 上游熔断：按提供商与提供商下的单条凭证分别统计，连接失败与 5xx 计为失败；
+// 熔断期间直接失败（有候选提供商时转移），到期后半开放行一个探测请求，成功则恢复
+type GaiaCircuitBreaker struct {
+	FailureThreshold int     `mapstructure:"failure-threshold" json:"failure-threshold" yaml:"failure-threshold"` // 连续失败次数达到后熔断，0 为不按连续失败熔断
+	ErrorRate        float64 `mapstructure:"error-rate" json:"error-rate" yaml:"error-rate"`                      // 统计窗口内失败率达到后熔断（0~1），0 为不按失败率熔断
+	MinRequests      int     `mapstructure:"min-requests" json:"min-requests" yaml:"min-requests"`                // 按失败率熔断时窗口内的最少请求数
+	Window           int     `mapstructure:"window" json:"window" yaml:"window"`                                  // 失败率统计窗口（秒），默认 60
+	OpenDuration     int     `mapstructure:"open-duration" json:"open-duration" yaml:"open-duration"`             // 熔断持续时间（秒），默认 30
+}
+
+// GaiaGatewayCache 网关响应缓存：非流式 chat/completions、v1/messages、embeddings 请求按 provider + path + 规范化 body 精确匹配，
+// 请求头 X-Gaia-Cache: true 或模型命中 models 时启用（X-Gaia-Cache: false 可强制跳过）
+type GaiaGatewayCache struct {
+	TTL         int      `mapstructure:"ttl" json:"ttl" yaml:"ttl"`                            // 缓存有效期（秒），0 为关闭缓存
+	MaxBytes    int      `mapstructure:"max-bytes" json:"max-bytes" yaml:"max-bytes"`          // 单条响应最大缓存字节数，超过则不缓存
+	Models      []string `mapstructure:"models" json:"models" yaml:"models"`                   // 默认启用缓存的模型（支持 * 后缀）
+	HitDiscount float64  `mapstructure:"hit-discount" json:"hit-discount" yaml:"hit-discount"` // 命中缓存时按原价的比例计费，0 为免费
 }
@@ -8,6 +8,7 @@ import (
 	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/metrics"
 	"github.com/robfig/cron/v3"
 	"time"
 )
@@ -34,9 +35,11 @@ func Corn() {
 			return
 		}
 		lock = true
+		done := metrics.ObserveCron("sync_user")
 		user := system.UserExtendService{}
 		user.SyncUser()
 		gaia.SyncUserStatus()
+		done()
 		lock = false
 	}); err != nil {
 		global.GVA_LOG.Fatal("Start Cron Error:" + err.Error())
@@ -52,6 +55,7 @@ func Corn() {
 			return
 		}
 		dashService := gaia.DashboardService{}
+		defer metrics.ObserveCron("app_quota_ranking")()
 		// 缓存前3页
 		for i := 1; i <= 3; i++ {
 			req := gaiaReq.GetAppQuotaRankingDataReq{
@@ -79,5 +83,25 @@ func Corn() {
 	}
 	global.GVA_LOG.Info("【定时任务-每6分钟执行1次】同步应用使用分析数据任务，已启动！")
 
+	// 每5分钟结算一次已完成的 Batch（OpenAI / Azure Batch API）
+	var batchLock bool
+	if _, err := c.AddFunc("0 */5 * * * *", func() {
+		if global.GVA_DB == nil || !initDBService.IfInit() {
+			return
+		}
+		if batchLock {
+			return
+		}
+		batchLock = true
+		defer func() { batchLock = false }()
+		defer metrics.ObserveCron("settle_batches")()
+		modelProvider := gaia.ModelProviderService{}
+		modelProvider.SettleBatches()
+	}); err != nil {
+		global.GVA_LOG.Fatal("Start Cron Error:" + err.Error())
+		return
+	}
+	global.GVA_LOG.Info("【定时任务-每5分钟执行1次】Batch 结算任务，已启动！")
+
 	c.Start()
 }
@@ -53,6 +53,17 @@ var tables = []interface{}{
 	gaia.AppVersionDownload{},  // 应用版本各平台安装包
 	gaia.ModelProviderConfig{}, // 模型提供商配置
 	gaia.ModelProxyLog{},       // 模型中转请求日志
+	gaia.GatewayKey{},          // 网关虚拟 API Key
+	gaia.ModelRoute{},          // 模型路由表
+	gaia.CustomProvider{},      // 自定义提供商
+	gaia.ModelBatchFile{},      // Batch 输入文件归属
+	gaia.ModelBatch{},          // Batch 归属与结算
+	gaia.ModelResponse{},       // Responses API 响应归属
+	gaia.DLPRule{},             // 提示词 DLP 规则
+	gaia.DLPAuditLog{},         // 提示词 DLP 命中审计
+	gaia.QuotaHold{},           // 代理请求额度预占表
+	gaia.RateLimitRule{},       // 网关 RPM/TPM 限流规则
+	gaia.ModelAccessRule{},     // 模型访问策略
 	system.SysUserGlobalCode{}, // Extend Global Code
 	// Extend gaia model
 }
@@ -131,6 +142,17 @@ func RegisterTables(db *gorm.DB) {
 		gaia.AppVersionDownload{},  // 应用版本各平台安装包
 		gaia.ModelProviderConfig{}, // 模型提供商配置
 		gaia.ModelProxyLog{},       // 模型中转请求日志
+		gaia.GatewayKey{},          // 网关虚拟 API Key
+		gaia.ModelRoute{},          // 模型路由表
+		gaia.CustomProvider{},      // 自定义提供商
+		gaia.ModelBatchFile{},      // Batch 输入文件归属
+		gaia.ModelBatch{},          // Batch 归属与结算
+		gaia.ModelResponse{},       // Responses API 响应归属
+		gaia.DLPRule{},             // 提示词 DLP 规则
+		gaia.DLPAuditLog{},         // 提示词 DLP 命中审计
+		gaia.QuotaHold{},           // 代理请求额度预占表
+		gaia.RateLimitRule{},       // 网关 RPM/TPM 限流规则
+		gaia.ModelAccessRule{},     // 模型访问策略
 		system.SysUserGlobalCode{}, // Extend Global Code
 	)
 
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAuth /metrics 访问校验：配置了 gaia.metrics-token 时要求 Authorization: Bearer <token>；
+// 未配置时仅允许本机回环地址直连访问（按 TCP 对端地址判断，不信任 X-Forwarded-For），避免指标中的用户与花费信息公开暴露。
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := global.GVA_CONFIG.Gaia.MetricsToken
+		if token == "" {
+			host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+			if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package gaia
+
+import "time"
+
+// 自定义提供商上游鉴权方式
+const (
+	CustomProviderAuthBearer = "bearer"  // Authorization: Bearer <api_key>
+	CustomProviderAuthAPIKey = "api_key" // <auth_header>: <api_key>，auth_header 默认 api-key
+	CustomProviderAuthNone   = "none"    // 不携带鉴权头（内网 vLLM / Ollama 等）
+)
+
+// CustomProviderDefaultAuthHeader api_key 鉴权方式未指定头名时使用的默认头
+const CustomProviderDefaultAuthHeader = "api-key"
+
+// CustomProviderAPIKeyPrefix api_key 列密文前缀：Blowfish 加密（密钥为 jwt.signing-key，与系统集成 AppSecret 相同），无前缀的为旧版明文记录
+const CustomProviderAPIKeyPrefix = "enc:"
+
+// CustomProviderCacheTTL 自定义提供商进程内缓存时长（管理端修改后本实例立即生效，其他实例最迟在 TTL 后生效）
+const CustomProviderCacheTTL = 30 * time.Second
+
+// CustomProvider 自定义提供商表：管理端登记的自建 OpenAI 兼容上游（vLLM / Ollama 等），
+// 与内置提供商一样参与提供商列表、按模型路由、模型发现与计费；启用状态与已选模型仍存于 ModelProviderConfig。
+type CustomProvider struct {
+	Id         uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	Name       string    `json:"name" gorm:"size:32;uniqueIndex;not null;column:name;comment:提供商短名(不可与内置提供商重名)"`
+	BaseURL    string    `json:"base_url" gorm:"size:512;not null;column:base_url;comment:上游根地址(不含 /v1)"`
+	AuthScheme string    `json:"auth_scheme" gorm:"size:16;not null;column:auth_scheme;comment:鉴权方式(bearer/api_key/none)"`
+	AuthHeader string    `json:"auth_header" gorm:"size:64;column:auth_header;comment:api_key 方式使用的请求头名"`
+	APIKey     string    `json:"-" gorm:"type:text;column:api_key;comment:上游 API Key(加密存储)"`
+	KeyMask    string    `json:"key_mask" gorm:"size:32;column:key_mask;comment:脱敏展示（前四位+后四位）"`
+	Models     string    `json:"models" gorm:"type:text;column:models;comment:上游提供的模型列表(JSON数组)"`
+	Pricing    string    `json:"pricing" gorm:"type:text;column:pricing;comment:模型定价(JSON对象，模型名 → ModelPricing)"`
+	Remark     string    `json:"remark" gorm:"size:255;column:remark;comment:备注"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName CustomProvider自定义表名 custom_provider_extend
+func (CustomProvider) TableName() string {
+	return "custom_provider_extend"
+}
@@ -0,0 +1,78 @@
+package gaia
+
+import "time"
+
+// DLP 规则类型
+const (
+	DLPRuleTypeRegex   = "regex"   // pattern 为正则表达式
+	DLPRuleTypeKeyword = "keyword" // pattern 为关键词列表(JSON数组)，不区分大小写
+	DLPRuleTypeBuiltin = "builtin" // pattern 为内置检测器名称
+)
+
+// DLP 命中后的动作
+const (
+	DLPActionRedact = "redact" // 替换为 replacement 后继续转发
+	DLPActionBlock  = "block"  // 拒绝请求，不转发上游
+	DLPActionLog    = "log"    // 仅记录审计
+)
+
+// DLP 内置检测器（带校验位 / Luhn 校验，减少误报）
+const (
+	DLPDetectorCNIDCard = "cn_id_card" // 中国居民身份证号（18 位，校验位）
+	DLPDetectorCNMobile = "cn_mobile"  // 中国大陆手机号（可带 +86）
+	DLPDetectorBankCard = "bank_card"  // 银行卡号（13-19 位，Luhn 校验）
+	DLPDetectorAPIKey   = "api_key"    // 常见 API Key / 访问密钥 / 私钥（OpenAI、AWS、GitHub、Slack、Google 等）
+)
+
+// DLPRuleCacheTTL DLP 规则进程内缓存时长（管理端修改后本实例立即生效，其他实例最迟在 TTL 后生效）；
+// DLPAuditSampleLimit 每条审计记录保留的脱敏样本数上限
+const (
+	DLPRuleCacheTTL     = 30 * time.Second
+	DLPAuditSampleLimit = 5
+)
+
+// DLPRule 提示词 DLP 规则：转发上游前检查 JSON 请求体中的文本，命中后按 action 脱敏、拦截或仅记录。
+// providers / authorities 为空表示对全部提供商 / 角色（用户组）生效；同一请求命中 block 规则时整体拒绝。
+type DLPRule struct {
+	Id          uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	Name        string    `json:"name" gorm:"size:128;not null;column:name;comment:规则名称"`
+	Type        string    `json:"type" gorm:"size:16;not null;column:type;comment:规则类型(regex/keyword/builtin)"`
+	Pattern     string    `json:"pattern" gorm:"type:text;not null;column:pattern;comment:正则 / 关键词列表(JSON数组) / 内置检测器名称"`
+	Action      string    `json:"action" gorm:"size:16;not null;column:action;comment:动作(redact/block/log)"`
+	Replacement string    `json:"replacement" gorm:"size:128;column:replacement;comment:脱敏替换文本(空为[REDACTED])"`
+	Providers   string    `json:"providers" gorm:"type:text;column:providers;comment:生效的提供商(JSON数组，空为全部)"`
+	Authorities string    `json:"authorities" gorm:"type:text;column:authorities;comment:生效的角色(用户组)ID(JSON数组，空为全部)"`
+	Enabled     bool      `json:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Remark      string    `json:"remark" gorm:"size:255;column:remark;comment:备注"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName DLPRule自定义表名 dlp_rule_extend
+func (DLPRule) TableName() string {
+	return "dlp_rule_extend"
+}
+
+// DLPAuditLog DLP 命中审计：每个请求每条命中规则一条记录，只保存脱敏样本与 HMAC 指纹，不保存原文。
+type DLPAuditLog struct {
+	Id           uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	RuleId       uint      `json:"rule_id" gorm:"index;column:rule_id;comment:规则ID"`
+	RuleName     string    `json:"rule_name" gorm:"size:128;column:rule_name;comment:规则名称"`
+	Action       string    `json:"action" gorm:"size:16;index;column:action;comment:执行的动作(redact/block/log)"`
+	AccountId    string    `json:"account_id" gorm:"type:uuid;index;column:account_id;comment:账号ID"`
+	KeyId        uint      `json:"key_id" gorm:"default:0;column:key_id;comment:网关Key ID(JWT / 转发 Token 调用为0)"`
+	Providers    string    `json:"providers" gorm:"size:255;column:providers;comment:候选提供商(逗号分隔)"`
+	ModelName    string    `json:"model_name" gorm:"size:128;column:model_name;comment:模型名"`
+	Path         string    `json:"path" gorm:"size:255;column:path;comment:请求路径"`
+	RequestId    string    `json:"request_id" gorm:"size:64;index;column:request_id;comment:请求ID(X-Request-Id)"`
+	ClientIp     string    `json:"client_ip" gorm:"size:64;column:client_ip;comment:客户端IP"`
+	MatchCount   int       `json:"match_count" gorm:"default:0;column:match_count;comment:命中次数"`
+	Samples      string    `json:"samples" gorm:"type:text;column:samples;comment:脱敏样本(JSON数组)"`
+	Fingerprints string    `json:"fingerprints" gorm:"type:text;column:fingerprints;comment:命中内容的 HMAC-SHA256 指纹(JSON数组，用于关联同一敏感值)"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index;column:created_at;comment:创建时间"`
+}
+
+// TableName DLPAuditLog自定义表名 dlp_audit_log_extend
+func (DLPAuditLog) TableName() string {
+	return "dlp_audit_log_extend"
+}
@@ -0,0 +1,31 @@
+package gaia
+
+import "time"
+
+// GatewayKeyPrefix 网关虚拟 Key 的明文前缀，用于与 JWT / 转发 Token 区分
+const GatewayKeyPrefix = "sk-gaia-"
+
+// GatewayKey 网关虚拟 API Key（每个开发者/服务一个，明文仅创建时返回一次，库中只存 SHA256）
+type GatewayKey struct {
+	Id            uint       `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	AccountId     string     `json:"account_id" gorm:"type:uuid;not null;index;column:account_id;comment:绑定的账号ID"`
+	Name          string     `json:"name" gorm:"size:128;not null;column:name;comment:Key 名称"`
+	KeyHash       string     `json:"-" gorm:"size:64;uniqueIndex;not null;column:key_hash;comment:SHA256(key)"`
+	KeyMask       string     `json:"key_mask" gorm:"size:32;column:key_mask;comment:脱敏展示（前缀+后四位）"`
+	AllowedModels string     `json:"allowed_models" gorm:"type:text;column:allowed_models;comment:允许的模型列表(JSON数组，空为不限)"`
+	AllowedPaths  string     `json:"allowed_paths" gorm:"type:text;column:allowed_paths;comment:允许的路径前缀列表(JSON数组，空为不限)"`
+	QuotaLimit    float64    `json:"quota_limit" gorm:"default:0;column:quota_limit;comment:Key 花费上限(USD，0 为不限)"`
+	UsedQuota     float64    `json:"used_quota" gorm:"default:0;column:used_quota;comment:Key 已用金额(USD)"`
+	Enabled       bool       `json:"enabled" gorm:"default:true;column:enabled;comment:是否启用"`
+	ExpiresAt     *time.Time `json:"expires_at" gorm:"column:expires_at;comment:过期时间(空为永久)"`
+	LastUsedAt    *time.Time `json:"last_used_at" gorm:"column:last_used_at;comment:最后使用时间"`
+	LastUsedIP    string     `json:"last_used_ip" gorm:"size:64;column:last_used_ip;comment:最后使用IP"`
+	CreatedBy     uint       `json:"created_by" gorm:"column:created_by;comment:创建人(sys_users.id)"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName GatewayKey自定义表名 gateway_key_extend
+func (GatewayKey) TableName() string {
+	return "gateway_key_extend"
+}
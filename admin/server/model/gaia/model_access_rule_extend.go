@@ -0,0 +1,32 @@
+package gaia
+
+import "time"
+
+// 模型访问规则的作用范围与效果
+const (
+	ModelAccessScopeAccount   = "account"   // subject 为账号 ID
+	ModelAccessScopeAuthority = "authority" // subject 为角色（用户组）ID
+	ModelAccessEffectAllow    = "allow"
+	ModelAccessEffectDeny     = "deny"
+)
+
+// ModelAccessRule 模型访问策略：按账号 / 角色允许或禁止调用指定模型。
+// 判定顺序：账号规则 > 角色规则 > 全局规则（subject 为空），同一层级 deny 优先；
+// 调用方未命中任何规则时，若该模型存在针对他人的 allow 规则则视为受限模型而拒绝，否则放行。
+type ModelAccessRule struct {
+	Id        uint      `json:"id" gorm:"primarykey;column:id;comment:id;"`
+	Name      string    `json:"name" gorm:"size:128;column:name;comment:规则名称"`
+	Scope     string    `json:"scope" gorm:"size:16;not null;index;column:scope;comment:作用范围(account/authority)"`
+	Subject   string    `json:"subject" gorm:"size:64;column:subject;comment:账号ID或角色ID(空为全部)"`
+	Models    string    `json:"models" gorm:"type:text;not null;column:models;comment:模型列表(JSON数组，支持*后缀)"`
+	Effect    string    `json:"effect" gorm:"size:8;not null;column:effect;comment:效果(allow/deny)"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Remark    string    `json:"remark" gorm:"size:255;column:remark;comment:备注"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName ModelAccessRule自定义表名 model_access_rule_extend
+func (ModelAccessRule) TableName() string {
+	return "model_access_rule_extend"
+}
@@ -0,0 +1,53 @@
+package gaia
+
+import "time"
+
+// ModelBatchFile 经网关上传到上游的 Batch 输入文件：记录所属账号、上传时使用的提供商 / 凭证及文件中请求的模型，
+// 创建 Batch、查询 / 下载 / 删除文件时据此校验归属并固定到同一凭证（上游文件 ID 仅在上传凭证所属组织内有效），
+// 创建 Batch 时按记录的模型校验 Key 范围、访问策略与限流。
+type ModelBatchFile struct {
+	Id           uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	FileId       string    `json:"file_id" gorm:"size:128;not null;uniqueIndex:idx_model_batch_file;column:file_id;comment:上游文件ID"`
+	ProviderName string    `json:"provider_name" gorm:"size:64;not null;uniqueIndex:idx_model_batch_file;column:provider_name;comment:提供商"`
+	CredentialId string    `json:"credential_id" gorm:"size:64;column:credential_id;comment:上传时使用的凭证ID"`
+	AccountId    string    `json:"account_id" gorm:"type:uuid;not null;index;column:account_id;comment:所属账号ID"`
+	KeyId        uint      `json:"key_id" gorm:"default:0;column:key_id;comment:网关Key ID(JWT / 转发 Token 调用为0)"`
+	Purpose      string    `json:"purpose" gorm:"size:32;column:purpose;comment:文件用途(batch 等)"`
+	Bytes        int64     `json:"bytes" gorm:"default:0;column:bytes;comment:文件大小(字节)"`
+	Models       string    `json:"models" gorm:"type:text;column:models;comment:Batch 输入文件中请求的模型(JSON数组，上传时解析)"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+}
+
+// TableName ModelBatchFile自定义表名 model_batch_file_extend
+func (ModelBatchFile) TableName() string {
+	return "model_batch_file_extend"
+}
+
+// ModelBatch 经网关创建的上游 Batch：记录所属账号与使用的提供商 / 凭证，
+// 定时任务轮询到终态后下载输出文件，按逐行 usage 汇总并按 Batch 折扣价扣费（BilledAt 非空表示已结算）。
+type ModelBatch struct {
+	Id             uint       `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	BatchId        string     `json:"batch_id" gorm:"size:128;not null;uniqueIndex:idx_model_batch;column:batch_id;comment:上游Batch ID"`
+	ProviderName   string     `json:"provider_name" gorm:"size:64;not null;uniqueIndex:idx_model_batch;column:provider_name;comment:提供商"`
+	CredentialId   string     `json:"credential_id" gorm:"size:64;column:credential_id;comment:创建时使用的凭证ID"`
+	AccountId      string     `json:"account_id" gorm:"type:uuid;not null;index;column:account_id;comment:所属账号ID"`
+	KeyId          uint       `json:"key_id" gorm:"default:0;column:key_id;comment:网关Key ID(JWT / 转发 Token 调用为0)"`
+	Endpoint       string     `json:"endpoint" gorm:"size:64;column:endpoint;comment:Batch 请求的接口(如 /v1/chat/completions)"`
+	InputFileId    string     `json:"input_file_id" gorm:"size:128;column:input_file_id;comment:输入文件ID"`
+	OutputFileId   string     `json:"output_file_id" gorm:"size:128;index;column:output_file_id;comment:输出文件ID"`
+	ErrorFileId    string     `json:"error_file_id" gorm:"size:128;index;column:error_file_id;comment:错误文件ID"`
+	Status         string     `json:"status" gorm:"size:32;index;column:status;comment:上游状态(validating/in_progress/completed/failed/expired/cancelled 等)"`
+	RequestTotal   int        `json:"request_total" gorm:"default:0;column:request_total;comment:请求总数"`
+	RequestTokens  int        `json:"request_tokens" gorm:"default:0;column:request_tokens;comment:结算的请求token数"`
+	ResponseTokens int        `json:"response_tokens" gorm:"default:0;column:response_tokens;comment:结算的响应token数"`
+	CostUsd        float64    `json:"cost_usd" gorm:"default:0;column:cost_usd;comment:结算扣费金额(USD)"`
+	BilledAt       *time.Time `json:"billed_at" gorm:"index;column:billed_at;comment:结算时间(为空表示未结算)"`
+	SettleError    string     `json:"settle_error" gorm:"type:text;column:settle_error;comment:最近一次结算失败原因"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName ModelBatch自定义表名 model_batch_extend
+func (ModelBatch) TableName() string {
+	return "model_batch_extend"
+}
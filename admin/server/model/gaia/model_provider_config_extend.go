@@ -4,16 +4,53 @@ import "time"
 
 // ModelPricing 从 Dify Console API 拉取的模型定价信息（对应 pricing 字段）
 type ModelPricing struct {
-	Input    float64 `json:"input"`    // 每 unit 的输入单价
-	Output   float64 `json:"output"`   // 每 unit 的输出单价（0 表示与 Input 相同或不区分）
-	Unit     float64 `json:"unit"`     // 计费单位（通常 0.001，即每千 token）
-	Currency string  `json:"currency"` // 货币（USD / RMB）
+	Input      float64 `json:"input"`                 // 每 unit 的输入单价
+	Output     float64 `json:"output"`                // 每 unit 的输出单价（0 表示与 Input 相同或不区分）
+	CacheRead  float64 `json:"cache_read,omitempty"`  // 每 unit 的缓存命中输入单价（0 表示按 Input 计）
+	CacheWrite float64 `json:"cache_write,omitempty"` // 每 unit 的缓存写入输入单价（0 表示按 Input 计）
+	Reasoning  float64 `json:"reasoning,omitempty"`   // 每 unit 的推理输出单价（0 表示按 Output 计）
+	// Realtime 等多模态模型的音频 token 单价（0 表示分别按 Input / Output 计）
+	AudioInput  float64 `json:"audio_input,omitempty"`
+	AudioOutput float64 `json:"audio_output,omitempty"`
+	Unit        float64 `json:"unit"`     // 计费单位（通常 0.001，即每千 token）
+	Currency    string  `json:"currency"` // 货币（USD / RMB）
+
+	// 非 token 计量接口的单价（与 Unit 无关，直接按 Currency 计）
+	Images    map[string]float64 `json:"images,omitempty"`      // 图片按张单价，key 为「质量|尺寸」，* 匹配任意，如 "hd|1024x1792"、"*|*"
+	PerKChars float64            `json:"per_k_chars,omitempty"` // 语音合成：每千输入字符单价
+	PerMinute float64            `json:"per_minute,omitempty"`  // 语音识别 / 翻译：每分钟音频单价
 }
 
 // ModelUsage OpenAI 格式响应中的 usage 字段（非流式及流式末尾行）
 type ModelUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails OpenAI usage.prompt_tokens_details（cached_tokens 已包含在 prompt_tokens 内）
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CompletionTokensDetails OpenAI usage.completion_tokens_details（reasoning_tokens 已包含在 completion_tokens 内）
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// TokenUsage 归一化后的 token 用量，按类别分别计费：
+// PromptTokens 为全部输入（含缓存命中与缓存写入），CompletionTokens 为全部输出（含推理）。
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CacheReadTokens  int // 缓存命中的输入 token（OpenAI cached_tokens / Anthropic cache_read_input_tokens / Gemini cachedContentTokenCount）
+	CacheWriteTokens int // 写入缓存的输入 token（Anthropic cache_creation_input_tokens）
+	ReasoningTokens  int // 推理输出 token（OpenAI reasoning_tokens / Gemini thoughtsTokenCount）
+
+	AudioInputTokens  int // 未命中缓存的音频输入 token（OpenAI Realtime input_token_details.audio_tokens 扣除缓存部分）
+	AudioOutputTokens int // 音频输出 token（OpenAI Realtime output_token_details.audio_tokens）
 }
 
 // ModelUsageResponse OpenAI 格式响应体（仅用于提取 usage 字段）
@@ -61,17 +98,38 @@ func (ModelProviderConfig) TableName() string {
 	return "model_provider_config_extend"
 }
 
+// ProviderBalanceConfig 提供商多凭证负载均衡配置（存于 ModelProviderConfig.Config）
+type ProviderBalanceConfig struct {
+	BalanceStrategy   string         `json:"balance_strategy,omitempty"`   // weighted_round_robin / least_inflight
+	CredentialWeights map[string]int `json:"credential_weights,omitempty"` // 凭证 ID → 权重，未配置视为 1，0 表示不参与轮询
+}
+
 // ModelProxyLog 模型中转请求日志表
 type ModelProxyLog struct {
-	Id             uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
-	UserId         string    `json:"user_id" gorm:"type:uuid;not null;column:user_id;comment:用户ID"`
-	ProviderName   string    `json:"provider_name" gorm:"column:provider_name;comment:提供商"`
-	ModelName      string    `json:"model_name" gorm:"column:model_name;comment:模型名"`
-	RequestTokens  int       `json:"request_tokens" gorm:"column:request_tokens;comment:请求token数"`
-	ResponseTokens int       `json:"response_tokens" gorm:"column:response_tokens;comment:响应token数"`
-	Status         string    `json:"status" gorm:"column:status;comment:状态"`
-	ErrorMessage   string    `json:"error_message" gorm:"type:text;column:error_message;comment:错误信息"`
-	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	Id                uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	UserId            string    `json:"user_id" gorm:"type:uuid;not null;column:user_id;comment:用户ID"`
+	ProviderName      string    `json:"provider_name" gorm:"column:provider_name;comment:提供商"`
+	ModelName         string    `json:"model_name" gorm:"column:model_name;comment:模型名"`
+	CredentialId      string    `json:"credential_id" gorm:"size:64;column:credential_id;comment:本次使用的凭证ID"`
+	FailoverChain     string    `json:"failover_chain" gorm:"type:text;column:failover_chain;comment:失败转移链路(如 anthropic:529,aws)"`
+	RequestTokens     int       `json:"request_tokens" gorm:"column:request_tokens;comment:请求token数"`
+	ResponseTokens    int       `json:"response_tokens" gorm:"column:response_tokens;comment:响应token数"`
+	CacheReadTokens   int       `json:"cache_read_tokens" gorm:"default:0;column:cache_read_tokens;comment:缓存命中token数(含于请求token)"`
+	CacheWriteTokens  int       `json:"cache_write_tokens" gorm:"default:0;column:cache_write_tokens;comment:缓存写入token数(含于请求token)"`
+	ReasoningTokens   int       `json:"reasoning_tokens" gorm:"default:0;column:reasoning_tokens;comment:推理token数(含于响应token)"`
+	AudioInputTokens  int       `json:"audio_input_tokens" gorm:"default:0;column:audio_input_tokens;comment:音频输入token数(含于请求token)"`
+	AudioOutputTokens int       `json:"audio_output_tokens" gorm:"default:0;column:audio_output_tokens;comment:音频输出token数(含于响应token)"`
+	CacheHit          bool      `json:"cache_hit" gorm:"default:false;column:cache_hit;comment:是否命中网关响应缓存"`
+	DurationMs        int64     `json:"duration_ms" gorm:"default:0;column:duration_ms;comment:请求耗时(毫秒)"`
+	TtfbMs            int64     `json:"ttfb_ms" gorm:"default:0;column:ttfb_ms;comment:上游首字节耗时(毫秒)"`
+	UpstreamStatus    int       `json:"upstream_status" gorm:"default:0;column:upstream_status;comment:上游HTTP状态码(0为未响应或命中缓存)"`
+	CostUsd           float64   `json:"cost_usd" gorm:"default:0;column:cost_usd;comment:本次扣费金额(USD)"`
+	RequestId         string    `json:"request_id" gorm:"size:64;index;column:request_id;comment:请求ID(X-Request-Id)"`
+	Stream            bool      `json:"stream" gorm:"default:false;column:stream;comment:是否流式请求"`
+	ClientIp          string    `json:"client_ip" gorm:"size:64;column:client_ip;comment:客户端IP"`
+	Status            string    `json:"status" gorm:"column:status;comment:状态(success/error/cancelled)"`
+	ErrorMessage      string    `json:"error_message" gorm:"type:text;column:error_message;comment:错误信息"`
+	CreatedAt         time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
 }
 
 // TableName ModelProxyLog自定义表名 model_proxy_log
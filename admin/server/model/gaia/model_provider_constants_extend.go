@@ -1,5 +1,7 @@
 package gaia
 
+import "time"
+
 // 模型提供商逻辑名称（列表展示与内部 key）
 const (
 	ProviderOpenai    = "openai"
@@ -9,7 +11,8 @@ const (
 	ProviderAzure     = "azure"
 	ProviderZhipuai   = "zhipuai"
 	ProviderMinimax   = "minimax"
-	ProviderAWS       = "aws" // AWS Bedrock 渠道（用于转发 Claude 等 Anthropic 模型）
+	ProviderAWS       = "aws"    // AWS Bedrock 渠道（Claude 走 InvokeModel，Nova / Llama / Mistral 等走 Converse）
+	ProviderVertex    = "vertex" // Google Vertex AI 渠道（服务账号鉴权，Gemini 走 generateContent，Claude 走 rawPredict）
 )
 
 // DifyProviderTypeCustom Dify providers 表 provider_type 枚举
@@ -22,12 +25,20 @@ const (
 	ConfigKeyOpenaiAPIVersion = "openai_api_version"
 	ConfigKeyDashScopeAPIKey  = "dashscope_api_key"
 	ConfigKeyAPIKey           = "api_key"
+	// AWS Bedrock（Dify bedrock 插件凭证字段）
+	ConfigKeyAWSAccessKeyID     = "aws_access_key_id"
+	ConfigKeyAWSSecretAccessKey = "aws_secret_access_key"
+	ConfigKeyAWSRegion          = "aws_region"
+	// Google Vertex AI（Dify vertex_ai 插件凭证字段）：服务账号 JSON key 为 base64 编码
+	ConfigKeyVertexProjectID         = "vertex_project_id"
+	ConfigKeyVertexLocation          = "vertex_location"
+	ConfigKeyVertexServiceAccountKey = "vertex_service_account_key"
 )
 
 // SupportedProviders 列表展示的提供商顺序
-var SupportedProviders = []string{ProviderOpenai, ProviderTongyi, ProviderGoogle, ProviderAnthropic, ProviderAWS, ProviderAzure, ProviderZhipuai, ProviderMinimax}
+var SupportedProviders = []string{ProviderOpenai, ProviderTongyi, ProviderGoogle, ProviderVertex, ProviderAnthropic, ProviderAWS, ProviderAzure, ProviderZhipuai, ProviderMinimax}
 
-// DefaultChatCompletionsEndpoints 各提供商聊天接口默认完整 URL（兼容旧 ProxyChat）
+// DefaultChatCompletionsEndpoints 各提供商聊天接口默认完整 URL（未配置 openai_api_base 时使用）
 var DefaultChatCompletionsEndpoints = map[string]string{
 	ProviderOpenai:  "https://api.openai.com/v1/chat/completions",
 	ProviderTongyi:  "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions",
@@ -55,22 +66,99 @@ var CredentialKeyFallback = []string{ConfigKeyOpenaiAPIKey, ConfigKeyAPIKey, Con
 // RmbToUSDRate 人民币兑美元汇率
 const RmbToUSDRate = 7.26
 
-// DefaultImageGenerationPriceUSD 图片生成等按次计费接口的默认单价（USD），无 usage 时使用
+// DefaultImageGenerationPriceUSD 图片生成、语音等接口无法按张数 / 字符 / 时长计量时的每次请求默认单价（USD）
 const DefaultImageGenerationPriceUSD = 0.04
 
+// ProxyLogExportMaxRows 代理日志单次导出的最大行数
+const ProxyLogExportMaxRows = 50000
+
 // DefaultQuotaFallbackUSDPerToken 未命中定价时的兜底单价：每 token 的 USD 金额（仅做记账占位，约 $0.001/千 token）
 const DefaultQuotaFallbackUSDPerToken = 0.000001
 
+// RateLimitWindow 网关 RPM/TPM 限流的滑动窗口长度；RateLimitRuleCacheTTL 限流规则的进程内缓存时间
+const (
+	RateLimitWindow       = time.Minute
+	RateLimitRuleCacheTTL = 30 * time.Second
+)
+
+// ModelAccessRuleCacheTTL 模型访问规则的进程内缓存时间
+const ModelAccessRuleCacheTTL = 30 * time.Second
+
+// 额度预占：请求未指定 max_tokens 时按 QuotaHoldDefaultMaxTokens 预估输出；
+// 预占超过 QuotaHoldTTL 未结算（上游超时为 5 分钟）视为遗留，不再计入可用余额。
+const (
+	QuotaHoldDefaultMaxTokens = 4096
+	QuotaHoldTTL              = 10 * time.Minute
+)
+
 // Gaia 相关 Redis Key（GVA_REDIS / GVA_Dify_REDIS）
 const (
-	RedisKeyGaiaAdminConsoleToken          = "gaia:admin_console_token"
-	RedisKeyGaiaModelPricingPrefix         = "gaia:model_pricing:"
-	RedisKeyGaiaForwardDingPrefix          = "gaia:forward:ding:"
-	RedisKeyModelProviderCredentialsPrefix = "model_provider_credentials:"
+	RedisKeyGaiaAdminConsoleToken             = "gaia:admin_console_token"
+	RedisKeyGaiaModelPricingPrefix            = "gaia:model_pricing:"
+	RedisKeyGaiaForwardDingPrefix             = "gaia:forward:ding:"
+	RedisKeyGaiaRateLimitPrefix               = "gaia:ratelimit:"
+	RedisKeyGaiaResponseCachePrefix           = "gaia:resp_cache:"
+	RedisKeyModelProviderCredentialsPrefix    = "model_provider_credentials:"
+	RedisKeyModelProviderCredentialListPrefix = "model_provider_credential_list:"
+)
+
+// 多凭证负载均衡策略（ModelProviderConfig.Config 中的 balance_strategy）
+const (
+	BalanceStrategyWeightedRoundRobin = "weighted_round_robin" // 加权轮询（默认）
+	BalanceStrategyLeastInflight      = "least_inflight"       // 最少在途请求
+)
+
+// 凭证被上游拒绝后的冷却时长：401/403 多为 Key 失效，冷却更久；429 为限流，优先使用 Retry-After
+const (
+	CredentialCooldownUnauthorized = 5 * time.Minute
+	CredentialCooldownRateLimited  = 30 * time.Second
+)
+
+// 上游熔断器状态
+const (
+	CircuitStateClosed   = "closed"    // 正常放行
+	CircuitStateOpen     = "open"      // 熔断中，直接失败
+	CircuitStateHalfOpen = "half_open" // 半开，仅放行一个探测请求
+)
+
+// Anthropic Messages 协议常量
+const (
+	AnthropicAPIVersion       = "2023-06-01"         // 直连 api.anthropic.com 的 anthropic-version 头
+	BedrockAnthropicVersion   = "bedrock-2023-05-31" // Bedrock InvokeModel body 中的 anthropic_version
+	VertexAnthropicVersion    = "vertex-2023-10-16"  // Vertex AI rawPredict body 中的 anthropic_version
+	AnthropicDefaultMaxTokens = 4096                 // OpenAI 请求未指定 max_tokens 时的默认值（Anthropic 必填）
+)
+
+// Batch API：上游对 Batch 请求按同步价格的折扣计费（OpenAI / Azure 均为 50%）；每轮结算的 Batch 数量上限
+const (
+	BatchPricingDiscount = 0.5
+	BatchSettleLimit     = 50
 )
 
+// BatchTerminalStatuses Batch 终态：到达后输出文件不再变化，可下载结算
+var BatchTerminalStatuses = []string{"completed", "failed", "expired", "cancelled"}
+
+// OpenAI Realtime（WebSocket）协议常量
+const (
+	AzureRealtimeAPIVersion             = "2024-10-01-preview" // Azure Realtime 客户端未指定 api-version 时使用
+	RealtimeHandshakeTimeout            = 30 * time.Second     // 与上游建立 WebSocket 的握手超时
+	RealtimeDefaultBalanceCheckInterval = 30 * time.Second     // 会话中余额检查默认间隔
+)
+
+// Google Vertex AI：服务账号 JWT 换取 OAuth access token，token 在过期前 VertexTokenRefreshBefore 内重新获取
+const (
+	VertexDefaultLocation    = "us-central1"
+	VertexDefaultTokenURI    = "https://oauth2.googleapis.com/token"
+	VertexOAuthScope         = "https://www.googleapis.com/auth/cloud-platform"
+	VertexTokenRefreshBefore = 5 * time.Minute
+)
+
+// ModelRouteCacheTTL 模型路由进程内缓存时长（管理端修改后本实例立即生效，其他实例最迟在 TTL 后生效）
+const ModelRouteCacheTTL = 30 * time.Second
+
 // BuiltinModelPricing 内置兜底定价表（当 Dify Console API 未返回该模型定价时使用）。
 // 价格单位：每千 token（与 ModelPricing.Unit=0.001 对应），货币为各模型实际结算货币。
+// CacheRead / CacheWrite / Reasoning 未配置时分别按 Input / Input / Output 计费。
 // 通义/百炼模型官方定价（人民币，参考 https://help.aliyun.com/document_detail/2586379.html）：
 //   - 输入/输出价格均为「每百万 token」，换算为每千 token 时除以 1000。
 var BuiltinModelPricing = map[string]ModelPricing{
@@ -108,18 +196,65 @@ var BuiltinModelPricing = map[string]ModelPricing{
 
 	// ──── Anthropic Claude 系列（USD / 百万 token） ────
 	// Claude 4.6 / 4.7 系列（Sonnet 与 Opus）；anthropic 直连与 AWS Bedrock 走同一份定价
-	"claude-sonnet-4-6": {Input: 3.0 / 1000, Output: 15.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"claude-sonnet-4-7": {Input: 3.0 / 1000, Output: 15.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"claude-opus-4-6":   {Input: 15.0 / 1000, Output: 75.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"claude-opus-4-7":   {Input: 15.0 / 1000, Output: 75.0 / 1000, Unit: 0.001, Currency: "USD"},
+	// 提示缓存：命中按输入价 0.1 倍，写入（5 分钟）按输入价 1.25 倍
+	"claude-sonnet-4-6": {Input: 3.0 / 1000, Output: 15.0 / 1000, CacheRead: 0.3 / 1000, CacheWrite: 3.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"claude-sonnet-4-7": {Input: 3.0 / 1000, Output: 15.0 / 1000, CacheRead: 0.3 / 1000, CacheWrite: 3.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"claude-opus-4-6":   {Input: 15.0 / 1000, Output: 75.0 / 1000, CacheRead: 1.5 / 1000, CacheWrite: 18.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"claude-opus-4-7":   {Input: 15.0 / 1000, Output: 75.0 / 1000, CacheRead: 1.5 / 1000, CacheWrite: 18.75 / 1000, Unit: 0.001, Currency: "USD"},
 	// AWS Bedrock 上常用的模型 ID 形式（带 anthropic. 前缀与 -v1:0 后缀），单独列出避免前缀匹配漂移
-	"anthropic.claude-sonnet-4-6-v1:0": {Input: 3.0 / 1000, Output: 15.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"anthropic.claude-sonnet-4-7-v1:0": {Input: 3.0 / 1000, Output: 15.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"anthropic.claude-opus-4-6-v1:0":   {Input: 15.0 / 1000, Output: 75.0 / 1000, Unit: 0.001, Currency: "USD"},
-	"anthropic.claude-opus-4-7-v1:0":   {Input: 15.0 / 1000, Output: 75.0 / 1000, Unit: 0.001, Currency: "USD"},
-
-	// ──── OpenAI 图片生成（按次计费，Input 字段表示「每次请求的 USD 单价」） ────
-	// 命中分支见 service/gaia/model_provider.go 中的 isImageOrPerRequestPath 与 ProxyRequest 计费逻辑
-	"gpt-image-1": {Input: 0.04, Currency: "USD"},
+	"anthropic.claude-sonnet-4-6-v1:0": {Input: 3.0 / 1000, Output: 15.0 / 1000, CacheRead: 0.3 / 1000, CacheWrite: 3.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"anthropic.claude-sonnet-4-7-v1:0": {Input: 3.0 / 1000, Output: 15.0 / 1000, CacheRead: 0.3 / 1000, CacheWrite: 3.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"anthropic.claude-opus-4-6-v1:0":   {Input: 15.0 / 1000, Output: 75.0 / 1000, CacheRead: 1.5 / 1000, CacheWrite: 18.75 / 1000, Unit: 0.001, Currency: "USD"},
+	"anthropic.claude-opus-4-7-v1:0":   {Input: 15.0 / 1000, Output: 75.0 / 1000, CacheRead: 1.5 / 1000, CacheWrite: 18.75 / 1000, Unit: 0.001, Currency: "USD"},
+
+	// ──── AWS Bedrock 非 Anthropic 模型（Converse 接口） ────
+	"amazon.nova-micro-v1:0":          {Input: 0.035 / 1000, Output: 0.14 / 1000, Unit: 0.001, Currency: "USD"},
+	"amazon.nova-lite-v1:0":           {Input: 0.06 / 1000, Output: 0.24 / 1000, Unit: 0.001, Currency: "USD"},
+	"amazon.nova-pro-v1:0":            {Input: 0.8 / 1000, Output: 3.2 / 1000, Unit: 0.001, Currency: "USD"},
+	"meta.llama3-3-70b-instruct-v1:0": {Input: 0.72 / 1000, Output: 0.72 / 1000, Unit: 0.001, Currency: "USD"},
+	"meta.llama3-1-8b-instruct-v1:0":  {Input: 0.22 / 1000, Output: 0.22 / 1000, Unit: 0.001, Currency: "USD"},
+	"mistral.mistral-large-2407-v1:0": {Input: 2.0 / 1000, Output: 6.0 / 1000, Unit: 0.001, Currency: "USD"},
+	"mistral.mistral-small-2402-v1:0": {Input: 1.0 / 1000, Output: 3.0 / 1000, Unit: 0.001, Currency: "USD"},
+
+	// ──── Embeddings（按 usage.total_tokens 计为输入 token） ────
+	"text-embedding-3-small": {Input: 0.02 / 1000, Unit: 0.001, Currency: "USD"},
+	"text-embedding-3-large": {Input: 0.13 / 1000, Unit: 0.001, Currency: "USD"},
+	"text-embedding-ada-002": {Input: 0.10 / 1000, Unit: 0.001, Currency: "USD"},
+	"text-embedding-v3":      {Input: 0.0005, Unit: 0.001, Currency: "RMB"},
+	"text-embedding-v4":      {Input: 0.0005, Unit: 0.001, Currency: "RMB"},
+
+	// ──── OpenAI 图片生成（按张 × 尺寸 × 质量计费，USD / 张） ────
+	// 计量逻辑见 service/gaia/endpoint_billing_extend.go；未命中 Images 分档时 Input 表示每张单价
+	// 质量未指定（auto）时按 medium / standard 计
+	"gpt-image-1": {Input: 0.04, Currency: "USD", Images: map[string]float64{
+		"low|1024x1024": 0.011, "low|*": 0.016,
+		"medium|1024x1024": 0.042, "medium|*": 0.063,
+		"high|1024x1024": 0.167, "high|*": 0.25,
+		"*|1024x1024": 0.042, "*|*": 0.063,
+	}},
 	"gpt-image-2": {Input: 0.05, Currency: "USD"},
+	"dall-e-3": {Input: 0.04, Currency: "USD", Images: map[string]float64{
+		"hd|1024x1024": 0.08, "hd|*": 0.12,
+		"*|1024x1024": 0.04, "*|*": 0.08,
+	}},
+	"dall-e-2": {Input: 0.02, Currency: "USD", Images: map[string]float64{
+		"*|256x256": 0.016, "*|512x512": 0.018, "*|*": 0.02,
+	}},
+
+	// ──── OpenAI Realtime（USD / 百万 token，文本与音频分别计价，缓存命中不区分模态） ────
+	"gpt-realtime": {Input: 4.0 / 1000, Output: 16.0 / 1000, CacheRead: 0.4 / 1000,
+		AudioInput: 32.0 / 1000, AudioOutput: 64.0 / 1000, Unit: 0.001, Currency: "USD"},
+	"gpt-4o-realtime-preview": {Input: 5.0 / 1000, Output: 20.0 / 1000, CacheRead: 2.5 / 1000,
+		AudioInput: 40.0 / 1000, AudioOutput: 80.0 / 1000, Unit: 0.001, Currency: "USD"},
+	"gpt-4o-mini-realtime-preview": {Input: 0.6 / 1000, Output: 2.4 / 1000, CacheRead: 0.3 / 1000,
+		AudioInput: 10.0 / 1000, AudioOutput: 20.0 / 1000, Unit: 0.001, Currency: "USD"},
+
+	// ──── OpenAI 语音合成（按输入字符计费，USD / 千字符） ────
+	"tts-1":    {PerKChars: 0.015, Currency: "USD"},
+	"tts-1-hd": {PerKChars: 0.03, Currency: "USD"},
+
+	// ──── OpenAI 语音识别 / 翻译（按音频时长计费，USD / 分钟） ────
+	"whisper-1":              {PerMinute: 0.006, Currency: "USD"},
+	"gpt-4o-transcribe":      {PerMinute: 0.006, Currency: "USD"},
+	"gpt-4o-mini-transcribe": {PerMinute: 0.003, Currency: "USD"},
 }
@@ -0,0 +1,20 @@
+package gaia
+
+import "time"
+
+// ModelResponse 经网关创建并由上游保存（store 默认开启）的 Responses API 响应：记录所属账号与使用的提供商 / 凭证，
+// 查询 / 删除 / 取消已有响应、以 previous_response_id 续接对话时据此校验归属并固定到同一凭证（上游响应 ID 仅在该凭证所属组织内有效）。
+type ModelResponse struct {
+	Id           uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	ResponseId   string    `json:"response_id" gorm:"size:128;not null;uniqueIndex:idx_model_response;column:response_id;comment:上游响应ID"`
+	ProviderName string    `json:"provider_name" gorm:"size:64;not null;uniqueIndex:idx_model_response;column:provider_name;comment:提供商"`
+	CredentialId string    `json:"credential_id" gorm:"size:64;column:credential_id;comment:创建时使用的凭证ID"`
+	AccountId    string    `json:"account_id" gorm:"type:uuid;not null;index;column:account_id;comment:所属账号ID"`
+	KeyId        uint      `json:"key_id" gorm:"default:0;column:key_id;comment:网关Key ID(JWT / 转发 Token 调用为0)"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+}
+
+// TableName ModelResponse自定义表名 model_response_extend
+func (ModelResponse) TableName() string {
+	return "model_response_extend"
+}
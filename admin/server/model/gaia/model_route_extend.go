@@ -0,0 +1,32 @@
+package gaia
+
+import "time"
+
+// 模型路由匹配方式
+const (
+	ModelRouteMatchExact  = "exact"  // 模型名完全一致（不区分大小写）
+	ModelRouteMatchPrefix = "prefix" // 模型名以 pattern 开头
+	ModelRouteMatchRegex  = "regex"  // 正则匹配
+	ModelRouteMatchAlias  = "alias"  // 别名（如 fast → qwen3.5-turbo），按 exact 匹配并改写为 upstream_model
+)
+
+// ModelRoute 模型路由表：按模型名规则映射到有序的提供商列表与上游模型 ID，优先于代码内置的名称推断规则
+type ModelRoute struct {
+	Id             uint      `json:"id" form:"id" gorm:"primarykey;column:id;comment:id;"`
+	Name           string    `json:"name" gorm:"size:128;column:name;comment:规则名称"`
+	MatchType      string    `json:"match_type" gorm:"size:16;not null;column:match_type;comment:匹配方式(exact/prefix/regex/alias)"`
+	Pattern        string    `json:"pattern" gorm:"size:255;not null;column:pattern;comment:模型名规则"`
+	Providers      string    `json:"providers" gorm:"type:text;column:providers;comment:有序提供商列表(JSON数组，前者优先)"`
+	UpstreamModel  string    `json:"upstream_model" gorm:"size:255;column:upstream_model;comment:转发时改写的上游模型ID(空为不改写)"`
+	ProviderModels string    `json:"provider_models" gorm:"type:text;column:provider_models;comment:按提供商指定上游模型ID(JSON对象，优先于 upstream_model)"`
+	Priority       int       `json:"priority" gorm:"default:0;column:priority;comment:优先级(越大越先匹配)"`
+	Enabled        bool      `json:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Remark         string    `json:"remark" gorm:"size:255;column:remark;comment:备注"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName ModelRoute自定义表名 model_route_extend
+func (ModelRoute) TableName() string {
+	return "model_route_extend"
+}
@@ -0,0 +1,20 @@
+package gaia
+
+import "time"
+
+// QuotaHold 代理请求的额度预占：请求上游前按最大可能花费预占，完成后按实际花费结算并删除，失败时直接释放。
+// 可用余额 = total_quota - used_quota - 未过期预占之和；过期预占（进程崩溃等未释放的）不再计入。
+type QuotaHold struct {
+	Id        uint      `json:"id" gorm:"primarykey;column:id;comment:id;"`
+	AccountId string    `json:"account_id" gorm:"type:uuid;not null;index;column:account_id;comment:账号ID"`
+	KeyId     uint      `json:"key_id" gorm:"default:0;index;column:key_id;comment:网关Key ID(0 为非Key调用)"`
+	Amount    float64   `json:"amount" gorm:"not null;column:amount;comment:预占金额(USD)"`
+	ModelName string    `json:"model_name" gorm:"size:128;column:model_name;comment:模型名称"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;column:expires_at;comment:过期时间"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+}
+
+// TableName QuotaHold自定义表名 quota_hold_extend
+func (QuotaHold) TableName() string {
+	return "quota_hold_extend"
+}
@@ -0,0 +1,29 @@
+package gaia
+
+import "time"
+
+// 限流规则作用范围
+const (
+	RateLimitScopeAccount   = "account"   // 按账号计数：subject 为账号 ID，空表示对每个账号分别生效
+	RateLimitScopeAuthority = "authority" // 按角色（用户组）计数：subject 为角色 ID，空表示对每个角色分别生效；同组账号共享额度
+)
+
+// RateLimitRule 模型网关 RPM/TPM 限流规则（Redis 滑动窗口计数，一分钟窗口）
+type RateLimitRule struct {
+	Id        uint      `json:"id" gorm:"primarykey;column:id;comment:id;"`
+	Name      string    `json:"name" gorm:"size:128;column:name;comment:规则名称"`
+	Scope     string    `json:"scope" gorm:"size:16;not null;index;column:scope;comment:作用范围(account/authority)"`
+	Subject   string    `json:"subject" gorm:"size:64;column:subject;comment:账号ID或角色ID(空为该范围内全部)"`
+	Models    string    `json:"models" gorm:"type:text;column:models;comment:适用模型(JSON数组，支持*后缀，空为全部)"`
+	Rpm       int       `json:"rpm" gorm:"default:0;column:rpm;comment:每分钟请求数(0 为不限)"`
+	Tpm       int       `json:"tpm" gorm:"default:0;column:tpm;comment:每分钟token数(0 为不限)"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled;comment:是否启用"`
+	Remark    string    `json:"remark" gorm:"size:255;column:remark;comment:备注"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;comment:更新时间"`
+}
+
+// TableName RateLimitRule自定义表名 rate_limit_rule_extend
+func (RateLimitRule) TableName() string {
+	return "rate_limit_rule_extend"
+}
@@ -1,18 +1,186 @@
 package request
 
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// ProxyLogFilter 代理日志筛选条件（查询、统计、导出共用）
+type ProxyLogFilter struct {
+	UserId    string `form:"user_id" binding:"omitempty,uuid"` // 账号 ID
+	Provider  string `form:"provider"`                         // 提供商
+	Model     string `form:"model"`                            // 模型名（模糊匹配）
+	Status    string `form:"status"`                           // success / error / cancelled
+	Keyword   string `form:"keyword"`                          // 错误信息关键字
+	StartTime string `form:"start_time"`                       // 开始时间（2006-01-02 / 2006-01-02 15:04:05 / RFC3339）
+	EndTime   string `form:"end_time"`                         // 结束时间，仅日期时包含当天
+}
+
 // GetProxyLogsReq 代理日志分页请求
 type GetProxyLogsReq struct {
+	ProxyLogFilter
 	Page     int `form:"page"`      // 页码，从 1 开始
 	PageSize int `form:"page_size"` // 每页条数，最大 100
 }
 
-// ChatRequest 聊天请求（OpenAI 兼容）
-type ChatRequest struct {
-	Model       string                   `json:"model"`
-	Messages    []map[string]interface{} `json:"messages"`
-	Stream      bool                     `json:"stream"`
-	Temperature float64                  `json:"temperature,omitempty"`
-	MaxTokens   int                      `json:"max_tokens,omitempty"`
-	Tools       []map[string]interface{} `json:"tools,omitempty"`
-	ToolChoice  interface{}              `json:"tool_choice,omitempty"`
+// GetProxyLogStatsReq 代理日志统计请求
+type GetProxyLogStatsReq struct {
+	ProxyLogFilter
+	GroupBy string `form:"group_by" binding:"omitempty,oneof=user model provider day"` // 分组维度，空为仅汇总
+}
+
+// ExportProxyLogsReq 代理日志导出请求
+type ExportProxyLogsReq struct {
+	ProxyLogFilter
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx"` // 导出格式，默认 xlsx
+}
+
+// ProxyCaller 代理调用方信息（由 handler 鉴权后填充，贯穿转发与计费）
+type ProxyCaller struct {
+	AccountId   string   // Dify 账号 ID（计费主体）
+	KeyId       uint     // 网关虚拟 Key ID，0 表示 JWT / 转发 Token 调用
+	HoldId      uint     // 额度预占 ID，扣费时一并结算；0 表示未预占
+	AuthorityId uint     // 账号所属角色（用户组）ID，0 表示未知（按需从 sys_users 查询）
+	TPMCounters []string // 命中的 TPM 限流计数 key 前缀，响应结束后按实际 token 累加
+	RequestId   string   // 请求 ID（客户端 X-Request-Id 或网关生成），写入代理日志
+	ClientIP    string   // 客户端 IP
+}
+
+// GetGatewayKeysReq 网关 Key 分页请求
+type GetGatewayKeysReq struct {
+	Page      int    `form:"page"`       // 页码，从 1 开始
+	PageSize  int    `form:"page_size"`  // 每页条数，最大 100
+	AccountId string `form:"account_id"` // 按绑定账号过滤（可选）
+}
+
+// GatewayKeyCreateReq 创建网关 Key
+type GatewayKeyCreateReq struct {
+	Name          string     `json:"name" binding:"required"` // Key 名称
+	AccountId     string     `json:"account_id"`              // 绑定账号，空则绑定当前登录用户
+	AllowedModels []string   `json:"allowed_models"`          // 允许的模型，空为不限；支持 "qwen*" 前缀通配
+	AllowedPaths  []string   `json:"allowed_paths"`           // 允许的路径前缀，空为不限（如 v1/chat/completions）
+	QuotaLimit    float64    `json:"quota_limit"`             // 花费上限（USD），0 为不限
+	ExpiresAt     *time.Time `json:"expires_at"`              // 过期时间，空为永久
+}
+
+// GatewayKeyUpdateReq 更新网关 Key（不可修改绑定账号与明文）
+type GatewayKeyUpdateReq struct {
+	Name          string     `json:"name" binding:"required"`
+	Enabled       bool       `json:"enabled"`
+	AllowedModels []string   `json:"allowed_models"`
+	AllowedPaths  []string   `json:"allowed_paths"`
+	QuotaLimit    float64    `json:"quota_limit"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ResetUsage    bool       `json:"reset_usage"` // 是否清零已用金额
+}
+
+// ProviderBalanceReq 设置提供商多凭证负载均衡策略与权重
+type ProviderBalanceReq struct {
+	ProviderName      string         `json:"provider_name" binding:"required"`
+	BalanceStrategy   string         `json:"balance_strategy" binding:"omitempty,oneof=weighted_round_robin least_inflight"`
+	CredentialWeights map[string]int `json:"credential_weights"` // 凭证 ID → 权重
+}
+
+// GetModelRoutesReq 模型路由分页请求
+type GetModelRoutesReq struct {
+	Page     int    `form:"page"`      // 页码，从 1 开始
+	PageSize int    `form:"page_size"` // 每页条数，最大 100
+	Keyword  string `form:"keyword"`   // 按规则名称 / pattern 模糊查询（可选）
+}
+
+// ModelRouteReq 创建/更新模型路由
+type ModelRouteReq struct {
+	Name           string            `json:"name"`
+	MatchType      string            `json:"match_type" binding:"required,oneof=exact prefix regex alias"`
+	Pattern        string            `json:"pattern" binding:"required"`
+	Providers      []string          `json:"providers" binding:"required,min=1"` // 有序提供商列表，前者优先，后者作为失败转移候选
+	UpstreamModel  string            `json:"upstream_model"`                     // 上游模型 ID，alias 必填
+	ProviderModels map[string]string `json:"provider_models"`                    // 提供商 → 上游模型 ID（如 aws → anthropic.claude-...）
+	Priority       int               `json:"priority"`
+	Enabled        bool              `json:"enabled"`
+	Remark         string            `json:"remark"`
+}
+
+// CustomProviderReq 创建/更新自定义提供商
+type CustomProviderReq struct {
+	Name       string                       `json:"name" binding:"required"`                                  // 提供商短名，小写字母 / 数字 / - / _
+	BaseURL    string                       `json:"base_url" binding:"required"`                              // 上游根地址，如 http://vllm.internal:8000（不含 /v1）
+	AuthScheme string                       `json:"auth_scheme" binding:"required,oneof=bearer api_key none"` // 鉴权方式
+	AuthHeader string                       `json:"auth_header"`                                              // api_key 方式的请求头名，默认 api-key
+	APIKey     string                       `json:"api_key"`                                                  // 更新时留空表示不修改
+	Models     []string                     `json:"models"`                                                   // 上游提供的模型列表（另会合并 /v1/models 发现结果）
+	Pricing    map[string]gaia.ModelPricing `json:"pricing"`                                                  // 模型名 → 定价，unit 未填时按每千 token
+	Remark     string                       `json:"remark"`
+}
+
+// GetRateLimitRulesReq 限流规则分页请求
+type GetRateLimitRulesReq struct {
+	Page     int    `form:"page"`      // 页码，从 1 开始
+	PageSize int    `form:"page_size"` // 每页条数，最大 100
+	Scope    string `form:"scope"`     // 按作用范围筛选（可选）
+	Keyword  string `form:"keyword"`   // 按规则名称 / subject 模糊查询（可选）
+}
+
+// RateLimitRuleReq 创建/更新限流规则
+type RateLimitRuleReq struct {
+	Name    string   `json:"name"`
+	Scope   string   `json:"scope" binding:"required,oneof=account authority"`
+	Subject string   `json:"subject"`             // 账号 ID 或角色 ID，空为该范围内全部（分别计数）
+	Models  []string `json:"models"`              // 适用模型，支持 * 后缀前缀匹配，空为全部
+	Rpm     int      `json:"rpm" binding:"min=0"` // 每分钟请求数，0 为不限
+	Tpm     int      `json:"tpm" binding:"min=0"` // 每分钟 token 数，0 为不限
+	Enabled bool     `json:"enabled"`
+	Remark  string   `json:"remark"`
+}
+
+// GetModelAccessRulesReq 模型访问规则分页请求
+type GetModelAccessRulesReq struct {
+	Page     int    `form:"page"`      // 页码，从 1 开始
+	PageSize int    `form:"page_size"` // 每页条数，最大 100
+	Scope    string `form:"scope"`     // 按作用范围筛选（可选）
+	Keyword  string `form:"keyword"`   // 按规则名称 / subject / 模型模糊查询（可选）
+}
+
+// ModelAccessRuleReq 创建/更新模型访问规则
+type ModelAccessRuleReq struct {
+	Name    string   `json:"name"`
+	Scope   string   `json:"scope" binding:"required,oneof=account authority"`
+	Subject string   `json:"subject"`                                    // 账号 ID 或角色 ID，空为全部
+	Models  []string `json:"models" binding:"required,min=1"`            // 模型列表，支持 * 后缀前缀匹配
+	Effect  string   `json:"effect" binding:"required,oneof=allow deny"` // allow / deny
+	Enabled bool     `json:"enabled"`
+	Remark  string   `json:"remark"`
+}
+
+// GetDLPRulesReq DLP 规则分页请求
+type GetDLPRulesReq struct {
+	Page     int    `form:"page"`      // 页码，从 1 开始
+	PageSize int    `form:"page_size"` // 每页条数，最大 100
+	Action   string `form:"action"`    // 按动作筛选（可选）
+	Keyword  string `form:"keyword"`   // 按规则名称 / pattern 模糊查询（可选）
+}
+
+// DLPRuleReq 创建/更新 DLP 规则
+type DLPRuleReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Type        string   `json:"type" binding:"required,oneof=regex keyword builtin"` // regex / keyword / builtin
+	Pattern     string   `json:"pattern"`                                             // 正则或内置检测器名称（keyword 类型使用 keywords）
+	Keywords    []string `json:"keywords"`                                            // 关键词列表（keyword 类型）
+	Action      string   `json:"action" binding:"required,oneof=redact block log"`    // redact / block / log
+	Replacement string   `json:"replacement"`                                         // 脱敏替换文本，空为 [REDACTED]
+	Providers   []string `json:"providers"`                                           // 生效的提供商，空为全部
+	Authorities []uint   `json:"authorities"`                                         // 生效的角色（用户组）ID，空为全部
+	Enabled     bool     `json:"enabled"`
+	Remark      string   `json:"remark"`
+}
+
+// GetDLPAuditLogsReq DLP 审计分页请求
+type GetDLPAuditLogsReq struct {
+	Page      int    `form:"page"`                                // 页码，从 1 开始
+	PageSize  int    `form:"page_size"`                           // 每页条数，最大 100
+	RuleId    uint   `form:"rule_id"`                             // 按规则筛选（可选）
+	AccountId string `form:"account_id" binding:"omitempty,uuid"` // 按账号筛选（可选）
+	Action    string `form:"action"`                              // 按动作筛选（可选）
+	RequestId string `form:"request_id"`                          // 按请求 ID 筛选（可选）
 }
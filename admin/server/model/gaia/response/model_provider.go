@@ -1,16 +1,31 @@
 package response
 
+import "time"
+
 // ProviderCredentials 提供商凭证（内部/代理用）
 type ProviderCredentials struct {
 	APIKey     string `json:"api_key"`
 	Endpoint   string `json:"endpoint,omitempty"`
 	APIVersion string `json:"api_version,omitempty"` // Azure OpenAI API 版本
 
+	// 多凭证负载均衡用：来源凭证（provider_credentials / provider_model_credentials 的 id）
+	CredentialID   string `json:"credential_id,omitempty"`
+	CredentialName string `json:"credential_name,omitempty"`
+
 	// AWS Bedrock 直连用：access key + secret + region（不走 APIKey/Endpoint）
 	AWSAccessKeyID     string `json:"aws_access_key_id,omitempty"`
 	AWSSecretAccessKey string `json:"aws_secret_access_key,omitempty"`
 	AWSSessionToken    string `json:"aws_session_token,omitempty"`
 	AWSRegion          string `json:"aws_region,omitempty"`
+
+	// Google Vertex AI 用：服务账号 JSON key + 项目 + 区域（access token 由服务账号换取，不走 APIKey）
+	VertexServiceAccountKey string `json:"vertex_service_account_key,omitempty"`
+	VertexProjectID         string `json:"vertex_project_id,omitempty"`
+	VertexLocation          string `json:"vertex_location,omitempty"`
+
+	// 自定义提供商用：上游鉴权方式（bearer / api_key / none，空视为 bearer）及 api_key 方式的请求头名
+	AuthScheme string `json:"auth_scheme,omitempty"`
+	AuthHeader string `json:"auth_header,omitempty"`
 }
 
 // ModelInfo 模型信息
@@ -71,3 +86,70 @@ type GeminiModelItem struct {
 	BaseModelID string `json:"baseModelId"`
 	DisplayName string `json:"displayName"`
 }
+
+// GatewayKeyCreated 创建网关 Key 的返回（明文 Key 仅此一次返回）
+type GatewayKeyCreated struct {
+	Id        uint   `json:"id"`
+	Key       string `json:"key"`
+	KeyMask   string `json:"key_mask"`
+	AccountId string `json:"account_id"`
+}
+
+// ProviderCredentialStatus 提供商凭证负载均衡状态（Key 脱敏）
+type ProviderCredentialStatus struct {
+	CredentialID   string     `json:"credential_id"`
+	CredentialName string     `json:"credential_name"`
+	APIKey         string     `json:"api_key"`
+	Weight         int        `json:"weight"`
+	Inflight       int        `json:"inflight"`
+	CooldownUntil  *time.Time `json:"cooldown_until,omitempty"`
+}
+
+// ProviderCredentialsResponse 提供商凭证列表与当前负载均衡策略
+type ProviderCredentialsResponse struct {
+	ProviderName    string                     `json:"provider_name"`
+	BalanceStrategy string                     `json:"balance_strategy"`
+	Credentials     []ProviderCredentialStatus `json:"credentials"`
+}
+
+// CircuitBreakerStatus 上游熔断器状态：CredentialID 为空时为提供商级熔断器
+type CircuitBreakerStatus struct {
+	ProviderName        string     `json:"provider_name"`
+	CredentialID        string     `json:"credential_id,omitempty"`
+	State               string     `json:"state"` // closed / open / half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	WindowRequests      int        `json:"window_requests"`
+	WindowFailures      int        `json:"window_failures"`
+	ErrorRate           float64    `json:"error_rate"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"` // 熔断到期、可半开探测的时间
+}
+
+// RateLimitResult 网关限流检查结果：Headers 为 OpenAI 风格的 x-ratelimit-* 响应头（取最严格的规则）
+type RateLimitResult struct {
+	Allowed    bool              // 是否放行
+	Type       string            // 触发的限流类型：requests / tokens
+	Message    string            // 拒绝原因
+	RetryAfter int               // 建议重试等待秒数（Retry-After）
+	Headers    map[string]string // x-ratelimit-limit-requests 等响应头
+}
+
+// ProxyLogStatsItem 代理日志统计行（汇总或单个分组），花费为 cost_usd 合计（历史日志按定价表估算）
+type ProxyLogStatsItem struct {
+	Key              string  `json:"key" gorm:"column:group_key"` // 分组值（账号 ID / 模型 / 提供商 / 日期），汇总行为空
+	Requests         int64   `json:"requests" gorm:"column:requests"`
+	Errors           int64   `json:"errors" gorm:"column:errors"`
+	CacheHits        int64   `json:"cache_hits" gorm:"column:cache_hits"`
+	PromptTokens     int64   `json:"prompt_tokens" gorm:"column:prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens" gorm:"column:completion_tokens"`
+	CostUSD          float64 `json:"cost_usd" gorm:"column:cost_usd"`
+	P50Ms            float64 `json:"p50_ms" gorm:"column:p50_ms"` // 耗时中位数（毫秒）
+	P95Ms            float64 `json:"p95_ms" gorm:"column:p95_ms"` // 耗时 P95（毫秒）
+}
+
+// ProxyLogStats 代理日志统计结果
+type ProxyLogStats struct {
+	Summary ProxyLogStatsItem   `json:"summary"`
+	Groups  []ProxyLogStatsItem `json:"groups"`
+}
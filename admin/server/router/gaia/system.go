@@ -19,15 +19,15 @@ func (s *SystemRouter) InitSystemRouter(Router *gin.RouterGroup) {
 		// 邮箱 API 配置测试
 		systemRouter.POST("dingtalk/test-email-config", systemApi.TestEmailApiConfig) // 测试第三方邮箱 API 配置
 		// 转发 Token 管理
-		systemRouter.GET("forward-tokens", systemApi.GetForwardTokens)          // 获取转发 Token 列表
-		systemRouter.POST("forward-tokens", systemApi.CreateForwardToken)       // 新增转发 Token
+		systemRouter.GET("forward-tokens", systemApi.GetForwardTokens)           // 获取转发 Token 列表
+		systemRouter.POST("forward-tokens", systemApi.CreateForwardToken)        // 新增转发 Token
 		systemRouter.DELETE("forward-tokens/:seq", systemApi.DeleteForwardToken) // 删除转发 Token（按序列号）
 	}
 }
 
 // InitForwardProxyRouter 初始化 GPT 转发代理路由
 func (s *SystemRouter) InitForwardProxyRouter(PublicRouter *gin.RouterGroup) {
-	// 免 JWT 转发入口，通过 forwarding token + ding_id 鉴权
+	// 免 JWT 转发入口，通过网关 Key（sk-gaia-...）或 forwarding token + ding_id 鉴权
 	PublicRouter.Any("gaia/forward/proxy/*path", forwardProxyApi.ForwardProxy)
 }
 
@@ -36,17 +36,47 @@ func (s *SystemRouter) InitModelProviderRouter(Router *gin.RouterGroup) {
 	// 管理端 API（需要 JWT 认证）
 	modelProviderRouter := Router.Group("gaia/model-provider")
 	{
-		modelProviderRouter.GET("list", modelProviderApi.GetProviderList)                     // 获取提供商配置列表
-		modelProviderRouter.POST("update", modelProviderApi.UpdateProviderConfig)             // 更新提供商配置
-		modelProviderRouter.GET("available-models", modelProviderApi.GetAvailableModels)      // 获取可用模型
-		modelProviderRouter.GET("test-credentials", modelProviderApi.TestProviderCredentials) // 测试凭证
-		modelProviderRouter.GET("logs", modelProviderApi.GetProxyLogs)                        // 获取代理日志
+		modelProviderRouter.GET("list", modelProviderApi.GetProviderList)                         // 获取提供商配置列表
+		modelProviderRouter.POST("update", modelProviderApi.UpdateProviderConfig)                 // 更新提供商配置
+		modelProviderRouter.GET("available-models", modelProviderApi.GetAvailableModels)          // 获取可用模型
+		modelProviderRouter.GET("test-credentials", modelProviderApi.TestProviderCredentials)     // 测试凭证
+		modelProviderRouter.GET("logs", modelProviderApi.GetProxyLogs)                            // 获取代理日志
+		modelProviderRouter.GET("logs/stats", modelProviderApi.GetProxyLogStats)                  // 代理日志统计（汇总、分组花费与耗时分位）
+		modelProviderRouter.GET("logs/export", modelProviderApi.ExportProxyLogs)                  // 导出代理日志（csv / xlsx）
+		modelProviderRouter.GET("credentials", modelProviderApi.GetProviderCredentials)           // 凭证负载均衡状态
+		modelProviderRouter.POST("credentials/balance", modelProviderApi.UpdateProviderBalance)   // 设置负载均衡策略与权重
+		modelProviderRouter.GET("circuit-breakers", modelProviderApi.GetCircuitBreakers)          // 上游熔断器状态
+		modelProviderRouter.GET("keys", modelProviderApi.GetGatewayKeys)                          // 网关 Key 列表
+		modelProviderRouter.POST("keys", modelProviderApi.CreateGatewayKey)                       // 创建网关 Key（明文仅返回一次）
+		modelProviderRouter.PUT("keys/:id", modelProviderApi.UpdateGatewayKey)                    // 更新网关 Key
+		modelProviderRouter.DELETE("keys/:id", modelProviderApi.DeleteGatewayKey)                 // 删除网关 Key
+		modelProviderRouter.GET("routes", modelProviderApi.GetModelRoutes)                        // 模型路由列表
+		modelProviderRouter.POST("routes", modelProviderApi.CreateModelRoute)                     // 创建模型路由
+		modelProviderRouter.PUT("routes/:id", modelProviderApi.UpdateModelRoute)                  // 更新模型路由
+		modelProviderRouter.DELETE("routes/:id", modelProviderApi.DeleteModelRoute)               // 删除模型路由
+		modelProviderRouter.GET("custom-providers", modelProviderApi.GetCustomProviders)          // 自定义提供商列表
+		modelProviderRouter.POST("custom-providers", modelProviderApi.CreateCustomProvider)       // 创建自定义提供商
+		modelProviderRouter.PUT("custom-providers/:id", modelProviderApi.UpdateCustomProvider)    // 更新自定义提供商
+		modelProviderRouter.DELETE("custom-providers/:id", modelProviderApi.DeleteCustomProvider) // 删除自定义提供商
+		modelProviderRouter.GET("rate-limits", modelProviderApi.GetRateLimitRules)                // 限流规则列表
+		modelProviderRouter.POST("rate-limits", modelProviderApi.CreateRateLimitRule)             // 创建限流规则
+		modelProviderRouter.PUT("rate-limits/:id", modelProviderApi.UpdateRateLimitRule)          // 更新限流规则
+		modelProviderRouter.DELETE("rate-limits/:id", modelProviderApi.DeleteRateLimitRule)       // 删除限流规则
+		modelProviderRouter.GET("access-rules", modelProviderApi.GetModelAccessRules)             // 模型访问规则列表
+		modelProviderRouter.POST("access-rules", modelProviderApi.CreateModelAccessRule)          // 创建模型访问规则
+		modelProviderRouter.PUT("access-rules/:id", modelProviderApi.UpdateModelAccessRule)       // 更新模型访问规则
+		modelProviderRouter.DELETE("access-rules/:id", modelProviderApi.DeleteModelAccessRule)    // 删除模型访问规则
+		modelProviderRouter.GET("dlp-rules", modelProviderApi.GetDLPRules)                        // DLP 规则列表
+		modelProviderRouter.POST("dlp-rules", modelProviderApi.CreateDLPRule)                     // 创建 DLP 规则
+		modelProviderRouter.PUT("dlp-rules/:id", modelProviderApi.UpdateDLPRule)                  // 更新 DLP 规则
+		modelProviderRouter.DELETE("dlp-rules/:id", modelProviderApi.DeleteDLPRule)               // 删除 DLP 规则
+		modelProviderRouter.GET("dlp-audits", modelProviderApi.GetDLPAuditLogs)                   // DLP 命中审计列表
 	}
 
 	// 第三方 API（需要 JWT 认证）
 	gaiaRouter := Router.Group("gaia")
 	{
 		gaiaRouter.GET("models", modelProviderApi.GetModels)  // 获取开启的模型列表（OpenAI 格式）
-		gaiaRouter.Any("proxy/*path", modelProviderApi.Proxy) // 通用中转 API：按路径转发（v1/chat/completions、v1/messages、v1/images/generations、v1/embeddings 等）
+		gaiaRouter.Any("proxy/*path", modelProviderApi.Proxy) // 通用中转 API：按路径转发（v1/chat/completions、v1/responses、v1/messages、v1/images/generations、v1/embeddings、v1/realtime WebSocket、v1/files / v1/batches 等）
 	}
 }
@@ -0,0 +1,204 @@
+package gaia
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// readSSEData 按行读取 SSE 流，对每个 data 行回调其 JSON 负载（忽略 event/注释/空行与 [DONE]）。
+func readSSEData(r io.Reader, fn func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "" || payload == "[DONE]" {
+			continue
+		}
+		if err := fn([]byte(payload)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// newAnthropicMessagesRequest 构建直连 Anthropic /v1/messages 的请求（x-api-key + anthropic-version）。
+func (s *ModelProviderService) newAnthropicMessagesRequest(ctx context.Context,
+	creds *gaiaResponse.ProviderCredentials, payload []byte, streaming bool) (*http.Request, error) {
+	base := s.getUpstreamBase(gaia.ProviderAnthropic, creds)
+	if base == "" {
+		return nil, fmt.Errorf("提供商 %s 无可用上游地址", gaia.ProviderAnthropic)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", creds.APIKey)
+	httpReq.Header.Set("anthropic-version", gaia.AnthropicAPIVersion)
+	if streaming {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	return httpReq, nil
+}
+
+// proxyChatViaAnthropic 将 OpenAI chat/completions 请求转换为 Anthropic Messages，转发到 anthropic 直连、AWS Bedrock 或 Vertex AI，
+// 再把响应（含流式事件与 usage）转换回 OpenAI 格式写给客户端；计费仍走 calcUsageCost。
+func (s *ModelProviderService) proxyChatViaAnthropic(
+	att *proxyAttempt, body []byte, writer io.Writer, creds *gaiaResponse.ProviderCredentials) error {
+	areq, err := openAIToAnthropicRequest(body)
+	if err != nil {
+		return err
+	}
+	modelID, streaming := areq.Model, areq.Stream
+	if modelID == "" {
+		return fmt.Errorf("chat/completions 请求缺少 model 字段")
+	}
+	clientModel := att.model
+	if clientModel == "" {
+		clientModel = modelID
+	}
+
+	// 1) 构建上游请求：Bedrock 的 model 在 URL 中、流式由 URL 决定，body 需带 anthropic_version
+	var httpReq *http.Request
+	if att.provider == gaia.ProviderAWS {
+		if creds == nil || creds.AWSAccessKeyID == "" || creds.AWSSecretAccessKey == "" {
+			return fmt.Errorf("AWS Bedrock 凭证缺失（需要 aws_access_key_id / aws_secret_access_key）")
+		}
+		areq.Model, areq.Stream, areq.AnthropicVersion = "", false, gaia.BedrockAnthropicVersion
+		payload, e := json.Marshal(areq)
+		if e != nil {
+			return fmt.Errorf("构建 Anthropic 请求失败：%w", e)
+		}
+		httpReq, err = newBedrockInvokeRequest(att.ctx, creds, modelID, payload, streaming)
+	} else if att.provider == gaia.ProviderVertex {
+		// Vertex 的 model 在 URL 中，body 带 anthropic_version，流式仍由 body 的 stream 指定
+		areq.Model, areq.AnthropicVersion = "", gaia.VertexAnthropicVersion
+		payload, e := json.Marshal(areq)
+		if e != nil {
+			return fmt.Errorf("构建 Anthropic 请求失败：%w", e)
+		}
+		httpReq, err = s.newVertexAnthropicRequest(att.ctx, creds, modelID, payload, streaming)
+	} else {
+		payload, e := json.Marshal(areq)
+		if e != nil {
+			return fmt.Errorf("构建 Anthropic 请求失败：%w", e)
+		}
+		httpReq, err = s.newAnthropicMessagesRequest(att.ctx, creds, payload, streaming)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2) 发起请求；可转移的失败在写回前交还 ProxyRequest
+	att.stream = streaming
+	startTime := time.Now()
+	resp, err := doUpstream(att, creds, httpReq)
+	if err != nil {
+		if att.clientCancelled() {
+			s.logCancelledAttempt(att, creds, modelID, startTime, gaia.TokenUsage{}, err)
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: att.provider, Err: err}
+		}
+		s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	att.trackFirstByte(resp)
+
+	w, _ := writer.(http.ResponseWriter)
+	if resp.StatusCode != http.StatusOK {
+		reportCredentialStatus(att.provider, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return &upstreamRetryableError{Provider: att.provider, Status: resp.StatusCode}
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+		}
+		_, _ = writer.Write(anthropicErrorToOpenAI(raw))
+		s.logProxyAttempt(att, creds, modelID, "error",
+			fmt.Sprintf("%s %d: %s", att.provider, resp.StatusCode, string(raw)), startTime, gaia.TokenUsage{})
+		return nil
+	}
+
+	// 3) 转换响应
+	var usage gaia.TokenUsage
+	if streaming {
+		if w != nil {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher, _ := writer.(http.Flusher)
+		translator := newAnthropicStreamTranslator(clientModel)
+		onEvent := func(event []byte) error {
+			att.trackStreamed(usageFormatAnthropic, event)
+			for _, data := range translator.translate(event) {
+				if _, e := writer.Write([]byte("data: " + string(data) + "\n\n")); e != nil {
+					return e
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		}
+		if att.provider == gaia.ProviderAWS {
+			err = readBedrockEvents(resp.Body, onEvent)
+		} else {
+			err = readSSEData(resp.Body, onEvent)
+		}
+		usage = translator.usage
+		if err != nil {
+			if att.clientCancelled() {
+				s.logCancelledAttempt(att, creds, modelID, startTime, usage, err)
+				return err
+			}
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	} else {
+		raw, e := io.ReadAll(resp.Body)
+		if e != nil {
+			s.logProxyAttempt(att, creds, modelID, att.failureStatus(), e.Error(), startTime, gaia.TokenUsage{})
+			return e
+		}
+		var converted []byte
+		if converted, usage, err = anthropicToOpenAIResponse(raw, clientModel); err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+			return err
+		}
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}
+		if _, err = writer.Write(converted); err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	}
+
+	// 4) 记录日志 + 计费扣款
+	att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	s.logProxyAttempt(att, creds, modelID, "success", "", startTime, usage)
+	return nil
+}
@@ -0,0 +1,532 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// OpenAI Chat Completions ⇄ Anthropic Messages 协议转换。
+// 请求：OpenAI chat/completions body → Anthropic Messages body（messages/system/tools/tool_choice/images/stop）
+// 响应：Anthropic Messages JSON 或 SSE 事件 → OpenAI chat.completion / chat.completion.chunk
+
+// openAIChatRequest OpenAI chat/completions 请求中参与转换的字段
+type openAIChatRequest struct {
+	Model               string            `json:"model"`
+	Messages            []openAIChatMsg   `json:"messages"`
+	MaxTokens           int               `json:"max_tokens"`
+	MaxCompletionTokens int               `json:"max_completion_tokens"`
+	Temperature         *float64          `json:"temperature"`
+	TopP                *float64          `json:"top_p"`
+	Stop                json.RawMessage   `json:"stop"`
+	Stream              bool              `json:"stream"`
+	Tools               []openAITool      `json:"tools"`
+	ToolChoice          json.RawMessage   `json:"tool_choice"`
+	User                string            `json:"user"`
+	Metadata            map[string]string `json:"metadata"`
+}
+
+type openAIChatMsg struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// anthropicBlock Anthropic Messages content block（text / image / tool_use / tool_result / thinking）
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Source    *anthropicImage `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+}
+
+type anthropicImage struct {
+	Type      string `json:"type"` // base64 / url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model            string             `json:"model,omitempty"`
+	AnthropicVersion string             `json:"anthropic_version,omitempty"` // 仅 Bedrock / Vertex AI 使用
+	System           string             `json:"system,omitempty"`
+	Messages         []anthropicMessage `json:"messages"`
+	MaxTokens        int                `json:"max_tokens"`
+	Temperature      *float64           `json:"temperature,omitempty"`
+	TopP             *float64           `json:"top_p,omitempty"`
+	StopSequences    []string           `json:"stop_sequences,omitempty"`
+	Stream           bool               `json:"stream,omitempty"`
+	Tools            []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice       interface{}        `json:"tool_choice,omitempty"`
+	Metadata         map[string]string  `json:"metadata,omitempty"`
+}
+
+// anthropicResponse 非流式 Anthropic Messages 响应
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicUsage Anthropic usage；input_tokens 不含缓存命中与缓存写入部分
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// isChatCompletionsPath 判断是否为 OpenAI chat/completions 接口路径。
+func isChatCompletionsPath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(strings.ToLower(path), "/"), "chat/completions")
+}
+
+// openAIContentText 将 OpenAI content（字符串或 parts 数组）中的文本拼接为字符串。
+func openAIContentText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var parts []openAIContentPart
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type == "text" {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// openAIContentBlocks 将 OpenAI content 转为 Anthropic content blocks（文本 + 图片）。
+func openAIContentBlocks(raw json.RawMessage) []anthropicBlock {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if text == "" {
+			return nil
+		}
+		return []anthropicBlock{{Type: "text", Text: text}}
+	}
+	var parts []openAIContentPart
+	if json.Unmarshal(raw, &parts) != nil {
+		return nil
+	}
+	blocks := make([]anthropicBlock, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: p.Text})
+			}
+		case "image_url":
+			if p.ImageURL != nil {
+				if img := openAIImageToAnthropic(p.ImageURL.URL); img != nil {
+					blocks = append(blocks, anthropicBlock{Type: "image", Source: img})
+				}
+			}
+		}
+	}
+	return blocks
+}
+
+// openAIImageToAnthropic data URL → base64 source，http(s) URL → url source。
+func openAIImageToAnthropic(url string) *anthropicImage {
+	if strings.HasPrefix(url, "data:") {
+		// data:image/png;base64,xxxx
+		meta, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok {
+			return nil
+		}
+		return &anthropicImage{Type: "base64", MediaType: strings.TrimSuffix(meta, ";base64"), Data: data}
+	}
+	if url == "" {
+		return nil
+	}
+	return &anthropicImage{Type: "url", URL: url}
+}
+
+// appendAnthropicMessage 追加消息；与上一条角色相同时合并（Anthropic 要求 user/assistant 交替）。
+func appendAnthropicMessage(msgs []anthropicMessage, role string, blocks []anthropicBlock) []anthropicMessage {
+	if len(blocks) == 0 {
+		return msgs
+	}
+	if n := len(msgs); n > 0 && msgs[n-1].Role == role {
+		msgs[n-1].Content = append(msgs[n-1].Content, blocks...)
+		return msgs
+	}
+	return append(msgs, anthropicMessage{Role: role, Content: blocks})
+}
+
+// openAIToAnthropicRequest 将 OpenAI chat/completions 请求体转换为 Anthropic Messages 请求。
+func openAIToAnthropicRequest(body []byte) (*anthropicRequest, error) {
+	var req openAIChatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("解析 chat/completions 请求失败：%w", err)
+	}
+	out := &anthropicRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+	if req.MaxCompletionTokens > 0 {
+		out.MaxTokens = req.MaxCompletionTokens
+	}
+	if out.MaxTokens <= 0 {
+		out.MaxTokens = gaia.AnthropicDefaultMaxTokens
+	}
+	if req.User != "" {
+		out.Metadata = map[string]string{"user_id": req.User}
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if t := openAIContentText(m.Content); t != "" {
+				system = append(system, t)
+			}
+		case "user":
+			out.Messages = appendAnthropicMessage(out.Messages, "user", openAIContentBlocks(m.Content))
+		case "assistant":
+			blocks := openAIContentBlocks(m.Content)
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) || len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+			}
+			out.Messages = appendAnthropicMessage(out.Messages, "assistant", blocks)
+		case "tool":
+			content, _ := json.Marshal(openAIContentText(m.Content))
+			out.Messages = appendAnthropicMessage(out.Messages, "user", []anthropicBlock{
+				{Type: "tool_result", ToolUseID: m.ToolCallID, Content: content},
+			})
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, t := range req.Tools {
+		if t.Type != "" && t.Type != "function" {
+			continue
+		}
+		schema := t.Function.Parameters
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out.Tools = append(out.Tools, anthropicTool{
+			Name: t.Function.Name, Description: t.Function.Description, InputSchema: schema,
+		})
+	}
+	out.ToolChoice = openAIToolChoiceToAnthropic(req.ToolChoice)
+	if out.ToolChoice != nil && len(out.Tools) == 0 {
+		out.ToolChoice = nil
+	}
+	out.StopSequences = parseOpenAIStop(req.Stop)
+	return out, nil
+}
+
+// openAIToolChoiceToAnthropic auto→auto，required→any，none→none，指定函数→tool。
+func openAIToolChoiceToAnthropic(raw json.RawMessage) interface{} {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var mode string
+	if json.Unmarshal(raw, &mode) == nil {
+		switch mode {
+		case "auto":
+			return map[string]string{"type": "auto"}
+		case "required":
+			return map[string]string{"type": "any"}
+		case "none":
+			return map[string]string{"type": "none"}
+		}
+		return nil
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(raw, &named) == nil && named.Function.Name != "" {
+		return map[string]string{"type": "tool", "name": named.Function.Name}
+	}
+	return nil
+}
+
+// parseOpenAIStop stop 可能是字符串或字符串数组。
+func parseOpenAIStop(raw json.RawMessage) []string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		if one == "" {
+			return nil
+		}
+		return []string{one}
+	}
+	var many []string
+	_ = json.Unmarshal(raw, &many)
+	return many
+}
+
+// anthropicStopToOpenAI 映射 stop_reason → finish_reason。
+func anthropicStopToOpenAI(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "":
+		return ""
+	}
+	return "stop" // end_turn / stop_sequence / refusal 等
+}
+
+// openAIChatResponse OpenAI chat.completion / chat.completion.chunk 响应结构
+type openAIChatResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []openAIChatChoice `json:"choices"`
+	Usage   *gaia.ModelUsage   `json:"usage,omitempty"`
+}
+
+type openAIChatChoice struct {
+	Index        int            `json:"index"`
+	Message      *openAIChatOut `json:"message,omitempty"`
+	Delta        *openAIChatOut `json:"delta,omitempty"`
+	FinishReason *string        `json:"finish_reason"`
+}
+
+type openAIChatOut struct {
+	Role             string           `json:"role,omitempty"`
+	Content          *string          `json:"content,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	ToolCalls        []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// anthropicToOpenAIResponse 将非流式 Anthropic Messages 响应转换为 OpenAI chat.completion，并返回 token 用量。
+func anthropicToOpenAIResponse(data []byte, model string) ([]byte, gaia.TokenUsage, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, gaia.TokenUsage{}, fmt.Errorf("解析 Anthropic 响应失败：%w", err)
+	}
+	msg := &openAIChatOut{Role: "assistant"}
+	var text, reasoning strings.Builder
+	for _, b := range resp.Content {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "thinking":
+			reasoning.WriteString(b.Thinking)
+		case "tool_use":
+			tc := openAIToolCall{ID: b.ID, Type: "function"}
+			tc.Function.Name = b.Name
+			tc.Function.Arguments = string(b.Input)
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+	}
+	content := text.String()
+	msg.Content = &content
+	msg.ReasoningContent = reasoning.String()
+	finish := anthropicStopToOpenAI(resp.StopReason)
+	usage := resp.Usage.tokens()
+	out := openAIChatResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openAIChatChoice{{Index: 0, Message: msg, FinishReason: &finish}},
+		Usage:   openAIUsageFrom(usage),
+	}
+	b, err := json.Marshal(out)
+	return b, usage, err
+}
+
+// anthropicErrorToOpenAI 将 Anthropic 错误体 {"type":"error","error":{...}} 转为 OpenAI 错误格式；无法识别时原样返回。
+func anthropicErrorToOpenAI(data []byte) []byte {
+	var e struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+		Message string `json:"message"` // Bedrock 错误体
+	}
+	if json.Unmarshal(data, &e) != nil {
+		return data
+	}
+	msg, typ := e.Error.Message, e.Error.Type
+	if msg == "" {
+		msg = e.Message
+	}
+	if msg == "" {
+		return data
+	}
+	if typ == "" {
+		typ = "upstream_error"
+	}
+	b, _ := json.Marshal(map[string]interface{}{"error": map[string]string{"message": msg, "type": typ}})
+	return b
+}
+
+// anthropicStreamTranslator 将 Anthropic SSE 事件逐个转换为 OpenAI chat.completion.chunk
+type anthropicStreamTranslator struct {
+	id        string
+	model     string
+	created   int64
+	toolIndex map[int]int // content block index → tool_calls index
+	usage     gaia.TokenUsage
+}
+
+func newAnthropicStreamTranslator(model string) *anthropicStreamTranslator {
+	return &anthropicStreamTranslator{model: model, created: time.Now().Unix(), toolIndex: map[int]int{}}
+}
+
+// anthropicStreamEvent Anthropic 流式事件中参与转换的字段
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		ID    string         `json:"id"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage  `json:"usage"`
+	Error json.RawMessage `json:"error"`
+}
+
+func (t *anthropicStreamTranslator) chunk(delta *openAIChatOut, finish *string) []byte {
+	b, _ := json.Marshal(openAIChatResponse{
+		ID: t.id, Object: "chat.completion.chunk", Created: t.created, Model: t.model,
+		Choices: []openAIChatChoice{{Index: 0, Delta: delta, FinishReason: finish}},
+	})
+	return b
+}
+
+// translate 转换一个 Anthropic 事件 JSON，返回需写给客户端的 OpenAI data 负载（不含 "data: " 前缀）。
+func (t *anthropicStreamTranslator) translate(event []byte) [][]byte {
+	var ev anthropicStreamEvent
+	if json.Unmarshal(event, &ev) != nil {
+		return nil
+	}
+	switch ev.Type {
+	case "message_start":
+		t.id = ev.Message.ID
+		mergeTokenUsage(&t.usage, ev.Message.Usage.tokens())
+		empty := ""
+		return [][]byte{t.chunk(&openAIChatOut{Role: "assistant", Content: &empty}, nil)}
+	case "content_block_start":
+		if ev.ContentBlock.Type != "tool_use" {
+			return nil
+		}
+		idx := len(t.toolIndex)
+		t.toolIndex[ev.Index] = idx
+		tc := openAIToolCall{Index: &idx, ID: ev.ContentBlock.ID, Type: "function"}
+		tc.Function.Name = ev.ContentBlock.Name
+		return [][]byte{t.chunk(&openAIChatOut{ToolCalls: []openAIToolCall{tc}}, nil)}
+	case "content_block_delta":
+		switch ev.Delta.Type {
+		case "text_delta":
+			text := ev.Delta.Text
+			return [][]byte{t.chunk(&openAIChatOut{Content: &text}, nil)}
+		case "thinking_delta":
+			return [][]byte{t.chunk(&openAIChatOut{ReasoningContent: ev.Delta.Thinking}, nil)}
+		case "input_json_delta":
+			idx, ok := t.toolIndex[ev.Index]
+			if !ok {
+				return nil
+			}
+			tc := openAIToolCall{Index: &idx}
+			tc.Function.Arguments = ev.Delta.PartialJSON
+			return [][]byte{t.chunk(&openAIChatOut{ToolCalls: []openAIToolCall{tc}}, nil)}
+		}
+	case "message_delta":
+		mergeTokenUsage(&t.usage, ev.Usage.tokens())
+		if ev.Delta.StopReason != "" {
+			finish := anthropicStopToOpenAI(ev.Delta.StopReason)
+			return [][]byte{t.chunk(&openAIChatOut{}, &finish)}
+		}
+	case "message_stop":
+		// 末尾附带 usage（与 stream_options.include_usage=true 的 OpenAI 行为一致），随后 [DONE]
+		usage, _ := json.Marshal(openAIChatResponse{
+			ID: t.id, Object: "chat.completion.chunk", Created: t.created, Model: t.model,
+			Choices: []openAIChatChoice{},
+			Usage:   openAIUsageFrom(t.usage),
+		})
+		return [][]byte{usage, []byte("[DONE]")}
+	case "error":
+		return [][]byte{anthropicErrorToOpenAI(event)}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package gaia
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestOpenAIToAnthropicRequest 测试 system / tools / tool_calls / tool 结果 / 图片 / stop 的转换
+func TestOpenAIToAnthropicRequest(t *testing.T) {
+	body := `{
+		"model": "claude-sonnet-4-6",
+		"stream": true,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "你是助手"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "看图"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"深圳\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "晴"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`
+	req, err := openAIToAnthropicRequest([]byte(body))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if req.System != "你是助手" || !req.Stream || req.MaxTokens <= 0 {
+		t.Errorf("system/stream/max_tokens 转换错误: %+v", req)
+	}
+	if len(req.StopSequences) != 1 || req.StopSequences[0] != "END" {
+		t.Errorf("stop 转换错误: %v", req.StopSequences)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("期望 3 条消息（user/assistant/user），got: %d", len(req.Messages))
+	}
+	if img := req.Messages[0].Content[1]; img.Type != "image" || img.Source.MediaType != "image/png" || img.Source.Data != "AAAA" {
+		t.Errorf("图片转换错误: %+v", img)
+	}
+	if tu := req.Messages[1].Content[0]; tu.Type != "tool_use" || tu.ID != "call_1" || !strings.Contains(string(tu.Input), "深圳") {
+		t.Errorf("tool_calls 转换错误: %+v", tu)
+	}
+	if tr := req.Messages[2].Content[0]; tr.Type != "tool_result" || tr.ToolUseID != "call_1" {
+		t.Errorf("tool 结果转换错误: %+v", tr)
+	}
+	if choice, _ := json.Marshal(req.ToolChoice); string(choice) != `{"type":"any"}` {
+		t.Errorf("tool_choice 转换错误: %s", choice)
+	}
+}
+
+// TestAnthropicStreamTranslator 测试流式事件转换为 OpenAI chunk 及 usage 汇总
+func TestAnthropicStreamTranslator(t *testing.T) {
+	tr := newAnthropicStreamTranslator("claude-sonnet-4-6")
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"你好"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	}
+	var out []string
+	for _, e := range events {
+		for _, d := range tr.translate([]byte(e)) {
+			out = append(out, string(d))
+		}
+	}
+	joined := strings.Join(out, "\n")
+	for _, want := range []string{`"content":"你好"`, `"name":"get_weather"`, `"arguments":"{\"city\":"`,
+		`"finish_reason":"tool_calls"`, `"prompt_tokens":12`, `"completion_tokens":30`} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("输出缺少 %s\n%s", want, joined)
+		}
+	}
+	if out[len(out)-1] != "[DONE]" {
+		t.Errorf("最后一条应为 [DONE]，got: %s", out[len(out)-1])
+	}
+	if tr.usage.PromptTokens != 12 || tr.usage.CompletionTokens != 30 {
+		t.Errorf("usage 汇总错误: %+v", tr.usage)
+	}
+}
+
+// TestAnthropicToOpenAIResponse 测试非流式响应转换
+func TestAnthropicToOpenAIResponse(t *testing.T) {
+	raw := `{"id":"msg_1","content":[{"type":"text","text":"晴天"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`
+	out, usage, err := anthropicToOpenAIResponse([]byte(raw), "claude-sonnet-4-6")
+	if err != nil || usage.PromptTokens != 5 || usage.CompletionTokens != 2 {
+		t.Fatalf("转换失败: err=%v usage=%+v", err, usage)
+	}
+	for _, want := range []string{`"object":"chat.completion"`, `"content":"晴天"`, `"finish_reason":"stop"`, `"prompt_tokens":5`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("输出缺少 %s: %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,638 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	estream "github.com/aws/aws-sdk-go/private/protocol/eventstream"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// AWS Bedrock Converse API：统一的对话接口，支持 Amazon Nova、Meta Llama、Mistral 等非 Anthropic 模型。
+// 原生调用：model/{modelId}/converse 与 model/{modelId}/converse-stream，body 原样转发
+// OpenAI 调用：chat/completions → Anthropic Messages（复用 openAIToAnthropicRequest）→ Converse；
+// 响应（含流式事件）先转为 Anthropic 事件，再复用 anthropicStreamTranslator 转为 OpenAI 格式
+// 计费：Converse usage（metadata.usage / usage）中 inputTokens 不含缓存命中与缓存写入部分，与 Anthropic 一致
+
+// converseContent Converse content block（text / image / toolUse / toolResult / reasoningContent）
+type converseContent struct {
+	Text             string              `json:"text,omitempty"`
+	Image            *converseImage      `json:"image,omitempty"`
+	ToolUse          *converseToolUse    `json:"toolUse,omitempty"`
+	ToolResult       *converseToolResult `json:"toolResult,omitempty"`
+	ReasoningContent *converseReasoning  `json:"reasoningContent,omitempty"`
+}
+
+type converseImage struct {
+	Format string `json:"format"` // png / jpeg / gif / webp
+	Source struct {
+		Bytes string `json:"bytes"` // base64
+	} `json:"source"`
+}
+
+type converseToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type converseToolResult struct {
+	ToolUseID string            `json:"toolUseId"`
+	Content   []converseContent `json:"content"`
+}
+
+type converseReasoning struct {
+	ReasoningText *struct {
+		Text string `json:"text"`
+	} `json:"reasoningText,omitempty"`
+}
+
+type converseMessage struct {
+	Role    string            `json:"role"`
+	Content []converseContent `json:"content"`
+}
+
+type converseInferenceConfig struct {
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type converseTool struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON json.RawMessage `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+type converseToolConfig struct {
+	Tools      []converseTool         `json:"tools"`
+	ToolChoice map[string]interface{} `json:"toolChoice,omitempty"`
+}
+
+type converseRequest struct {
+	Messages        []converseMessage        `json:"messages"`
+	System          []converseContent        `json:"system,omitempty"`
+	InferenceConfig *converseInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *converseToolConfig      `json:"toolConfig,omitempty"`
+}
+
+// converseUsage Converse usage；inputTokens 不含缓存命中与缓存写入部分
+type converseUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+}
+
+func (u converseUsage) anthropic() anthropicUsage {
+	return anthropicUsage{
+		InputTokens:              u.InputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+		CacheCreationInputTokens: u.CacheWriteInputTokens,
+	}
+}
+
+// converseResponse 非流式 Converse 响应
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string        `json:"stopReason"`
+	Usage      converseUsage `json:"usage"`
+}
+
+// bedrockConversePath 解析原生 Converse 路径 model/{modelId}/converse(-stream)，返回模型 ID 与是否流式。
+func bedrockConversePath(path string) (modelID string, streaming bool, ok bool) {
+	idx := strings.Index(path, "model/")
+	if idx < 0 {
+		return "", false, false
+	}
+	rest := strings.TrimSuffix(path[idx+len("model/"):], "/")
+	switch {
+	case strings.HasSuffix(rest, "/converse-stream"):
+		modelID, streaming = strings.TrimSuffix(rest, "/converse-stream"), true
+	case strings.HasSuffix(rest, "/converse"):
+		modelID = strings.TrimSuffix(rest, "/converse")
+	default:
+		return "", false, false
+	}
+	if unescaped, err := url.PathUnescape(modelID); err == nil {
+		modelID = unescaped
+	}
+	return modelID, streaming, modelID != ""
+}
+
+// isBedrockAnthropicModel 判断 Bedrock 模型 ID 是否为 Anthropic 模型（含 us./eu./global. 跨区域推理配置前缀）。
+func isBedrockAnthropicModel(modelID string) bool {
+	lower := strings.ToLower(modelID)
+	return strings.Contains(lower, "anthropic.") || strings.HasPrefix(lower, "claude")
+}
+
+// anthropicTextContent 将 tool_result 的 content（字符串或 content block 数组）转为 Converse content。
+func anthropicTextContent(raw json.RawMessage) []converseContent {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []converseContent{{Text: text}}
+	}
+	var blocks []anthropicBlock
+	_ = json.Unmarshal(raw, &blocks)
+	out := make([]converseContent, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Type == "text" {
+			out = append(out, converseContent{Text: b.Text})
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, converseContent{Text: ""})
+	}
+	return out
+}
+
+// anthropicToConverseBlocks 将 Anthropic content blocks 转为 Converse content；url 图片不受 Converse 支持，跳过。
+func anthropicToConverseBlocks(blocks []anthropicBlock) []converseContent {
+	out := make([]converseContent, 0, len(blocks))
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			out = append(out, converseContent{Text: b.Text})
+		case "image":
+			if b.Source == nil || b.Source.Type != "base64" {
+				continue
+			}
+			img := &converseImage{Format: strings.TrimPrefix(b.Source.MediaType, "image/")}
+			if img.Format == "jpg" {
+				img.Format = "jpeg"
+			}
+			img.Source.Bytes = b.Source.Data
+			out = append(out, converseContent{Image: img})
+		case "tool_use":
+			out = append(out, converseContent{ToolUse: &converseToolUse{ToolUseID: b.ID, Name: b.Name, Input: b.Input}})
+		case "tool_result":
+			out = append(out, converseContent{ToolResult: &converseToolResult{
+				ToolUseID: b.ToolUseID, Content: anthropicTextContent(b.Content),
+			}})
+		}
+	}
+	return out
+}
+
+// openAIToConverseRequest 将 OpenAI chat/completions 请求体转换为 Converse 请求，返回模型 ID 与是否流式。
+// 客户端未指定 max_tokens 时不下发 maxTokens，由模型使用默认上限。
+func openAIToConverseRequest(body []byte) (*converseRequest, string, bool, error) {
+	areq, err := openAIToAnthropicRequest(body)
+	if err != nil {
+		return nil, "", false, err
+	}
+	var limits struct {
+		MaxTokens           int `json:"max_tokens"`
+		MaxCompletionTokens int `json:"max_completion_tokens"`
+	}
+	_ = json.Unmarshal(body, &limits)
+
+	out := &converseRequest{Messages: make([]converseMessage, 0, len(areq.Messages))}
+	for _, m := range areq.Messages {
+		if content := anthropicToConverseBlocks(m.Content); len(content) > 0 {
+			out.Messages = append(out.Messages, converseMessage{Role: m.Role, Content: content})
+		}
+	}
+	if areq.System != "" {
+		out.System = []converseContent{{Text: areq.System}}
+	}
+	cfg := &converseInferenceConfig{Temperature: areq.Temperature, TopP: areq.TopP, StopSequences: areq.StopSequences}
+	if limits.MaxTokens > 0 || limits.MaxCompletionTokens > 0 {
+		cfg.MaxTokens = areq.MaxTokens
+	}
+	if cfg.MaxTokens > 0 || cfg.Temperature != nil || cfg.TopP != nil || len(cfg.StopSequences) > 0 {
+		out.InferenceConfig = cfg
+	}
+
+	if len(areq.Tools) > 0 {
+		tc := &converseToolConfig{Tools: make([]converseTool, 0, len(areq.Tools))}
+		for _, t := range areq.Tools {
+			var ct converseTool
+			ct.ToolSpec.Name, ct.ToolSpec.Description = t.Name, t.Description
+			ct.ToolSpec.InputSchema.JSON = t.InputSchema
+			tc.Tools = append(tc.Tools, ct)
+		}
+		// Converse 不支持 none：保留工具定义（历史消息中的 toolUse 需要），按 auto 处理
+		if choice, ok := areq.ToolChoice.(map[string]string); ok {
+			switch choice["type"] {
+			case "auto":
+				tc.ToolChoice = map[string]interface{}{"auto": struct{}{}}
+			case "any":
+				tc.ToolChoice = map[string]interface{}{"any": struct{}{}}
+			case "tool":
+				tc.ToolChoice = map[string]interface{}{"tool": map[string]string{"name": choice["name"]}}
+			}
+		}
+		out.ToolConfig = tc
+	}
+	return out, areq.Model, areq.Stream, nil
+}
+
+// converseToAnthropicResponse 将非流式 Converse 响应转换为 Anthropic Messages 响应 JSON（供 anthropicToOpenAIResponse 使用）。
+func converseToAnthropicResponse(data []byte, id string) ([]byte, error) {
+	var resp converseResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("解析 Converse 响应失败：%w", err)
+	}
+	out := anthropicResponse{ID: id, StopReason: resp.StopReason, Usage: resp.Usage.anthropic()}
+	for _, c := range resp.Output.Message.Content {
+		switch {
+		case c.ToolUse != nil:
+			out.Content = append(out.Content, anthropicBlock{
+				Type: "tool_use", ID: c.ToolUse.ToolUseID, Name: c.ToolUse.Name, Input: c.ToolUse.Input,
+			})
+		case c.ReasoningContent != nil && c.ReasoningContent.ReasoningText != nil:
+			out.Content = append(out.Content, anthropicBlock{Type: "thinking", Thinking: c.ReasoningContent.ReasoningText.Text})
+		case c.Text != "":
+			out.Content = append(out.Content, anthropicBlock{Type: "text", Text: c.Text})
+		}
+	}
+	return json.Marshal(out)
+}
+
+// parseConverseUsage 从非流式 Converse 响应中提取 usage。
+func parseConverseUsage(data []byte) gaia.TokenUsage {
+	var resp converseResponse
+	if json.Unmarshal(data, &resp) != nil {
+		return gaia.TokenUsage{}
+	}
+	return resp.Usage.anthropic().tokens()
+}
+
+// converseStreamEvent ConverseStream 事件中参与转换的字段
+type converseStreamEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+	Start             struct {
+		ToolUse *converseToolUse `json:"toolUse"`
+	} `json:"start"`
+	Delta struct {
+		Text    string `json:"text"`
+		ToolUse *struct {
+			Input string `json:"input"`
+		} `json:"toolUse"`
+		ReasoningContent *struct {
+			Text string `json:"text"`
+		} `json:"reasoningContent"`
+	} `json:"delta"`
+	StopReason string        `json:"stopReason"`
+	Usage      converseUsage `json:"usage"`
+}
+
+// converseEventToAnthropic 将一个 ConverseStream 事件转换为等价的 Anthropic 流式事件 JSON（可能为 0~2 个）：
+// messageStart→message_start，contentBlockStart(toolUse)→content_block_start，contentBlockDelta→content_block_delta，
+// messageStop→message_delta(stop_reason)，metadata→message_delta(usage)+message_stop，error→error。
+func converseEventToAnthropic(id, eventType string, payload []byte) [][]byte {
+	if eventType == "error" {
+		return [][]byte{payload}
+	}
+	var ev converseStreamEvent
+	if json.Unmarshal(payload, &ev) != nil {
+		return nil
+	}
+	marshal := func(v interface{}) []byte {
+		b, _ := json.Marshal(v)
+		return b
+	}
+	switch eventType {
+	case "messageStart":
+		return [][]byte{marshal(map[string]interface{}{
+			"type":    "message_start",
+			"message": map[string]interface{}{"id": id, "role": "assistant", "usage": anthropicUsage{}},
+		})}
+	case "contentBlockStart":
+		if ev.Start.ToolUse == nil {
+			return nil
+		}
+		return [][]byte{marshal(map[string]interface{}{
+			"type":  "content_block_start",
+			"index": ev.ContentBlockIndex,
+			"content_block": anthropicBlock{
+				Type: "tool_use", ID: ev.Start.ToolUse.ToolUseID, Name: ev.Start.ToolUse.Name, Input: json.RawMessage("{}"),
+			},
+		})}
+	case "contentBlockDelta":
+		delta := map[string]string{}
+		switch {
+		case ev.Delta.ToolUse != nil:
+			delta["type"], delta["partial_json"] = "input_json_delta", ev.Delta.ToolUse.Input
+		case ev.Delta.ReasoningContent != nil:
+			if ev.Delta.ReasoningContent.Text == "" {
+				return nil // 签名等非文本推理增量
+			}
+			delta["type"], delta["thinking"] = "thinking_delta", ev.Delta.ReasoningContent.Text
+		default:
+			delta["type"], delta["text"] = "text_delta", ev.Delta.Text
+		}
+		return [][]byte{marshal(map[string]interface{}{
+			"type": "content_block_delta", "index": ev.ContentBlockIndex, "delta": delta,
+		})}
+	case "messageStop":
+		return [][]byte{marshal(map[string]interface{}{
+			"type": "message_delta", "delta": map[string]string{"stop_reason": ev.StopReason},
+		})}
+	case "metadata":
+		return [][]byte{
+			marshal(map[string]interface{}{"type": "message_delta", "delta": map[string]string{}, "usage": ev.Usage.anthropic()}),
+			[]byte(`{"type":"message_stop"}`),
+		}
+	}
+	return nil
+}
+
+// readBedrockConverseEvents 解析 ConverseStream 的 vnd.amazon.eventstream 二进制流，回调事件类型（:event-type 头）与 JSON 负载；
+// 异常帧转换为 Anthropic 错误事件 {"type":"error","error":{...}}，事件类型为 error。
+func readBedrockConverseEvents(r io.Reader, fn func(eventType string, payload []byte) error) error {
+	dec := estream.NewDecoder(r)
+	payloadBuf := make([]byte, 0, 32*1024)
+	header := func(msg estream.Message, name string) string {
+		if v := msg.Headers.Get(name); v != nil {
+			return v.String()
+		}
+		return ""
+	}
+	for {
+		msg, err := dec.Decode(payloadBuf)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("eventstream decode 失败：%w", err)
+		}
+		switch header(msg, ":message-type") {
+		case "exception", "error":
+			var body struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(msg.Payload, &body)
+			typ := header(msg, ":exception-type") + header(msg, ":error-code")
+			if body.Message == "" {
+				body.Message = header(msg, ":error-message")
+			}
+			b, _ := json.Marshal(map[string]interface{}{
+				"type": "error", "error": map[string]string{"type": typ, "message": body.Message},
+			})
+			err = fn("error", b)
+		default:
+			err = fn(header(msg, ":event-type"), msg.Payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// proxyChatViaConverse 将 OpenAI chat/completions 请求转换为 Bedrock Converse（非 Anthropic 模型），
+// 响应（含流式事件与 usage）转换回 OpenAI 格式写给客户端；计费仍走 calcUsageCost。
+func (s *ModelProviderService) proxyChatViaConverse(
+	att *proxyAttempt, body []byte, writer io.Writer, creds *gaiaResponse.ProviderCredentials) error {
+	if creds == nil || creds.AWSAccessKeyID == "" || creds.AWSSecretAccessKey == "" {
+		return fmt.Errorf("AWS Bedrock 凭证缺失（需要 aws_access_key_id / aws_secret_access_key）")
+	}
+	creq, modelID, streaming, err := openAIToConverseRequest(body)
+	if err != nil {
+		return err
+	}
+	if modelID == "" {
+		return fmt.Errorf("chat/completions 请求缺少 model 字段")
+	}
+	clientModel := att.model
+	if clientModel == "" {
+		clientModel = modelID
+	}
+	payload, err := json.Marshal(creq)
+	if err != nil {
+		return fmt.Errorf("构建 Converse 请求失败：%w", err)
+	}
+	op := "converse"
+	if streaming {
+		op = "converse-stream"
+	}
+	httpReq, err := newBedrockRuntimeRequest(att.ctx, creds, modelID, op, payload, streaming)
+	if err != nil {
+		return err
+	}
+
+	// 1) 发起请求；可转移的失败在写回前交还 ProxyRequest
+	att.stream = streaming
+	startTime := time.Now()
+	resp, err := doUpstream(att, creds, httpReq)
+	if err != nil {
+		if att.clientCancelled() {
+			s.logCancelledAttempt(att, creds, modelID, startTime, gaia.TokenUsage{}, err)
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: att.provider, Err: err}
+		}
+		s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	att.trackFirstByte(resp)
+
+	w, _ := writer.(http.ResponseWriter)
+	if resp.StatusCode != http.StatusOK {
+		reportCredentialStatus(att.provider, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return &upstreamRetryableError{Provider: att.provider, Status: resp.StatusCode}
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+		}
+		_, _ = writer.Write(anthropicErrorToOpenAI(raw))
+		s.logProxyAttempt(att, creds, modelID, "error",
+			fmt.Sprintf("bedrock %d: %s", resp.StatusCode, string(raw)), startTime, gaia.TokenUsage{})
+		return nil
+	}
+
+	// 2) 转换响应
+	msgID := fmt.Sprintf("chatcmpl-bedrock-%d", startTime.UnixNano())
+	var usage gaia.TokenUsage
+	if streaming {
+		if w != nil {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher, _ := writer.(http.Flusher)
+		translator := newAnthropicStreamTranslator(clientModel)
+		err = readBedrockConverseEvents(resp.Body, func(eventType string, payload []byte) error {
+			for _, event := range converseEventToAnthropic(msgID, eventType, payload) {
+				att.trackStreamed(usageFormatAnthropic, event)
+				for _, data := range translator.translate(event) {
+					if _, e := writer.Write([]byte("data: " + string(data) + "\n\n")); e != nil {
+						return e
+					}
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+		usage = translator.usage
+		if err != nil {
+			if att.clientCancelled() {
+				s.logCancelledAttempt(att, creds, modelID, startTime, usage, err)
+				return err
+			}
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	} else {
+		raw, e := io.ReadAll(resp.Body)
+		if e != nil {
+			s.logProxyAttempt(att, creds, modelID, att.failureStatus(), e.Error(), startTime, gaia.TokenUsage{})
+			return e
+		}
+		var converted []byte
+		if converted, err = converseToAnthropicResponse(raw, msgID); err == nil {
+			converted, usage, err = anthropicToOpenAIResponse(converted, clientModel)
+		}
+		if err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+			return err
+		}
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}
+		if _, err = writer.Write(converted); err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	}
+
+	// 3) 记录日志 + 计费扣款
+	att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	s.logProxyAttempt(att, creds, modelID, "success", "", startTime, usage)
+	return nil
+}
+
+// proxyBedrockConverse 原生 Converse / ConverseStream 转发：body 原样签名转发；
+// 流式响应的 eventstream 帧重组为 SSE（event: <事件类型>\ndata: <json>\n\n），usage 取自 metadata 事件。
+func (s *ModelProviderService) proxyBedrockConverse(
+	att *proxyAttempt, path string, body []byte, writer io.Writer, creds *gaiaResponse.ProviderCredentials) error {
+	if creds == nil || creds.AWSAccessKeyID == "" || creds.AWSSecretAccessKey == "" {
+		return fmt.Errorf("AWS Bedrock 凭证缺失（需要 aws_access_key_id / aws_secret_access_key）")
+	}
+	modelID, streaming, _ := bedrockConversePath(path)
+	if att.upstream != "" && att.upstream != att.model {
+		modelID = att.upstream
+	}
+	op := "converse"
+	if streaming {
+		op = "converse-stream"
+	}
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	httpReq, err := newBedrockRuntimeRequest(att.ctx, creds, modelID, op, body, streaming)
+	if err != nil {
+		return err
+	}
+
+	att.stream = streaming
+	startTime := time.Now()
+	resp, err := doUpstream(att, creds, httpReq)
+	if err != nil {
+		if att.clientCancelled() {
+			s.logCancelledAttempt(att, creds, modelID, startTime, gaia.TokenUsage{}, err)
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: gaia.ProviderAWS, Err: err}
+		}
+		s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	att.trackFirstByte(resp)
+
+	w, _ := writer.(http.ResponseWriter)
+	if resp.StatusCode != http.StatusOK {
+		reportCredentialStatus(gaia.ProviderAWS, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return &upstreamRetryableError{Provider: gaia.ProviderAWS, Status: resp.StatusCode}
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+		}
+		_, _ = writer.Write(raw)
+		s.logProxyAttempt(att, creds, modelID, "error",
+			fmt.Sprintf("bedrock %d: %s", resp.StatusCode, string(raw)), startTime, gaia.TokenUsage{})
+		return nil
+	}
+
+	var usage gaia.TokenUsage
+	if streaming {
+		if w != nil {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher, _ := writer.(http.Flusher)
+		tracker := &proxyUsage{format: usageFormatAnthropic}
+		err = readBedrockConverseEvents(resp.Body, func(eventType string, payload []byte) error {
+			for _, event := range converseEventToAnthropic("", eventType, payload) {
+				tracker.feed(event)
+				att.trackStreamed(usageFormatAnthropic, event)
+			}
+			if _, e := writer.Write([]byte("event: " + eventType + "\ndata: " + string(payload) + "\n\n")); e != nil {
+				return e
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+		usage = tracker.tokens
+		if err != nil {
+			if att.clientCancelled() {
+				s.logCancelledAttempt(att, creds, modelID, startTime, usage, err)
+				return err
+			}
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	} else {
+		var buf bytes.Buffer
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}
+		if _, err = io.Copy(writer, io.TeeReader(resp.Body, &buf)); err != nil {
+			s.logProxyAttempt(att, creds, modelID, att.failureStatus(), err.Error(), startTime, gaia.TokenUsage{})
+			return err
+		}
+		usage = parseConverseUsage(buf.Bytes())
+	}
+
+	att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	s.logProxyAttempt(att, creds, modelID, "success", "", startTime, usage)
+	return nil
+}
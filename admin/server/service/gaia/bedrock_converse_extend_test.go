@@ -0,0 +1,176 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	estream "github.com/aws/aws-sdk-go/private/protocol/eventstream"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// TestBedrockConversePath 测试从原生 Converse 路径解析模型 ID 与流式标记
+func TestBedrockConversePath(t *testing.T) {
+	cases := []struct {
+		path      string
+		model     string
+		streaming bool
+		ok        bool
+	}{
+		{"model/amazon.nova-pro-v1:0/converse", "amazon.nova-pro-v1:0", false, true},
+		{"v1/model/meta.llama3-3-70b-instruct-v1%3A0/converse-stream", "meta.llama3-3-70b-instruct-v1:0", true, true},
+		{"model/amazon.nova-pro-v1:0/invoke", "", false, false},
+		{"v1beta/models/gemini-2.5-pro:generateContent", "", false, false},
+	}
+	for _, c := range cases {
+		model, streaming, ok := bedrockConversePath(c.path)
+		if model != c.model || streaming != c.streaming || ok != c.ok {
+			t.Errorf("%s：得到 (%q, %v, %v)", c.path, model, streaming, ok)
+		}
+	}
+	if !isBedrockAnthropicModel("us.anthropic.claude-sonnet-4-6-v1:0") || isBedrockAnthropicModel("amazon.nova-lite-v1:0") {
+		t.Error("Anthropic 模型识别错误")
+	}
+}
+
+// TestBedrockRuntimeRequestModelID 测试路径中的模型 ID 校验与转义：拒绝 / ? # .. 等改写签名请求目标的 ID，ARN 转义后放行
+func TestBedrockRuntimeRequestModelID(t *testing.T) {
+	creds := &gaiaResponse.ProviderCredentials{AWSAccessKeyID: "AKIAEXAMPLE", AWSSecretAccessKey: "secret", AWSRegion: "us-west-2"}
+	modelID, _, _ := bedrockConversePath("model/..%2Fasync-invoke%3F/converse")
+	for _, bad := range []string{modelID, "amazon.nova-pro-v1:0/../../async-invoke", "nova#frag", "nova?x=1", ""} {
+		if _, err := newBedrockRuntimeRequest(context.Background(), creds, bad, "converse", []byte("{}"), false); err == nil {
+			t.Errorf("非法模型 ID 应拒绝：%q", bad)
+		}
+	}
+	req, err := newBedrockRuntimeRequest(context.Background(), creds, "us.amazon.nova-pro-v1:0", "converse", []byte("{}"), false)
+	if err != nil || req.URL.EscapedPath() != "/model/us.amazon.nova-pro-v1:0/converse" {
+		t.Errorf("模型 ID 请求地址错误：%v %v", req, err)
+	}
+	arn := "arn:aws:bedrock:us-west-2:123456789012:inference-profile/us.anthropic.claude-sonnet-4-6-v1:0"
+	req, err = newBedrockRuntimeRequest(context.Background(), creds, arn, "converse-stream", []byte("{}"), true)
+	if err != nil || req.URL.EscapedPath() != "/model/"+url.PathEscape(arn)+"/converse-stream" {
+		t.Errorf("ARN 请求地址错误：%v %v", req, err)
+	}
+}
+
+// TestOpenAIToConverseRequest 测试 OpenAI 请求转换为 Converse：system、工具调用与结果、推理参数
+func TestOpenAIToConverseRequest(t *testing.T) {
+	body := `{"model":"amazon.nova-pro-v1:0","stream":true,"temperature":0.2,"messages":[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]},
+		{"role":"assistant","content":null,"tool_calls":[{"id":"t1","type":"function","function":{"name":"get","arguments":"{\"q\":1}"}}]},
+		{"role":"tool","tool_call_id":"t1","content":"ok"}],
+		"tools":[{"type":"function","function":{"name":"get","parameters":{"type":"object"}}}],"tool_choice":"required"}`
+	req, model, streaming, err := openAIToConverseRequest([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model != "amazon.nova-pro-v1:0" || !streaming {
+		t.Errorf("模型或流式标记错误：%s %v", model, streaming)
+	}
+	if len(req.System) != 1 || req.System[0].Text != "be brief" {
+		t.Errorf("system 转换错误：%+v", req.System)
+	}
+	if len(req.Messages) != 3 || req.Messages[0].Content[1].Image == nil || req.Messages[0].Content[1].Image.Format != "png" {
+		t.Fatalf("消息转换错误：%+v", req.Messages)
+	}
+	if tu := req.Messages[1].Content[0].ToolUse; tu == nil || tu.ToolUseID != "t1" || string(tu.Input) != `{"q":1}` {
+		t.Errorf("toolUse 转换错误：%+v", req.Messages[1])
+	}
+	if tr := req.Messages[2].Content[0].ToolResult; tr == nil || tr.Content[0].Text != "ok" {
+		t.Errorf("toolResult 转换错误：%+v", req.Messages[2])
+	}
+	if req.InferenceConfig == nil || req.InferenceConfig.MaxTokens != 0 || *req.InferenceConfig.Temperature != 0.2 {
+		t.Errorf("未指定 max_tokens 时不应下发 maxTokens：%+v", req.InferenceConfig)
+	}
+	if req.ToolConfig == nil || req.ToolConfig.ToolChoice["any"] == nil {
+		t.Errorf("tool_choice=required 应转换为 any：%+v", req.ToolConfig)
+	}
+}
+
+// TestConverseResponseToOpenAI 测试非流式 Converse 响应转换为 OpenAI chat.completion 与 usage 提取
+func TestConverseResponseToOpenAI(t *testing.T) {
+	raw := []byte(`{"output":{"message":{"role":"assistant","content":[{"text":"hello"},
+		{"toolUse":{"toolUseId":"t1","name":"get","input":{"q":1}}}]}},"stopReason":"tool_use",
+		"usage":{"inputTokens":10,"outputTokens":5,"totalTokens":15,"cacheReadInputTokens":4}}`)
+	converted, err := converseToAnthropicResponse(raw, "msg-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, usage, err := anthropicToOpenAIResponse(converted, "nova")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp openAIChatResponse
+	_ = json.Unmarshal(out, &resp)
+	msg := resp.Choices[0].Message
+	if *msg.Content != "hello" || len(msg.ToolCalls) != 1 || *resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("响应转换错误：%s", out)
+	}
+	if usage.PromptTokens != 14 || usage.CompletionTokens != 5 || usage.CacheReadTokens != 4 {
+		t.Errorf("usage 错误：%+v", usage)
+	}
+	if u := parseConverseUsage(raw); u != usage {
+		t.Errorf("parseConverseUsage 与转换结果不一致：%+v", u)
+	}
+}
+
+// TestConverseStreamToOpenAI 测试 ConverseStream 事件流解码并转换为 OpenAI chunk，usage 取自 metadata
+func TestConverseStreamToOpenAI(t *testing.T) {
+	var stream bytes.Buffer
+	enc := estream.NewEncoder(&stream)
+	events := []struct{ typ, payload string }{
+		{"messageStart", `{"role":"assistant"}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hel"}}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"lo"}}`},
+		{"contentBlockStop", `{"contentBlockIndex":0}`},
+		{"messageStop", `{"stopReason":"end_turn"}`},
+		{"metadata", `{"usage":{"inputTokens":7,"outputTokens":2,"totalTokens":9},"metrics":{"latencyMs":100}}`},
+	}
+	for _, e := range events {
+		var headers estream.Headers
+		headers.Set(":message-type", estream.StringValue("event"))
+		headers.Set(":event-type", estream.StringValue(e.typ))
+		if err := enc.Encode(estream.Message{Headers: headers, Payload: []byte(e.payload)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	translator := newAnthropicStreamTranslator("nova")
+	var text strings.Builder
+	var finish string
+	done := false
+	err := readBedrockConverseEvents(&stream, func(eventType string, payload []byte) error {
+		for _, event := range converseEventToAnthropic("msg-1", eventType, payload) {
+			for _, data := range translator.translate(event) {
+				if string(data) == "[DONE]" {
+					done = true
+					continue
+				}
+				var chunk openAIChatResponse
+				_ = json.Unmarshal(data, &chunk)
+				for _, c := range chunk.Choices {
+					if c.Delta != nil && c.Delta.Content != nil {
+						text.WriteString(*c.Delta.Content)
+					}
+					if c.FinishReason != nil {
+						finish = *c.FinishReason
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "Hello" || finish != "stop" || !done {
+		t.Errorf("流式转换错误：text=%q finish=%q done=%v", text.String(), finish, done)
+	}
+	if translator.usage.PromptTokens != 7 || translator.usage.CompletionTokens != 2 {
+		t.Errorf("usage 错误：%+v", translator.usage)
+	}
+}
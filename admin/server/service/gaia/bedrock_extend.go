@@ -2,11 +2,14 @@ package gaia
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -20,7 +23,7 @@ import (
 	"go.uber.org/zap"
 )
 
-// proxyBedrockRequest 直连 AWS Bedrock 原生 API 转发 Anthropic Messages 请求。
+// proxyBedrockRequest 直连 AWS Bedrock 原生 API 转发 Anthropic Messages 请求（非 Anthropic 模型走 Converse，见 bedrock_converse_extend.go）。
 //
 // 路径转换：v1/messages → model/{modelId}/invoke 或 model/{modelId}/invoke-with-response-stream
 // 鉴权：SigV4（service=bedrock，region 来自 Dify 凭证 aws_region 字段）
@@ -30,19 +33,16 @@ import (
 //   - 流式：vnd.amazon.eventstream 二进制帧，每帧 payload 是 {"bytes":"<base64>"}，
 //     解出后是 Anthropic SSE 事件 JSON，在此重组为标准 SSE 写回客户端
 //
-// 计费：成功后按 (input_tokens, output_tokens) 调 calcQuotaDelta 扣额。
+// 计费：成功后按 usage（含缓存命中/写入 token）调 calcUsageCost 扣额。
+// 失败转移：att.allowRetry 为 true 时，连接失败或 5xx/429 以 *upstreamRetryableError 返回，不写回客户端。
 func (s *ModelProviderService) proxyBedrockRequest(
-	userID, _ /* path */, method string, _ /* reqHeader */ http.Header, body []byte, writer io.Writer,
+	att *proxyAttempt, _ /* path */, _ /* method */ string, _ /* reqHeader */ http.Header, body []byte, writer io.Writer,
 	creds *gaiaResponse.ProviderCredentials,
 ) error {
 	// 1) 校验 AWS 凭证
 	if creds == nil || creds.AWSAccessKeyID == "" || creds.AWSSecretAccessKey == "" {
 		return fmt.Errorf("AWS Bedrock 凭证缺失（需要 aws_access_key_id / aws_secret_access_key）")
 	}
-	region := creds.AWSRegion
-	if region == "" {
-		region = "us-east-1"
-	}
 
 	// 2) 解析 body：拿到 modelId 与 stream 标记，并改写为 Bedrock 期望的格式
 	if len(body) == 0 {
@@ -56,6 +56,9 @@ func (s *ModelProviderService) proxyBedrockRequest(
 	if modelID == "" {
 		return fmt.Errorf("Bedrock 请求 body 缺少 model 字段")
 	}
+	if !isBedrockAnthropicModel(modelID) {
+		return fmt.Errorf("Bedrock 模型 %s 不是 Anthropic 模型，请使用 chat/completions 或 model/{modelId}/converse 接口", modelID)
+	}
 	streaming := false
 	if v, ok := bodyObj["stream"].(bool); ok {
 		streaming = v
@@ -66,49 +69,44 @@ func (s *ModelProviderService) proxyBedrockRequest(
 	delete(bodyObj, "stream_options")
 	// 注入 Bedrock 必需的 anthropic_version
 	if _, ok := bodyObj["anthropic_version"]; !ok {
-		bodyObj["anthropic_version"] = "bedrock-2023-05-31"
+		bodyObj["anthropic_version"] = gaia.BedrockAnthropicVersion
 	}
 	rewritten, err := json.Marshal(bodyObj)
 	if err != nil {
 		return fmt.Errorf("重写 Bedrock 请求 body 失败：%w", err)
 	}
 
-	// 3) 构建 Bedrock URL
-	host := fmt.Sprintf("bedrock-runtime.%s.amazonaws.com", region)
-	op := "invoke"
-	if streaming {
-		op = "invoke-with-response-stream"
-	}
-	requestURL := fmt.Sprintf("https://%s/model/%s/%s", host, modelID, op)
-
-	httpReq, err := http.NewRequest(method, requestURL, bytes.NewReader(rewritten))
+	// 3) 构建并签名 Bedrock 请求
+	httpReq, err := newBedrockInvokeRequest(att.ctx, creds, modelID, rewritten, streaming)
 	if err != nil {
-		return fmt.Errorf("构建 Bedrock 请求失败：%w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if streaming {
-		httpReq.Header.Set("Accept", "application/vnd.amazon.eventstream")
-		httpReq.Header.Set("X-Amzn-Bedrock-Accept", "application/json")
-	} else {
-		httpReq.Header.Set("Accept", "application/json")
-	}
-
-	// 4) SigV4 签名（service=bedrock）
-	awsCreds := credentials.NewStaticCredentials(creds.AWSAccessKeyID, creds.AWSSecretAccessKey, creds.AWSSessionToken)
-	signer := v4.NewSigner(awsCreds)
-	if _, err = signer.Sign(httpReq, bytes.NewReader(rewritten), "bedrock", region, time.Now()); err != nil {
-		return fmt.Errorf("Bedrock SigV4 签名失败：%w", err)
+		return err
 	}
 
 	// 5) 发起请求
+	att.stream = streaming
 	startTime := time.Now()
-	client := &http.Client{Timeout: 5 * time.Minute}
-	resp, err := client.Do(httpReq)
+	resp, err := doUpstream(att, creds, httpReq)
 	if err != nil {
-		s.logBedrock(userID, modelID, "error", err.Error(), startTime, 0, 0)
+		if att.clientCancelled() {
+			s.logCancelledAttempt(att, creds, modelID, startTime, gaia.TokenUsage{}, err)
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: gaia.ProviderAWS, Err: err}
+		}
+		s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
+	att.trackFirstByte(resp)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		reportCredentialStatus(gaia.ProviderAWS, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return &upstreamRetryableError{Provider: gaia.ProviderAWS, Status: resp.StatusCode}
+		}
+	}
 
 	// 6) 写回响应头/状态码（流式改写 Content-Type 为 SSE）
 	if w, ok := writer.(http.ResponseWriter); ok {
@@ -117,6 +115,9 @@ func (s *ModelProviderService) proxyBedrockRequest(
 			if streaming && (lower == "content-type" || lower == "content-length") {
 				continue
 			}
+			if gatewayRateLimitHeaderSet(w.Header(), k) {
+				continue
+			}
 			for _, vv := range v {
 				w.Header().Add(k, vv)
 			}
@@ -132,55 +133,112 @@ func (s *ModelProviderService) proxyBedrockRequest(
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		raw, _ := io.ReadAll(resp.Body)
 		_, _ = writer.Write(raw)
-		s.logBedrock(userID, modelID, "error",
-			fmt.Sprintf("bedrock %d: %s", resp.StatusCode, string(raw)), startTime, 0, 0)
+		s.logProxyAttempt(att, creds, modelID, "error",
+			fmt.Sprintf("bedrock %d: %s", resp.StatusCode, string(raw)), startTime, gaia.TokenUsage{})
 		return nil
 	}
 
 	// 7) 处理响应体
-	var inputTokens, outputTokens int
+	var usage gaia.TokenUsage
 	if streaming {
-		inputTokens, outputTokens, err = s.streamBedrockEventStream(resp.Body, writer)
+		usage, err = s.streamBedrockEventStream(att, resp.Body, writer)
 		if err != nil {
-			s.logBedrock(userID, modelID, "error", err.Error(), startTime, inputTokens, outputTokens)
+			if att.clientCancelled() {
+				s.logCancelledAttempt(att, creds, modelID, startTime, usage, err)
+				return err
+			}
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
 			return err
 		}
 	} else {
 		var buf bytes.Buffer
 		tee := io.TeeReader(resp.Body, &buf)
 		if _, err = io.Copy(writer, tee); err != nil {
-			s.logBedrock(userID, modelID, "error", err.Error(), startTime, 0, 0)
+			s.logProxyAttempt(att, creds, modelID, att.failureStatus(), err.Error(), startTime, gaia.TokenUsage{})
 			return err
 		}
-		inputTokens, outputTokens = parseAnthropicUsage(buf.Bytes())
+		usage = parseAnthropicUsage(buf.Bytes())
 	}
 
 	// 8) 记录日志 + 计费扣款
-	s.logBedrock(userID, modelID, "success", "", startTime, inputTokens, outputTokens)
-	if inputTokens > 0 || outputTokens > 0 {
-		pricing, _ := s.fetchModelPricingFromDify(modelID)
-		delta := calcQuotaDelta(pricing, modelID, inputTokens, outputTokens)
-		deductAccountQuota(userID, delta)
-	}
+	att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	s.logProxyAttempt(att, creds, modelID, "success", "", startTime, usage)
 	return nil
 }
 
-// streamBedrockEventStream 解析 Bedrock 的 vnd.amazon.eventstream 二进制流，
-// 把每个事件还原为 Anthropic SSE（event: <type>\ndata: <json>\n\n）写给客户端。
-// 返回累计的 input/output token 数（用于计费）。
-func (s *ModelProviderService) streamBedrockEventStream(r io.Reader, w io.Writer) (int, int, error) {
-	flusher, _ := w.(http.Flusher)
+// newBedrockInvokeRequest 构建 Bedrock InvokeModel（流式为 invoke-with-response-stream）请求并完成 SigV4 签名。
+// payload 为已去掉 model/stream 字段的 Anthropic Messages body。
+func newBedrockInvokeRequest(ctx context.Context,
+	creds *gaiaResponse.ProviderCredentials, modelID string, payload []byte, streaming bool) (*http.Request, error) {
+	op := "invoke"
+	if streaming {
+		op = "invoke-with-response-stream"
+	}
+	return newBedrockRuntimeRequest(ctx, creds, modelID, op, payload, streaming)
+}
+
+// bedrockModelIDPattern 模型 ID / 跨区域推理配置 ID（如 us.anthropic.claude-sonnet-4-6-v1:0）
+var bedrockModelIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// bedrockModelARNPattern Bedrock 资源 ARN（基础模型、推理配置、预置吞吐量等），资源类型与 ID 之间允许一个 /
+var bedrockModelARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:bedrock:[a-z0-9-]+:(\d{12})?:[a-z-]+/[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// validBedrockModelID 校验模型 ID：模型 ID 来自客户端路径，拼入 URL 后以网关凭证签名，须拒绝 / ? # .. 等可改写请求目标的字符（ARN 单独放行）。
+func validBedrockModelID(modelID string) bool {
+	if strings.Contains(modelID, "..") {
+		return false
+	}
+	return bedrockModelIDPattern.MatchString(modelID) || bedrockModelARNPattern.MatchString(modelID)
+}
+
+// newBedrockRuntimeRequest 构建 bedrock-runtime 的 model/{modelId}/{op} 请求并完成 SigV4 签名（模型 ID 校验后按路径段转义）；
+// 流式接口（invoke-with-response-stream / converse-stream）返回 vnd.amazon.eventstream。
+func newBedrockRuntimeRequest(ctx context.Context,
+	creds *gaiaResponse.ProviderCredentials, modelID, op string, payload []byte, streaming bool) (*http.Request, error) {
+	if !validBedrockModelID(modelID) {
+		return nil, fmt.Errorf("非法的 Bedrock 模型 ID：%q", modelID)
+	}
+	region := creds.AWSRegion
+	if region == "" {
+		region = "us-east-1"
+	}
+	host := fmt.Sprintf("bedrock-runtime.%s.amazonaws.com", region)
+	requestURL := fmt.Sprintf("https://%s/model/%s/%s", host, url.PathEscape(modelID), op)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("构建 Bedrock 请求失败：%w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if streaming {
+		httpReq.Header.Set("Accept", "application/vnd.amazon.eventstream")
+		if op == "invoke-with-response-stream" {
+			httpReq.Header.Set("X-Amzn-Bedrock-Accept", "application/json")
+		}
+	} else {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+
+	// SigV4 签名（service=bedrock）
+	awsCreds := credentials.NewStaticCredentials(creds.AWSAccessKeyID, creds.AWSSecretAccessKey, creds.AWSSessionToken)
+	signer := v4.NewSigner(awsCreds)
+	if _, err = signer.Sign(httpReq, bytes.NewReader(payload), "bedrock", region, time.Now()); err != nil {
+		return nil, fmt.Errorf("Bedrock SigV4 签名失败：%w", err)
+	}
+	return httpReq, nil
+}
+
+// readBedrockEvents 解析 Bedrock 的 vnd.amazon.eventstream 二进制流，对每个事件回调解包后的 Anthropic 事件 JSON。
+func readBedrockEvents(r io.Reader, fn func(inner []byte) error) error {
 	dec := estream.NewDecoder(r)
 	payloadBuf := make([]byte, 0, 32*1024)
-
-	var inputTokens, outputTokens int
 	for {
 		msg, err := dec.Decode(payloadBuf)
 		if err != nil {
 			if err == io.EOF {
-				return inputTokens, outputTokens, nil
+				return nil
 			}
-			return inputTokens, outputTokens, fmt.Errorf("eventstream decode 失败：%w", err)
+			return fmt.Errorf("eventstream decode 失败：%w", err)
 		}
 
 		// Bedrock 的事件 payload 形如 {"bytes":"<base64-encoded inner JSON>"}
@@ -197,35 +255,27 @@ func (s *ModelProviderService) streamBedrockEventStream(r io.Reader, w io.Writer
 			// 非包装格式（如错误/ping），直接用原 payload
 			inner = msg.Payload
 		}
+		if err = fn(inner); err != nil {
+			return err
+		}
+	}
+}
 
+// streamBedrockEventStream 解析 Bedrock 的 vnd.amazon.eventstream 二进制流，
+// 把每个事件还原为 Anthropic SSE（event: <type>\ndata: <json>\n\n）写给客户端。
+// 返回累计的 token 用量（用于计费）。
+func (s *ModelProviderService) streamBedrockEventStream(att *proxyAttempt, r io.Reader, w io.Writer) (gaia.TokenUsage, error) {
+	flusher, _ := w.(http.Flusher)
+	usage := &proxyUsage{format: usageFormatAnthropic}
+	err := readBedrockEvents(r, func(inner []byte) error {
 		// 解析事件类型和 usage（Anthropic 在 message_start.message.usage 给 input_tokens，
 		// message_delta.usage 给 output_tokens）
 		var ev struct {
-			Type    string `json:"type"`
-			Message struct {
-				Usage struct {
-					InputTokens  int `json:"input_tokens"`
-					OutputTokens int `json:"output_tokens"`
-				} `json:"usage"`
-			} `json:"message"`
-			Usage struct {
-				InputTokens  int `json:"input_tokens"`
-				OutputTokens int `json:"output_tokens"`
-			} `json:"usage"`
+			Type string `json:"type"`
 		}
 		_ = json.Unmarshal(inner, &ev)
-		if ev.Message.Usage.InputTokens > 0 {
-			inputTokens = ev.Message.Usage.InputTokens
-		}
-		if ev.Message.Usage.OutputTokens > 0 {
-			outputTokens = ev.Message.Usage.OutputTokens
-		}
-		if ev.Usage.InputTokens > 0 {
-			inputTokens = ev.Usage.InputTokens
-		}
-		if ev.Usage.OutputTokens > 0 {
-			outputTokens = ev.Usage.OutputTokens
-		}
+		usage.feed(inner)
+		att.trackStreamed(usageFormatAnthropic, inner)
 
 		// 重组为 Anthropic SSE 写回
 		eventName := ev.Type
@@ -233,41 +283,57 @@ func (s *ModelProviderService) streamBedrockEventStream(r io.Reader, w io.Writer
 			eventName = "message"
 		}
 		sse := "event: " + eventName + "\ndata: " + string(inner) + "\n\n"
-		if _, err = w.Write([]byte(sse)); err != nil {
-			return inputTokens, outputTokens, err
+		if _, err := w.Write([]byte(sse)); err != nil {
+			return err
 		}
 		if flusher != nil {
 			flusher.Flush()
 		}
-	}
+		return nil
+	})
+	return usage.tokens, err
 }
 
 // parseAnthropicUsage 从非流式 Anthropic Messages 响应 JSON 中提取 usage 字段。
-func parseAnthropicUsage(data []byte) (input, output int) {
+func parseAnthropicUsage(data []byte) gaia.TokenUsage {
 	var obj struct {
-		Usage struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
-		} `json:"usage"`
+		Usage anthropicUsage `json:"usage"`
 	}
 	if json.Unmarshal(data, &obj) == nil {
-		return obj.Usage.InputTokens, obj.Usage.OutputTokens
+		return obj.Usage.tokens()
 	}
-	return 0, 0
+	return gaia.TokenUsage{}
 }
 
-// logBedrock 记录代理日志（与 ProxyRequest 中的 ModelProxyLog 行为一致）。
-func (s *ModelProviderService) logBedrock(userID, modelID, status, errMsg string, startTime time.Time, in, out int) {
-	if err := global.GVA_DB.Create(&gaia.ModelProxyLog{
-		UserId:         userID,
-		ProviderName:   gaia.ProviderAWS,
-		ModelName:      modelID,
-		RequestTokens:  in,
-		ResponseTokens: out,
-		Status:         status,
-		ErrorMessage:   errMsg,
-		CreatedAt:      startTime,
-	}).Error; err != nil {
-		global.GVA_LOG.Warn("logBedrock 写日志失败", zap.Error(err))
+// logProxyAttempt 记录一次转发的代理日志（与 ProxyRequest 中的 ModelProxyLog 行为一致，供 Bedrock / 协议转换路径使用）。
+func (s *ModelProviderService) logProxyAttempt(
+	att *proxyAttempt, creds *gaiaResponse.ProviderCredentials, modelID, status, errMsg string, startTime time.Time, usage gaia.TokenUsage) {
+	log := &gaia.ModelProxyLog{
+		UserId:            att.caller.AccountId,
+		ProviderName:      att.provider,
+		ModelName:         modelID,
+		CredentialId:      creds.CredentialID,
+		FailoverChain:     att.failoverChain(),
+		RequestTokens:     usage.PromptTokens,
+		ResponseTokens:    usage.CompletionTokens,
+		CacheReadTokens:   usage.CacheReadTokens,
+		CacheWriteTokens:  usage.CacheWriteTokens,
+		ReasoningTokens:   usage.ReasoningTokens,
+		AudioInputTokens:  usage.AudioInputTokens,
+		AudioOutputTokens: usage.AudioOutputTokens,
+		DurationMs:        time.Since(startTime).Milliseconds(),
+		TtfbMs:            elapsedMs(startTime, att.firstByteAt),
+		UpstreamStatus:    att.upstreamStatus,
+		CostUsd:           att.cost,
+		RequestId:         att.caller.RequestId,
+		Stream:            att.stream,
+		ClientIp:          att.caller.ClientIP,
+		Status:            status,
+		ErrorMessage:      errMsg,
+		CreatedAt:         startTime,
+	}
+	if err := global.GVA_DB.Create(log).Error; err != nil {
+		global.GVA_LOG.Warn("logProxyAttempt 写日志失败", zap.Error(err))
 	}
+	observeProxyLog(log)
 }
@@ -0,0 +1,239 @@
+package gaia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+)
+
+// CircuitOpenError 提供商（或其全部凭证）处于熔断中，本次未请求上游
+type CircuitOpenError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("提供商 %s 上游连续失败已熔断，请 %d 秒后重试", e.Provider, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds 返回 Retry-After 响应头的秒数（至少 1 秒）
+func (e *CircuitOpenError) RetryAfterSeconds() int {
+	if sec := int(math.Ceil(e.RetryAfter.Seconds())); sec > 1 {
+		return sec
+	}
+	return 1
+}
+
+// String 用于转移链路记录，如 "tongyi:open"。
+func (e *CircuitOpenError) String() string {
+	return e.Provider + ":open"
+}
+
+// breakerEntry 单个熔断器（提供商或提供商下的一条凭证）的状态
+type breakerEntry struct {
+	provider     string
+	credentialID string
+	state        string
+	consecutive  int       // 连续失败次数
+	windowStart  time.Time // 失败率统计窗口起点
+	requests     int       // 窗口内请求数
+	failures     int       // 窗口内失败数
+	openedAt     time.Time // 最近一次熔断时间
+	probeAt      time.Time // 半开状态下探测请求的放行时间，零值表示尚未放行
+	lastError    string
+}
+
+// circuitBreaker 进程内上游熔断器：按 provider 与 provider|credential 分别维护状态。
+type circuitBreaker struct {
+	mutex   sync.Mutex
+	entries map[string]*breakerEntry
+}
+
+// 全局上游熔断器实例
+var globalCircuitBreaker = newCircuitBreaker()
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{entries: make(map[string]*breakerEntry)}
+}
+
+// circuitBreakerConfig 返回熔断配置（窗口与熔断时长未配置时取默认值），两种熔断条件均未配置时视为关闭。
+func circuitBreakerConfig() (cfg config.GaiaCircuitBreaker, window, openDuration time.Duration, enabled bool) {
+	cfg = global.GVA_CONFIG.Gaia.Gateway.CircuitBreaker
+	window, openDuration = time.Minute, 30*time.Second
+	if cfg.Window > 0 {
+		window = time.Duration(cfg.Window) * time.Second
+	}
+	if cfg.OpenDuration > 0 {
+		openDuration = time.Duration(cfg.OpenDuration) * time.Second
+	}
+	return cfg, window, openDuration, cfg.FailureThreshold > 0 || cfg.ErrorRate > 0
+}
+
+func circuitKey(providerName, credentialID string) string {
+	if credentialID == "" {
+		return providerName
+	}
+	return balancerKey(providerName, credentialID)
+}
+
+// check 判断是否放行，不放行时返回预计可重试的等待时长；claim 为 true 时占用半开探测名额（熔断到期即转为半开）。
+func (b *circuitBreaker) check(providerName, credentialID string, now time.Time, claim bool) (bool, time.Duration) {
+	_, _, openDuration, enabled := circuitBreakerConfig()
+	if !enabled {
+		return true, 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	e := b.entries[circuitKey(providerName, credentialID)]
+	if e == nil || e.state == gaia.CircuitStateClosed {
+		return true, 0
+	}
+	since := e.openedAt
+	if e.state == gaia.CircuitStateHalfOpen {
+		// 探测请求未上报结果（如请求未发出）时，超过熔断时长后允许再次探测
+		since = e.probeAt
+	}
+	if !since.IsZero() && now.Sub(since) < openDuration {
+		return false, openDuration - now.Sub(since)
+	}
+	if claim {
+		e.state, e.probeAt = gaia.CircuitStateHalfOpen, now
+	}
+	return true, 0
+}
+
+// allow 判断是否放行并占用半开探测名额，供真正发起请求前调用。
+func (b *circuitBreaker) allow(providerName, credentialID string, now time.Time) (bool, time.Duration) {
+	return b.check(providerName, credentialID, now, true)
+}
+
+// ready 只读判断是否放行，用于筛选候选凭证。
+func (b *circuitBreaker) ready(providerName, credentialID string, now time.Time) (bool, time.Duration) {
+	return b.check(providerName, credentialID, now, false)
+}
+
+// record 记录一次上游结果：半开探测成功则恢复，失败则重新熔断；关闭状态下连续失败或窗口失败率达到阈值时熔断。
+func (b *circuitBreaker) record(providerName, credentialID string, failed bool, reason string, now time.Time) {
+	cfg, window, _, enabled := circuitBreakerConfig()
+	if !enabled {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := circuitKey(providerName, credentialID)
+	e := b.entries[key]
+	if e == nil {
+		e = &breakerEntry{provider: providerName, credentialID: credentialID, state: gaia.CircuitStateClosed, windowStart: now}
+		b.entries[key] = e
+	}
+	if now.Sub(e.windowStart) >= window {
+		e.windowStart, e.requests, e.failures = now, 0, 0
+	}
+	e.requests++
+
+	if !failed {
+		e.consecutive = 0
+		if e.state != gaia.CircuitStateClosed {
+			e.state, e.probeAt = gaia.CircuitStateClosed, time.Time{}
+			e.windowStart, e.requests, e.failures = now, 1, 0
+			global.GVA_LOG.Info("上游熔断恢复", zap.String("provider", providerName), zap.String("credential_id", credentialID))
+		}
+		return
+	}
+	e.failures++
+	e.consecutive++
+	e.lastError = reason
+
+	trip := e.state == gaia.CircuitStateHalfOpen
+	if e.state == gaia.CircuitStateClosed {
+		minRequests := cfg.MinRequests
+		if minRequests < 1 {
+			minRequests = 1
+		}
+		trip = (cfg.FailureThreshold > 0 && e.consecutive >= cfg.FailureThreshold) ||
+			(cfg.ErrorRate > 0 && e.requests >= minRequests && float64(e.failures)/float64(e.requests) >= cfg.ErrorRate)
+	}
+	if trip {
+		e.state, e.openedAt, e.probeAt = gaia.CircuitStateOpen, now, time.Time{}
+		global.GVA_LOG.Warn("上游熔断",
+			zap.String("provider", providerName),
+			zap.String("credential_id", credentialID),
+			zap.Int("consecutive_failures", e.consecutive),
+			zap.Int("window_requests", e.requests),
+			zap.Int("window_failures", e.failures),
+			zap.String("last_error", reason))
+	}
+}
+
+// report 按上游响应上报提供商与凭证熔断器：连接失败与 5xx 计为失败，客户端取消不计。
+func (b *circuitBreaker) report(providerName string, creds *gaiaResponse.ProviderCredentials, resp *http.Response, err error) {
+	if err != nil && errors.Is(err, context.Canceled) {
+		return
+	}
+	var failed bool
+	var reason string
+	if err != nil {
+		failed, reason = true, err.Error()
+	} else if resp.StatusCode >= http.StatusInternalServerError {
+		failed, reason = true, fmt.Sprintf("upstream %d", resp.StatusCode)
+	}
+	now := time.Now()
+	b.record(providerName, "", failed, reason, now)
+	if creds != nil && creds.CredentialID != "" {
+		b.record(providerName, creds.CredentialID, failed, reason, now)
+	}
+}
+
+// snapshot 返回全部熔断器状态（按提供商、凭证排序）。
+func (b *circuitBreaker) snapshot() []gaiaResponse.CircuitBreakerStatus {
+	_, _, openDuration, _ := circuitBreakerConfig()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	list := make([]gaiaResponse.CircuitBreakerStatus, 0, len(b.entries))
+	for _, e := range b.entries {
+		item := gaiaResponse.CircuitBreakerStatus{
+			ProviderName:        e.provider,
+			CredentialID:        e.credentialID,
+			State:               e.state,
+			ConsecutiveFailures: e.consecutive,
+			WindowRequests:      e.requests,
+			WindowFailures:      e.failures,
+			LastError:           e.lastError,
+		}
+		if e.requests > 0 {
+			item.ErrorRate = float64(e.failures) / float64(e.requests)
+		}
+		if !e.openedAt.IsZero() {
+			openedAt := e.openedAt
+			item.OpenedAt = &openedAt
+		}
+		if e.state == gaia.CircuitStateOpen {
+			retryAt := e.openedAt.Add(openDuration)
+			item.RetryAt = &retryAt
+		}
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ProviderName != list[j].ProviderName {
+			return list[i].ProviderName < list[j].ProviderName
+		}
+		return list[i].CredentialID < list[j].CredentialID
+	})
+	return list
+}
+
+// GetCircuitBreakers 返回本实例全部上游熔断器状态（仅出现过请求的提供商与凭证）。
+func (s *ModelProviderService) GetCircuitBreakers() []gaiaResponse.CircuitBreakerStatus {
+	return globalCircuitBreaker.snapshot()
+}
@@ -0,0 +1,128 @@
+package gaia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+)
+
+func setCircuitBreakerConfig(t *testing.T, cfg config.GaiaCircuitBreaker) {
+	oldCfg, oldLog := global.GVA_CONFIG.Gaia.Gateway.CircuitBreaker, global.GVA_LOG
+	t.Cleanup(func() {
+		global.GVA_CONFIG.Gaia.Gateway.CircuitBreaker, global.GVA_LOG = oldCfg, oldLog
+	})
+	global.GVA_CONFIG.Gaia.Gateway.CircuitBreaker = cfg
+	global.GVA_LOG = zap.NewNop()
+}
+
+// TestCircuitBreaker_ConsecutiveFailures 测试连续失败熔断、到期后仅放行一个探测请求，探测成功恢复
+func TestCircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	setCircuitBreakerConfig(t, config.GaiaCircuitBreaker{FailureThreshold: 3, OpenDuration: 30})
+	b := newCircuitBreaker()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		b.record("tongyi", "", true, "upstream 503", now)
+	}
+	if ok, _ := b.allow("tongyi", "", now); !ok {
+		t.Fatal("未达到阈值时不应熔断")
+	}
+	b.record("tongyi", "", true, "upstream 503", now)
+	if ok, wait := b.allow("tongyi", "", now.Add(10*time.Second)); ok || wait != 20*time.Second {
+		t.Fatalf("连续失败达到阈值应熔断，ok=%v wait=%v", ok, wait)
+	}
+
+	probeAt := now.Add(31 * time.Second)
+	if ok, _ := b.ready("tongyi", "", probeAt); !ok {
+		t.Fatal("熔断到期后应可探测")
+	}
+	if ok, _ := b.allow("tongyi", "", probeAt); !ok {
+		t.Fatal("熔断到期后应放行探测请求")
+	}
+	if ok, _ := b.allow("tongyi", "", probeAt); ok {
+		t.Fatal("半开状态下探测未完成时不应放行其他请求")
+	}
+	b.record("tongyi", "", false, "", probeAt)
+	if ok, _ := b.allow("tongyi", "", probeAt); !ok {
+		t.Fatal("探测成功后应恢复")
+	}
+	if s := b.snapshot(); len(s) != 1 || s[0].State != gaia.CircuitStateClosed || s[0].ConsecutiveFailures != 0 {
+		t.Errorf("恢复后状态错误：%+v", s)
+	}
+}
+
+// TestCircuitBreaker_HalfOpenFailure 测试探测失败后重新熔断
+func TestCircuitBreaker_HalfOpenFailure(t *testing.T) {
+	setCircuitBreakerConfig(t, config.GaiaCircuitBreaker{FailureThreshold: 1, OpenDuration: 30})
+	b := newCircuitBreaker()
+	now := time.Now()
+	b.record("openai", "cred-1", true, "timeout", now)
+	probeAt := now.Add(31 * time.Second)
+	if ok, _ := b.allow("openai", "cred-1", probeAt); !ok {
+		t.Fatal("熔断到期后应放行探测请求")
+	}
+	b.record("openai", "cred-1", true, "timeout", probeAt)
+	if ok, _ := b.allow("openai", "cred-1", probeAt.Add(time.Second)); ok {
+		t.Fatal("探测失败后应重新熔断")
+	}
+	if ok, _ := b.allow("openai", "", probeAt); !ok {
+		t.Error("凭证熔断不应影响提供商级熔断器")
+	}
+}
+
+// TestCircuitBreaker_ErrorRate 测试窗口内失败率达到阈值且请求数足够时熔断，窗口过期后重新统计
+func TestCircuitBreaker_ErrorRate(t *testing.T) {
+	setCircuitBreakerConfig(t, config.GaiaCircuitBreaker{ErrorRate: 0.5, MinRequests: 4, Window: 60})
+	b := newCircuitBreaker()
+	now := time.Now()
+	b.record("azure_openai", "", true, "upstream 500", now)
+	b.record("azure_openai", "", false, "", now)
+	b.record("azure_openai", "", true, "upstream 500", now)
+	if ok, _ := b.allow("azure_openai", "", now); !ok {
+		t.Fatal("请求数不足时不应按失败率熔断")
+	}
+	b.record("azure_openai", "", false, "", now.Add(61*time.Second))
+	b.record("azure_openai", "", true, "upstream 500", now.Add(61*time.Second))
+	if ok, _ := b.allow("azure_openai", "", now.Add(61*time.Second)); !ok {
+		t.Fatal("窗口过期后应重新统计")
+	}
+	b.record("azure_openai", "", false, "", now.Add(62*time.Second))
+	b.record("azure_openai", "", true, "upstream 500", now.Add(62*time.Second))
+	if ok, _ := b.allow("azure_openai", "", now.Add(62*time.Second)); ok {
+		t.Error("失败率达到阈值应熔断")
+	}
+}
+
+// TestCircuitBreaker_Report 测试上报规则：5xx 与连接失败计为失败，4xx 不计，客户端取消忽略；未配置阈值时不熔断
+func TestCircuitBreaker_Report(t *testing.T) {
+	setCircuitBreakerConfig(t, config.GaiaCircuitBreaker{FailureThreshold: 2})
+	b := newCircuitBreaker()
+	creds := &gaiaResponse.ProviderCredentials{CredentialID: "k1"}
+	b.report("tongyi", creds, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+	b.report("tongyi", creds, nil, fmt.Errorf("Post upstream: %w", context.Canceled))
+	b.report("tongyi", creds, &http.Response{StatusCode: http.StatusBadRequest}, nil)
+	b.report("tongyi", creds, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+	if ok, _ := b.allow("tongyi", "k1", time.Now()); !ok {
+		t.Fatal("4xx 应重置连续失败，客户端取消不应计入")
+	}
+	b.report("tongyi", creds, nil, errors.New("connection refused"))
+	if ok, _ := b.allow("tongyi", "", time.Now()); ok {
+		t.Error("提供商连续失败应熔断")
+	}
+	if ok, _ := b.allow("tongyi", "k1", time.Now()); ok {
+		t.Error("凭证连续失败应熔断")
+	}
+
+	global.GVA_CONFIG.Gaia.Gateway.CircuitBreaker = config.GaiaCircuitBreaker{}
+	if ok, _ := b.allow("tongyi", "", time.Now()); !ok {
+		t.Error("未配置熔断阈值时应始终放行")
+	}
+}
@@ -0,0 +1,384 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+)
+
+// weightedCredential 参与负载均衡的单条凭证
+type weightedCredential struct {
+	ID     string
+	Name   string
+	Weight int
+	Creds  *gaiaResponse.ProviderCredentials
+}
+
+// credentialBalancer 进程内多凭证负载均衡器：按 provider|credential 维护平滑加权轮询的当前权重、在途请求数与冷却截止时间。
+type credentialBalancer struct {
+	mutex    sync.Mutex
+	current  map[string]int
+	inflight map[string]int
+	cooldown map[string]time.Time
+}
+
+// 全局凭证负载均衡器实例
+var globalCredentialBalancer = newCredentialBalancer()
+
+func newCredentialBalancer() *credentialBalancer {
+	return &credentialBalancer{
+		current:  make(map[string]int),
+		inflight: make(map[string]int),
+		cooldown: make(map[string]time.Time),
+	}
+}
+
+func balancerKey(providerName, credentialID string) string {
+	return providerName + "|" + credentialID
+}
+
+// pick 选出一条凭证并占用一个在途名额；冷却中的凭证跳过，全部冷却时取最早解除冷却的一条，避免整体不可用。
+func (b *credentialBalancer) pick(providerName, strategy string, entries []weightedCredential, now time.Time) *weightedCredential {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	available := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if e.Weight <= 0 {
+			continue
+		}
+		if until, ok := b.cooldown[balancerKey(providerName, e.ID)]; ok && now.Before(until) {
+			continue
+		}
+		available = append(available, i)
+	}
+
+	chosen := -1
+	if len(available) == 0 {
+		var earliest time.Time
+		for i, e := range entries {
+			if e.Weight <= 0 {
+				continue
+			}
+			until := b.cooldown[balancerKey(providerName, e.ID)]
+			if chosen < 0 || until.Before(earliest) {
+				chosen, earliest = i, until
+			}
+		}
+	} else if strategy == gaia.BalanceStrategyLeastInflight {
+		// 最少在途：在途数 / 权重 最小者优先
+		best := 0.0
+		for _, i := range available {
+			load := float64(b.inflight[balancerKey(providerName, entries[i].ID)]) / float64(entries[i].Weight)
+			if chosen < 0 || load < best {
+				chosen, best = i, load
+			}
+		}
+	} else {
+		// 平滑加权轮询（与 nginx 一致）：每轮 current += weight，选最大者后减去总权重
+		total := 0
+		for _, i := range available {
+			key := balancerKey(providerName, entries[i].ID)
+			b.current[key] += entries[i].Weight
+			total += entries[i].Weight
+			if chosen < 0 || b.current[key] > b.current[balancerKey(providerName, entries[chosen].ID)] {
+				chosen = i
+			}
+		}
+		b.current[balancerKey(providerName, entries[chosen].ID)] -= total
+	}
+	if chosen < 0 {
+		return nil
+	}
+	b.inflight[balancerKey(providerName, entries[chosen].ID)]++
+	return &entries[chosen]
+}
+
+// release 释放在途名额
+func (b *credentialBalancer) release(providerName, credentialID string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := balancerKey(providerName, credentialID)
+	if b.inflight[key] > 0 {
+		b.inflight[key]--
+	}
+}
+
+// markCooldown 将凭证移出轮询一段时间
+func (b *credentialBalancer) markCooldown(providerName, credentialID string, d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.cooldown[balancerKey(providerName, credentialID)] = time.Now().Add(d)
+}
+
+// snapshot 返回凭证当前在途数与冷却截止时间（仅用于管理端展示）
+func (b *credentialBalancer) snapshot(providerName, credentialID string) (int, *time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := balancerKey(providerName, credentialID)
+	if until, ok := b.cooldown[key]; ok && time.Now().Before(until) {
+		return b.inflight[key], &until
+	}
+	return b.inflight[key], nil
+}
+
+// getProviderBalanceConfig 读取提供商的负载均衡配置（model_provider_config_extend.config），未配置时返回默认加权轮询。
+func (s *ModelProviderService) getProviderBalanceConfig(providerName string) gaia.ProviderBalanceConfig {
+	cfg := gaia.ProviderBalanceConfig{BalanceStrategy: gaia.BalanceStrategyWeightedRoundRobin}
+	var config gaia.ModelProviderConfig
+	if err := global.GVA_DB.Where("provider_name = ?", providerName).First(&config).Error; err != nil || config.Config == "" {
+		return cfg
+	}
+	if err := json.Unmarshal([]byte(config.Config), &cfg); err != nil {
+		global.GVA_LOG.Warn("解析提供商负载均衡配置失败", zap.String("provider", providerName), zap.Error(err))
+	}
+	if cfg.BalanceStrategy == "" {
+		cfg.BalanceStrategy = gaia.BalanceStrategyWeightedRoundRobin
+	}
+	return cfg
+}
+
+// difyProviderNameAliases 网关提供商短名在 Dify 中的提供商名（与短名相同的不列出）
+var difyProviderNameAliases = map[string][]string{
+	gaia.ProviderAzure:  {"azure_openai"},
+	gaia.ProviderAWS:    {"bedrock"},
+	gaia.ProviderVertex: {"vertex_ai"},
+	gaia.ProviderGoogle: {"gemini"},
+}
+
+// difyProviderNameCandidates 返回网关提供商短名对应的 Dify 提供商名（含 langgenius/<name>/<name> 插件名），用于精确匹配凭证，
+// 避免 LIKE 匹配把 azure_openai 等其他提供商的凭证混入 openai 的轮换池。
+func difyProviderNameCandidates(providerName string) []string {
+	names := append([]string{providerName}, difyProviderNameAliases[providerName]...)
+	candidates := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		candidates = append(candidates, name, "langgenius/"+name+"/"+name)
+	}
+	return candidates
+}
+
+// listProviderCredentials 读取提供商下全部凭证（已解密），结果缓存到 Dify Redis（1 小时）。
+// 与 GetDifyProviderCredentials 的优先级与过滤条件一致：优先 provider_credentials（所属 providers 须为 custom 且有效），
+// 无记录时回落到 provider_model_credentials；提供商名按 difyProviderNameCandidates 精确匹配。
+func (s *ModelProviderService) listProviderCredentials(providerName string) ([]weightedCredential, error) {
+	ctx := context.Background()
+	cacheKey := gaia.RedisKeyModelProviderCredentialListPrefix + providerName
+	var list []weightedCredential
+	if cached, err := global.GVA_Dify_REDIS.Get(ctx, cacheKey).Result(); err == nil {
+		if json.Unmarshal([]byte(cached), &list) == nil && len(list) > 0 {
+			return list, nil
+		}
+	}
+
+	var firstTenant gaia.Tenants
+	tenantID := firstTenant.GetSuperAdminTenantId()
+	candidates := difyProviderNameCandidates(providerName)
+	var rows []gaia.ProviderCredential
+	if err := global.GVA_DB.Table("provider_credentials").
+		Select("provider_credentials.id, provider_credentials.tenant_id, provider_credentials.provider_name, "+
+			"provider_credentials.credential_name, provider_credentials.encrypted_config, provider_credentials.updated_at").
+		Joins("JOIN providers ON providers.tenant_id = provider_credentials.tenant_id AND providers.provider_name = provider_credentials.provider_name").
+		Where("provider_credentials.tenant_id = ? AND provider_credentials.provider_name IN ? AND providers.provider_type = ? AND providers.is_valid = ?",
+			tenantID, candidates, gaia.DifyProviderTypeCustom, true).
+		Order("provider_credentials.updated_at DESC").
+		Find(&rows).Error; err != nil || len(rows) == 0 {
+		rows = nil
+		if err = global.GVA_DB.Table("provider_model_credentials").
+			Select("id, tenant_id, provider_name, credential_name, encrypted_config, updated_at").
+			Where("tenant_id = ? AND provider_name IN ?", tenantID, candidates).
+			Order("updated_at DESC").
+			Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("查询提供商 %s 的凭证失败：%w", providerName, err)
+		}
+	}
+
+	for _, row := range rows {
+		if row.EncryptedConfig == "" {
+			continue
+		}
+		creds, err := s.parseProviderCredentialConfig(row.EncryptedConfig, row.TenantID)
+		if err != nil {
+			global.GVA_LOG.Warn("解析凭证失败，跳过", zap.String("provider", providerName),
+				zap.String("credential_id", row.ID), zap.Error(err))
+			continue
+		}
+		creds.CredentialID = row.ID
+		creds.CredentialName = row.CredentialName
+		list = append(list, weightedCredential{ID: row.ID, Name: row.CredentialName, Weight: 1, Creds: creds})
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("未找到提供商 %s 的凭证配置", providerName)
+	}
+
+	if b, err := json.Marshal(list); err == nil {
+		global.GVA_Dify_REDIS.Set(ctx, cacheKey, b, time.Hour)
+	}
+	return list, nil
+}
+
+// AcquireProviderCredential 按负载均衡策略为本次请求选出一条凭证，返回的 release 必须在请求结束后调用。
+// 凭证列表读取失败时回落到 GetDifyProviderCredentials（单凭证），保证兼容。
+func (s *ModelProviderService) AcquireProviderCredential(providerName string) (
+	creds *gaiaResponse.ProviderCredentials, release func(), err error) {
+	// 自定义提供商只有一条登记凭证，不参与负载均衡
+	if provider := lookupCustomProvider(providerName); provider != nil {
+		return provider.credentials(), func() {}, nil
+	}
+	list, err := s.listProviderCredentials(providerName)
+	if err != nil {
+		creds, err = s.GetDifyProviderCredentials(providerName)
+		return creds, func() {}, err
+	}
+
+	cfg := s.getProviderBalanceConfig(providerName)
+	for i := range list {
+		if w, ok := cfg.CredentialWeights[list[i].ID]; ok {
+			list[i].Weight = w
+		}
+	}
+	// 熔断中的凭证不参与轮询；全部熔断时直接失败，由 ProxyRequest 转移到其他提供商
+	now := time.Now()
+	ready := make([]weightedCredential, 0, len(list))
+	var retryAfter time.Duration
+	for _, c := range list {
+		if ok, wait := globalCircuitBreaker.ready(providerName, c.ID, now); ok {
+			ready = append(ready, c)
+		} else if retryAfter == 0 || wait < retryAfter {
+			retryAfter = wait
+		}
+	}
+	if len(ready) == 0 && len(list) > 0 {
+		return nil, func() {}, &CircuitOpenError{Provider: providerName, RetryAfter: retryAfter}
+	}
+	chosen := globalCredentialBalancer.pick(providerName, cfg.BalanceStrategy, ready, now)
+	if chosen == nil {
+		return nil, func() {}, fmt.Errorf("提供商 %s 没有可用凭证（权重均为 0）", providerName)
+	}
+	globalCircuitBreaker.allow(providerName, chosen.ID, now)
+	credentialID := chosen.ID
+	return chosen.Creds, func() { globalCredentialBalancer.release(providerName, credentialID) }, nil
+}
+
+// acquireAttemptCredential 为一次转发取凭证：att.credential 非空时固定使用该凭证，否则按负载均衡策略选取并回填到 att.credential。
+func (s *ModelProviderService) acquireAttemptCredential(att *proxyAttempt) (
+	creds *gaiaResponse.ProviderCredentials, release func(), err error) {
+	if att.credential == "" {
+		if creds, release, err = s.AcquireProviderCredential(att.provider); err == nil {
+			att.credential = creds.CredentialID
+		}
+		return creds, release, err
+	}
+	creds, err = s.providerCredentialByID(att.provider, att.credential)
+	return creds, func() {}, err
+}
+
+// providerCredentialByID 按 ID 取提供商的某条凭证（不参与负载均衡与熔断筛选）；凭证已被删除时返回错误。
+func (s *ModelProviderService) providerCredentialByID(providerName, credentialID string) (*gaiaResponse.ProviderCredentials, error) {
+	if provider := lookupCustomProvider(providerName); provider != nil {
+		return provider.credentials(), nil
+	}
+	list, err := s.listProviderCredentials(providerName)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		if c.ID == credentialID {
+			return c.Creds, nil
+		}
+	}
+	return nil, fmt.Errorf("提供商 %s 的凭证 %s 已不存在", providerName, credentialID)
+}
+
+// reportCredentialStatus 根据上游状态码调整凭证可用性：401/403 与 429 时临时移出轮询。
+func reportCredentialStatus(providerName string, creds *gaiaResponse.ProviderCredentials, statusCode int, retryAfter string) {
+	if creds == nil || creds.CredentialID == "" {
+		return
+	}
+	var cooldown time.Duration
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		cooldown = gaia.CredentialCooldownUnauthorized
+	case http.StatusTooManyRequests:
+		cooldown = gaia.CredentialCooldownRateLimited
+		if sec, err := strconv.Atoi(retryAfter); err == nil && sec > 0 {
+			cooldown = time.Duration(sec) * time.Second
+		}
+	default:
+		return
+	}
+	globalCredentialBalancer.markCooldown(providerName, creds.CredentialID, cooldown)
+	global.GVA_LOG.Warn("凭证被上游拒绝，临时移出轮询",
+		zap.String("provider", providerName),
+		zap.String("credential_id", creds.CredentialID),
+		zap.Int("status", statusCode),
+		zap.Duration("cooldown", cooldown))
+}
+
+// GetProviderCredentialStatus 返回提供商全部凭证（Key 脱敏）及其权重、在途数与冷却状态。
+func (s *ModelProviderService) GetProviderCredentialStatus(providerName string) (*gaiaResponse.ProviderCredentialsResponse, error) {
+	list, err := s.listProviderCredentials(providerName)
+	if err != nil {
+		return nil, err
+	}
+	cfg := s.getProviderBalanceConfig(providerName)
+	resp := &gaiaResponse.ProviderCredentialsResponse{
+		ProviderName:    providerName,
+		BalanceStrategy: cfg.BalanceStrategy,
+		Credentials:     make([]gaiaResponse.ProviderCredentialStatus, 0, len(list)),
+	}
+	for _, c := range list {
+		weight := c.Weight
+		if w, ok := cfg.CredentialWeights[c.ID]; ok {
+			weight = w
+		}
+		inflight, cooldownUntil := globalCredentialBalancer.snapshot(providerName, c.ID)
+		resp.Credentials = append(resp.Credentials, gaiaResponse.ProviderCredentialStatus{
+			CredentialID:   c.ID,
+			CredentialName: c.Name,
+			APIKey:         maskSecret(c.Creds.APIKey),
+			Weight:         weight,
+			Inflight:       inflight,
+			CooldownUntil:  cooldownUntil,
+		})
+	}
+	return resp, nil
+}
+
+// UpdateProviderBalance 保存提供商的负载均衡策略与凭证权重（写入 model_provider_config_extend.config）。
+func (s *ModelProviderService) UpdateProviderBalance(req gaiaRequest.ProviderBalanceReq) error {
+	var config gaia.ModelProviderConfig
+	if err := global.GVA_DB.Where("provider_name = ?", req.ProviderName).
+		FirstOrCreate(&config, gaia.ModelProviderConfig{ProviderName: req.ProviderName, Models: "[]"}).Error; err != nil {
+		return err
+	}
+	// 保留 Config 中的其他字段，仅覆盖负载均衡相关项
+	raw := map[string]interface{}{}
+	if config.Config != "" {
+		_ = json.Unmarshal([]byte(config.Config), &raw)
+	}
+	raw["balance_strategy"] = req.BalanceStrategy
+	raw["credential_weights"] = req.CredentialWeights
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return global.GVA_DB.Model(&config).Update("config", string(b)).Error
+}
+
+// maskSecret 脱敏展示密钥：保留前后各 4 位
+func maskSecret(secret string) string {
+	if len(secret) > 8 {
+		return secret[:4] + "****" + secret[len(secret)-4:]
+	}
+	return "****"
+}
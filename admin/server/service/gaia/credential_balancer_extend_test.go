@@ -0,0 +1,72 @@
+package gaia
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestCredentialBalancer_WeightedRoundRobin 测试平滑加权轮询的分配比例
+func TestCredentialBalancer_WeightedRoundRobin(t *testing.T) {
+	b := newCredentialBalancer()
+	entries := []weightedCredential{{ID: "a", Weight: 3}, {ID: "b", Weight: 1}, {ID: "c", Weight: 0}}
+	counts := map[string]int{}
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		c := b.pick("openai", gaia.BalanceStrategyWeightedRoundRobin, entries, now)
+		counts[c.ID]++
+		b.release("openai", c.ID)
+	}
+	if counts["a"] != 6 || counts["b"] != 2 || counts["c"] != 0 {
+		t.Errorf("加权轮询分配错误，got: %v", counts)
+	}
+}
+
+// TestCredentialBalancer_LeastInflight 测试最少在途策略
+func TestCredentialBalancer_LeastInflight(t *testing.T) {
+	b := newCredentialBalancer()
+	entries := []weightedCredential{{ID: "a", Weight: 1}, {ID: "b", Weight: 1}}
+	now := time.Now()
+	first := b.pick("tongyi", gaia.BalanceStrategyLeastInflight, entries, now)
+	second := b.pick("tongyi", gaia.BalanceStrategyLeastInflight, entries, now)
+	if first.ID == second.ID {
+		t.Errorf("在途请求未释放时应选择另一条凭证，got: %s, %s", first.ID, second.ID)
+	}
+	b.release("tongyi", first.ID)
+	if third := b.pick("tongyi", gaia.BalanceStrategyLeastInflight, entries, now); third.ID != first.ID {
+		t.Errorf("应选择在途数更少的凭证 %s，got: %s", first.ID, third.ID)
+	}
+}
+
+// TestCredentialBalancer_Cooldown 测试冷却中的凭证被跳过，全部冷却时仍返回最早解除的一条
+func TestCredentialBalancer_Cooldown(t *testing.T) {
+	b := newCredentialBalancer()
+	entries := []weightedCredential{{ID: "a", Weight: 1}, {ID: "b", Weight: 1}}
+	b.markCooldown("azure", "a", time.Minute)
+	for i := 0; i < 4; i++ {
+		if c := b.pick("azure", gaia.BalanceStrategyWeightedRoundRobin, entries, time.Now()); c.ID != "b" {
+			t.Fatalf("冷却中的凭证不应被选中，got: %s", c.ID)
+		}
+	}
+	b.markCooldown("azure", "b", 2*time.Minute)
+	if c := b.pick("azure", gaia.BalanceStrategyWeightedRoundRobin, entries, time.Now()); c == nil || c.ID != "a" {
+		t.Errorf("全部冷却时应返回最早解除冷却的凭证 a")
+	}
+	if c := b.pick("azure", gaia.BalanceStrategyWeightedRoundRobin, entries, time.Now().Add(3*time.Minute)); c == nil {
+		t.Errorf("冷却结束后应恢复轮询")
+	}
+}
+
+// TestDifyProviderNameCandidates 测试凭证查询的候选提供商名：精确匹配短名、Dify 别名与插件名，不包含其他提供商
+func TestDifyProviderNameCandidates(t *testing.T) {
+	if got := strings.Join(difyProviderNameCandidates(gaia.ProviderOpenai), ","); got != "openai,langgenius/openai/openai" {
+		t.Errorf("openai 候选名错误：%s", got)
+	}
+	got := difyProviderNameCandidates(gaia.ProviderAzure)
+	if !slices.Contains(got, "langgenius/azure_openai/azure_openai") || !slices.Contains(got, "azure") {
+		t.Errorf("azure 候选名错误：%v", got)
+	}
+}
@@ -0,0 +1,422 @@
+package gaia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// customProviderNamePattern 自定义提供商短名：小写字母 / 数字开头，可含 - 与 _，最长 32 位
+var customProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// compiledCustomProvider 已解析的自定义提供商（models / pricing 已反序列化）
+type compiledCustomProvider struct {
+	gaia.CustomProvider
+	apiKey  string // 解密后的上游 API Key
+	models  []string
+	pricing map[string]gaia.ModelPricing
+}
+
+// declares 判断该提供商是否登记了该模型（不区分大小写）。
+func (p *compiledCustomProvider) declares(model string) bool {
+	for _, m := range p.models {
+		if strings.EqualFold(m, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// credentials 将自定义提供商转换为代理使用的凭证（Endpoint 即 base_url）。
+func (p *compiledCustomProvider) credentials() *gaiaResponse.ProviderCredentials {
+	return &gaiaResponse.ProviderCredentials{
+		APIKey:         p.apiKey,
+		Endpoint:       p.BaseURL,
+		CredentialName: p.Name,
+		AuthScheme:     p.AuthScheme,
+		AuthHeader:     p.AuthHeader,
+	}
+}
+
+// modelPricing 返回该提供商为模型配置的定价，未配置返回 nil。
+func (p *compiledCustomProvider) modelPricing(model string) *gaia.ModelPricing {
+	if pricing, ok := p.pricing[model]; ok {
+		return &pricing
+	}
+	for name, pricing := range p.pricing {
+		if strings.EqualFold(name, model) {
+			return &pricing
+		}
+	}
+	return nil
+}
+
+// encryptCustomProviderKey 加密上游 API Key 后入库（已是密文时原样返回）。
+func encryptCustomProviderKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, gaia.CustomProviderAPIKeyPrefix) {
+		return key, nil
+	}
+	encrypted, err := utils.EncryptBlowfish([]byte(key), global.GVA_CONFIG.JWT.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("api_key 加密失败：%w", err)
+	}
+	return gaia.CustomProviderAPIKeyPrefix + encrypted, nil
+}
+
+// decryptCustomProviderKey 解密入库的上游 API Key；无密文前缀的旧版记录按明文返回。
+func decryptCustomProviderKey(stored string) (string, error) {
+	encrypted, ok := strings.CutPrefix(stored, gaia.CustomProviderAPIKeyPrefix)
+	if !ok {
+		return stored, nil
+	}
+	key, err := utils.DecryptBlowfish(encrypted, global.GVA_CONFIG.JWT.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("api_key 解密失败：%w", err)
+	}
+	return key, nil
+}
+
+// compileCustomProvider 解析单条自定义提供商记录（解密 API Key）。
+func compileCustomProvider(record gaia.CustomProvider) (*compiledCustomProvider, error) {
+	provider := &compiledCustomProvider{CustomProvider: record}
+	apiKey, err := decryptCustomProviderKey(record.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	provider.apiKey = apiKey
+	if record.Models != "" {
+		if err := json.Unmarshal([]byte(record.Models), &provider.models); err != nil {
+			return nil, fmt.Errorf("models 格式错误：%w", err)
+		}
+	}
+	if record.Pricing != "" {
+		if err := json.Unmarshal([]byte(record.Pricing), &provider.pricing); err != nil {
+			return nil, fmt.Errorf("pricing 格式错误：%w", err)
+		}
+	}
+	return provider, nil
+}
+
+// customProviderCache 进程内自定义提供商缓存：管理端增删改时立即失效，多实例部署下依赖 TTL 收敛
+type customProviderCache struct {
+	mu        sync.RWMutex
+	providers []*compiledCustomProvider
+	loadedAt  time.Time
+}
+
+var globalCustomProviderCache = &customProviderCache{}
+
+// invalidate 清空缓存，下次查询时重新加载。
+func (c *customProviderCache) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// loadCustomProviders 返回全部自定义提供商（按 id 排序，带缓存）；查询失败时返回旧缓存，保证代理可用。
+func loadCustomProviders() []*compiledCustomProvider {
+	c := globalCustomProviderCache
+	c.mu.RLock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < gaia.CustomProviderCacheTTL {
+		providers := c.providers
+		c.mu.RUnlock()
+		return providers
+	}
+	c.mu.RUnlock()
+
+	var records []gaia.CustomProvider
+	if err := global.GVA_DB.Order("id ASC").Find(&records).Error; err != nil {
+		global.GVA_LOG.Warn("加载自定义提供商失败", zap.Error(err))
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.providers
+	}
+	providers := make([]*compiledCustomProvider, 0, len(records))
+	for _, record := range records {
+		migrateCustomProviderKey(&record)
+		provider, err := compileCustomProvider(record)
+		if err != nil {
+			global.GVA_LOG.Warn("跳过非法自定义提供商", zap.String("name", record.Name), zap.Error(err))
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	c.mu.Lock()
+	c.providers, c.loadedAt = providers, time.Now()
+	c.mu.Unlock()
+	return providers
+}
+
+// migrateCustomProviderKey 将旧版明文存储的 API Key 加密后写回（失败时本次仍按明文使用，下次加载重试）。
+func migrateCustomProviderKey(record *gaia.CustomProvider) {
+	if record.APIKey == "" || strings.HasPrefix(record.APIKey, gaia.CustomProviderAPIKeyPrefix) {
+		return
+	}
+	encrypted, err := encryptCustomProviderKey(record.APIKey)
+	if err == nil {
+		err = global.GVA_DB.Model(&gaia.CustomProvider{}).Where("id = ?", record.Id).Update("api_key", encrypted).Error
+	}
+	if err != nil {
+		global.GVA_LOG.Warn("加密自定义提供商 api_key 失败", zap.String("name", record.Name), zap.Error(err))
+		return
+	}
+	record.APIKey = encrypted
+}
+
+// lookupCustomProvider 按短名查找自定义提供商，内置提供商或不存在时返回 nil。
+func lookupCustomProvider(name string) *compiledCustomProvider {
+	if name == "" || isBuiltinProvider(name) {
+		return nil
+	}
+	for _, p := range loadCustomProviders() {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// customProviderCandidates 返回可能服务该模型的自定义提供商：登记了该模型的在前，其余的在后（由已选模型列表最终决定）。
+func customProviderCandidates(providers []*compiledCustomProvider, model string) (declared, others []string) {
+	for _, p := range providers {
+		if p.declares(model) {
+			declared = append(declared, p.Name)
+		} else {
+			others = append(others, p.Name)
+		}
+	}
+	return declared, others
+}
+
+// customProviderPricing 返回自定义提供商 provider 为该模型配置的定价；provider 为内置提供商或未配置时返回 nil。
+func customProviderPricing(provider, model string) *gaia.ModelPricing {
+	if p := lookupCustomProvider(provider); p != nil {
+		return p.modelPricing(model)
+	}
+	return nil
+}
+
+// setUpstreamAuth 按凭证的鉴权方式设置上游请求头：bearer（默认）/ api_key（自定义头名）/ none。
+func setUpstreamAuth(header http.Header, creds *gaiaResponse.ProviderCredentials) {
+	switch creds.AuthScheme {
+	case gaia.CustomProviderAuthNone:
+	case gaia.CustomProviderAuthAPIKey:
+		name := creds.AuthHeader
+		if name == "" {
+			name = gaia.CustomProviderDefaultAuthHeader
+		}
+		header.Set(name, creds.APIKey)
+	default:
+		header.Set("Authorization", "Bearer "+creds.APIKey)
+	}
+}
+
+// maskAPIKey 脱敏展示 API Key（前四位 + 后四位）。
+func maskAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
+
+// buildCustomProvider 校验请求并转换为表记录（api_key 加密存储）；existing 非空时为更新，api_key 留空沿用原值。
+func buildCustomProvider(req gaiaRequest.CustomProviderReq, existing *gaia.CustomProvider) (*gaia.CustomProvider, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !customProviderNamePattern.MatchString(name) {
+		return nil, errors.New("name 仅支持小写字母、数字、- 与 _，且不超过 32 位")
+	}
+	if isBuiltinProvider(name) {
+		return nil, fmt.Errorf("name 不能与内置提供商 %s 重名", name)
+	}
+	baseURL := strings.TrimSuffix(strings.TrimSpace(req.BaseURL), "/")
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("base_url 必须是 http(s) 地址")
+	}
+	if strings.HasSuffix(baseURL, "/v1") {
+		return nil, errors.New("base_url 不应包含 /v1，转发时会按请求路径拼接")
+	}
+	apiKey := strings.TrimSpace(req.APIKey)
+	keyMask := maskAPIKey(apiKey)
+	if apiKey == "" && existing != nil {
+		apiKey, keyMask = existing.APIKey, existing.KeyMask
+	}
+	authHeader := ""
+	switch req.AuthScheme {
+	case gaia.CustomProviderAuthNone:
+		apiKey, keyMask = "", ""
+	case gaia.CustomProviderAuthAPIKey:
+		if authHeader = strings.TrimSpace(req.AuthHeader); authHeader == "" {
+			authHeader = gaia.CustomProviderDefaultAuthHeader
+		}
+		fallthrough
+	default:
+		if apiKey == "" {
+			return nil, errors.New("该鉴权方式必须填写 api_key")
+		}
+	}
+
+	models := make([]string, 0, len(req.Models))
+	seen := make(map[string]bool, len(req.Models))
+	for _, m := range req.Models {
+		if m = strings.TrimSpace(m); m != "" && !seen[m] {
+			seen[m] = true
+			models = append(models, m)
+		}
+	}
+	modelsJSON, _ := json.Marshal(models)
+	pricingJSON := ""
+	if len(req.Pricing) > 0 {
+		pricing := make(map[string]gaia.ModelPricing, len(req.Pricing))
+		for m, p := range req.Pricing {
+			if p.Input < 0 || p.Output < 0 || p.Unit < 0 {
+				return nil, fmt.Errorf("模型 %s 的定价不能为负数", m)
+			}
+			if p.Unit == 0 {
+				p.Unit = 0.001 // 默认按千 token 计费
+			}
+			if p.Currency == "" {
+				p.Currency = "USD"
+			}
+			pricing[strings.TrimSpace(m)] = p
+		}
+		b, _ := json.Marshal(pricing)
+		pricingJSON = string(b)
+	}
+	encryptedKey, err := encryptCustomProviderKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &gaia.CustomProvider{
+		Name:       name,
+		BaseURL:    baseURL,
+		AuthScheme: req.AuthScheme,
+		AuthHeader: authHeader,
+		APIKey:     encryptedKey,
+		KeyMask:    keyMask,
+		Models:     string(modelsJSON),
+		Pricing:    pricingJSON,
+		Remark:     req.Remark,
+	}, nil
+}
+
+// GetCustomProviders 查询全部自定义提供商（API Key 仅返回脱敏值）。
+func (s *ModelProviderService) GetCustomProviders() (list []gaia.CustomProvider, err error) {
+	if err = global.GVA_DB.Order("id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("查询自定义提供商失败：%w", err)
+	}
+	return list, nil
+}
+
+// CreateCustomProvider 创建自定义提供商；创建后需在提供商列表中启用并勾选模型才会参与路由。
+func (s *ModelProviderService) CreateCustomProvider(req gaiaRequest.CustomProviderReq) (*gaia.CustomProvider, error) {
+	record, err := buildCustomProvider(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	if err = global.GVA_DB.Model(&gaia.CustomProvider{}).Where("name = ?", record.Name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("提供商 %s 已存在", record.Name)
+	}
+	if err = global.GVA_DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	globalCustomProviderCache.invalidate()
+	return record, nil
+}
+
+// UpdateCustomProvider 更新自定义提供商；短名不可修改（启用状态、已选模型与日志均按短名关联）。
+func (s *ModelProviderService) UpdateCustomProvider(id uint, req gaiaRequest.CustomProviderReq) error {
+	var existing gaia.CustomProvider
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("自定义提供商不存在")
+		}
+		return err
+	}
+	record, err := buildCustomProvider(req, &existing)
+	if err != nil {
+		return err
+	}
+	if record.Name != existing.Name {
+		return errors.New("name 不可修改")
+	}
+	if err = global.GVA_DB.Model(&existing).Updates(map[string]interface{}{
+		"base_url":    record.BaseURL,
+		"auth_scheme": record.AuthScheme,
+		"auth_header": record.AuthHeader,
+		"api_key":     record.APIKey,
+		"key_mask":    record.KeyMask,
+		"models":      record.Models,
+		"pricing":     record.Pricing,
+		"remark":      record.Remark,
+	}).Error; err != nil {
+		return err
+	}
+	globalCustomProviderCache.invalidate()
+	return nil
+}
+
+// DeleteCustomProvider 删除自定义提供商，并一并删除其启用状态与已选模型配置。
+func (s *ModelProviderService) DeleteCustomProvider(id uint) error {
+	var existing gaia.CustomProvider
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("自定义提供商不存在")
+		}
+		return err
+	}
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return tx.Where("provider_name = ?", existing.Name).Delete(&gaia.ModelProviderConfig{}).Error
+	}); err != nil {
+		return err
+	}
+	globalCustomProviderCache.invalidate()
+	return nil
+}
+
+// getCustomProviderModels 返回自定义提供商的可用模型：登记的模型列表合并上游 /v1/models 发现结果（发现失败时仅返回登记列表）。
+func (s *ModelProviderService) getCustomProviderModels(provider *compiledCustomProvider) []gaiaResponse.ModelInfo {
+	list := make([]gaiaResponse.ModelInfo, 0, len(provider.models))
+	seen := make(map[string]bool, len(provider.models))
+	for _, m := range provider.models {
+		seen[m] = true
+		list = append(list, gaiaResponse.ModelInfo{ID: m, Name: m})
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	discovered, err := s.fetchOpenAICompatibleModels(client, provider.BaseURL, provider.credentials())
+	if err != nil {
+		global.GVA_LOG.Warn("自定义提供商模型发现失败", zap.String("provider", provider.Name), zap.Error(err))
+		return list
+	}
+	for _, m := range discovered {
+		if !seen[m.ID] {
+			seen[m.ID] = true
+			list = append(list, m)
+		}
+	}
+	return list
+}
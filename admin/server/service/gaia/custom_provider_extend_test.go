@@ -0,0 +1,151 @@
+package gaia
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// TestBuildCustomProvider 测试自定义提供商校验：短名规则、内置重名、base_url、鉴权方式与定价默认值
+func TestBuildCustomProvider(t *testing.T) {
+	origSigningKey := global.GVA_CONFIG.JWT.SigningKey
+	t.Cleanup(func() { global.GVA_CONFIG.JWT.SigningKey = origSigningKey })
+	global.GVA_CONFIG.JWT.SigningKey = "b1f3c1de-5a4e-4f7a-9c53-0d3f1a2b4c5d"
+	req := gaiaRequest.CustomProviderReq{
+		Name: "vLLM-Internal", BaseURL: "http://vllm.internal:8000/", AuthScheme: gaia.CustomProviderAuthAPIKey,
+		APIKey: "secret-key-1234", Models: []string{"qwen3-32b", " qwen3-32b", ""},
+		Pricing: map[string]gaia.ModelPricing{"qwen3-32b": {Input: 0.001, Output: 0.002}},
+	}
+	record, err := buildCustomProvider(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "vllm-internal" || record.BaseURL != "http://vllm.internal:8000" ||
+		record.AuthHeader != gaia.CustomProviderDefaultAuthHeader || record.KeyMask != "secr****1234" {
+		t.Errorf("记录转换错误：%+v", record)
+	}
+	compiled, err := compileCustomProvider(*record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(record.APIKey, "secret-key-1234") || compiled.apiKey != "secret-key-1234" ||
+		compiled.credentials().APIKey != "secret-key-1234" {
+		t.Errorf("api_key 应加密存储、加载时解密：%s / %s", record.APIKey, compiled.apiKey)
+	}
+	if len(compiled.models) != 1 || !compiled.declares("Qwen3-32B") {
+		t.Errorf("模型列表应去重并不区分大小写匹配：%v", compiled.models)
+	}
+	if p := compiled.modelPricing("qwen3-32b"); p == nil || p.Unit != 0.001 || p.Currency != "USD" {
+		t.Errorf("定价默认值错误：%+v", p)
+	}
+
+	// 更新时 api_key 留空沿用原值；none 方式不保存 Key
+	req.APIKey = ""
+	if updated, err := buildCustomProvider(req, record); err != nil || updated.APIKey != record.APIKey || updated.KeyMask != "secr****1234" {
+		t.Errorf("更新时应沿用原 api_key：%v", err)
+	}
+	req.AuthScheme = gaia.CustomProviderAuthNone
+	if updated, err := buildCustomProvider(req, record); err != nil || updated.APIKey != "" || updated.KeyMask != "" || updated.AuthHeader != "" {
+		t.Errorf("none 方式不应保存 api_key：%+v %v", updated, err)
+	}
+
+	bad := []gaiaRequest.CustomProviderReq{
+		{Name: "openai", BaseURL: "http://a", AuthScheme: gaia.CustomProviderAuthNone},
+		{Name: "my llm", BaseURL: "http://a", AuthScheme: gaia.CustomProviderAuthNone},
+		{Name: "ollama", BaseURL: "ftp://a", AuthScheme: gaia.CustomProviderAuthNone},
+		{Name: "ollama", BaseURL: "http://a:11434/v1", AuthScheme: gaia.CustomProviderAuthNone},
+		{Name: "ollama", BaseURL: "http://a", AuthScheme: gaia.CustomProviderAuthBearer},
+	}
+	for _, r := range bad {
+		if _, err := buildCustomProvider(r, nil); err == nil {
+			t.Errorf("应校验失败：%+v", r)
+		}
+	}
+}
+
+// TestCustomProviderCandidates 测试登记了该模型的自定义提供商排在前面，其余排在后面
+func TestCustomProviderCandidates(t *testing.T) {
+	providers := []*compiledCustomProvider{
+		{CustomProvider: gaia.CustomProvider{Name: "ollama"}, models: []string{"llama3"}},
+		{CustomProvider: gaia.CustomProvider{Name: "vllm"}, models: []string{"qwen3-32b"}},
+	}
+	declared, others := customProviderCandidates(providers, "qwen3-32b")
+	if len(declared) != 1 || declared[0] != "vllm" || len(others) != 1 || others[0] != "ollama" {
+		t.Errorf("候选顺序错误：%v %v", declared, others)
+	}
+}
+
+// TestCustomProviderModelDiscovery 测试按鉴权方式调用 /v1/models 发现模型
+func TestCustomProviderModelDiscovery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("X-Api-Key") != "k1" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"qwen3-32b"},{"id":"llama3"}]}`))
+	}))
+	defer srv.Close()
+
+	creds := &gaiaResponse.ProviderCredentials{APIKey: "k1", AuthScheme: gaia.CustomProviderAuthAPIKey, AuthHeader: "X-Api-Key"}
+	list, err := (&ModelProviderService{}).fetchOpenAICompatibleModels(srv.Client(), srv.URL, creds)
+	if err != nil || len(list) != 2 || list[1].ID != "llama3" {
+		t.Errorf("模型发现失败：%v %v", list, err)
+	}
+
+	header := http.Header{}
+	setUpstreamAuth(header, &gaiaResponse.ProviderCredentials{APIKey: "k2", AuthScheme: gaia.CustomProviderAuthNone})
+	if len(header) != 0 {
+		t.Errorf("none 方式不应携带鉴权头：%v", header)
+	}
+	setUpstreamAuth(header, &gaiaResponse.ProviderCredentials{APIKey: "k2"})
+	if header.Get("Authorization") != "Bearer k2" {
+		t.Errorf("默认应使用 Bearer：%v", header)
+	}
+}
+
+// TestMigrateCustomProviderKey 测试旧版明文 api_key 在加载时加密写回，解密后可正常使用
+func TestMigrateCustomProviderKey(t *testing.T) {
+	setupTestDB(t, &gaia.CustomProvider{})
+	globalCustomProviderCache.invalidate()
+	t.Cleanup(globalCustomProviderCache.invalidate)
+	origSigningKey := global.GVA_CONFIG.JWT.SigningKey
+	t.Cleanup(func() { global.GVA_CONFIG.JWT.SigningKey = origSigningKey })
+	global.GVA_CONFIG.JWT.SigningKey = "b1f3c1de-5a4e-4f7a-9c53-0d3f1a2b4c5d"
+	global.GVA_DB.Create(&gaia.CustomProvider{Name: "legacy", BaseURL: "http://vllm.internal:8000",
+		AuthScheme: gaia.CustomProviderAuthBearer, APIKey: "plain-key-5678"})
+
+	p := lookupCustomProvider("legacy")
+	if p == nil || p.credentials().APIKey != "plain-key-5678" {
+		t.Fatalf("旧版明文 api_key 应可继续使用：%+v", p)
+	}
+	var stored gaia.CustomProvider
+	global.GVA_DB.First(&stored, "name = ?", "legacy")
+	if !strings.HasPrefix(stored.APIKey, gaia.CustomProviderAPIKeyPrefix) || strings.Contains(stored.APIKey, "plain-key-5678") {
+		t.Errorf("旧版明文 api_key 应加密写回：%s", stored.APIKey)
+	}
+}
+
+// TestCustomProviderPricingByProvider 测试自定义定价只对该自定义提供商服务的请求生效，内置提供商的同名模型不受影响
+func TestCustomProviderPricingByProvider(t *testing.T) {
+	setupTestDB(t, &gaia.CustomProvider{})
+	globalCustomProviderCache.invalidate()
+	t.Cleanup(globalCustomProviderCache.invalidate)
+	global.GVA_DB.Create(&gaia.CustomProvider{Name: "vllm", BaseURL: "http://vllm.internal:8000", AuthScheme: gaia.CustomProviderAuthNone,
+		Models: `["gpt-4o"]`, Pricing: `{"gpt-4o":{"input":0.0001,"output":0.0002,"unit":0.001,"currency":"USD"}}`})
+
+	if p := customProviderPricing("vllm", "gpt-4o"); p == nil || p.Input != 0.0001 {
+		t.Errorf("自定义提供商定价错误：%+v", p)
+	}
+	if p := customProviderPricing(gaia.ProviderOpenai, "gpt-4o"); p != nil {
+		t.Errorf("内置提供商不应使用自定义定价：%+v", p)
+	}
+	if p := customProviderPricing("", "gpt-4o"); p != nil {
+		t.Errorf("提供商未知时不应使用自定义定价：%+v", p)
+	}
+}
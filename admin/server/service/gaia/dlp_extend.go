@@ -0,0 +1,721 @@
+package gaia
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PromptBlockedError 请求内容命中 block 动作的 DLP 规则，或含无法检查的内容（音频、图片、二进制文件等）而有 redact / block 规则生效，未转发上游
+type PromptBlockedError struct {
+	Rule        string
+	Unscannable bool
+}
+
+func (e *PromptBlockedError) Error() string {
+	if e.Unscannable {
+		return fmt.Sprintf("请求包含无法做敏感信息检查的内容（音频、图片或非文本文件等），数据防泄漏规则「%s」要求脱敏或拦截，已拒绝", e.Rule)
+	}
+	return fmt.Sprintf("请求内容命中数据防泄漏规则「%s」，已拦截，请删除敏感信息后重试", e.Rule)
+}
+
+// dlpDefaultReplacement 脱敏规则未配置替换文本时使用
+const dlpDefaultReplacement = "[REDACTED]"
+
+// dlpDetector 内置检测器：正则初筛，check 对命中片段做边界与校验位验证
+type dlpDetector struct {
+	re    *regexp.Regexp
+	check func(s string, loc []int) bool
+}
+
+var dlpDetectors = map[string]dlpDetector{
+	gaia.DLPDetectorCNIDCard: {
+		re: regexp.MustCompile(`[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]`),
+		check: func(s string, loc []int) bool {
+			return digitBounded(s, loc) && validCNIDCard(s[loc[0]:loc[1]])
+		},
+	},
+	gaia.DLPDetectorCNMobile: {
+		re:    regexp.MustCompile(`(?:\+86[- ]?)?1[3-9]\d{9}`),
+		check: digitBounded,
+	},
+	gaia.DLPDetectorBankCard: {
+		re: regexp.MustCompile(`[1-9]\d{3}(?:[ -]?\d{4}){2,3}(?:[ -]?\d{1,3})?`),
+		check: func(s string, loc []int) bool {
+			digits := strings.NewReplacer(" ", "", "-", "").Replace(s[loc[0]:loc[1]])
+			return digitBounded(s, loc) && len(digits) >= 13 && len(digits) <= 19 && validLuhn(digits)
+		},
+	},
+	gaia.DLPDetectorAPIKey: {
+		re: regexp.MustCompile(`(?s)\b(?:sk-(?:proj-|ant-(?:api\d{2}-)?|gaia-)?[A-Za-z0-9_\-]{20,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_\-]{35}|` +
+			`gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|xox[abprs]-[A-Za-z0-9-]{10,}|glpat-[A-Za-z0-9_\-]{20,})|` +
+			`-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`),
+		check: func(string, []int) bool { return true },
+	},
+}
+
+// digitBounded 命中片段前后不是数字（避免从更长的数字串中截取误报）
+func digitBounded(s string, loc []int) bool {
+	isDigit := func(b byte) bool { return b >= '0' && b <= '9' }
+	return (loc[0] == 0 || !isDigit(s[loc[0]-1])) && (loc[1] == len(s) || !isDigit(s[loc[1]]))
+}
+
+// validCNIDCard 校验 18 位身份证号的校验位（GB 11643）
+func validCNIDCard(id string) bool {
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(id[i]-'0') * w
+	}
+	return "10X98765432"[sum%11] == strings.ToUpper(id[17:])[0]
+}
+
+// validLuhn Luhn 校验（银行卡号）
+func validLuhn(digits string) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// compiledDLPRule 已编译的 DLP 规则
+type compiledDLPRule struct {
+	gaia.DLPRule
+	providers   []string
+	authorities []uint
+	re          *regexp.Regexp
+	check       func(s string, loc []int) bool // 内置检测器的二次校验，正则 / 关键词规则为 nil
+}
+
+// compileDLPRule 解析作用范围并编译匹配器。
+func compileDLPRule(record gaia.DLPRule) (*compiledDLPRule, error) {
+	rule := &compiledDLPRule{DLPRule: record}
+	if record.Providers != "" {
+		if err := json.Unmarshal([]byte(record.Providers), &rule.providers); err != nil {
+			return nil, fmt.Errorf("providers 格式错误：%w", err)
+		}
+	}
+	if record.Authorities != "" {
+		if err := json.Unmarshal([]byte(record.Authorities), &rule.authorities); err != nil {
+			return nil, fmt.Errorf("authorities 格式错误：%w", err)
+		}
+	}
+	switch record.Type {
+	case gaia.DLPRuleTypeRegex:
+		re, err := regexp.Compile(record.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式非法：%w", err)
+		}
+		rule.re = re
+	case gaia.DLPRuleTypeKeyword:
+		var keywords []string
+		if err := json.Unmarshal([]byte(record.Pattern), &keywords); err != nil || len(keywords) == 0 {
+			return nil, errors.New("关键词列表不能为空")
+		}
+		quoted := make([]string, len(keywords))
+		for i, k := range keywords {
+			quoted[i] = regexp.QuoteMeta(k)
+		}
+		rule.re = regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	case gaia.DLPRuleTypeBuiltin:
+		d, ok := dlpDetectors[record.Pattern]
+		if !ok {
+			return nil, fmt.Errorf("未知的内置检测器：%s", record.Pattern)
+		}
+		rule.re, rule.check = d.re, d.check
+	default:
+		return nil, fmt.Errorf("未知的规则类型：%s", record.Type)
+	}
+	return rule, nil
+}
+
+// applies 规则是否对本次请求生效：候选提供商未知时（无法判断去向）按生效处理。
+func (r *compiledDLPRule) applies(providers []string, authorityID uint) bool {
+	if len(r.authorities) > 0 && !slices.Contains(r.authorities, authorityID) {
+		return false
+	}
+	if len(r.providers) == 0 || len(providers) == 0 {
+		return true
+	}
+	for _, p := range providers {
+		if slices.Contains(r.providers, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// find 返回文本中的命中区间
+func (r *compiledDLPRule) find(s string) [][]int {
+	locs := r.re.FindAllStringIndex(s, -1)
+	if r.check == nil {
+		return locs
+	}
+	kept := locs[:0]
+	for _, loc := range locs {
+		if r.check(s, loc) {
+			kept = append(kept, loc)
+		}
+	}
+	return kept
+}
+
+func (r *compiledDLPRule) replacement() string {
+	if r.Replacement != "" {
+		return r.Replacement
+	}
+	return dlpDefaultReplacement
+}
+
+// dlpSkipKeys 不检查的 JSON 字段 / 表单字段（模型名）
+var dlpSkipKeys = map[string]bool{"model": true}
+
+// dlpBinaryKeys 承载 base64 音频 / 图片的 JSON 字段（OpenAI input_audio.data、Realtime audio、Anthropic / Gemini 图片 data 等），内容无法按文本检查
+var dlpBinaryKeys = map[string]bool{"audio": true, "data": true, "b64_json": true}
+
+// dlpScanResult 一次请求（或 Realtime 帧）的检查结果
+type dlpScanResult struct {
+	body        []byte                        // 脱敏后的内容（无脱敏时为原内容）
+	hits        map[*compiledDLPRule][]string // 各规则命中的原文（仅用于生成审计样本与指纹，不落库）
+	order       []*compiledDLPRule            // 命中规则的顺序（与规则 ID 顺序一致）
+	blocked     *compiledDLPRule              // 命中的第一条 block 规则
+	unscannable bool                          // 存在无法按文本检查的内容（二进制文件、base64 音频 / 图片、非 JSON 正文等）
+}
+
+// dlpScanner 按规则检查文本并执行脱敏，累计命中结果
+type dlpScanner struct {
+	rules  []*compiledDLPRule
+	result dlpScanResult
+}
+
+func newDLPScanner(rules []*compiledDLPRule) *dlpScanner {
+	return &dlpScanner{rules: rules, result: dlpScanResult{hits: map[*compiledDLPRule][]string{}}}
+}
+
+// scanString 检查一段文本，返回按 redact 规则脱敏后的文本。
+func (d *dlpScanner) scanString(t string) string {
+	for _, rule := range d.rules {
+		locs := rule.find(t)
+		if len(locs) == 0 {
+			continue
+		}
+		if _, seen := d.result.hits[rule]; !seen {
+			d.result.order = append(d.result.order, rule)
+		}
+		for _, loc := range locs {
+			d.result.hits[rule] = append(d.result.hits[rule], t[loc[0]:loc[1]])
+		}
+		switch rule.Action {
+		case gaia.DLPActionBlock:
+			if d.result.blocked == nil {
+				d.result.blocked = rule
+			}
+		case gaia.DLPActionRedact:
+			t = replaceSpans(t, locs, rule.replacement())
+		}
+	}
+	return t
+}
+
+// scanJSON 检查 JSON 中的全部字符串值（模型名除外；base64 音频 / 图片与 data: URL 记为无法检查）；不是 JSON 时返回 ok=false。
+func (d *dlpScanner) scanJSON(data []byte) (out []byte, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root interface{}
+	if dec.Decode(&root) != nil {
+		return data, false
+	}
+	changed := false
+	var walk func(v interface{}, key string) interface{}
+	walk = func(v interface{}, key string) interface{} {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, child := range t {
+				t[k] = walk(child, k)
+			}
+		case []interface{}:
+			for i, child := range t {
+				t[i] = walk(child, key)
+			}
+		case string:
+			if dlpSkipKeys[key] || t == "" {
+				return t
+			}
+			if dlpBinaryKeys[key] || strings.HasPrefix(t, "data:") {
+				d.result.unscannable = true
+				return t
+			}
+			redacted := d.scanString(t)
+			changed = changed || redacted != t
+			return redacted
+		}
+		return v
+	}
+	root = walk(root, "")
+	if !changed {
+		return data, true
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(root) != nil {
+		return data, true
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), true
+}
+
+// scanJSONLines 逐行按 JSON 检查 JSONL（Batch 输入文件）；任一非空行不是 JSON 时返回 ok=false。
+func (d *dlpScanner) scanJSONLines(data []byte) (out []byte, ok bool) {
+	lines := bytes.Split(data, []byte("\n"))
+	changed := false
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 {
+			continue
+		}
+		scanned, isJSON := d.scanJSON(trimmed)
+		if !isJSON {
+			return data, false
+		}
+		if !bytes.Equal(scanned, trimmed) {
+			lines[i], changed = scanned, true
+		}
+	}
+	if !changed {
+		return data, true
+	}
+	return bytes.Join(lines, []byte("\n")), true
+}
+
+// scanMultipart 检查 multipart 表单：文本字段按文本检查，文件按 JSONL 检查（Batch 输入文件），其他文件（音频、图片等）记为无法检查。
+// 有脱敏时以原 boundary 重建请求体，Content-Type 无需改动。
+func (d *dlpScanner) scanMultipart(boundary string, body []byte) []byte {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if mw.SetBoundary(boundary) != nil {
+		d.result.unscannable = true
+		return body
+	}
+	changed := false
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		var data []byte
+		if err == nil {
+			data, err = io.ReadAll(part)
+		}
+		if err != nil {
+			d.result.unscannable = true
+			return body
+		}
+		out := data
+		switch {
+		case dlpSkipKeys[part.FormName()]:
+		case part.FileName() == "":
+			out = []byte(d.scanString(string(data)))
+		default:
+			var ok bool
+			if out, ok = d.scanJSONLines(data); !ok {
+				d.result.unscannable = true
+			}
+		}
+		changed = changed || !bytes.Equal(out, data)
+		w, err := mw.CreatePart(part.Header)
+		if err == nil {
+			_, err = w.Write(out)
+		}
+		if err != nil {
+			d.result.unscannable = true
+			return body
+		}
+	}
+	if !changed || mw.Close() != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+// scanBody 按 Content-Type 检查请求体：multipart 表单逐部分检查，其余按 JSON 检查（不是 JSON 时记为无法检查）。
+func (d *dlpScanner) scanBody(contentType string, body []byte) []byte {
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && mediaType == "multipart/form-data" {
+		return d.scanMultipart(params["boundary"], body)
+	}
+	out, ok := d.scanJSON(body)
+	if !ok {
+		d.result.unscannable = true
+	}
+	return out
+}
+
+// scanDLP 按规则检查请求体并执行脱敏，返回检查结果（命中规则按 ID 排序，blocked 为 ID 最小的 block 规则）。
+func scanDLP(rules []*compiledDLPRule, contentType string, body []byte) dlpScanResult {
+	d := newDLPScanner(rules)
+	d.result.body = d.scanBody(contentType, body)
+	result := d.result
+	// 按规则 ID 排序，保证拦截 / 审计结果与遍历 map 的顺序无关
+	slices.SortFunc(result.order, func(a, b *compiledDLPRule) int { return int(a.Id) - int(b.Id) })
+	if result.blocked != nil {
+		for _, rule := range result.order {
+			if rule.Action == gaia.DLPActionBlock {
+				result.blocked = rule
+				break
+			}
+		}
+	}
+	return result
+}
+
+// replaceSpans 将 s 中的命中区间替换为 replacement
+func replaceSpans(s string, locs [][]int, replacement string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(replacement)
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// dlpMask 审计样本脱敏：只保留末尾少量字符，其余以 * 代替（最多 12 个）
+func dlpMask(s string) string {
+	n := utf8.RuneCountInString(s)
+	keep := 0
+	switch {
+	case n >= 10:
+		keep = 4
+	case n >= 5:
+		keep = 2
+	}
+	runes := []rune(s)
+	return strings.Repeat("*", min(n-keep, 12)) + string(runes[n-keep:])
+}
+
+// dlpFingerprint 命中内容的 HMAC-SHA256 指纹（以 JWT 签名密钥为 key，截取前 16 字节），可关联同一敏感值但无法离线穷举还原。
+func dlpFingerprint(s string) string {
+	mac := hmac.New(sha256.New, []byte(global.GVA_CONFIG.JWT.SigningKey))
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// dlpRuleCache 进程内 DLP 规则缓存：管理端增删改时立即失效，多实例部署下依赖 TTL 收敛
+type dlpRuleCache struct {
+	mu       sync.RWMutex
+	rules    []*compiledDLPRule
+	loadedAt time.Time
+}
+
+var globalDLPRuleCache = &dlpRuleCache{}
+
+func (c *dlpRuleCache) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// loadDLPRules 返回已启用的 DLP 规则（带缓存）；查询失败时返回旧缓存。
+func (s *ModelProviderService) loadDLPRules() []*compiledDLPRule {
+	c := globalDLPRuleCache
+	c.mu.RLock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < gaia.DLPRuleCacheTTL {
+		rules := c.rules
+		c.mu.RUnlock()
+		return rules
+	}
+	c.mu.RUnlock()
+
+	var records []gaia.DLPRule
+	if err := global.GVA_DB.Where("enabled = ?", true).Order("id ASC").Find(&records).Error; err != nil {
+		global.GVA_LOG.Warn("加载 DLP 规则失败", zap.Error(err))
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.rules
+	}
+	rules := make([]*compiledDLPRule, 0, len(records))
+	for _, record := range records {
+		rule, err := compileDLPRule(record)
+		if err != nil {
+			global.GVA_LOG.Warn("跳过非法 DLP 规则", zap.Uint("id", record.Id), zap.Error(err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	c.mu.Lock()
+	c.rules, c.loadedAt = rules, time.Now()
+	c.mu.Unlock()
+	return rules
+}
+
+// dlpRulesFor 返回对本次调用生效的 DLP 规则：按候选提供商与调用方角色筛选（存在角色规则而角色未知时先查询）。
+func (s *ModelProviderService) dlpRulesFor(caller *gaiaRequest.ProxyCaller, providers []string) []*compiledDLPRule {
+	all := s.loadDLPRules()
+	if len(all) == 0 {
+		return nil
+	}
+	if caller.AuthorityId == 0 && slices.ContainsFunc(all, func(r *compiledDLPRule) bool { return len(r.authorities) > 0 }) {
+		caller.AuthorityId = accountAuthorityID(caller.AccountId)
+	}
+	rules := make([]*compiledDLPRule, 0, len(all))
+	for _, r := range all {
+		if r.applies(providers, caller.AuthorityId) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// strictDLPRule 返回生效规则中第一条 redact / block 规则（无法检查的内容遇到此类规则时拒绝转发）。
+func strictDLPRule(rules []*compiledDLPRule) *compiledDLPRule {
+	for _, r := range rules {
+		if r.Action == gaia.DLPActionRedact || r.Action == gaia.DLPActionBlock {
+			return r
+		}
+	}
+	return nil
+}
+
+// ApplyPromptPolicy 转发上游前按 DLP 规则检查请求体：返回脱敏后的请求体，拒绝时返回 *PromptBlockedError。
+// 规则按候选提供商（X-Gaia-Provider 或按模型解析）与调用方角色筛选；JSON 正文检查全部文本字段，
+// multipart 上传检查文本字段与 JSONL 文件（Batch 输入文件）。音频、图片等无法按文本检查的内容在有 redact / block 规则生效时整体拒绝。
+func (s *ModelProviderService) ApplyPromptPolicy(caller gaiaRequest.ProxyCaller, path string, reqHeader http.Header, body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+	model := modelFromRequest(path, body)
+	var providers []string
+	if p := strings.TrimSpace(strings.ToLower(reqHeader.Get("X-Gaia-Provider"))); p != "" {
+		providers = []string{p}
+	} else if model != "" {
+		providers, _ = s.resolveProvidersByModel(model)
+	}
+	rules := s.dlpRulesFor(&caller, providers)
+	if len(rules) == 0 {
+		return body, nil
+	}
+	return s.enforceDLP(caller, providers, model, path, rules, scanDLP(rules, reqHeader.Get("Content-Type"), body))
+}
+
+// enforceDLP 按检查结果写审计并返回可转发的内容：命中 block 规则，或存在无法检查的内容且有 redact / block 规则生效时拒绝。
+func (s *ModelProviderService) enforceDLP(caller gaiaRequest.ProxyCaller, providers []string, model, path string,
+	rules []*compiledDLPRule, result dlpScanResult) ([]byte, error) {
+	var blockedErr *PromptBlockedError
+	if result.blocked != nil {
+		blockedErr = &PromptBlockedError{Rule: result.blocked.Name}
+	} else if strict := strictDLPRule(rules); result.unscannable && strict != nil {
+		blockedErr = &PromptBlockedError{Rule: strict.Name, Unscannable: true}
+		if _, seen := result.hits[strict]; !seen {
+			result.order = append(result.order, strict)
+		}
+		result.blocked = strict
+	}
+	if len(result.order) > 0 {
+		s.recordDLPAudit(caller, providers, model, path, result)
+	}
+	if blockedErr != nil {
+		return nil, blockedErr
+	}
+	return result.body, nil
+}
+
+// recordDLPAudit 写入命中审计（样本脱敏、指纹去重，各最多 gaia.DLPAuditSampleLimit 个）；请求被拒绝时各记录的动作均为 block。
+func (s *ModelProviderService) recordDLPAudit(caller gaiaRequest.ProxyCaller, providers []string, model, path string, result dlpScanResult) {
+	logs := make([]gaia.DLPAuditLog, 0, len(result.order))
+	for _, rule := range result.order {
+		matches := result.hits[rule]
+		samples, fingerprints := []string{}, []string{}
+		for _, m := range matches {
+			fp := dlpFingerprint(m)
+			if slices.Contains(fingerprints, fp) {
+				continue
+			}
+			fingerprints = append(fingerprints, fp)
+			samples = append(samples, dlpMask(m))
+			if len(fingerprints) >= gaia.DLPAuditSampleLimit {
+				break
+			}
+		}
+		action := rule.Action
+		if result.blocked != nil {
+			action = gaia.DLPActionBlock
+		}
+		samplesJSON, _ := json.Marshal(samples)
+		fingerprintsJSON, _ := json.Marshal(fingerprints)
+		logs = append(logs, gaia.DLPAuditLog{
+			RuleId: rule.Id, RuleName: rule.Name, Action: action,
+			AccountId: caller.AccountId, KeyId: caller.KeyId, Providers: strings.Join(providers, ","),
+			ModelName: model, Path: path, RequestId: caller.RequestId, ClientIp: caller.ClientIP,
+			MatchCount: len(matches), Samples: string(samplesJSON), Fingerprints: string(fingerprintsJSON),
+		})
+	}
+	if err := global.GVA_DB.Create(&logs).Error; err != nil {
+		global.GVA_LOG.Warn("写入 DLP 审计失败", zap.String("request_id", caller.RequestId), zap.Error(err))
+	}
+}
+
+// buildDLPRule 校验请求并转换为表记录（规则可编译才允许保存）。
+func buildDLPRule(req gaiaRequest.DLPRuleReq) (*gaia.DLPRule, error) {
+	record := &gaia.DLPRule{
+		Name:        strings.TrimSpace(req.Name),
+		Type:        req.Type,
+		Pattern:     strings.TrimSpace(req.Pattern),
+		Action:      req.Action,
+		Replacement: req.Replacement,
+		Enabled:     req.Enabled,
+		Remark:      req.Remark,
+	}
+	if req.Type == gaia.DLPRuleTypeKeyword {
+		keywords := make([]string, 0, len(req.Keywords))
+		for _, k := range req.Keywords {
+			if k = strings.TrimSpace(k); k != "" && !slices.Contains(keywords, k) {
+				keywords = append(keywords, k)
+			}
+		}
+		b, _ := json.Marshal(keywords)
+		record.Pattern = string(b)
+	}
+	if len(req.Providers) > 0 {
+		providers := make([]string, 0, len(req.Providers))
+		for _, p := range req.Providers {
+			if p = strings.TrimSpace(strings.ToLower(p)); p != "" {
+				providers = append(providers, p)
+			}
+		}
+		b, _ := json.Marshal(providers)
+		record.Providers = string(b)
+	}
+	if len(req.Authorities) > 0 {
+		b, _ := json.Marshal(req.Authorities)
+		record.Authorities = string(b)
+	}
+	if _, err := compileDLPRule(*record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetDLPRules 分页查询 DLP 规则。
+func (s *ModelProviderService) GetDLPRules(info gaiaRequest.GetDLPRulesReq) (list []gaia.DLPRule, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.DLPRule{})
+	if info.Action != "" {
+		db = db.Where("action = ?", info.Action)
+	}
+	if kw := strings.TrimSpace(info.Keyword); kw != "" {
+		db = db.Where("name LIKE ? OR pattern LIKE ?", "%"+kw+"%", "%"+kw+"%")
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 DLP 规则总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("id ASC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 DLP 规则列表失败：%w", err)
+	}
+	return list, total, nil
+}
+
+// CreateDLPRule 创建 DLP 规则。
+func (s *ModelProviderService) CreateDLPRule(req gaiaRequest.DLPRuleReq) (*gaia.DLPRule, error) {
+	record, err := buildDLPRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = global.GVA_DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	globalDLPRuleCache.invalidate()
+	return record, nil
+}
+
+// UpdateDLPRule 更新 DLP 规则。
+func (s *ModelProviderService) UpdateDLPRule(id uint, req gaiaRequest.DLPRuleReq) error {
+	var existing gaia.DLPRule
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("DLP 规则不存在")
+		}
+		return err
+	}
+	record, err := buildDLPRule(req)
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Model(&existing).Updates(map[string]interface{}{
+		"name":        record.Name,
+		"type":        record.Type,
+		"pattern":     record.Pattern,
+		"action":      record.Action,
+		"replacement": record.Replacement,
+		"providers":   record.Providers,
+		"authorities": record.Authorities,
+		"enabled":     record.Enabled,
+		"remark":      record.Remark,
+	}).Error; err != nil {
+		return err
+	}
+	globalDLPRuleCache.invalidate()
+	return nil
+}
+
+// DeleteDLPRule 删除 DLP 规则（审计记录保留）。
+func (s *ModelProviderService) DeleteDLPRule(id uint) error {
+	if err := global.GVA_DB.Delete(&gaia.DLPRule{}, id).Error; err != nil {
+		return err
+	}
+	globalDLPRuleCache.invalidate()
+	return nil
+}
+
+// GetDLPAuditLogs 分页查询 DLP 命中审计（按时间倒序）。
+func (s *ModelProviderService) GetDLPAuditLogs(info gaiaRequest.GetDLPAuditLogsReq) (list []gaia.DLPAuditLog, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.DLPAuditLog{})
+	if info.RuleId != 0 {
+		db = db.Where("rule_id = ?", info.RuleId)
+	}
+	if info.AccountId != "" {
+		db = db.Where("account_id = ?::uuid", info.AccountId)
+	}
+	if info.Action != "" {
+		db = db.Where("action = ?", info.Action)
+	}
+	if info.RequestId != "" {
+		db = db.Where("request_id = ?", info.RequestId)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 DLP 审计总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("created_at DESC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 DLP 审计列表失败：%w", err)
+	}
+	return list, total, nil
+}
@@ -0,0 +1,248 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+)
+
+// endpointMeter 接口的计量方式
+type endpointMeter int
+
+const (
+	meterTokens        endpointMeter = iota // 按 usage token（chat/completions、messages、Gemini 等）
+	meterEmbeddings                         // 按 usage.total_tokens 计为输入 token
+	meterImages                             // 按张数 × 尺寸 × 质量
+	meterSpeech                             // 语音合成：按输入字符数
+	meterTranscription                      // 语音识别 / 翻译：按音频时长
+)
+
+// detectEndpointMeter 按请求路径判断计量方式。
+func detectEndpointMeter(path string) endpointMeter {
+	lpath := strings.ToLower(path)
+	switch {
+	case strings.Contains(lpath, "embeddings"):
+		return meterEmbeddings
+	case strings.Contains(lpath, "images/generations"), strings.Contains(lpath, "images/edits"),
+		strings.Contains(lpath, "images/variations"):
+		return meterImages
+	case strings.Contains(lpath, "audio/speech"):
+		return meterSpeech
+	case strings.Contains(lpath, "audio/transcriptions"), strings.Contains(lpath, "audio/translations"):
+		return meterTranscription
+	}
+	return meterTokens
+}
+
+// endpointRequest 计量所需的请求参数（JSON 或 multipart/form-data）
+type endpointRequest struct {
+	Model        string
+	N            int
+	Size         string
+	Quality      string
+	Input        string  // 语音合成的输入文本
+	AudioSeconds float64 // 上传音频的时长（仅能从 WAV 头解析，其余格式为 0）
+}
+
+// parseEndpointRequest 解析请求体：以 "{" 开头按 JSON，以 "--" 开头按 multipart（boundary 取自首行）。
+func parseEndpointRequest(body []byte) endpointRequest {
+	var req endpointRequest
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var obj struct {
+			Model   string      `json:"model"`
+			N       int         `json:"n"`
+			Size    string      `json:"size"`
+			Quality string      `json:"quality"`
+			Input   interface{} `json:"input"`
+		}
+		if json.Unmarshal(trimmed, &obj) == nil {
+			req.Model, req.N, req.Size, req.Quality = obj.Model, obj.N, obj.Size, obj.Quality
+			req.Input, _ = obj.Input.(string)
+		}
+		return req
+	}
+	if !bytes.HasPrefix(body, []byte("--")) {
+		return req
+	}
+	line := body
+	if i := bytes.IndexAny(body, "\r\n"); i >= 0 {
+		line = body[:i]
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), string(line[2:]))
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FileName() != "" {
+			if part.FormName() == "file" {
+				// WAV 头在文件开头，读取前 64KB 足以定位 fmt / data 块
+				head, _ := io.ReadAll(io.LimitReader(part, 64<<10))
+				req.AudioSeconds = wavDurationSeconds(head)
+			}
+			continue
+		}
+		value, _ := io.ReadAll(io.LimitReader(part, 4<<10))
+		switch part.FormName() {
+		case "model":
+			req.Model = string(value)
+		case "n":
+			req.N, _ = strconv.Atoi(strings.TrimSpace(string(value)))
+		case "size":
+			req.Size = string(value)
+		case "quality":
+			req.Quality = string(value)
+		}
+	}
+	return req
+}
+
+// wavDurationSeconds 从 WAV（RIFF）头计算音频时长：data 块大小 / fmt 块中的 byteRate；非 WAV 返回 0。
+func wavDurationSeconds(head []byte) float64 {
+	if len(head) < 12 || string(head[0:4]) != "RIFF" || string(head[8:12]) != "WAVE" {
+		return 0
+	}
+	var byteRate uint32
+	for off := 12; off+8 <= len(head); {
+		id, size := string(head[off:off+4]), binary.LittleEndian.Uint32(head[off+4:off+8])
+		switch id {
+		case "fmt ":
+			if off+20 <= len(head) {
+				byteRate = binary.LittleEndian.Uint32(head[off+16 : off+20])
+			}
+		case "data":
+			if byteRate == 0 {
+				return 0
+			}
+			return float64(size) / float64(byteRate)
+		}
+		off += 8 + int(size) + int(size%2)
+	}
+	return 0
+}
+
+// transcriptionSeconds 从语音识别响应中取音频时长：verbose_json 的 duration 或 usage（type=duration）的 seconds。
+func transcriptionSeconds(respBody []byte) float64 {
+	var obj struct {
+		Duration float64 `json:"duration"`
+		Usage    struct {
+			Type    string  `json:"type"`
+			Seconds float64 `json:"seconds"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(respBody, &obj) != nil {
+		return 0
+	}
+	if obj.Duration > 0 {
+		return obj.Duration
+	}
+	if obj.Usage.Type == "duration" {
+		return obj.Usage.Seconds
+	}
+	return 0
+}
+
+// embeddingTokens 从 embeddings 响应中取用量：优先 usage.total_tokens，缺失时取 prompt_tokens。
+func embeddingTokens(respBody []byte) int {
+	var obj gaia.ModelUsageResponse
+	if json.Unmarshal(respBody, &obj) != nil || obj.Usage == nil {
+		return 0
+	}
+	if obj.Usage.TotalTokens > 0 {
+		return obj.Usage.TotalTokens
+	}
+	return obj.Usage.PromptTokens
+}
+
+// imageUnitPrice 按质量与尺寸查图片单价，依次匹配「质量|尺寸」「质量|*」「*|尺寸」「*|*」；
+// 质量为空或 auto 时只匹配 * 档，standard 与 * 档等价。
+func imageUnitPrice(p *gaia.ModelPricing, quality, size string) (float64, bool) {
+	if p == nil || len(p.Images) == 0 {
+		return 0, false
+	}
+	quality, size = strings.ToLower(strings.TrimSpace(quality)), strings.ToLower(strings.TrimSpace(size))
+	if quality == "" || quality == "auto" || quality == "standard" {
+		quality = "*"
+	}
+	if size == "" || size == "auto" {
+		size = "1024x1024"
+	}
+	for _, key := range []string{quality + "|" + size, quality + "|*", "*|" + size, "*|*"} {
+		if price, ok := p.Images[key]; ok {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+// endpointCost 计算图片、语音合成与语音识别接口的花费（USD）；respBody 为空时（额度预占）按请求参数估算，
+// 无法计量时按次计费（s.perRequestPrice）。
+func (s *ModelProviderService) endpointCost(meter endpointMeter, provider, modelName string, req endpointRequest, respBody []byte) float64 {
+	if modelName == "" {
+		modelName = req.Model
+	}
+	p := builtinPricing(modelName)
+	var cost float64
+	switch meter {
+	case meterImages:
+		n := max(req.N, 1)
+		if price, ok := imageUnitPrice(p, req.Quality, req.Size); ok {
+			cost = float64(n) * price
+		} else if p != nil && p.Input > 0 {
+			cost = float64(n) * p.Input
+		} else {
+			return float64(n) * s.perRequestPrice(provider, modelName)
+		}
+	case meterSpeech:
+		if p == nil || p.PerKChars <= 0 {
+			return s.perRequestPrice(provider, modelName)
+		}
+		cost = float64(utf8.RuneCountInString(req.Input)) / 1000 * p.PerKChars
+	case meterTranscription:
+		seconds := transcriptionSeconds(respBody)
+		if seconds <= 0 {
+			seconds = req.AudioSeconds
+		}
+		if p == nil || p.PerMinute <= 0 || seconds <= 0 {
+			return s.perRequestPrice(provider, modelName)
+		}
+		// 与 OpenAI 一致按秒计费，不足 1 秒按 1 秒
+		cost = math.Ceil(seconds) / 60 * p.PerMinute
+	default:
+		return 0
+	}
+	if strings.EqualFold(p.Currency, "RMB") || strings.EqualFold(p.Currency, "CNY") {
+		cost = rmbToUSD(cost)
+	}
+	return cost
+}
+
+// chargeEndpoint 按接口计量方式对成功的请求扣费，返回扣费金额（USD）：
+// embeddings 将 tokens 改写为按 total_tokens 计的输入 token；图片 / 语音按张数、字符、时长计价；其余按 token 计费。
+func (s *ModelProviderService) chargeEndpoint(caller gaiaRequest.ProxyCaller, provider, path, modelName string,
+	reqBody, respBody []byte, tokens *gaia.TokenUsage) float64 {
+	switch meter := detectEndpointMeter(path); meter {
+	case meterEmbeddings:
+		if total := embeddingTokens(respBody); total > 0 {
+			*tokens = gaia.TokenUsage{PromptTokens: total}
+		}
+	case meterImages, meterSpeech, meterTranscription:
+		cost := s.endpointCost(meter, provider, modelName, parseEndpointRequest(reqBody), respBody)
+		chargeCaller(caller, cost)
+		return cost
+	}
+	if hasTokens(*tokens) {
+		return s.chargeUsage(caller, provider, modelName, *tokens)
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"mime/multipart"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// testWAV 构造指定时长的 16kHz 单声道 16bit PCM WAV 文件
+func testWAV(seconds int) []byte {
+	const byteRate = 16000 * 2
+	dataSize := uint32(seconds * byteRate)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{16000, byteRate})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+// TestParseEndpointRequest 测试解析 JSON 与 multipart 请求体中的计量参数
+func TestParseEndpointRequest(t *testing.T) {
+	req := parseEndpointRequest([]byte(`{"model":"gpt-image-1","n":2,"size":"1536x1024","quality":"high"}`))
+	if req.Model != "gpt-image-1" || req.N != 2 || req.Size != "1536x1024" || req.Quality != "high" {
+		t.Errorf("JSON 解析错误：%+v", req)
+	}
+	if req := parseEndpointRequest([]byte(`{"model":"tts-1","input":"你好 world"}`)); req.Input != "你好 world" {
+		t.Errorf("语音合成输入解析错误：%+v", req)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("model", "whisper-1")
+	fw, _ := w.CreateFormFile("file", "a.wav")
+	_, _ = fw.Write(testWAV(3))
+	_ = w.Close()
+	req = parseEndpointRequest(body.Bytes())
+	if req.Model != "whisper-1" || math.Abs(req.AudioSeconds-3) > 1e-9 {
+		t.Errorf("multipart 解析错误：%+v", req)
+	}
+	if m := modelFromRequest("v1/audio/transcriptions", body.Bytes()); m != "whisper-1" {
+		t.Errorf("multipart 请求应能取到模型名，实际 %q", m)
+	}
+	if req := parseEndpointRequest([]byte("not a body")); req != (endpointRequest{}) {
+		t.Errorf("无法识别的请求体应返回零值：%+v", req)
+	}
+}
+
+// TestImageUnitPrice 测试图片单价按质量与尺寸的匹配顺序
+func TestImageUnitPrice(t *testing.T) {
+	p := &gaia.ModelPricing{Images: map[string]float64{
+		"high|1024x1024": 0.167, "high|*": 0.25, "*|1024x1024": 0.042, "*|*": 0.063,
+	}}
+	cases := []struct {
+		quality, size string
+		want          float64
+	}{
+		{"high", "1024x1024", 0.167},
+		{"HIGH", "1536x1024", 0.25},
+		{"", "", 0.042},
+		{"auto", "1024x1536", 0.063},
+		{"standard", "1024x1024", 0.042},
+	}
+	for _, c := range cases {
+		if got, ok := imageUnitPrice(p, c.quality, c.size); !ok || got != c.want {
+			t.Errorf("%s/%s：期望 %v，实际 %v", c.quality, c.size, c.want, got)
+		}
+	}
+	if _, ok := imageUnitPrice(&gaia.ModelPricing{Input: 0.05}, "high", ""); ok {
+		t.Error("未配置分档时不应命中")
+	}
+}
+
+// TestEndpointUsageFromResponse 测试从 embeddings 与语音识别响应中取用量
+func TestEndpointUsageFromResponse(t *testing.T) {
+	if n := embeddingTokens([]byte(`{"data":[],"usage":{"prompt_tokens":8,"total_tokens":8}}`)); n != 8 {
+		t.Errorf("embeddings total_tokens 解析错误：%d", n)
+	}
+	if n := embeddingTokens([]byte(`{"data":[]}`)); n != 0 {
+		t.Errorf("无 usage 时应为 0：%d", n)
+	}
+	if s := transcriptionSeconds([]byte(`{"text":"hi","duration":12.5}`)); s != 12.5 {
+		t.Errorf("verbose_json duration 解析错误：%v", s)
+	}
+	if s := transcriptionSeconds([]byte(`{"text":"hi","usage":{"type":"duration","seconds":7}}`)); s != 7 {
+		t.Errorf("usage.seconds 解析错误：%v", s)
+	}
+	if s := transcriptionSeconds([]byte(`{"text":"hi","usage":{"type":"tokens","input_tokens":10}}`)); s != 0 {
+		t.Errorf("token 计量的 usage 不应视为时长：%v", s)
+	}
+}
+
+// TestEndpointCost 测试图片、语音合成、语音识别的内置定价计费
+func TestEndpointCost(t *testing.T) {
+	s := &ModelProviderService{}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	if c := s.endpointCost(meterImages, "", "gpt-image-1", endpointRequest{N: 3, Quality: "low", Size: "1024x1024"}, nil); !near(c, 0.033) {
+		t.Errorf("图片应按张数 × 分档单价计费：%v", c)
+	}
+	if c := s.endpointCost(meterImages, "", "gpt-image-2", endpointRequest{N: 2}, nil); !near(c, 0.1) {
+		t.Errorf("未配置分档时应按 input 每张计费：%v", c)
+	}
+	if c := s.endpointCost(meterSpeech, "", "tts-1", endpointRequest{Input: "你好世界"}, nil); !near(c, 4.0/1000*0.015) {
+		t.Errorf("语音合成应按字符计费：%v", c)
+	}
+	// 响应中的时长优先于 WAV 头估算，不足 1 秒按 1 秒
+	resp := []byte(`{"text":"hi","duration":59.2}`)
+	if c := s.endpointCost(meterTranscription, "", "", endpointRequest{Model: "whisper-1", AudioSeconds: 3}, resp); !near(c, 0.006) {
+		t.Errorf("语音识别应按响应时长计费：%v", c)
+	}
+	if c := s.endpointCost(meterTranscription, "", "gpt-4o-mini-transcribe", endpointRequest{AudioSeconds: 120}, nil); !near(c, 0.006) {
+		t.Errorf("额度预占应按 WAV 时长估算：%v", c)
+	}
+	if c := s.endpointCost(meterTokens, "", "gpt-4o", endpointRequest{}, nil); c != 0 {
+		t.Errorf("token 计量接口不应由 endpointCost 计费：%v", c)
+	}
+}
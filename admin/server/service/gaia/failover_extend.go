@@ -0,0 +1,122 @@
+package gaia
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+)
+
+// proxyAttempt 一次上游转发尝试的上下文：调用方、本次使用的提供商、已经历的转移链路，以及是否允许失败后转移。
+type proxyAttempt struct {
+	ctx        context.Context // 客户端请求 context，断开时取消上游请求
+	caller     gaiaRequest.ProxyCaller
+	provider   string
+	model      string   // 客户端请求的模型名
+	upstream   string   // 按模型路由改写后的上游模型 ID，与 model 相同时不改写 body
+	failover   []string // 含本次在内的提供商尝试记录，如 ["anthropic:529", "aws"]
+	allowRetry bool     // 为 true 时，上游可重试错误不写回客户端，交由 ProxyRequest 转移到下一个提供商
+	credential string   // 非空时固定使用该凭证 ID（Batch / 文件须与创建时同一凭证），否则取得凭证后回填本次选中的凭证 ID
+
+	// 以下字段在转发过程中填充，写入代理日志
+	stream         bool      // 是否流式请求
+	upstreamStatus int       // 上游 HTTP 状态码
+	firstByteAt    time.Time // 读到上游响应体首字节的时间
+	cost           float64   // 本次扣费金额（USD）
+	promptTokens   int       // 按请求体估算的输入 token，客户端断开且上游未返回 usage 时计费用
+	streamedTokens int       // 已流式写回客户端的输出 token 估算
+}
+
+// failoverChain 返回写入代理日志的转移链路（单次直达时为空）。
+func (a *proxyAttempt) failoverChain() string {
+	if len(a.failover) <= 1 {
+		return ""
+	}
+	return strings.Join(a.failover, ",")
+}
+
+// upstreamRetryableError 上游在任何字节写回客户端之前失败（5xx / 429 / 连接错误），可转移到下一个提供商。
+type upstreamRetryableError struct {
+	Provider string
+	Status   int // 0 表示连接错误
+	Err      error
+}
+
+func (e *upstreamRetryableError) Error() string {
+	if e.Status > 0 {
+		return fmt.Sprintf("上游 %s 返回 %d", e.Provider, e.Status)
+	}
+	return fmt.Sprintf("上游 %s 连接失败：%v", e.Provider, e.Err)
+}
+
+func (e *upstreamRetryableError) Unwrap() error {
+	return e.Err
+}
+
+// String 用于转移链路记录，如 "anthropic:529"、"openai:conn"。
+func (e *upstreamRetryableError) String() string {
+	if e.Status > 0 {
+		return fmt.Sprintf("%s:%d", e.Provider, e.Status)
+	}
+	return e.Provider + ":conn"
+}
+
+// isRetryableStatus 判断上游状态码是否值得转移到其他提供商（5xx 与 429）。
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// failoverAttempts 返回本次请求最多尝试的提供商数量：1 + 配置的转移次数，且不超过候选数。
+func failoverAttempts(candidates int) int {
+	n := 1 + global.GVA_CONFIG.Gaia.Gateway.FailoverRetries
+	if n < 1 {
+		n = 1
+	}
+	if n > candidates {
+		n = candidates
+	}
+	return n
+}
+
+// resolveProvidersByModel 返回所有已启用且已选该模型的候选提供商（按优先级排序），供失败转移使用。
+// 命中模型路由时以路由的提供商列表为准：别名不会出现在已选模型列表中，只要求提供商已启用；
+// exact / prefix / regex 路由要求改写后的上游模型已在该提供商下勾选，避免绕过管理端的模型开关。
+func (s *ModelProviderService) resolveProvidersByModel(modelName string) ([]string, error) {
+	if route := s.lookupModelRoute(modelName); route != nil {
+		var enabled []string
+		for _, p := range route.providers {
+			if route.MatchType == gaia.ModelRouteMatchAlias {
+				if s.isProviderEnabled(p) {
+					enabled = append(enabled, p)
+				}
+			} else if s.isModelEnabled(p, route.upstreamModelFor(p, modelName)) {
+				enabled = append(enabled, p)
+			}
+		}
+		if len(enabled) == 0 {
+			return nil, fmt.Errorf("模型 %s 的路由 %s 无已启用且已选该模型的提供商", modelName, route.Pattern)
+		}
+		return enabled, nil
+	}
+	candidates := s.getProviderCandidatesByModel(modelName)
+	if len(candidates) == 0 {
+		global.GVA_LOG.Warn("resolveProvidersByModel 无法识别提供商", zap.String("model", modelName))
+		return nil, fmt.Errorf("无法识别模型 %s 的提供商", modelName)
+	}
+	var enabled []string
+	for _, p := range candidates {
+		if s.isModelEnabled(p, modelName) {
+			enabled = append(enabled, p)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("模型 %s 未开启", modelName)
+	}
+	return enabled, nil
+}
@@ -0,0 +1,93 @@
+package gaia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestFailoverAttempts 测试转移次数受配置与候选数共同限制
+func TestFailoverAttempts(t *testing.T) {
+	old := global.GVA_CONFIG.Gaia.Gateway.FailoverRetries
+	defer func() { global.GVA_CONFIG.Gaia.Gateway.FailoverRetries = old }()
+
+	cases := []struct {
+		retries, candidates, want int
+	}{
+		{0, 2, 1},
+		{1, 2, 2},
+		{3, 2, 2},
+		{-1, 3, 1},
+	}
+	for _, c := range cases {
+		global.GVA_CONFIG.Gaia.Gateway.FailoverRetries = c.retries
+		if got := failoverAttempts(c.candidates); got != c.want {
+			t.Errorf("retries=%d candidates=%d 期望 %d，got: %d", c.retries, c.candidates, c.want, got)
+		}
+	}
+}
+
+// TestUpstreamRetryableError 测试可重试状态判断与转移链路记录
+func TestUpstreamRetryableError(t *testing.T) {
+	for status, want := range map[int]bool{200: false, 400: false, 401: false, 429: true, 500: true, 529: true} {
+		if got := isRetryableStatus(status); got != want {
+			t.Errorf("isRetryableStatus(%d) 期望 %v，got: %v", status, want, got)
+		}
+	}
+
+	var err error = &upstreamRetryableError{Provider: "anthropic", Status: 529}
+	var retryErr *upstreamRetryableError
+	if !errors.As(err, &retryErr) || retryErr.String() != "anthropic:529" {
+		t.Errorf("转移链路记录错误，got: %v", retryErr)
+	}
+	att := &proxyAttempt{failover: []string{"anthropic:529", "aws"}}
+	if chain := att.failoverChain(); chain != "anthropic:529,aws" {
+		t.Errorf("failoverChain 错误，got: %s", chain)
+	}
+	if chain := (&proxyAttempt{failover: []string{"aws"}}).failoverChain(); chain != "" {
+		t.Errorf("单次直达不应记录链路，got: %s", chain)
+	}
+}
+
+// TestResolveProvidersByModelRoute 测试命中路由时的候选提供商：exact / prefix / regex 要求上游模型已勾选，别名只要求提供商已启用
+func TestResolveProvidersByModelRoute(t *testing.T) {
+	setupTestDB(t, &gaia.ModelProviderConfig{}, &gaia.ModelRoute{})
+	globalModelRouteCache.invalidate()
+	t.Cleanup(globalModelRouteCache.invalidate)
+	for _, c := range []gaia.ModelProviderConfig{
+		{ProviderName: gaia.ProviderOpenai, Enabled: true, Models: `["gpt-4o"]`},
+		{ProviderName: gaia.ProviderAzure, Enabled: true, Models: `["gpt-4o-mini"]`},
+	} {
+		if err := global.GVA_DB.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []gaia.ModelRoute{
+		{MatchType: gaia.ModelRouteMatchPrefix, Pattern: "gpt-4o", Providers: `["openai","azure"]`, Enabled: true},
+		{MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast", Providers: `["openai","azure"]`, UpstreamModel: "gpt-4o-mini", Enabled: true},
+	} {
+		if err := global.GVA_DB.Create(&r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &ModelProviderService{}
+
+	cases := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o", "openai"},
+		{"gpt-4o-mini", "azure"},
+		{"fast", "openai,azure"},
+		{"gpt-4o-audio", ""},
+	}
+	for _, c := range cases {
+		providers, err := s.resolveProvidersByModel(c.model)
+		if got := strings.Join(providers, ","); got != c.want || (c.want == "") != (err != nil) {
+			t.Errorf("resolveProvidersByModel(%q) 期望 %q，got: %q %v", c.model, c.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package gaia
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// hashGatewayKey 计算网关 Key 的 SHA256（库中只存哈希，与转发 Token 保持一致）。
+func hashGatewayKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateGatewayKey 生成 sk-gaia- 前缀的随机 Key，返回明文与脱敏展示串。
+func generateGatewayKey() (key, mask string, err error) {
+	buf := make([]byte, 24)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("生成随机 Key 失败：%w", err)
+	}
+	key = gaia.GatewayKeyPrefix + hex.EncodeToString(buf)
+	mask = gaia.GatewayKeyPrefix + "****" + key[len(key)-4:]
+	return key, mask, nil
+}
+
+// IsGatewayKey 判断 Bearer / api-key 中携带的是否为网关虚拟 Key。
+func IsGatewayKey(token string) bool {
+	return strings.HasPrefix(token, gaia.GatewayKeyPrefix)
+}
+
+// CreateGatewayKey 为指定账号创建网关 Key；accountId 为空时绑定 defaultAccountId（当前登录用户）。
+func (s *ModelProviderService) CreateGatewayKey(
+	req gaiaRequest.GatewayKeyCreateReq, createdBy uint, defaultAccountId string) (*gaiaResponse.GatewayKeyCreated, error) {
+	accountId := strings.TrimSpace(req.AccountId)
+	if accountId == "" {
+		accountId = defaultAccountId
+	}
+	if accountId == "" {
+		return nil, errors.New("未指定绑定账号")
+	}
+	var account gaia.Account
+	if err := global.GVA_DB.Select("id").Where("id = ?", accountId).First(&account).Error; err != nil {
+		return nil, fmt.Errorf("绑定账号不存在：%w", err)
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("过期时间不能早于当前时间")
+	}
+
+	key, mask, err := generateGatewayKey()
+	if err != nil {
+		return nil, err
+	}
+	allowedModels, _ := json.Marshal(req.AllowedModels)
+	allowedPaths, _ := json.Marshal(req.AllowedPaths)
+	record := gaia.GatewayKey{
+		AccountId:     accountId,
+		Name:          req.Name,
+		KeyHash:       hashGatewayKey(key),
+		KeyMask:       mask,
+		AllowedModels: string(allowedModels),
+		AllowedPaths:  string(allowedPaths),
+		QuotaLimit:    req.QuotaLimit,
+		Enabled:       true,
+		ExpiresAt:     req.ExpiresAt,
+		CreatedBy:     createdBy,
+	}
+	if err = global.GVA_DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &gaiaResponse.GatewayKeyCreated{
+		Id:        record.Id,
+		Key:       key,
+		KeyMask:   mask,
+		AccountId: accountId,
+	}, nil
+}
+
+// GetGatewayKeys 分页查询网关 Key（不返回哈希）。
+func (s *ModelProviderService) GetGatewayKeys(info gaiaRequest.GetGatewayKeysReq) (
+	list []gaia.GatewayKey, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.GatewayKey{})
+	if info.AccountId != "" {
+		db = db.Where("account_id = ?", info.AccountId)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 Key 总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("id DESC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询 Key 列表失败：%w", err)
+	}
+	return list, total, nil
+}
+
+// UpdateGatewayKey 更新 Key 的名称、启用状态、作用域、预算与过期时间。
+func (s *ModelProviderService) UpdateGatewayKey(id uint, req gaiaRequest.GatewayKeyUpdateReq) error {
+	var record gaia.GatewayKey
+	if err := global.GVA_DB.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("Key 不存在")
+		}
+		return err
+	}
+	allowedModels, _ := json.Marshal(req.AllowedModels)
+	allowedPaths, _ := json.Marshal(req.AllowedPaths)
+	updates := map[string]interface{}{
+		"name":           req.Name,
+		"enabled":        req.Enabled,
+		"allowed_models": string(allowedModels),
+		"allowed_paths":  string(allowedPaths),
+		"quota_limit":    req.QuotaLimit,
+		"expires_at":     req.ExpiresAt,
+	}
+	if req.ResetUsage {
+		updates["used_quota"] = 0
+	}
+	return global.GVA_DB.Model(&record).Updates(updates).Error
+}
+
+// DeleteGatewayKey 删除 Key，删除后立即失效。
+func (s *ModelProviderService) DeleteGatewayKey(id uint) error {
+	return global.GVA_DB.Delete(&gaia.GatewayKey{}, id).Error
+}
+
+// AuthenticateGatewayKey 校验网关 Key：存在、已启用、未过期；通过后记录最后使用时间与 IP。
+func (s *ModelProviderService) AuthenticateGatewayKey(key, clientIP string) (*gaia.GatewayKey, error) {
+	var record gaia.GatewayKey
+	if err := global.GVA_DB.Where("key_hash = ?", hashGatewayKey(key)).First(&record).Error; err != nil {
+		return nil, errors.New("无效的 API Key")
+	}
+	if !record.Enabled {
+		return nil, errors.New("API Key 已禁用")
+	}
+	now := time.Now()
+	if record.ExpiresAt != nil && now.After(*record.ExpiresAt) {
+		return nil, errors.New("API Key 已过期")
+	}
+	if err := global.GVA_DB.Model(&gaia.GatewayKey{}).Where("id = ?", record.Id).
+		UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": clientIP}).Error; err != nil {
+		global.GVA_LOG.Warn("更新 Key 最后使用时间失败", zap.Uint("key_id", record.Id), zap.Error(err))
+	}
+	return &record, nil
+}
+
+// CheckGatewayKeyScope 校验本次请求是否在 Key 的路径/模型作用域及预算内。
+func (s *ModelProviderService) CheckGatewayKeyScope(key *gaia.GatewayKey, path, model string) error {
+	if key == nil {
+		return nil
+	}
+	var allowedPaths []string
+	if key.AllowedPaths != "" {
+		_ = json.Unmarshal([]byte(key.AllowedPaths), &allowedPaths)
+	}
+	if len(allowedPaths) > 0 {
+		reqPath := strings.TrimPrefix(path, "/")
+		matched := false
+		for _, p := range allowedPaths {
+			if strings.HasPrefix(reqPath, strings.TrimPrefix(p, "/")) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("API Key 无权访问路径 %s", reqPath)
+		}
+	}
+
+	if !gatewayKeyAllowsModel(key, model) {
+		return fmt.Errorf("API Key 无权调用模型 %s", model)
+	}
+
+	if key.QuotaLimit > 0 && key.UsedQuota >= key.QuotaLimit {
+		return fmt.Errorf("API Key 预算已用尽，已用 %.6f / 上限 %.6f USD", key.UsedQuota, key.QuotaLimit)
+	}
+	return nil
+}
+
+// gatewayKeyAllowsModel 模型是否在 Key 的模型作用域内（未限制模型或模型未知时放行）。
+func gatewayKeyAllowsModel(key *gaia.GatewayKey, model string) bool {
+	var allowedModels []string
+	if key.AllowedModels != "" {
+		_ = json.Unmarshal([]byte(key.AllowedModels), &allowedModels)
+	}
+	return len(allowedModels) == 0 || model == "" || matchModelPattern(allowedModels, model)
+}
+
+// GatewayKeyScopeError 路由改写后的上游模型不在 Key 的模型作用域内
+type GatewayKeyScopeError struct {
+	Model string
+}
+
+func (e *GatewayKeyScopeError) Error() string {
+	return fmt.Sprintf("API Key 无权调用模型 %s", e.Model)
+}
+
+// callerGatewayKey 取调用方使用的网关 Key（JWT / 转发 Token 调用时为 nil）。
+func callerGatewayKey(caller gaiaRequest.ProxyCaller) (*gaia.GatewayKey, error) {
+	if caller.KeyId == 0 {
+		return nil, nil
+	}
+	var record gaia.GatewayKey
+	if err := global.GVA_DB.First(&record, caller.KeyId).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// checkUpstreamModel 校验路由改写后的上游模型：须同时在 Key 的模型作用域与账号的模型访问策略内。
+func (s *ModelProviderService) checkUpstreamModel(caller gaiaRequest.ProxyCaller, key *gaia.GatewayKey, model string) error {
+	if key != nil && !gatewayKeyAllowsModel(key, model) {
+		return &GatewayKeyScopeError{Model: model}
+	}
+	return s.checkModelAccess(caller, model)
+}
+
+// routeUpstreamsAllowed 路由可能改写成的全部上游模型是否都可被调用方使用（无路由时为 true）。
+func (s *ModelProviderService) routeUpstreamsAllowed(caller gaiaRequest.ProxyCaller, key *gaia.GatewayKey,
+	route *compiledModelRoute, requested string) bool {
+	if route == nil {
+		return true
+	}
+	for _, m := range route.upstreamModels(requested) {
+		if m != requested && s.checkUpstreamModel(caller, key, m) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchModelPattern 判断模型是否命中列表：精确匹配，或以 * 结尾的前缀匹配（不区分大小写）。
+func matchModelPattern(patterns []string, model string) bool {
+	lower := strings.ToLower(model)
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if p == "*" || p == lower {
+			return true
+		}
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(lower, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// deductGatewayKeyQuota 将消耗计入 gateway_key_extend.used_quota（原子累加），keyID 为 0 时跳过。
+func deductGatewayKeyQuota(keyID uint, delta float64) {
+	if keyID == 0 || delta <= 0 {
+		return
+	}
+	if err := global.GVA_DB.Exec(
+		`UPDATE gateway_key_extend SET used_quota = used_quota + ?, updated_at = NOW() WHERE id = ?`,
+		delta, keyID,
+	).Error; err != nil {
+		global.GVA_LOG.Warn("deductGatewayKeyQuota 失败",
+			zap.Uint("key_id", keyID), zap.Float64("delta", delta), zap.Error(err))
+	}
+}
+
+// chargeCaller 按调用方扣费：账号额度必扣，使用网关 Key 时同步累加 Key 的已用金额；
+// 请求前有额度预占时，按实际花费扣费后释放预占（先扣后放，避免中间时刻余额被高估）。
+func chargeCaller(caller gaiaRequest.ProxyCaller, delta float64) {
+	deductAccountQuota(caller.AccountId, delta)
+	deductGatewayKeyQuota(caller.KeyId, delta)
+	releaseQuotaHold(caller.HoldId)
+}
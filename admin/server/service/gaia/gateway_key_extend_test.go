@@ -0,0 +1,122 @@
+package gaia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+)
+
+// TestGenerateGatewayKey 测试 Key 生成格式与脱敏
+func TestGenerateGatewayKey(t *testing.T) {
+	key, mask, err := generateGatewayKey()
+	if err != nil {
+		t.Fatalf("生成 Key 失败: %v", err)
+	}
+	if !IsGatewayKey(key) || len(key) != len(gaia.GatewayKeyPrefix)+48 {
+		t.Errorf("Key 格式错误，got: %s", key)
+	}
+	if !strings.HasSuffix(mask, key[len(key)-4:]) || strings.Contains(mask, key[len(gaia.GatewayKeyPrefix):len(key)-4]) {
+		t.Errorf("脱敏结果错误，got: %s", mask)
+	}
+	if hashGatewayKey(key) == hashGatewayKey(key+"x") {
+		t.Errorf("不同 Key 的哈希不应相同")
+	}
+}
+
+// TestCheckGatewayKeyScope 测试 Key 的路径/模型作用域与预算校验
+func TestCheckGatewayKeyScope(t *testing.T) {
+	s := &ModelProviderService{}
+	key := &gaia.GatewayKey{
+		AllowedModels: `["qwen*","gpt-4o"]`,
+		AllowedPaths:  `["v1/chat/completions","/v1/embeddings"]`,
+		QuotaLimit:    10,
+		UsedQuota:     1,
+	}
+	if err := s.CheckGatewayKeyScope(key, "/v1/chat/completions", "qwen3.5-plus"); err != nil {
+		t.Errorf("通配模型应放行，got: %v", err)
+	}
+	if err := s.CheckGatewayKeyScope(key, "v1/embeddings", "GPT-4o"); err != nil {
+		t.Errorf("精确模型应放行（不区分大小写），got: %v", err)
+	}
+	if err := s.CheckGatewayKeyScope(key, "v1/chat/completions", "claude-opus-4-7"); err == nil {
+		t.Errorf("未授权模型应拦截")
+	}
+	if err := s.CheckGatewayKeyScope(key, "v1/images/generations", "gpt-4o"); err == nil {
+		t.Errorf("未授权路径应拦截")
+	}
+	key.UsedQuota = 10
+	if err := s.CheckGatewayKeyScope(key, "v1/chat/completions", "gpt-4o"); err == nil {
+		t.Errorf("预算用尽应拦截")
+	}
+	if err := s.CheckGatewayKeyScope(&gaia.GatewayKey{}, "any/path", "any-model"); err != nil {
+		t.Errorf("空作用域应不限，got: %v", err)
+	}
+}
+
+// TestCheckUpstreamModel 测试路由改写后的上游模型校验：Key 模型作用域外返回 *GatewayKeyScopeError，无 Key 时只看访问策略
+func TestCheckUpstreamModel(t *testing.T) {
+	setupTestDB(t, &gaia.ModelAccessRule{})
+	globalModelAccessCache.invalidate()
+	t.Cleanup(globalModelAccessCache.invalidate)
+	s := &ModelProviderService{}
+	key := &gaia.GatewayKey{AllowedModels: `["qwen*"]`}
+	caller := gaiaRequest.ProxyCaller{AccountId: "6f1c2f3e-6f0a-4a8e-9a59-2f7f3c1d2b4a"}
+
+	var scopeErr *GatewayKeyScopeError
+	if err := s.checkUpstreamModel(caller, key, "claude-opus-4-7"); !errors.As(err, &scopeErr) {
+		t.Errorf("Key 作用域外的上游模型应拒绝：%v", err)
+	}
+	if err := s.checkUpstreamModel(caller, key, "qwen3-32b"); err != nil {
+		t.Errorf("Key 作用域内的上游模型应放行：%v", err)
+	}
+	if err := s.checkUpstreamModel(caller, nil, "claude-opus-4-7"); err != nil {
+		t.Errorf("无 Key 且无访问规则时应放行：%v", err)
+	}
+}
+
+// TestRequestModelFromPath 测试模型名取自路径：Gemini 原生接口与 Bedrock Converse 的模型同样参与 Key 作用域校验
+func TestRequestModelFromPath(t *testing.T) {
+	s := &ModelProviderService{}
+	if got := s.RequestModel("v1beta/models/gemini-2.5-pro:generateContent", []byte(`{"contents":[]}`)); got != "gemini-2.5-pro" {
+		t.Errorf("Gemini 路径模型错误：%s", got)
+	}
+	if got := s.RequestModel("model/anthropic.claude-3-haiku-20240307-v1:0/converse", nil); got != "anthropic.claude-3-haiku-20240307-v1:0" {
+		t.Errorf("Converse 路径模型错误：%s", got)
+	}
+	key := &gaia.GatewayKey{AllowedModels: `["qwen*"]`}
+	if err := s.CheckGatewayKeyScope(key, "v1beta/models/gemini-2.5-pro:generateContent",
+		s.RequestModel("v1beta/models/gemini-2.5-pro:generateContent", nil)); err == nil {
+		t.Errorf("路径中的模型应受 Key 作用域约束")
+	}
+}
+
+// TestRouteUpstreamsAllowed 测试别名路由的上游模型含调用方无权使用的模型时不读写响应缓存
+func TestRouteUpstreamsAllowed(t *testing.T) {
+	setupTestDB(t, &gaia.ModelAccessRule{})
+	globalModelAccessCache.invalidate()
+	t.Cleanup(globalModelAccessCache.invalidate)
+	account := "6f1c2f3e-6f0a-4a8e-9a59-2f7f3c1d2b4a"
+	global.GVA_DB.Create(&gaia.ModelAccessRule{Scope: gaia.ModelAccessScopeAccount, Subject: account,
+		Models: `["claude-opus*"]`, Effect: gaia.ModelAccessEffectDeny, Enabled: true})
+	s := &ModelProviderService{}
+	caller := gaiaRequest.ProxyCaller{AccountId: account}
+
+	route, err := compileModelRoute(gaia.ModelRoute{MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast",
+		UpstreamModel: "claude-sonnet-4-5", Providers: `["anthropic","aws"]`, ProviderModels: `{"aws":"claude-opus-4-7"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.routeUpstreamsAllowed(caller, nil, route, "fast") {
+		t.Errorf("上游模型含无权使用的 claude-opus-4-7 时应返回 false")
+	}
+	if !s.routeUpstreamsAllowed(caller, nil, nil, "claude-sonnet-4-5") {
+		t.Errorf("无路由时应返回 true")
+	}
+	if s.routeUpstreamsAllowed(gaiaRequest.ProxyCaller{AccountId: "other"}, &gaia.GatewayKey{AllowedModels: `["fast","claude-sonnet*"]`}, route, "fast") {
+		t.Errorf("上游模型不在 Key 作用域内时应返回 false")
+	}
+}
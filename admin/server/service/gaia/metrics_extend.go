@@ -0,0 +1,180 @@
+package gaia
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsModelOther 未启用、未命中模型路由的模型在指标中统一归入的标签值
+const metricsModelOther = "other"
+
+// metricsModelCacheTTL 指标模型白名单的缓存时长
+const metricsModelCacheTTL = time.Minute
+
+// metricsModelCache 指标模型白名单：各提供商已选模型、exact / alias 路由的模型名及路由改写后的上游模型
+type metricsModelCache struct {
+	mu       sync.RWMutex
+	models   map[string]bool
+	loadedAt time.Time
+}
+
+var globalMetricsModelCache = &metricsModelCache{}
+
+// metricsModelLabel 返回指标中的模型标签：模型名来自客户端请求，不在白名单内的归为 other，避免标签基数无限增长。
+func metricsModelLabel(model string) string {
+	if model == "" {
+		return model
+	}
+	c := globalMetricsModelCache
+	c.mu.RLock()
+	fresh := !c.loadedAt.IsZero() && time.Since(c.loadedAt) < metricsModelCacheTTL
+	known := c.models[model]
+	c.mu.RUnlock()
+	if !fresh {
+		known = c.reload()[model]
+	}
+	if known {
+		return model
+	}
+	return metricsModelOther
+}
+
+// reload 重新加载白名单；查询失败时沿用旧值（同样按 TTL 刷新，避免每次写日志都查库）。
+func (c *metricsModelCache) reload() map[string]bool {
+	models := map[string]bool{}
+	var configs []gaia.ModelProviderConfig
+	ok := global.GVA_DB != nil && global.GVA_DB.Where("enabled = ?", true).Find(&configs).Error == nil
+	if ok {
+		for _, config := range configs {
+			var selected []string
+			if config.Models != "" && json.Unmarshal([]byte(config.Models), &selected) == nil {
+				for _, m := range selected {
+					models[m] = true
+				}
+			}
+		}
+		for _, route := range (&ModelProviderService{}).loadModelRoutes() {
+			if route.MatchType == gaia.ModelRouteMatchExact || route.MatchType == gaia.ModelRouteMatchAlias {
+				models[route.Pattern] = true
+			}
+			if route.UpstreamModel != "" {
+				models[route.UpstreamModel] = true
+			}
+			for _, m := range route.providerModels {
+				models[m] = true
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok {
+		c.models = models
+	}
+	c.loadedAt = time.Now()
+	return c.models
+}
+
+// observeProxyLog 按写入的代理日志更新网关指标（请求数、耗时、首字节、token 与花费）。
+func observeProxyLog(record *gaia.ModelProxyLog) {
+	status := record.Status
+	if record.CacheHit {
+		status = "cache_hit"
+	}
+	l := *record
+	l.ModelName = metricsModelLabel(l.ModelName)
+	metrics.GatewayRequests.WithLabelValues(l.ProviderName, l.ModelName, status).Inc()
+	metrics.GatewayRequestDuration.WithLabelValues(l.ProviderName, l.ModelName, status).
+		Observe(float64(l.DurationMs) / 1000)
+	if l.TtfbMs > 0 {
+		metrics.GatewayTTFB.WithLabelValues(l.ProviderName, l.ModelName).Observe(float64(l.TtfbMs) / 1000)
+	}
+	for typ, n := range map[string]int{
+		"prompt":      l.RequestTokens,
+		"completion":  l.ResponseTokens,
+		"cache_read":  l.CacheReadTokens,
+		"cache_write": l.CacheWriteTokens,
+		"reasoning":   l.ReasoningTokens,
+	} {
+		if n > 0 {
+			metrics.GatewayTokens.WithLabelValues(l.ProviderName, l.ModelName, typ).Add(float64(n))
+		}
+	}
+	if l.CostUsd > 0 {
+		metrics.GatewayCost.WithLabelValues(l.ProviderName, l.ModelName).Add(l.CostUsd)
+	}
+}
+
+// workerPoolCollector 采集时读取全局工作池状态：总工作器、各用户分配 / 运行中的工作器与队列深度，以及数据库中待处理任务数。
+type workerPoolCollector struct {
+	totalWorkers   *prometheus.Desc
+	userWorkers    *prometheus.Desc
+	runningWorkers *prometheus.Desc
+	queueDepth     *prometheus.Desc
+	pendingTasks   *prometheus.Desc
+}
+
+func newWorkerPoolCollector() *workerPoolCollector {
+	return &workerPoolCollector{
+		totalWorkers: prometheus.NewDesc("gaia_batch_workers_total",
+			"工作池总工作器数量", nil, nil),
+		userWorkers: prometheus.NewDesc("gaia_batch_user_workers",
+			"各用户分配的工作器数量", []string{"user_id"}, nil),
+		runningWorkers: prometheus.NewDesc("gaia_batch_running_workers",
+			"各用户运行中的工作器数量", []string{"user_id"}, nil),
+		queueDepth: prometheus.NewDesc("gaia_batch_queue_depth",
+			"各用户任务队列（userTaskChan）中等待处理的任务数", []string{"user_id"}, nil),
+		pendingTasks: prometheus.NewDesc("gaia_batch_pending_tasks",
+			"数据库中待调度（pending / queued）的任务数", nil, nil),
+	}
+}
+
+func (c *workerPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalWorkers
+	ch <- c.userWorkers
+	ch <- c.runningWorkers
+	ch <- c.queueDepth
+	ch <- c.pendingTasks
+}
+
+func (c *workerPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	if wp := GetWorkerPool(); wp != nil {
+		wp.mutex.RLock()
+		ch <- prometheus.MustNewConstMetric(c.totalWorkers, prometheus.GaugeValue, float64(wp.totalWorkers))
+		wp.mutex.RUnlock()
+
+		wp.userMutex.RLock()
+		for userID, allocation := range wp.userWorkers {
+			ch <- prometheus.MustNewConstMetric(c.userWorkers, prometheus.GaugeValue,
+				float64(allocation.Workers), strconv.FormatUint(uint64(userID), 10))
+		}
+		for userID, n := range wp.runningWorkers {
+			ch <- prometheus.MustNewConstMetric(c.runningWorkers, prometheus.GaugeValue,
+				float64(n), strconv.FormatUint(uint64(userID), 10))
+		}
+		for userID, queue := range wp.userTaskChan {
+			ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue,
+				float64(len(queue)), strconv.FormatUint(uint64(userID), 10))
+		}
+		wp.userMutex.RUnlock()
+	}
+
+	if global.GVA_DB != nil {
+		var pending int64
+		if err := global.GVA_DB.Model(&gaia.BatchWorkflowTask{}).
+			Where("status IN ?", []string{gaia.BatchTaskStatusPending, gaia.BatchTaskStatusQueued}).
+			Count(&pending).Error; err == nil {
+			ch <- prometheus.MustNewConstMetric(c.pendingTasks, prometheus.GaugeValue, float64(pending))
+		}
+	}
+}
+
+func init() {
+	metrics.Registry.MustRegister(newWorkerPoolCollector())
+}
@@ -0,0 +1,47 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestObserveProxyLog 测试代理日志写入后网关请求数、token 与花费指标的累加，白名单外的模型归为 other
+func TestObserveProxyLog(t *testing.T) {
+	c := globalMetricsModelCache
+	c.mu.Lock()
+	c.models, c.loadedAt = map[string]bool{"m1": true}, time.Now()
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.models, c.loadedAt = nil, time.Time{}
+		c.mu.Unlock()
+	}()
+
+	observeProxyLog(&gaia.ModelProxyLog{
+		ProviderName: "metrics-test", ModelName: "m1", Status: "success",
+		RequestTokens: 100, ResponseTokens: 20, CacheReadTokens: 40, DurationMs: 1500, TtfbMs: 300, CostUsd: 0.5,
+	})
+	observeProxyLog(&gaia.ModelProxyLog{ProviderName: "metrics-test", ModelName: "m1", Status: "success", CacheHit: true})
+
+	if v := testutil.ToFloat64(metrics.GatewayRequests.WithLabelValues("metrics-test", "m1", "success")); v != 1 {
+		t.Errorf("success 请求数期望 1，实际 %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.GatewayRequests.WithLabelValues("metrics-test", "m1", "cache_hit")); v != 1 {
+		t.Errorf("cache_hit 请求数期望 1，实际 %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.GatewayTokens.WithLabelValues("metrics-test", "m1", "cache_read")); v != 40 {
+		t.Errorf("cache_read token 期望 40，实际 %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.GatewayCost.WithLabelValues("metrics-test", "m1")); v != 0.5 {
+		t.Errorf("花费期望 0.5，实际 %v", v)
+	}
+
+	observeProxyLog(&gaia.ModelProxyLog{ProviderName: "metrics-test", ModelName: "random-1234", Status: "error"})
+	if v := testutil.ToFloat64(metrics.GatewayRequests.WithLabelValues("metrics-test", metricsModelOther, "error")); v != 1 {
+		t.Errorf("白名单外模型应计入 other，实际 %v", v)
+	}
+}
@@ -0,0 +1,254 @@
+package gaia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ModelAccessDeniedError 调用方无权使用所请求的模型
+type ModelAccessDeniedError struct {
+	Model string
+}
+
+func (e *ModelAccessDeniedError) Error() string {
+	return fmt.Sprintf("当前账号无权调用模型 %s，请联系管理员开通", e.Model)
+}
+
+// compiledModelAccessRule 已解析 models 的访问规则
+type compiledModelAccessRule struct {
+	gaia.ModelAccessRule
+	models []string
+}
+
+// level 返回规则对调用方的命中层级：3 账号规则，2 角色规则，1 全局规则，0 不适用。
+func (r *compiledModelAccessRule) level(caller gaiaRequest.ProxyCaller) int {
+	if r.Subject == "" {
+		return 1
+	}
+	switch r.Scope {
+	case gaia.ModelAccessScopeAccount:
+		if r.Subject == caller.AccountId {
+			return 3
+		}
+	case gaia.ModelAccessScopeAuthority:
+		if caller.AuthorityId != 0 && r.Subject == strconv.FormatUint(uint64(caller.AuthorityId), 10) {
+			return 2
+		}
+	}
+	return 0
+}
+
+// evaluateModelAccess 按规则判定调用方能否使用模型：取命中层级最高的规则，同层级 deny 优先；
+// 未命中任何规则时，模型若被其他主体的 allow 规则覆盖则视为受限模型，拒绝访问。
+func evaluateModelAccess(rules []*compiledModelAccessRule, caller gaiaRequest.ProxyCaller, model string) bool {
+	bestLevel, allowed, restricted := 0, true, false
+	for _, r := range rules {
+		if !matchModelPattern(r.models, model) {
+			continue
+		}
+		lvl := r.level(caller)
+		if lvl == 0 {
+			if r.Effect == gaia.ModelAccessEffectAllow {
+				restricted = true
+			}
+			continue
+		}
+		isAllow := r.Effect == gaia.ModelAccessEffectAllow
+		switch {
+		case lvl > bestLevel:
+			bestLevel, allowed = lvl, isAllow
+		case lvl == bestLevel && !isAllow:
+			allowed = false
+		}
+	}
+	if bestLevel == 0 {
+		return !restricted
+	}
+	return allowed
+}
+
+// modelAccessCache 进程内访问规则缓存：管理端增删改时立即失效，多实例部署下依赖 TTL 收敛
+type modelAccessCache struct {
+	mu       sync.RWMutex
+	rules    []*compiledModelAccessRule
+	loadedAt time.Time
+}
+
+var globalModelAccessCache = &modelAccessCache{}
+
+// invalidate 清空缓存，下次查询时重新加载。
+func (c *modelAccessCache) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// loadModelAccessRules 返回已启用的访问规则（带缓存）；查询失败时返回旧缓存。
+func (s *ModelProviderService) loadModelAccessRules() []*compiledModelAccessRule {
+	c := globalModelAccessCache
+	c.mu.RLock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < gaia.ModelAccessRuleCacheTTL {
+		rules := c.rules
+		c.mu.RUnlock()
+		return rules
+	}
+	c.mu.RUnlock()
+
+	var records []gaia.ModelAccessRule
+	if err := global.GVA_DB.Where("enabled = ?", true).Order("id ASC").Find(&records).Error; err != nil {
+		global.GVA_LOG.Warn("加载模型访问规则失败", zap.Error(err))
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.rules
+	}
+	rules := make([]*compiledModelAccessRule, 0, len(records))
+	for _, record := range records {
+		rule := &compiledModelAccessRule{ModelAccessRule: record}
+		if err := json.Unmarshal([]byte(record.Models), &rule.models); err != nil {
+			global.GVA_LOG.Warn("跳过非法模型访问规则", zap.Uint("id", record.Id), zap.Error(err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	c.mu.Lock()
+	c.rules, c.loadedAt = rules, time.Now()
+	c.mu.Unlock()
+	return rules
+}
+
+// modelAccessChecker 返回针对调用方的模型访问判定函数；存在角色规则而调用方角色未知时先从 sys_users 查询。
+func (s *ModelProviderService) modelAccessChecker(caller gaiaRequest.ProxyCaller) func(model string) bool {
+	rules := s.loadModelAccessRules()
+	if len(rules) == 0 {
+		return func(string) bool { return true }
+	}
+	if caller.AuthorityId == 0 {
+		for _, r := range rules {
+			if r.Scope == gaia.ModelAccessScopeAuthority && r.Subject != "" {
+				caller.AuthorityId = accountAuthorityID(caller.AccountId)
+				break
+			}
+		}
+	}
+	return func(model string) bool {
+		return evaluateModelAccess(rules, caller, model)
+	}
+}
+
+// checkModelAccess 校验调用方能否使用模型，无权时返回 *ModelAccessDeniedError。
+func (s *ModelProviderService) checkModelAccess(caller gaiaRequest.ProxyCaller, model string) error {
+	if model == "" || s.modelAccessChecker(caller)(model) {
+		return nil
+	}
+	return &ModelAccessDeniedError{Model: model}
+}
+
+// buildModelAccessRule 校验请求并转换为表记录。
+func buildModelAccessRule(req gaiaRequest.ModelAccessRuleReq) (*gaia.ModelAccessRule, error) {
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Scope == gaia.ModelAccessScopeAuthority && req.Subject != "" {
+		if _, err := strconv.ParseUint(req.Subject, 10, 64); err != nil {
+			return nil, fmt.Errorf("角色 ID 非法：%s", req.Subject)
+		}
+	}
+	models := make([]string, 0, len(req.Models))
+	for _, m := range req.Models {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	if len(models) == 0 {
+		return nil, errors.New("至少需要一个模型")
+	}
+	modelsJSON, _ := json.Marshal(models)
+	return &gaia.ModelAccessRule{
+		Name:    req.Name,
+		Scope:   req.Scope,
+		Subject: req.Subject,
+		Models:  string(modelsJSON),
+		Effect:  req.Effect,
+		Enabled: req.Enabled,
+		Remark:  req.Remark,
+	}, nil
+}
+
+// GetModelAccessRules 分页查询模型访问规则。
+func (s *ModelProviderService) GetModelAccessRules(info gaiaRequest.GetModelAccessRulesReq) (
+	list []gaia.ModelAccessRule, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.ModelAccessRule{})
+	if info.Scope != "" {
+		db = db.Where("scope = ?", info.Scope)
+	}
+	if kw := strings.TrimSpace(info.Keyword); kw != "" {
+		db = db.Where("name LIKE ? OR subject LIKE ? OR models LIKE ?", "%"+kw+"%", "%"+kw+"%", "%"+kw+"%")
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询访问规则总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("id ASC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询访问规则列表失败：%w", err)
+	}
+	return list, total, nil
+}
+
+// CreateModelAccessRule 创建模型访问规则。
+func (s *ModelProviderService) CreateModelAccessRule(req gaiaRequest.ModelAccessRuleReq) (*gaia.ModelAccessRule, error) {
+	record, err := buildModelAccessRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = global.GVA_DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	globalModelAccessCache.invalidate()
+	return record, nil
+}
+
+// UpdateModelAccessRule 更新模型访问规则。
+func (s *ModelProviderService) UpdateModelAccessRule(id uint, req gaiaRequest.ModelAccessRuleReq) error {
+	var existing gaia.ModelAccessRule
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("访问规则不存在")
+		}
+		return err
+	}
+	record, err := buildModelAccessRule(req)
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Model(&existing).Updates(map[string]interface{}{
+		"name":    record.Name,
+		"scope":   record.Scope,
+		"subject": record.Subject,
+		"models":  record.Models,
+		"effect":  record.Effect,
+		"enabled": record.Enabled,
+		"remark":  record.Remark,
+	}).Error; err != nil {
+		return err
+	}
+	globalModelAccessCache.invalidate()
+	return nil
+}
+
+// DeleteModelAccessRule 删除模型访问规则。
+func (s *ModelProviderService) DeleteModelAccessRule(id uint) error {
+	if err := global.GVA_DB.Delete(&gaia.ModelAccessRule{}, id).Error; err != nil {
+		return err
+	}
+	globalModelAccessCache.invalidate()
+	return nil
+}
@@ -0,0 +1,57 @@
+package gaia
+
+import (
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+)
+
+// TestEvaluateModelAccess 测试模型访问策略的层级优先级、deny 优先与受限模型的白名单语义
+func TestEvaluateModelAccess(t *testing.T) {
+	rule := func(scope, subject, effect string, models ...string) *compiledModelAccessRule {
+		return &compiledModelAccessRule{
+			ModelAccessRule: gaia.ModelAccessRule{Scope: scope, Subject: subject, Effect: effect},
+			models:          models,
+		}
+	}
+	rules := []*compiledModelAccessRule{
+		// opus 仅开放给角色 9528
+		rule(gaia.ModelAccessScopeAuthority, "9528", gaia.ModelAccessEffectAllow, "claude-opus-*"),
+		// 账号 acc-blocked 在角色 9528 内但单独禁用 opus
+		rule(gaia.ModelAccessScopeAccount, "acc-blocked", gaia.ModelAccessEffectDeny, "claude-opus-4-7"),
+		// 全局禁用 gpt-image-*，账号 acc-vip 单独放开
+		rule(gaia.ModelAccessScopeAccount, "", gaia.ModelAccessEffectDeny, "gpt-image-*"),
+		rule(gaia.ModelAccessScopeAccount, "acc-vip", gaia.ModelAccessEffectAllow, "gpt-image-1"),
+	}
+	cases := []struct {
+		name   string
+		caller gaiaRequest.ProxyCaller
+		model  string
+		want   bool
+	}{
+		{"无规则模型放行", gaiaRequest.ProxyCaller{AccountId: "acc-1", AuthorityId: 888}, "qwen3.5-plus", true},
+		{"受限模型非授权角色拒绝", gaiaRequest.ProxyCaller{AccountId: "acc-1", AuthorityId: 888}, "claude-opus-4-7", false},
+		{"受限模型授权角色放行", gaiaRequest.ProxyCaller{AccountId: "acc-2", AuthorityId: 9528}, "claude-opus-4-7", true},
+		{"账号规则优先于角色规则", gaiaRequest.ProxyCaller{AccountId: "acc-blocked", AuthorityId: 9528}, "claude-opus-4-7", false},
+		{"全局 deny", gaiaRequest.ProxyCaller{AccountId: "acc-1", AuthorityId: 888}, "gpt-image-1", false},
+		{"账号 allow 优先于全局 deny", gaiaRequest.ProxyCaller{AccountId: "acc-vip", AuthorityId: 888}, "gpt-image-1", true},
+	}
+	for _, c := range cases {
+		if got := evaluateModelAccess(rules, c.caller, c.model); got != c.want {
+			t.Errorf("%s: 期望 %v，got: %v", c.name, c.want, got)
+		}
+	}
+}
+
+// TestBuildModelAccessRule 测试访问规则校验
+func TestBuildModelAccessRule(t *testing.T) {
+	if _, err := buildModelAccessRule(gaiaRequest.ModelAccessRuleReq{
+		Scope: gaia.ModelAccessScopeAuthority, Subject: "dev", Models: []string{"gpt-5"}, Effect: gaia.ModelAccessEffectAllow}); err == nil {
+		t.Error("非数字角色 ID 应报错")
+	}
+	if _, err := buildModelAccessRule(gaiaRequest.ModelAccessRuleReq{
+		Scope: gaia.ModelAccessScopeAccount, Models: []string{" "}, Effect: gaia.ModelAccessEffectDeny}); err == nil {
+		t.Error("空模型列表应报错")
+	}
+}
@@ -0,0 +1,550 @@
+package gaia
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// BatchRequestError Files / Batches 接口在网关侧被拒绝（无权访问、不支持的操作等），Status 为返回给客户端的 HTTP 状态码
+type BatchRequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *BatchRequestError) Error() string {
+	return e.Message
+}
+
+// batchAPIRoute Files / Batches 接口路径解析结果
+type batchAPIRoute struct {
+	resource string // files / batches
+	id       string // 上游文件 ID / Batch ID，列表与创建时为空
+	action   string // content（下载文件）/ cancel（取消 Batch）
+}
+
+// parseBatchAPIPath 解析 v1/files[/{id}[/content]] 与 v1/batches[/{id}[/cancel]]，兼容 Azure 的 openai/ 前缀与不带 v1 的传统路径。
+func parseBatchAPIPath(path string) (batchAPIRoute, bool) {
+	p := strings.Trim(path, "/")
+	p = strings.TrimPrefix(p, "openai/")
+	p = strings.TrimPrefix(p, "v1/")
+	parts := strings.Split(p, "/")
+	if len(parts) > 3 || (parts[0] != "files" && parts[0] != "batches") {
+		return batchAPIRoute{}, false
+	}
+	route := batchAPIRoute{resource: parts[0]}
+	if len(parts) > 1 {
+		route.id = parts[1]
+	}
+	if len(parts) > 2 {
+		route.action = parts[2]
+	}
+	return route, true
+}
+
+// isBatchAPIPath 判断是否为 Files / Batches 接口（上传、创建不计费，Batch 由定时任务在完成后结算）。
+func isBatchAPIPath(path string) bool {
+	_, ok := parseBatchAPIPath(path)
+	return ok
+}
+
+// isBatchProvider 判断提供商是否支持 Batch API（OpenAI 与 Azure OpenAI）。
+func isBatchProvider(provider string) bool {
+	return provider == gaia.ProviderOpenai || provider == gaia.ProviderAzure
+}
+
+// batchFileObject 上游 File 对象（上传 / 查询的响应）
+type batchFileObject struct {
+	ID      string `json:"id"`
+	Purpose string `json:"purpose"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// batchObject 上游 Batch 对象（创建 / 查询 / 取消的响应）
+type batchObject struct {
+	ID            string `json:"id"`
+	Endpoint      string `json:"endpoint"`
+	InputFileID   string `json:"input_file_id"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	Status        string `json:"status"`
+	RequestCounts struct {
+		Total int `json:"total"`
+	} `json:"request_counts"`
+}
+
+// apply 将上游 Batch 状态同步到记录
+func (o batchObject) apply(b *gaia.ModelBatch) {
+	if o.Status != "" {
+		b.Status = o.Status
+	}
+	if o.OutputFileID != "" {
+		b.OutputFileId = o.OutputFileID
+	}
+	if o.ErrorFileID != "" {
+		b.ErrorFileId = o.ErrorFileID
+	}
+	if o.RequestCounts.Total > 0 {
+		b.RequestTotal = o.RequestCounts.Total
+	}
+}
+
+// proxyBatchRequest 代理 Files / Batches 接口：上传文件与创建 Batch 时记录所属账号与使用的凭证，
+// 后续查询 / 下载 / 取消 / 删除须为同一账号，并固定到创建时的提供商与凭证（上游 ID 仅在该凭证所属组织内有效）。
+// 列表接口会暴露同一上游组织下其他账号的文件与 Batch，网关不支持。
+func (s *ModelProviderService) proxyBatchRequest(ctx context.Context, caller gaiaRequest.ProxyCaller,
+	path, method string, reqHeader http.Header, body []byte, writer io.Writer) error {
+	route, _ := parseBatchAPIPath(path)
+	w, ok := writer.(http.ResponseWriter)
+	if !ok {
+		return fmt.Errorf("Files / Batches 接口仅支持 HTTP 调用")
+	}
+	att := &proxyAttempt{ctx: ctx, caller: caller}
+
+	var fileRecord *gaia.ModelBatchFile
+	var batchRecord *gaia.ModelBatch
+	switch {
+	case route.id == "" && method == http.MethodGet:
+		return &BatchRequestError{Status: http.StatusBadRequest, Message: "网关不支持列出文件 / Batch，请按 ID 查询"}
+	case route.resource == "files" && route.id == "" && method == http.MethodPost:
+		provider, err := s.batchUploadProvider(reqHeader)
+		if err != nil {
+			return err
+		}
+		// batch 用途的文件解析出请求的模型并提前校验，创建 Batch 时再按记录复核
+		purpose, content, err := readBatchUpload(reqHeader.Get("Content-Type"), body)
+		if err != nil {
+			return err
+		}
+		fileRecord = &gaia.ModelBatchFile{}
+		if purpose == "batch" {
+			models, endpoints, parseErr := batchInputModels(content)
+			if parseErr != nil {
+				return parseErr
+			}
+			if err = s.checkBatchModels(caller, endpoints, models); err != nil {
+				return err
+			}
+			b, _ := json.Marshal(models)
+			fileRecord.Models = string(b)
+		}
+		att.provider = provider
+	case route.resource == "files" && route.id != "":
+		provider, credential, record, err := s.batchFileOwner(caller, route.id)
+		if err != nil {
+			return err
+		}
+		att.provider, att.credential, fileRecord = provider, credential, record
+	case route.resource == "batches" && route.id == "" && method == http.MethodPost:
+		var req struct {
+			InputFileID string `json:"input_file_id"`
+			Endpoint    string `json:"endpoint"`
+		}
+		if json.Unmarshal(body, &req) != nil || req.InputFileID == "" {
+			return &BatchRequestError{Status: http.StatusBadRequest, Message: "创建 Batch 需提供 input_file_id"}
+		}
+		provider, credential, input, err := s.batchFileOwner(caller, req.InputFileID)
+		if err != nil {
+			return err
+		}
+		// 模型在输入文件内、请求体没有 model：按上传时解析出的模型逐个校验 Key 范围、访问策略与限流（每个模型计一次请求）
+		var models []string
+		if input == nil || input.Models == "" || json.Unmarshal([]byte(input.Models), &models) != nil || len(models) == 0 {
+			return &BatchRequestError{Status: http.StatusBadRequest,
+				Message: fmt.Sprintf("文件 %s 不是经网关以 purpose=batch 上传的输入文件，请重新上传", req.InputFileID)}
+		}
+		if err = s.checkBatchModels(caller, []string{req.Endpoint}, models); err != nil {
+			return err
+		}
+		for _, model := range models {
+			if limit := s.CheckRateLimit(&caller, model); !limit.Allowed {
+				return &BatchRequestError{Status: http.StatusTooManyRequests, Message: limit.Message}
+			}
+		}
+		if err = s.CheckBalance(caller); err != nil {
+			return err
+		}
+		att.provider, att.credential = provider, credential
+	case route.resource == "batches" && route.id != "":
+		record, err := s.batchOwner(caller, route.id)
+		if err != nil {
+			return err
+		}
+		att.provider, att.credential, batchRecord = record.ProviderName, record.CredentialId, record
+	default:
+		return &BatchRequestError{Status: http.StatusMethodNotAllowed, Message: fmt.Sprintf("不支持的 Files / Batches 操作：%s %s", method, path)}
+	}
+	if ok, wait := globalCircuitBreaker.allow(att.provider, "", time.Now()); !ok {
+		return &CircuitOpenError{Provider: att.provider, RetryAfter: wait}
+	}
+
+	// 记录归属需要上游返回的对象；下载文件内容不保留副本
+	limit := 1 << 20
+	if route.action == "content" {
+		limit = 1
+	}
+	capture := newResponseCaptureWriter(w, limit)
+	if err := s.proxyToProvider(att, path, method, reqHeader, body, capture); err != nil {
+		return err
+	}
+	if capture.overflow || (capture.status != http.StatusOK && capture.status != http.StatusCreated) {
+		return nil
+	}
+	s.recordBatchObject(att, route, method, capture.buf.Bytes(), fileRecord, batchRecord)
+	return nil
+}
+
+// recordBatchObject 按上游返回的对象维护归属记录：上传文件、创建 Batch 时新增，查询 / 取消时同步状态，删除文件时移除。
+func (s *ModelProviderService) recordBatchObject(att *proxyAttempt, route batchAPIRoute, method string, data []byte,
+	fileRecord *gaia.ModelBatchFile, batchRecord *gaia.ModelBatch) {
+	var err error
+	switch {
+	case route.resource == "files" && route.id == "":
+		var obj batchFileObject
+		if json.Unmarshal(data, &obj) != nil || obj.ID == "" {
+			return
+		}
+		record := &gaia.ModelBatchFile{
+			FileId: obj.ID, ProviderName: att.provider, CredentialId: att.credential,
+			AccountId: att.caller.AccountId, KeyId: att.caller.KeyId, Purpose: obj.Purpose, Bytes: obj.Bytes,
+		}
+		if fileRecord != nil {
+			record.Models = fileRecord.Models
+		}
+		err = global.GVA_DB.Create(record).Error
+	case route.resource == "files" && method == http.MethodDelete && fileRecord != nil:
+		err = global.GVA_DB.Delete(fileRecord).Error
+	case route.resource == "batches" && route.id == "":
+		var obj batchObject
+		if json.Unmarshal(data, &obj) != nil || obj.ID == "" {
+			return
+		}
+		record := &gaia.ModelBatch{
+			BatchId: obj.ID, ProviderName: att.provider, CredentialId: att.credential,
+			AccountId: att.caller.AccountId, KeyId: att.caller.KeyId, Endpoint: obj.Endpoint, InputFileId: obj.InputFileID,
+		}
+		obj.apply(record)
+		err = global.GVA_DB.Create(record).Error
+	case route.resource == "batches" && batchRecord != nil && batchRecord.BilledAt == nil:
+		var obj batchObject
+		if json.Unmarshal(data, &obj) != nil || obj.ID != batchRecord.BatchId {
+			return
+		}
+		obj.apply(batchRecord)
+		err = global.GVA_DB.Model(batchRecord).Select("status", "output_file_id", "error_file_id", "request_total").
+			Updates(batchRecord).Error
+	}
+	if err != nil {
+		global.GVA_LOG.Error("记录 Batch / 文件归属失败", zap.String("provider", att.provider),
+			zap.String("resource", route.resource), zap.Error(err))
+	}
+}
+
+// readBatchUpload 读取 multipart 上传请求中的 purpose 字段与 file 内容。
+func readBatchUpload(contentType string, body []byte) (purpose string, content []byte, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", nil, &BatchRequestError{Status: http.StatusBadRequest, Message: "上传文件须使用 multipart/form-data"}
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, partErr := reader.NextPart()
+		if partErr == io.EOF {
+			break
+		}
+		if partErr != nil {
+			return "", nil, &BatchRequestError{Status: http.StatusBadRequest, Message: "解析上传文件失败：" + partErr.Error()}
+		}
+		data, readErr := io.ReadAll(part)
+		if readErr != nil {
+			return "", nil, &BatchRequestError{Status: http.StatusBadRequest, Message: "读取上传文件失败：" + readErr.Error()}
+		}
+		switch part.FormName() {
+		case "purpose":
+			purpose = strings.TrimSpace(string(data))
+		case "file":
+			content = data
+		}
+	}
+	return purpose, content, nil
+}
+
+// batchInputModels 逐行解析 Batch 输入文件（JSONL），返回请求的模型与接口（均去重）；任一行缺少 body.model 时报错。
+func batchInputModels(content []byte) (models, endpoints []string, err error) {
+	reader := bufio.NewReader(bytes.NewReader(content))
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var req struct {
+				URL  string `json:"url"`
+				Body struct {
+					Model string `json:"model"`
+				} `json:"body"`
+			}
+			if json.Unmarshal(line, &req) != nil || req.Body.Model == "" {
+				return nil, nil, &BatchRequestError{Status: http.StatusBadRequest,
+					Message: fmt.Sprintf("Batch 输入文件第 %d 行格式错误：须为 JSON 且包含 body.model", lineNo)}
+			}
+			if !slices.Contains(models, req.Body.Model) {
+				models = append(models, req.Body.Model)
+			}
+			if !slices.Contains(endpoints, req.URL) {
+				endpoints = append(endpoints, req.URL)
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	if len(models) == 0 {
+		return nil, nil, &BatchRequestError{Status: http.StatusBadRequest, Message: "Batch 输入文件为空"}
+	}
+	return models, endpoints, nil
+}
+
+// checkBatchModels 对 Batch 中的每个模型校验网关 Key 的路径 / 模型范围与模型访问策略（路径为 Batch 行中的 url 或创建时的 endpoint）。
+func (s *ModelProviderService) checkBatchModels(caller gaiaRequest.ProxyCaller, endpoints, models []string) error {
+	key, err := callerGatewayKey(caller)
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		for _, endpoint := range endpoints {
+			if key == nil {
+				break
+			}
+			if err := s.CheckGatewayKeyScope(key, endpoint, model); err != nil {
+				return &BatchRequestError{Status: http.StatusForbidden, Message: err.Error()}
+			}
+		}
+		if err := s.checkModelAccess(caller, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchUploadProvider 上传文件使用的提供商：X-Gaia-Provider（或 query provider=）指定，否则取第一个已启用的 OpenAI / Azure。
+func (s *ModelProviderService) batchUploadProvider(reqHeader http.Header) (string, error) {
+	if p := strings.TrimSpace(strings.ToLower(reqHeader.Get("X-Gaia-Provider"))); p != "" {
+		if !isBatchProvider(p) {
+			return "", &BatchRequestError{Status: http.StatusBadRequest, Message: fmt.Sprintf("提供商 %s 不支持 Batch API（仅支持 openai / azure）", p)}
+		}
+		return p, nil
+	}
+	for _, p := range []string{gaia.ProviderOpenai, gaia.ProviderAzure} {
+		if s.isProviderEnabled(p) {
+			return p, nil
+		}
+	}
+	return "", &BatchRequestError{Status: http.StatusBadRequest, Message: "没有支持 Batch API 的已启用提供商（openai / azure）"}
+}
+
+// batchFileOwner 校验文件归属：经网关上传的文件，或本账号 Batch 的输出 / 错误文件；返回上传 / 创建时的提供商与凭证。
+func (s *ModelProviderService) batchFileOwner(caller gaiaRequest.ProxyCaller, fileID string) (
+	provider, credential string, record *gaia.ModelBatchFile, err error) {
+	var file gaia.ModelBatchFile
+	err = global.GVA_DB.Where("file_id = ? AND account_id = ?::uuid", fileID, caller.AccountId).First(&file).Error
+	if err == nil {
+		return file.ProviderName, file.CredentialId, &file, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", nil, err
+	}
+	var batch gaia.ModelBatch
+	err = global.GVA_DB.Where("(output_file_id = ? OR error_file_id = ?) AND account_id = ?::uuid", fileID, fileID, caller.AccountId).
+		First(&batch).Error
+	if err == nil {
+		return batch.ProviderName, batch.CredentialId, nil, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", nil, &BatchRequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("文件 %s 不存在或无权访问", fileID)}
+	}
+	return "", "", nil, err
+}
+
+// batchOwner 校验 Batch 归属，返回创建时的记录。
+func (s *ModelProviderService) batchOwner(caller gaiaRequest.ProxyCaller, batchID string) (*gaia.ModelBatch, error) {
+	var batch gaia.ModelBatch
+	err := global.GVA_DB.Where("batch_id = ? AND account_id = ?::uuid", batchID, caller.AccountId).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &BatchRequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("Batch %s 不存在或无权访问", batchID)}
+	}
+	return &batch, err
+}
+
+// summarizeBatchOutput 逐行解析 Batch 输出文件（JSONL），按响应中的模型汇总 status_code=200 的请求用量；
+// format 为 Batch endpoint 对应的 usage 格式（chat/completions、embeddings 为 OpenAI，responses 为 Responses）。
+func summarizeBatchOutput(r io.Reader, format usageFormat) (map[string]gaia.TokenUsage, error) {
+	totals := map[string]gaia.TokenUsage{}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var item struct {
+				Response *struct {
+					StatusCode int             `json:"status_code"`
+					Body       json.RawMessage `json:"body"`
+				} `json:"response"`
+			}
+			if json.Unmarshal(line, &item) == nil && item.Response != nil && item.Response.StatusCode == http.StatusOK {
+				usage := &proxyUsage{format: format}
+				usage.feed(item.Response.Body)
+				if hasTokens(usage.tokens) {
+					model := parseEndpointRequest(item.Response.Body).Model
+					total := totals[model]
+					addTokenUsage(&total, usage.tokens)
+					totals[model] = total
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return totals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// batchUpstreamGet 定时结算时直接请求上游（不经过代理日志与熔断），非 200 时返回错误。
+func (s *ModelProviderService) batchUpstreamGet(ctx context.Context, provider string,
+	creds *gaiaResponse.ProviderCredentials, path string) (*http.Response, error) {
+	base := s.getUpstreamBase(provider, creds)
+	if base == "" {
+		return nil, fmt.Errorf("提供商 %s 无可用上游地址", provider)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstreamRequestURL(provider, base, path, creds), nil)
+	if err != nil {
+		return nil, err
+	}
+	if provider == gaia.ProviderAzure {
+		req.Header.Set("api-key", creds.APIKey)
+	} else {
+		setUpstreamAuth(req.Header, creds)
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("上游 %s %s 返回 %d：%s", provider, path, resp.StatusCode, string(msg))
+	}
+	return resp, nil
+}
+
+// batchCredential 取 Batch 创建时使用的凭证；未记录凭证 ID（单凭证回落）时按常规方式获取。
+func (s *ModelProviderService) batchCredential(provider, credentialID string) (*gaiaResponse.ProviderCredentials, error) {
+	if credentialID != "" {
+		return s.providerCredentialByID(provider, credentialID)
+	}
+	creds, release, err := s.AcquireProviderCredential(provider)
+	release()
+	return creds, err
+}
+
+// SettleBatches 定时任务：轮询未结算的 Batch（按最近轮询时间依次处理，每轮最多 gaia.BatchSettleLimit 个），
+// 到达终态后下载输出文件，按逐行 usage 与模型定价计算费用并乘以 gaia.BatchPricingDiscount 扣费，按模型各记一条代理日志。
+// 失败 / 过期 / 取消的 Batch 同样结算其已完成部分；多实例并发时以 billed_at 条件更新保证只扣费一次。
+func (s *ModelProviderService) SettleBatches() {
+	var batches []gaia.ModelBatch
+	if err := global.GVA_DB.Where("billed_at IS NULL").Order("updated_at").
+		Limit(gaia.BatchSettleLimit).Find(&batches).Error; err != nil {
+		global.GVA_LOG.Error("查询待结算 Batch 失败", zap.Error(err))
+		return
+	}
+	for i := range batches {
+		b := &batches[i]
+		if err := s.settleBatch(context.Background(), b); err != nil {
+			global.GVA_LOG.Warn("Batch 结算失败", zap.String("batch_id", b.BatchId),
+				zap.String("provider", b.ProviderName), zap.Error(err))
+			global.GVA_DB.Model(b).Update("settle_error", err.Error())
+		}
+	}
+}
+
+// settleBatch 轮询单个 Batch，到达终态时结算。
+func (s *ModelProviderService) settleBatch(ctx context.Context, b *gaia.ModelBatch) error {
+	creds, err := s.batchCredential(b.ProviderName, b.CredentialId)
+	if err != nil {
+		return err
+	}
+	resp, err := s.batchUpstreamGet(ctx, b.ProviderName, creds, "v1/batches/"+b.BatchId)
+	if err != nil {
+		return err
+	}
+	var obj batchObject
+	err = json.NewDecoder(resp.Body).Decode(&obj)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("解析 Batch 状态失败：%w", err)
+	}
+	obj.apply(b)
+	if !slices.Contains(gaia.BatchTerminalStatuses, b.Status) {
+		// 未完成：仅同步状态（同时刷新 updated_at，使下一轮优先处理其他 Batch）
+		return global.GVA_DB.Model(b).Select("status", "output_file_id", "error_file_id", "request_total", "updated_at").
+			Updates(b).Error
+	}
+
+	totals := map[string]gaia.TokenUsage{}
+	if b.OutputFileId != "" {
+		if resp, err = s.batchUpstreamGet(ctx, b.ProviderName, creds, "v1/files/"+b.OutputFileId+"/content"); err != nil {
+			return err
+		}
+		totals, err = summarizeBatchOutput(resp.Body, detectUsageFormat(b.ProviderName, b.Endpoint))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("读取 Batch 输出文件失败：%w", err)
+		}
+	}
+
+	costs := make(map[string]float64, len(totals))
+	var sum gaia.TokenUsage
+	var total float64
+	for model, usage := range totals {
+		pricing, _ := s.fetchModelPricingFromDify(b.ProviderName, model)
+		costs[model] = calcUsageCost(pricing, model, usage) * gaia.BatchPricingDiscount
+		total += costs[model]
+		addTokenUsage(&sum, usage)
+	}
+
+	// 先以 billed_at IS NULL 为条件标记已结算，成功后再扣费，避免多实例重复扣费
+	now := time.Now()
+	res := global.GVA_DB.Model(&gaia.ModelBatch{}).Where("id = ? AND billed_at IS NULL", b.Id).Updates(map[string]interface{}{
+		"status": b.Status, "output_file_id": b.OutputFileId, "error_file_id": b.ErrorFileId, "request_total": b.RequestTotal,
+		"request_tokens": sum.PromptTokens, "response_tokens": sum.CompletionTokens, "cost_usd": total,
+		"billed_at": now, "settle_error": "",
+	})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+
+	caller := gaiaRequest.ProxyCaller{AccountId: b.AccountId, KeyId: b.KeyId, RequestId: b.BatchId}
+	for model, usage := range totals {
+		chargeCaller(caller, costs[model])
+		att := &proxyAttempt{caller: caller, provider: b.ProviderName, cost: costs[model], upstreamStatus: http.StatusOK}
+		s.logProxyAttempt(att, creds, model, "success", "", now, usage)
+	}
+	global.GVA_LOG.Info("Batch 结算完成", zap.String("batch_id", b.BatchId), zap.String("status", b.Status),
+		zap.String("account_id", b.AccountId), zap.Float64("cost_usd", total))
+	return nil
+}
@@ -0,0 +1,139 @@
+package gaia
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// TestParseBatchAPIPath 测试 Files / Batches 路径识别（兼容 Azure openai/ 前缀与传统路径）
+func TestParseBatchAPIPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want batchAPIRoute
+		ok   bool
+	}{
+		{"v1/files", batchAPIRoute{resource: "files"}, true},
+		{"/v1/files/file-abc/content", batchAPIRoute{resource: "files", id: "file-abc", action: "content"}, true},
+		{"openai/v1/batches/batch_1/cancel", batchAPIRoute{resource: "batches", id: "batch_1", action: "cancel"}, true},
+		{"openai/batches", batchAPIRoute{resource: "batches"}, true},
+		{"v1/chat/completions", batchAPIRoute{}, false},
+		{"v1/files/a/b/c", batchAPIRoute{}, false},
+	}
+	for _, c := range cases {
+		if got, ok := parseBatchAPIPath(c.path); ok != c.ok || got != c.want {
+			t.Errorf("%s 解析错误：%+v %v", c.path, got, ok)
+		}
+	}
+}
+
+// TestSummarizeBatchOutput 测试按模型汇总 Batch 输出文件中成功请求的用量（失败行与空行跳过）
+func TestSummarizeBatchOutput(t *testing.T) {
+	output := strings.Join([]string{
+		`{"id":"batch_req_1","custom_id":"a","response":{"status_code":200,"body":{"model":"gpt-4o-mini-2024-07-18",` +
+			`"usage":{"prompt_tokens":100,"completion_tokens":20,"prompt_tokens_details":{"cached_tokens":64}}}},"error":null}`,
+		`{"id":"batch_req_2","custom_id":"b","response":{"status_code":200,"body":{"model":"gpt-4o-mini-2024-07-18",` +
+			`"usage":{"prompt_tokens":50,"completion_tokens":10}}},"error":null}`,
+		`{"id":"batch_req_3","custom_id":"c","response":{"status_code":400,"body":{"error":{"message":"bad"}}},"error":null}`,
+		``,
+		`{"id":"batch_req_4","custom_id":"d","response":{"status_code":200,"body":{"model":"o3","usage":{"prompt_tokens":10,"completion_tokens":30,` +
+			`"completion_tokens_details":{"reasoning_tokens":25}}}},"error":null}`,
+	}, "\n")
+	totals, err := summarizeBatchOutput(strings.NewReader(output), usageFormatOpenAI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := totals["gpt-4o-mini-2024-07-18"]; got != (gaia.TokenUsage{PromptTokens: 150, CompletionTokens: 30, CacheReadTokens: 64}) {
+		t.Errorf("gpt-4o-mini 汇总错误：%+v", got)
+	}
+	if got := totals["o3"]; got != (gaia.TokenUsage{PromptTokens: 10, CompletionTokens: 30, ReasoningTokens: 25}) || len(totals) != 2 {
+		t.Errorf("o3 汇总错误：%+v（共 %d 个模型）", got, len(totals))
+	}
+
+	// /v1/responses 的 Batch 按 Responses 格式解析
+	responses := `{"response":{"status_code":200,"body":{"model":"gpt-5","usage":{"input_tokens":40,"output_tokens":8}}}}`
+	totals, _ = summarizeBatchOutput(strings.NewReader(responses), detectUsageFormat(gaia.ProviderOpenai, "/v1/responses"))
+	if totals["gpt-5"].PromptTokens != 40 || totals["gpt-5"].CompletionTokens != 8 {
+		t.Errorf("Responses Batch 汇总错误：%+v", totals)
+	}
+}
+
+// TestUpstreamRequestURL 测试上游地址拼接：Azure v1 接口不带 api-version，传统接口带凭证中的 api-version
+func TestUpstreamRequestURL(t *testing.T) {
+	creds := &gaiaResponse.ProviderCredentials{APIVersion: "2024-10-21"}
+	base := "https://res.openai.azure.com"
+	cases := map[string]string{
+		"v1/batches/batch_1":        base + "/openai/v1/batches/batch_1",
+		"openai/v1/files":           base + "/openai/v1/files",
+		"openai/deployments/x/chat": base + "/openai/deployments/x/chat?api-version=2024-10-21",
+		"batches":                   base + "/openai/batches?api-version=2024-10-21",
+		"responses":                 base + "/openai/v1/responses",
+	}
+	for path, want := range cases {
+		if got := upstreamRequestURL(gaia.ProviderAzure, base, path, creds); got != want {
+			t.Errorf("%s 拼接错误：%s", path, got)
+		}
+	}
+	if got := upstreamRequestURL(gaia.ProviderOpenai, "https://api.openai.com", "v1/files", creds); got != "https://api.openai.com/v1/files" {
+		t.Errorf("OpenAI 拼接错误：%s", got)
+	}
+}
+
+// TestBatchInputModels 测试从 multipart 上传中读取 Batch 输入文件并解析请求的模型与接口（缺少 body.model 的行报错）
+func TestBatchInputModels(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("purpose", "batch")
+	fw, _ := mw.CreateFormFile("file", "input.jsonl")
+	_, _ = fw.Write([]byte(`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}` + "\n" +
+		`{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[]}}` + "\n\n" +
+		`{"custom_id":"c","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}`))
+	_ = mw.Close()
+
+	purpose, content, err := readBatchUpload(mw.FormDataContentType(), buf.Bytes())
+	if err != nil || purpose != "batch" {
+		t.Fatalf("读取上传失败：%v %s", err, purpose)
+	}
+	models, endpoints, err := batchInputModels(content)
+	if err != nil || strings.Join(models, ",") != "gpt-4o-mini,gpt-4o" || strings.Join(endpoints, ",") != "/v1/chat/completions" {
+		t.Errorf("解析错误：%v %v %v", models, endpoints, err)
+	}
+	if _, _, err = batchInputModels([]byte(`{"custom_id":"a","body":{"messages":[]}}`)); err == nil {
+		t.Error("缺少 body.model 的行应报错")
+	}
+}
+
+// TestCheckBatchModels 测试 Batch 中的模型同样受网关 Key 模型范围与访问策略约束
+func TestCheckBatchModels(t *testing.T) {
+	setupTestDB(t, &gaia.GatewayKey{}, &gaia.ModelAccessRule{})
+	globalModelAccessCache.invalidate()
+	t.Cleanup(globalModelAccessCache.invalidate)
+	account := "6f1c2f3e-6f0a-4a8e-9a59-2f7f3c1d2b4a"
+	key := gaia.GatewayKey{AccountId: account, Name: "mini", KeyHash: "h", AllowedModels: `["gpt-4o-mini"]`}
+	global.GVA_DB.Create(&key)
+	global.GVA_DB.Create(&gaia.ModelAccessRule{Scope: gaia.ModelAccessScopeAccount, Subject: account,
+		Models: `["o3*"]`, Effect: gaia.ModelAccessEffectDeny, Enabled: true})
+	s := &ModelProviderService{}
+	endpoints := []string{"/v1/chat/completions"}
+
+	var batchErr *BatchRequestError
+	err := s.checkBatchModels(gaiaRequest.ProxyCaller{AccountId: account, KeyId: key.Id}, endpoints, []string{"gpt-4o-mini", "gpt-4o"})
+	if !errors.As(err, &batchErr) || batchErr.Status != http.StatusForbidden {
+		t.Errorf("Key 范围外的模型应拒绝：%v", err)
+	}
+	var denied *ModelAccessDeniedError
+	if err = s.checkBatchModels(gaiaRequest.ProxyCaller{AccountId: account}, endpoints, []string{"gpt-4o", "o3-mini"}); !errors.As(err, &denied) {
+		t.Errorf("访问策略拒绝的模型应拒绝：%v", err)
+	}
+	if err = s.checkBatchModels(gaiaRequest.ProxyCaller{AccountId: account, KeyId: key.Id}, endpoints, []string{"gpt-4o-mini"}); err != nil {
+		t.Errorf("允许的模型不应拒绝：%v", err)
+	}
+}
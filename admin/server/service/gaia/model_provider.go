@@ -17,6 +17,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -60,10 +61,14 @@ func (s *ModelProviderService) fetchAdminToken() (token string, err error) {
 }
 
 // fetchModelPricingFromDify 通过 Dify Console API 拉取 LLM 模型定价，结果按 model 名缓存到 Redis（TTL 1 小时）。
+// provider 为实际服务请求的提供商：为自定义提供商且其为该模型配置了定价时优先使用，不再请求 Dify（内置提供商不受自定义定价影响）。
 // Dify Console API：GET /console/api/workspaces/current/models/model-types/llm
 // 响应结构：{"data": [{"models": [{"model": "gpt-4o", "fetch_from": "...", "pricing": {"input":"0.005","output":"0.015","unit":"0.001","currency":"USD"}}]}]}
-func (s *ModelProviderService) fetchModelPricingFromDify(modelName string) (*gaia.ModelPricing, error) {
+func (s *ModelProviderService) fetchModelPricingFromDify(provider, modelName string) (*gaia.ModelPricing, error) {
 	const redisTTL = time.Hour
+	if p := customProviderPricing(provider, modelName); p != nil {
+		return p, nil
+	}
 	cacheKey := gaia.RedisKeyGaiaModelPricingPrefix + modelName
 	ctx := context.Background()
 
@@ -152,49 +157,101 @@ func rmbToUSD(rmb float64) float64 {
 
 // resolvePricing 返回模型定价：优先用从 Dify 拉取的 pricing，
 // 其次查内置兜底定价表（BuiltinModelPricing），最后返回 nil。
+// Dify pricing 未配置缓存/推理/音频单价时，若内置表同币种有配置则沿用内置值（按两者 unit 之比换算到 Dify pricing 的 unit）。
 func resolvePricing(pricing *gaia.ModelPricing, modelName string) *gaia.ModelPricing {
+	builtin := builtinPricing(modelName)
 	if pricing != nil && pricing.Unit > 0 {
-		return pricing
+		if builtin == nil || !strings.EqualFold(builtin.Currency, pricing.Currency) {
+			return pricing
+		}
+		builtinUnit := builtin.Unit
+		if builtinUnit == 0 {
+			builtinUnit = 0.001
+		}
+		scale := builtinUnit / pricing.Unit
+		cp := *pricing
+		if cp.CacheRead == 0 {
+			cp.CacheRead = builtin.CacheRead * scale
+		}
+		if cp.CacheWrite == 0 {
+			cp.CacheWrite = builtin.CacheWrite * scale
+		}
+		if cp.Reasoning == 0 {
+			cp.Reasoning = builtin.Reasoning * scale
+		}
+		if cp.AudioInput == 0 {
+			cp.AudioInput = builtin.AudioInput * scale
+		}
+		if cp.AudioOutput == 0 {
+			cp.AudioOutput = builtin.AudioOutput * scale
+		}
+		return &cp
 	}
-	// 内置定价表精确匹配
+	return builtin
+}
+
+// builtinPricing 查内置兜底定价表：先精确匹配，再按最长前缀模糊匹配（如 "qwen3.5-plus-xxx" 匹配 "qwen3.5-plus"，"tts-1-hd" 不会匹配到 "tts-1"）。
+func builtinPricing(modelName string) *gaia.ModelPricing {
 	if p, ok := gaia.BuiltinModelPricing[modelName]; ok {
 		return &p
 	}
-	// 前缀模糊匹配（如 "qwen3.5-plus-xxx" 匹配 "qwen3.5-plus"）
 	lower := strings.ToLower(modelName)
+	var (
+		best    gaia.ModelPricing
+		bestLen int
+	)
 	for k, p := range gaia.BuiltinModelPricing {
-		if strings.HasPrefix(lower, strings.ToLower(k)) {
-			cp := p
-			return &cp
+		if len(k) > bestLen && strings.HasPrefix(lower, strings.ToLower(k)) {
+			best, bestLen = p, len(k)
 		}
 	}
-	return nil
+	if bestLen == 0 {
+		return nil
+	}
+	return &best
 }
 
-// calcQuotaDelta 根据定价和 token 用量计算本次消耗的配额金额（统一以 USD 计）。
+// calcUsageCost 根据定价和 token 用量计算本次消耗的配额金额（统一以 USD 计）。
 // Dify pricing 字段语义：input/output 为每「unit」个 token 的价格，unit 通常为 0.001（千分之一），
 // 即 input=0.0014, unit=0.001 表示每千 token ¥0.0014 × (tokens/1000)。
-// 公式：cost = tokens × input × unit（因为 unit=1/1000，等价于 tokens/1000 × input）。
+// 公式：cost = tokens × price × unit（因为 unit=1/1000，等价于 tokens/1000 × price）。
+// 输入按「未命中缓存（文本 / 音频）/ 缓存命中 / 缓存写入」、输出按「普通输出 / 音频输出 / 推理」分别计价，
+// 缓存与音频输入单价未配置时按 input，推理与音频输出单价未配置时按 output（output 未配置时按 input）。
 // 若货币为 RMB/CNY，则除以汇率 7.26 换算为 USD，与 account_money_extend.used_quota 存储单位保持一致。
 // 若 Dify 未返回定价则查内置兜底表；均未命中时按极小默认值记账，避免多扣。
-func calcQuotaDelta(pricing *gaia.ModelPricing, modelName string, promptTokens, completionTokens int) float64 {
+func calcUsageCost(pricing *gaia.ModelPricing, modelName string, usage gaia.TokenUsage) float64 {
 	p := resolvePricing(pricing, modelName)
 	if p == nil {
 		// 兜底：仅做记账占位，不应大量触发
-		global.GVA_LOG.Warn("calcQuotaDelta 未找到模型定价，使用兜底值",
+		global.GVA_LOG.Warn("calcUsageCost 未找到模型定价，使用兜底值",
 			zap.String("model", modelName),
-			zap.Int("prompt_tokens", promptTokens),
-			zap.Int("completion_tokens", completionTokens),
+			zap.Int("prompt_tokens", usage.PromptTokens),
+			zap.Int("completion_tokens", usage.CompletionTokens),
 		)
-		return float64(promptTokens+completionTokens) * gaia.DefaultQuotaFallbackUSDPerToken
+		return float64(usage.PromptTokens+usage.CompletionTokens) * gaia.DefaultQuotaFallbackUSDPerToken
 	}
-	inputCost := float64(promptTokens) * p.Input * p.Unit
-	outputPrice := p.Output
-	if outputPrice == 0 {
-		outputPrice = p.Input
+	priceOr := func(price, fallback float64) float64 {
+		if price == 0 {
+			return fallback
+		}
+		return price
 	}
-	outputCost := float64(completionTokens) * outputPrice * p.Unit
-	total := inputCost + outputCost
+	outputPrice := priceOr(p.Output, p.Input)
+
+	cacheRead := min(usage.CacheReadTokens, usage.PromptTokens)
+	cacheWrite := min(usage.CacheWriteTokens, usage.PromptTokens-cacheRead)
+	reasoning := min(usage.ReasoningTokens, usage.CompletionTokens)
+	uncached := usage.PromptTokens - cacheRead - cacheWrite
+	audioIn := min(usage.AudioInputTokens, uncached)
+	audioOut := min(usage.AudioOutputTokens, usage.CompletionTokens-reasoning)
+
+	total := (float64(uncached-audioIn)*p.Input +
+		float64(audioIn)*priceOr(p.AudioInput, p.Input) +
+		float64(cacheRead)*priceOr(p.CacheRead, p.Input) +
+		float64(cacheWrite)*priceOr(p.CacheWrite, p.Input) +
+		float64(usage.CompletionTokens-reasoning-audioOut)*outputPrice +
+		float64(audioOut)*priceOr(p.AudioOutput, outputPrice) +
+		float64(reasoning)*priceOr(p.Reasoning, outputPrice)) * p.Unit
 
 	// RMB/CNY 定价统一换算为 USD 后再扣费，与 used_quota 存储单位保持一致
 	if strings.EqualFold(p.Currency, "RMB") || strings.EqualFold(p.Currency, "CNY") {
@@ -203,28 +260,6 @@ func calcQuotaDelta(pricing *gaia.ModelPricing, modelName string, promptTokens,
 	return total
 }
 
-// CheckAccountQuota 检查用户是否还有可用余额（total_quota - used_quota > 0）。
-// total_quota = 0 视为"未设置限额"，不拦截；total_quota > 0 时才做余额校验。
-func (s *ModelProviderService) CheckAccountQuota(userID string) error {
-	var row gaiaResponse.CheckAccountQuotaRow
-	err := global.GVA_DB.Table("account_money_extend").
-		Select("total_quota, used_quota").
-		Where("account_id = ?::uuid", userID).
-		First(&row).Error
-	if err != nil {
-		// 记录未找到：可能尚未初始化，放行
-		return nil
-	}
-	// total_quota = 0 表示不限额，放行
-	if row.TotalQuota <= 0 {
-		return nil
-	}
-	if row.UsedQuota >= row.TotalQuota {
-		return fmt.Errorf("余额不足，已用 %.6f / 总额 %.6f USD，请联系管理员充值", row.UsedQuota, row.TotalQuota)
-	}
-	return nil
-}
-
 // deductAccountQuota 将消耗配额计入 account_money_extend.used_quota（原子累加）。
 func deductAccountQuota(userID string, delta float64) {
 	if delta <= 0 {
@@ -256,6 +291,7 @@ type ModelProviderService struct{}
 //   - 启用/已选模型：来自 admin 表 model_provider_config，按短名存储（provider_name = openai 等）
 //   - 可用模型：通过各提供商官方 API 拉取（OpenAI/通义兼容 GET /v1/models），不再使用 Dify provider_models
 //   - 凭证：来自 Dify providers + provider_credentials，按候选名查（见 difyProviderNameCandidates）
+//   - 自定义提供商（custom_provider_extend）排在内置提供商之后，可用模型为登记列表合并其 /v1/models 发现结果
 func (s *ModelProviderService) GetProviderList() ([]gaiaResponse.ProviderListItem, error) {
 	var configs []gaia.ModelProviderConfig
 	if err := global.GVA_DB.Find(&configs).Error; err != nil {
@@ -263,8 +299,12 @@ func (s *ModelProviderService) GetProviderList() ([]gaiaResponse.ProviderListIte
 	}
 
 	// 只展示三种逻辑提供商；langgenius/openai/openai 等视为 openai 的数据来源，不单独列出
-	result := make([]gaiaResponse.ProviderListItem, len(gaia.SupportedProviders))
-	for i, providerName := range gaia.SupportedProviders {
+	providerNames := append([]string{}, gaia.SupportedProviders...)
+	for _, p := range loadCustomProviders() {
+		providerNames = append(providerNames, p.Name)
+	}
+	result := make([]gaiaResponse.ProviderListItem, len(providerNames))
+	for i, providerName := range providerNames {
 		var config *gaia.ModelProviderConfig
 		for j := range configs {
 			if configs[j].ProviderName == providerName {
@@ -290,7 +330,7 @@ func (s *ModelProviderService) GetProviderList() ([]gaiaResponse.ProviderListIte
 
 	// 异步并发拉取各提供商的可用模型
 	var wg sync.WaitGroup
-	for i, providerName := range gaia.SupportedProviders {
+	for i, providerName := range providerNames {
 		wg.Add(1)
 		go func(idx int, name string) {
 			defer wg.Done()
@@ -346,13 +386,13 @@ func (s *ModelProviderService) UpdateProviderConfig(providerName string, enabled
 	return global.GVA_DB.Save(&config).Error
 }
 
-// GetEnabledModels 获取所有已启用提供商的已选模型，以 OpenAI /v1/models 响应格式返回。
+// GetEnabledModels 获取所有已启用提供商的已选模型，以 OpenAI /v1/models 响应格式返回；按模型访问策略过滤掉调用方无权使用的模型。
 // @Tags System Integrated
 // @Summary 获取已启用的模型列表
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-func (s *ModelProviderService) GetEnabledModels() (gaiaResponse.OpenAIModelsResponse, error) {
+func (s *ModelProviderService) GetEnabledModels(caller gaiaRequest.ProxyCaller) (gaiaResponse.OpenAIModelsResponse, error) {
 	var configs []gaia.ModelProviderConfig
 	if err := global.GVA_DB.Where("enabled = ?", true).Find(&configs).Error; err != nil {
 		return gaiaResponse.OpenAIModelsResponse{}, err
@@ -361,6 +401,7 @@ func (s *ModelProviderService) GetEnabledModels() (gaiaResponse.OpenAIModelsResp
 	resp := gaiaResponse.OpenAIModelsResponse{
 		Data: []gaiaResponse.ModelInfo{},
 	}
+	canUse := s.modelAccessChecker(caller)
 
 	for _, config := range configs {
 		var models []string
@@ -371,6 +412,9 @@ func (s *ModelProviderService) GetEnabledModels() (gaiaResponse.OpenAIModelsResp
 		}
 
 		for _, modelID := range models {
+			if !canUse(modelID) {
+				continue
+			}
 			resp.Data = append(resp.Data, gaiaResponse.ModelInfo{
 				ID:   modelID,
 				Name: modelID,
@@ -378,6 +422,17 @@ func (s *ModelProviderService) GetEnabledModels() (gaiaResponse.OpenAIModelsResp
 		}
 	}
 
+	// 模型路由中的别名（如 fast）同样对外可见：别名本身及其至少一个上游模型有权使用时才列出
+	for _, route := range s.loadModelRoutes() {
+		if route.MatchType == gaia.ModelRouteMatchAlias && canUse(route.Pattern) &&
+			slices.ContainsFunc(route.upstreamModels(route.Pattern), canUse) {
+			resp.Data = append(resp.Data, gaiaResponse.ModelInfo{
+				ID:   route.Pattern,
+				Name: route.Pattern,
+			})
+		}
+	}
+
 	return resp, nil
 }
 
@@ -412,9 +467,13 @@ func (s *ModelProviderService) getAvailableModelsFromProviderModelCredentials(pr
 // GetAvailableModelsFromDify 获取提供商的可用模型列表。
 // - Azure：仅从 provider_model_credentials 表拉取，列表展示 model_name，实际请求 GPT 时由 API 侧用 encrypted_config 的 base_model_name。
 // - OpenAI / 通义 / Google：与原先一致，通过各提供商官方 API 拉取可用模型。未配置凭证时返回空列表且不报错。
+// - 自定义提供商：登记的模型列表合并其 OpenAI 兼容 /v1/models 发现结果。
 //
-// 参数 providerName 为短名（openai/tongyi/google/azure）。
+// 参数 providerName 为短名（openai/tongyi/google/azure 或自定义提供商短名）。
 func (s *ModelProviderService) GetAvailableModelsFromDify(providerName string) ([]gaiaResponse.ModelInfo, error) {
+	if provider := lookupCustomProvider(providerName); provider != nil {
+		return s.getCustomProviderModels(provider), nil
+	}
 	if providerName == gaia.ProviderAzure {
 		return s.getAvailableModelsFromProviderModelCredentials(providerName)
 	}
@@ -431,10 +490,10 @@ func (s *ModelProviderService) GetAvailableModelsFromDify(providerName string) (
 		if base == "" {
 			base = "https://api.openai.com"
 		}
-		return s.fetchOpenAICompatibleModels(client, base, creds.APIKey)
+		return s.fetchOpenAICompatibleModels(client, base, creds)
 	case gaia.ProviderTongyi:
 		return s.fetchOpenAICompatibleModels(
-			client, "https://dashscope.aliyuncs.com/api", creds.APIKey)
+			client, "https://dashscope.aliyuncs.com/api", creds)
 	case gaia.ProviderGoogle:
 		base := creds.Endpoint
 		if base == "" {
@@ -448,7 +507,7 @@ func (s *ModelProviderService) GetAvailableModelsFromDify(providerName string) (
 		return nil, nil
 	default:
 		if creds.Endpoint != "" {
-			return s.fetchOpenAICompatibleModels(client, creds.Endpoint, creds.APIKey)
+			return s.fetchOpenAICompatibleModels(client, creds.Endpoint, creds)
 		}
 		return nil, nil
 	}
@@ -458,14 +517,15 @@ func (s *ModelProviderService) GetAvailableModelsFromDify(providerName string) (
 // 兼容两种响应格式：
 // 1) OpenAI: { "data": [ { "id": "..." }, ... ] }
 // 2) 通义: { "success": true, "output": { "models": [ { "model": "...", "name": "..." }, ... ] } }
-func (s *ModelProviderService) fetchOpenAICompatibleModels(client *http.Client, baseURL, apiKey string) (
-	[]gaiaResponse.ModelInfo, error) {
+// 鉴权头按 creds 的鉴权方式设置（内置提供商为 Bearer，自定义提供商可为 api-key 头或不鉴权）。
+func (s *ModelProviderService) fetchOpenAICompatibleModels(client *http.Client, baseURL string,
+	creds *gaiaResponse.ProviderCredentials) ([]gaiaResponse.ModelInfo, error) {
 	url := strings.TrimSuffix(baseURL, "/") + "/v1/models"
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	setUpstreamAuth(req.Header, creds)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := client.Do(req)
 	if err != nil {
@@ -631,6 +691,10 @@ func (s *ModelProviderService) fetchAzureOpenAIModels(client *http.Client, baseU
 // @Produce application/json
 func (s *ModelProviderService) GetDifyProviderCredentials(providerName string) (
 	creds *gaiaResponse.ProviderCredentials, err error) {
+	// 自定义提供商的凭证登记在 custom_provider_extend，不查 Dify
+	if provider := lookupCustomProvider(providerName); provider != nil {
+		return provider.credentials(), nil
+	}
 	creds = &gaiaResponse.ProviderCredentials{}
 
 	// 首先尝试从Redis缓存获取（按请求的 providerName 缓存）
@@ -671,13 +735,30 @@ func (s *ModelProviderService) GetDifyProviderCredentials(providerName string) (
 		return creds, fmt.Errorf("未找到提供商 %s 的凭证配置", providerName)
 	}
 
-	// 兼容两种存储：1) 明文 JSON（如 {"openai_api_key":"...", "openai_api_base":"..."}）；2) Dify RSA+AES-EAX 加密后再 base64
+	if creds, err = s.parseProviderCredentialConfig(row.EncryptedConfig, row.TenantID); err != nil {
+		return nil, err
+	}
+
+	// 缓存凭证（1小时）
+	var cacheJSON []byte
+	if cacheJSON, err = json.Marshal(creds); err == nil {
+		global.GVA_Dify_REDIS.Set(context.Background(), cacheKey, cacheJSON, time.Hour)
+	}
+
+	return creds, nil
+}
+
+// parseProviderCredentialConfig 解析单条凭证的 encrypted_config（提取 API Key / base / 版本并解密）。
+// 兼容两种存储：1) 明文 JSON（如 {"openai_api_key":"...", "openai_api_base":"..."}）；2) Dify RSA+AES-EAX 加密后再 base64
+func (s *ModelProviderService) parseProviderCredentialConfig(encryptedConfig, tenantID string) (
+	creds *gaiaResponse.ProviderCredentials, err error) {
+	creds = &gaiaResponse.ProviderCredentials{}
 	var base, apiVersion string
 	var configMap map[string]interface{}
-	if err = json.Unmarshal([]byte(row.EncryptedConfig), &configMap); err == nil {
+	if err = json.Unmarshal([]byte(encryptedConfig), &configMap); err == nil {
 		// 解密函数用于处理加密的值
 		if config, ok := configMap[gaia.ConfigKeyOpenaiAPIKey]; ok {
-			creds.APIKey, err = s.decryptConfig(config.(string), row.TenantID)
+			creds.APIKey, err = s.decryptConfig(config.(string), tenantID)
 			if base, ok = configMap[gaia.ConfigKeyOpenaiAPIBase].(string); ok && strings.TrimSpace(base) != "" {
 				creds.Endpoint = strings.TrimSuffix(strings.TrimSpace(base), "/")
 			}
@@ -686,15 +767,15 @@ func (s *ModelProviderService) GetDifyProviderCredentials(providerName string) (
 				creds.APIVersion = strings.TrimSpace(apiVersion)
 			}
 		} else if config, ok = configMap[gaia.ConfigKeyDashScopeAPIKey]; ok {
-			creds.APIKey, err = s.decryptConfig(config.(string), row.TenantID)
+			creds.APIKey, err = s.decryptConfig(config.(string), tenantID)
 		} else if config, ok = configMap[gaia.ConfigKeyAPIKey]; ok {
-			creds.APIKey, err = s.decryptConfig(config.(string), row.TenantID)
+			creds.APIKey, err = s.decryptConfig(config.(string), tenantID)
 		} else {
 			// 尝试从备选字段中查找
 			for _, key := range gaia.CredentialKeyFallback {
 				var v string
 				if v, ok = configMap[key].(string); ok && v != "" {
-					if creds.APIKey, err = s.decryptConfig(v, row.TenantID); err == nil && creds.APIKey != "" {
+					if creds.APIKey, err = s.decryptConfig(v, tenantID); err == nil && creds.APIKey != "" {
 						break
 					}
 				}
@@ -707,18 +788,33 @@ func (s *ModelProviderService) GetDifyProviderCredentials(providerName string) (
 				creds.APIVersion = strings.TrimSpace(apiVersion)
 			}
 		}
+		// AWS Bedrock 凭证：access key + secret + region（与上面的 API Key 互不影响）
+		if v, ok := configMap[gaia.ConfigKeyAWSAccessKeyID].(string); ok && v != "" && err == nil {
+			if creds.AWSAccessKeyID, err = s.decryptConfig(v, tenantID); err == nil {
+				if v, ok = configMap[gaia.ConfigKeyAWSSecretAccessKey].(string); ok && v != "" {
+					creds.AWSSecretAccessKey, err = s.decryptConfig(v, tenantID)
+				}
+			}
+			if v, ok = configMap[gaia.ConfigKeyAWSRegion].(string); ok {
+				creds.AWSRegion = strings.TrimSpace(v)
+			}
+		}
+		// Google Vertex AI 凭证：服务账号 JSON key（加密存储）+ 项目 ID + 区域
+		if v, ok := configMap[gaia.ConfigKeyVertexServiceAccountKey].(string); ok && v != "" && err == nil {
+			creds.VertexServiceAccountKey, err = s.decryptConfig(v, tenantID)
+			if v, ok = configMap[gaia.ConfigKeyVertexProjectID].(string); ok {
+				creds.VertexProjectID = strings.TrimSpace(v)
+			}
+			if v, ok = configMap[gaia.ConfigKeyVertexLocation].(string); ok {
+				creds.VertexLocation = strings.TrimSpace(v)
+			}
+		}
 		if err != nil {
 			return nil, fmt.Errorf("解密凭证失败: %w", err)
 		}
 	}
-	if creds.APIKey == "" && creds.AWSAccessKeyID == "" {
-		return nil, fmt.Errorf("未能从配置中提取API Key（也未找到 AWS 凭证）")
-	}
-
-	// 缓存凭证（1小时）
-	var cacheJSON []byte
-	if cacheJSON, err = json.Marshal(creds); err == nil {
-		global.GVA_Dify_REDIS.Set(context.Background(), cacheKey, cacheJSON, time.Hour)
+	if creds.APIKey == "" && creds.AWSAccessKeyID == "" && creds.VertexServiceAccountKey == "" {
+		return nil, fmt.Errorf("未能从配置中提取API Key（也未找到 AWS / Vertex 凭证）")
 	}
 
 	return creds, nil
@@ -868,114 +964,21 @@ func (s *ModelProviderService) aesEAXDecrypt(key, nonce, ciphertext, tag []byte)
 	return plaintext, nil
 }
 
-// ProxyChat 将聊天请求代理到上游提供商，校验模型已开启并写入流式/非流式响应到 writer，并记录代理日志。
-// @Tags System Integrated
-// @Summary 代理聊天请求
-// @Security ApiKeyAuth
-// @accept application/json
-// @Produce application/json
-func (s *ModelProviderService) ProxyChat(userID string, req gaiaRequest.ChatRequest, writer io.Writer) error {
-	// 按“已选模型”解析实际渠道（如 gpt-5-chat 若只在 Azure 下勾选则走 azure）
-	providerName, err := s.resolveProviderByModel(req.Model)
-	if err != nil {
-		return err
-	}
-
-	// 获取提供商凭证
-	creds, err := s.GetDifyProviderCredentials(providerName)
-	if err != nil {
-		return err
-	}
-
-	// 获取上游端点
-	endpoint := s.getUpstreamEndpoint(providerName)
-
-	// 构建请求
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return err
-	}
-
-	httpReq, err := http.NewRequest("POST", endpoint, bytes.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
-
-	// 设置请求头
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", creds.APIKey))
-
-	// 发送请求
-	client := &http.Client{
-		Timeout: 5 * time.Minute,
-	}
-
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("上游返回错误: %d %s", resp.StatusCode, string(body))
-	}
-
-	// 记录开始时间（用于日志）
-	startTime := time.Now()
-	var requestTokens, responseTokens int
-	status := "success"
-	var errorMsg string
-
-	defer func() {
-		// 记录日志
-		global.GVA_DB.Create(&gaia.ModelProxyLog{
-			UserId:         userID,
-			ProviderName:   providerName,
-			ModelName:      req.Model,
-			RequestTokens:  requestTokens,
-			ResponseTokens: responseTokens,
-			Status:         status,
-			ErrorMessage:   errorMsg,
-			CreatedAt:      startTime,
-		})
-	}()
-
-	// 处理流式响应
-	if req.Stream {
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if _, err = writer.Write([]byte(line + "\n")); err != nil {
-				status = "error"
-				errorMsg = err.Error()
-				return err
-			}
-			// Flush if writer supports it
-			if flusher, ok := writer.(http.Flusher); ok {
-				flusher.Flush()
-			}
-		}
-		if err = scanner.Err(); err != nil {
-			status = "error"
-			errorMsg = err.Error()
-			return err
-		}
-	} else {
-		// 非流式响应
-		if _, err = io.Copy(writer, resp.Body); err != nil {
-			status = "error"
-			errorMsg = err.Error()
-			return err
-		}
-	}
-
-	return nil
-}
-
 // getProviderCandidatesByModel 返回可能服务该模型的提供商短名列表（用于按“已选模型”解析实际渠道）。
 // 例如 gpt 系列可能走 openai 或 azure，返回 [azure, openai] 以便优先匹配用户在 admin 里配置的渠道。
+// 自定义提供商中登记了该模型的排在内置规则之前，其余自定义提供商排在最后，由已选模型列表最终决定是否可用。
 func (s *ModelProviderService) getProviderCandidatesByModel(modelName string) []string {
+	// 管理端配置的模型路由优先于下方内置规则
+	if route := s.lookupModelRoute(modelName); route != nil {
+		return route.providers
+	}
+	declared, others := customProviderCandidates(loadCustomProviders(), modelName)
+	candidates := append(declared, builtinProviderCandidates(modelName)...)
+	return append(candidates, others...)
+}
+
+// builtinProviderCandidates 按内置名称规则返回可能服务该模型的内置提供商短名列表。
+func builtinProviderCandidates(modelName string) []string {
 	modelLower := strings.ToLower(modelName)
 	if strings.HasPrefix(modelLower, "gpt") || strings.Contains(modelLower, "openai") {
 		return []string{gaia.ProviderAzure, gaia.ProviderOpenai}
@@ -987,11 +990,11 @@ func (s *ModelProviderService) getProviderCandidatesByModel(modelName string) []
 		return []string{gaia.ProviderTongyi}
 	}
 	if strings.HasPrefix(modelLower, "gemini") || strings.Contains(modelLower, "google") {
-		return []string{gaia.ProviderGoogle}
+		return []string{gaia.ProviderGoogle, gaia.ProviderVertex}
 	}
 	if strings.Contains(modelLower, "claude") || strings.Contains(modelLower, "anthropic") {
-		// 顺序即优先级：anthropic 直连优先，未开启则回落到 AWS Bedrock；都开则走 anthropic
-		return []string{gaia.ProviderAnthropic, gaia.ProviderAWS}
+		// 顺序即优先级：anthropic 直连优先，未开启则回落到 AWS Bedrock、Vertex AI；都开则走 anthropic
+		return []string{gaia.ProviderAnthropic, gaia.ProviderAWS, gaia.ProviderVertex}
 	}
 	// Kimi / Moonshot 系列经由 tongyi（百炼）渠道转发
 	if strings.HasPrefix(modelLower, "kimi") || strings.Contains(modelLower, "moonshot") {
@@ -1008,24 +1011,11 @@ func (s *ModelProviderService) getProviderCandidatesByModel(modelName string) []
 	return nil
 }
 
-// resolveProviderByModel 根据模型名解析实际使用的提供商：在“可能服务该模型的”渠道中，取第一个已启用且已选模型列表包含该模型的渠道。
-// 这样当模型名是 gpt-5-chat 且用户只在 Azure 渠道下勾选了该模型时，会正确走 azure 而不是 openai。
-func (s *ModelProviderService) resolveProviderByModel(modelName string) (string, error) {
-	candidates := s.getProviderCandidatesByModel(modelName)
-	if len(candidates) == 0 {
-		global.GVA_LOG.Warn("resolveProviderByModel 无法识别提供商", zap.String("model", modelName), zap.String("model_lower", strings.ToLower(modelName)))
-		return "", fmt.Errorf("无法识别模型 %s 的提供商", modelName)
-	}
-	for _, p := range candidates {
-		if s.isModelEnabled(p, modelName) {
-			return p, nil
-		}
-	}
-	return "", fmt.Errorf("模型 %s 未开启", modelName)
-}
-
-// getProviderByModel 仅根据模型名称推断提供商短名（不查配置表）。代理校验“是否开启”请用 resolveProviderByModel。
+// getProviderByModel 仅根据模型名称推断提供商短名（不查配置表）。代理校验“是否开启”请用 resolveProvidersByModel。
 func (s *ModelProviderService) getProviderByModel(modelName string) (string, error) {
+	if route := s.lookupModelRoute(modelName); route != nil && len(route.providers) > 0 {
+		return route.providers[0], nil
+	}
 	modelLower := strings.ToLower(modelName)
 	if strings.HasPrefix(modelLower, "gpt") || strings.Contains(modelLower, "openai") {
 		return gaia.ProviderOpenai, nil
@@ -1037,7 +1027,7 @@ func (s *ModelProviderService) getProviderByModel(modelName string) (string, err
 		return gaia.ProviderGoogle, nil
 	}
 	if strings.Contains(modelLower, "claude") || strings.Contains(modelLower, "anthropic") {
-		// 仅按名字推断时默认 anthropic；实际渠道（含 AWS Bedrock）由 resolveProviderByModel 决定
+		// 仅按名字推断时默认 anthropic；实际渠道（含 AWS Bedrock）由 resolveProvidersByModel 决定
 		return gaia.ProviderAnthropic, nil
 	}
 	// Kimi / Moonshot 默认走 tongyi（百炼）渠道
@@ -1051,10 +1041,13 @@ func (s *ModelProviderService) getProviderByModel(modelName string) (string, err
 	if strings.HasPrefix(modelLower, "glm") || strings.Contains(modelLower, "zhipu") || strings.Contains(modelLower, "chatglm") {
 		return gaia.ProviderTongyi, nil
 	}
-	// MiniMax 类模型可能走 tongyi 或 minimax，仅推断时默认 tongyi（实际以 resolveProviderByModel + 已选模型为准）
+	// MiniMax 类模型可能走 tongyi 或 minimax，仅推断时默认 tongyi（实际以 resolveProvidersByModel + 已选模型为准）
 	if strings.HasPrefix(modelLower, "minimax") || strings.Contains(modelLower, "abab") {
 		return gaia.ProviderTongyi, nil
 	}
+	if declared, _ := customProviderCandidates(loadCustomProviders(), modelName); len(declared) > 0 {
+		return declared[0], nil
+	}
 	return "", fmt.Errorf("无法识别模型 %s 的提供商", modelName)
 }
 
@@ -1099,6 +1092,9 @@ func (s *ModelProviderService) getUpstreamEndpoint(providerName string) string {
 	if endpoint, ok := gaia.DefaultChatCompletionsEndpoints[providerName]; ok {
 		return endpoint
 	}
+	if provider := lookupCustomProvider(providerName); provider != nil {
+		return provider.BaseURL + "/v1/chat/completions"
+	}
 	return ""
 }
 
@@ -1120,41 +1116,186 @@ func (s *ModelProviderService) getUpstreamBase(providerName string, creds *gaiaR
 // @accept application/json
 // @Produce application/json
 // provider 可通过 X-Gaia-Provider 头、query provider= 或 body 中的 model 字段推断；上游 base 优先使用 creds.Endpoint（openai_api_base）。
-func (s *ModelProviderService) ProxyRequest(
-	userID, path, method string, reqHeader http.Header, body []byte, writer io.Writer) (err error) {
+// caller 为鉴权后的调用方（账号 + 可选网关 Key），用于日志与计费。
+// 按 model 推断时，若上游在写回任何字节前返回 5xx/429 或连接失败，会按候选顺序转移到下一个已启用的提供商（次数见 gaia.gateway.failover-retries）。
+func (s *ModelProviderService) ProxyRequest(ctx context.Context,
+	caller gaiaRequest.ProxyCaller, path, method string, reqHeader http.Header, body []byte, writer io.Writer) (err error) {
 	// init
-	var providerName string
+	var providers []string
+	var requestModel string
 	if path = strings.TrimPrefix(path, "/"); path == "" {
 		return fmt.Errorf("代理路径不能为空")
 	}
+	// Files / Batches：按文件与 Batch 的归属固定提供商与凭证，Batch 完成后由定时任务结算
+	if isBatchAPIPath(path) {
+		return s.proxyBatchRequest(ctx, caller, path, method, reqHeader, body, writer)
+	}
+	// 查询 / 删除 / 取消已有响应：按响应归属固定提供商与凭证
+	if responseID, ok := storedResponseID(path); ok {
+		return s.proxyStoredResponse(ctx, caller, responseID, path, method, reqHeader, body, writer)
+	}
 
 	// 解析 provider：头 > query 已在 handler 传入；此处从 body 取 model 仅当 body 为 JSON 且含 model 时用于推断
 	xGaiaProvider := reqHeader.Get("X-Gaia-Provider")
 	global.GVA_LOG.Info("ProxyRequest 解析 provider", zap.String("path", path), zap.String(
 		"X-Gaia-Provider", xGaiaProvider), zap.Int("body_len", len(body)))
-	if p := xGaiaProvider; p != "" {
-		providerName = strings.TrimSpace(strings.ToLower(p))
+	if p := strings.TrimSpace(strings.ToLower(xGaiaProvider)); p != "" {
+		// 显式指定提供商时不做转移
+		providers = []string{p}
 	}
-	if providerName == "" && len(body) > 0 {
+	if len(providers) == 0 && len(body) > 0 {
 		var obj map[string]interface{}
 		if err = json.Unmarshal(body, &obj); err == nil {
 			if m, ok := obj["model"].(string); ok && m != "" {
+				requestModel = m
 				global.GVA_LOG.Info("ProxyRequest 从 body 解析 model", zap.String("model", m))
-				// 按“已选模型”解析实际渠道（如 gpt-5-chat 若只在 Azure 下勾选则走 azure）
-				providerName, err = s.resolveProviderByModel(m)
+				// 按“已选模型”解析实际渠道（如 gpt-5-chat 若只在 Azure 下勾选则走 azure），多个渠道均开启时依次作为转移候选
+				providers, err = s.resolveProvidersByModel(m)
 				if err != nil {
-					global.GVA_LOG.Error("ProxyRequest resolveProviderByModel 失败", zap.String(
+					global.GVA_LOG.Error("ProxyRequest resolveProvidersByModel 失败", zap.String(
 						"model", m), zap.Error(err))
 					return err
 				}
-				global.GVA_LOG.Info("ProxyRequest 解析得到 provider", zap.String("provider", providerName))
+				global.GVA_LOG.Info("ProxyRequest 解析得到 provider", zap.Strings("providers", providers))
+			}
+		}
+	}
+	if len(providers) == 0 && detectUsageFormat("", path) == usageFormatGemini {
+		// Gemini 原生接口的模型在路径中（v1beta/models/{model}:generateContent）
+		if m := modelFromGeminiPath(path); m != "" {
+			if providers, err = s.resolveProvidersByModel(m); err != nil {
+				return err
+			}
+		}
+	}
+	if len(providers) == 0 {
+		// Bedrock 原生 Converse 接口的模型在路径中（model/{modelId}/converse）
+		if m, _, ok := bedrockConversePath(path); ok {
+			if providers, err = s.resolveProvidersByModel(m); err != nil {
+				return err
 			}
 		}
 	}
-	if providerName == "" {
+	if len(providers) == 0 {
 		return fmt.Errorf("请指定 provider：设置请求头 X-Gaia-Provider 或 query provider=，或在 body 中提供 model 字段")
 	}
 
+	// 模型访问策略：显式指定提供商时同样按 body / 路径中的模型校验
+	accessModel := requestModel
+	if accessModel == "" {
+		accessModel = modelFromRequest(path, body)
+	}
+	if err = s.checkModelAccess(caller, accessModel); err != nil {
+		return err
+	}
+	if isResponsesPath(path) && isResponsesBackground(body) {
+		return fmt.Errorf("Responses API 暂不支持 background 模式：创建时上游不返回 usage，网关无法计费")
+	}
+	// 以 previous_response_id 续接对话：上一轮响应须为本账号创建，固定到创建时的提供商与凭证
+	var pinnedCredential string
+	if prevID := previousResponseID(body); prevID != "" && isResponsesPath(path) {
+		prev, ownerErr := s.responseOwner(caller, prevID)
+		if ownerErr != nil {
+			return ownerErr
+		}
+		if !slices.Contains(providers, prev.ProviderName) {
+			return &BatchRequestError{Status: http.StatusBadRequest,
+				Message: fmt.Sprintf("响应 %s 由提供商 %s 创建，本次请求不能使用该提供商", prevID, prev.ProviderName)}
+		}
+		providers, pinnedCredential = []string{prev.ProviderName}, prev.CredentialId
+	}
+
+	// 模型路由：路由改写后的上游模型须在 Key 作用域与访问策略内，逐个提供商尝试时再校验
+	route := s.lookupModelRoute(requestModel)
+	var key *gaia.GatewayKey
+	if route != nil {
+		if key, err = callerGatewayKey(caller); err != nil {
+			return err
+		}
+	}
+
+	// 精确匹配响应缓存：命中直接回写，未命中时在上游成功后写入
+	// servedModel 为实际服务本次请求的上游模型（路由改写后），缓存命中时按它计费；
+	// 路由可能改写成调用方无权使用的上游模型时不读写缓存，避免拿到其他账号用该模型生成的响应
+	var servedProvider, servedModel string
+	if w, ok := writer.(http.ResponseWriter); ok && global.GVA_REDIS != nil &&
+		responseCacheEnabled(method, path, reqHeader, body, accessModel) && s.routeUpstreamsAllowed(caller, key, route, requestModel) {
+		cacheKey := responseCacheKey(providers[0], path, reqHeader, body)
+		if cached := loadCachedResponse(cacheKey); cached != nil {
+			return s.serveCachedResponse(caller, cached, writer)
+		}
+		w.Header().Set(responseCacheHeader, "MISS")
+		capture := newResponseCaptureWriter(w, global.GVA_CONFIG.Gaia.Gateway.Cache.MaxBytes)
+		writer = capture
+		defer func() {
+			if err == nil {
+				if servedModel == "" {
+					servedModel = accessModel
+				}
+				storeCachedResponse(cacheKey, path, capture, servedProvider, servedModel)
+			}
+		}()
+	}
+
+	// 依次尝试候选提供商：熔断中的提供商直接跳过（不占用转移次数），全部熔断时返回 *CircuitOpenError
+	promptTokens, _ := estimateRequestTokens(body)
+	attempts := failoverAttempts(len(providers))
+	var failover []string
+	tried := 0
+	for i := 0; i < len(providers) && tried < attempts; i++ {
+		if ok, wait := globalCircuitBreaker.allow(providers[i], "", time.Now()); !ok {
+			openErr := &CircuitOpenError{Provider: providers[i], RetryAfter: wait}
+			failover = append(failover, openErr.String())
+			err = openErr
+			continue
+		}
+		servedProvider = providers[i]
+		att := &proxyAttempt{
+			ctx:          ctx,
+			caller:       caller,
+			provider:     providers[i],
+			model:        requestModel,
+			upstream:     requestModel,
+			failover:     append(append([]string{}, failover...), providers[i]),
+			credential:   pinnedCredential,
+			allowRetry:   tried < attempts-1 && i < len(providers)-1,
+			promptTokens: promptTokens,
+		}
+		if route != nil {
+			att.upstream = route.upstreamModelFor(att.provider, requestModel)
+			// 路由改写后的上游模型同样受 Key 作用域与访问策略约束：无权使用时跳过该提供商（不占用转移次数）
+			if att.upstream != accessModel {
+				if denyErr := s.checkUpstreamModel(caller, key, att.upstream); denyErr != nil {
+					err = denyErr
+					continue
+				}
+			}
+		}
+		servedModel = att.upstream
+		err = s.proxyToProvider(att, path, method, reqHeader, body, writer)
+		var openErr *CircuitOpenError
+		if errors.As(err, &openErr) {
+			// 该提供商的凭证全部熔断
+			failover = append(failover, openErr.String())
+			continue
+		}
+		tried++
+		var retryErr *upstreamRetryableError
+		if !errors.As(err, &retryErr) || ctx.Err() != nil {
+			return err
+		}
+		global.GVA_LOG.Warn("ProxyRequest 上游失败，转移到下一个提供商",
+			zap.String("provider", att.provider), zap.Int("attempt", tried), zap.Error(err))
+		failover = append(failover, retryErr.String())
+	}
+	return err
+}
+
+// proxyToProvider 使用指定提供商完成一次转发；att.allowRetry 为 true 时，可重试的上游失败以 *upstreamRetryableError 返回且不写回客户端。
+func (s *ModelProviderService) proxyToProvider(
+	att *proxyAttempt, path, method string, reqHeader http.Header, body []byte, writer io.Writer) (err error) {
+	caller, providerName := att.caller, att.provider
+	userID := caller.AccountId
 	// 若未从 body model 解析出 provider，则只校验该提供商已启用
 	if !s.isProviderEnabled(providerName) {
 		return fmt.Errorf("提供商 %s 未开启", providerName)
@@ -1163,20 +1304,75 @@ func (s *ModelProviderService) ProxyRequest(
 	var base string
 	var bodyReader io.Reader
 	var creds *gaiaResponse.ProviderCredentials
-	if creds, err = s.GetDifyProviderCredentials(providerName); err != nil {
+	var releaseCredential func()
+	// 多凭证负载均衡：按策略选出本次使用的凭证，请求结束后释放在途名额
+	if creds, releaseCredential, err = s.acquireAttemptCredential(att); err != nil {
 		return err
 	}
+	defer releaseCredential()
+
+	// 模型路由改写上游模型 ID（别名、不同渠道下的模型 ID 差异）
+	if att.upstream != att.model && len(body) > 0 {
+		body = rewriteBodyModel(body, att.upstream)
+	}
+
+	// Responses API 仅 OpenAI 兼容渠道提供（OpenAI / Azure / 自定义提供商等），原生协议渠道不做转换
+	if isResponsesPath(path) && (providerName == gaia.ProviderAnthropic || providerName == gaia.ProviderAWS ||
+		providerName == gaia.ProviderVertex) {
+		return fmt.Errorf("提供商 %s 不支持 Responses API", providerName)
+	}
+
+	// Bedrock 原生 Converse 接口（model/{modelId}/converse），仅 AWS 渠道支持
+	if _, _, ok := bedrockConversePath(path); ok {
+		if providerName != gaia.ProviderAWS {
+			return fmt.Errorf("提供商 %s 不支持 Bedrock Converse 接口", providerName)
+		}
+		return s.proxyBedrockConverse(att, path, body, writer, creds)
+	}
+
+	// OpenAI chat/completions 调用 Claude：转换为 Anthropic Messages（anthropic 直连、AWS Bedrock 或 Vertex AI）；
+	// Bedrock 上的非 Anthropic 模型（Nova / Llama / Mistral 等）转换为 Converse
+	if (providerName == gaia.ProviderAnthropic || providerName == gaia.ProviderAWS || providerName == gaia.ProviderVertex) &&
+		isChatCompletionsPath(path) {
+		model := parseEndpointRequest(body).Model
+		if providerName == gaia.ProviderAWS && !isBedrockAnthropicModel(model) {
+			return s.proxyChatViaConverse(att, body, writer, creds)
+		}
+		if providerName == gaia.ProviderVertex && !isVertexAnthropicModel(model) {
+			return fmt.Errorf("Vertex AI 渠道的非 Claude 模型请使用 Gemini generateContent 原生接口")
+		}
+		return s.proxyChatViaAnthropic(att, body, writer, creds)
+	}
+
+	// Anthropic Messages 调用非 Claude 模型：转换为 OpenAI chat/completions
+	if providerName != gaia.ProviderAnthropic && providerName != gaia.ProviderAWS && providerName != gaia.ProviderVertex &&
+		isAnthropicMessagesPath(path) {
+		return s.proxyMessagesViaOpenAI(att, body, writer, creds)
+	}
 
-	if base = s.getUpstreamBase(providerName, creds); base == "" {
+	// AWS Bedrock 走 SigV4 原生接口
+	if providerName == gaia.ProviderAWS {
+		return s.proxyBedrockRequest(att, path, method, reqHeader, body, writer, creds)
+	}
+
+	if providerName == gaia.ProviderVertex {
+		// Vertex AI：服务账号换取 access token，路径与 body 改写为 Vertex 格式后走下方通用转发
+		if base, path, body, creds, err = s.prepareVertexRequest(att.ctx, path, body, creds); err != nil {
+			return err
+		}
+	} else if base = s.getUpstreamBase(providerName, creds); base == "" {
 		return fmt.Errorf("提供商 %s 无可用上游地址", providerName)
 	}
 
 	// 若 body 是 JSON 且含 stream: true，注入 stream_options.include_usage = true
 	// 这样上游会在 SSE 末尾的 data 行返回 usage，供后续计费解析使用。
+	// Anthropic / Gemini 原生接口与 Responses API（response.completed 事件自带 usage）不接受该字段，不注入。
+	usage := &proxyUsage{format: detectUsageFormat(providerName, path)}
 	if len(body) > 0 {
 		var bodyObj map[string]interface{}
 		if json.Unmarshal(body, &bodyObj) == nil {
-			if streamVal, ok := bodyObj["stream"].(bool); ok && streamVal {
+			att.stream, _ = bodyObj["stream"].(bool)
+			if att.stream && usage.format == usageFormatOpenAI {
 				if _, hasOpt := bodyObj["stream_options"]; !hasOpt {
 					bodyObj["stream_options"] = map[string]interface{}{"include_usage": true}
 					if injected, e := json.Marshal(bodyObj); e == nil {
@@ -1185,51 +1381,38 @@ func (s *ModelProviderService) ProxyRequest(
 				}
 			}
 		}
+	}
+	if len(body) > 0 {
 		bodyReader = bytes.NewReader(body)
 	}
 
 	// 构建请求 URL，Azure 需要特殊处理
-	var requestURL string
-	if providerName == gaia.ProviderAzure {
-		// Azure OpenAI 有两种 API 格式：
-		// 1. 新版 v1 API（2025年8月后）：/openai/v1/... 不需要 api-version 参数
-		// 2. 传统 API：/openai/deployments/{deployment}/... 需要 api-version 参数
-		// 参考：https://learn.microsoft.com/en-us/azure/ai-foundry/openai/api-version-lifecycle
-		//
-		// 当请求路径以 "v1/" 开头时，使用新版 v1 API，不添加 api-version
-		if strings.HasPrefix(path, "v1/") {
-			// 新版 v1 API：/openai/v1/chat/completions（不需要 api-version）
-			requestURL = base + "/openai/" + path
-		} else if strings.HasPrefix(path, "openai/v1/") {
-			// 已经包含完整的 openai/v1 前缀
-			requestURL = base + "/" + path
-		} else if strings.HasPrefix(path, "openai/") {
-			// 其他 openai 路径（如 openai/deployments/...），可能需要 api-version
-			requestURL = base + "/" + path
-			if creds.APIVersion != "" {
-				requestURL += "?api-version=" + creds.APIVersion
-			}
-		} else {
-			// 其他路径，添加 openai 前缀并使用传统 API
-			requestURL = base + "/openai/" + path
-			if creds.APIVersion != "" {
-				requestURL += "?api-version=" + creds.APIVersion
-			}
-		}
-	} else {
-		requestURL = base + "/" + path
-	}
+	requestURL := upstreamRequestURL(providerName, base, path, creds)
 
-	httpReq, err := http.NewRequest(method, requestURL, bodyReader)
+	httpReq, err := http.NewRequestWithContext(att.ctx, method, requestURL, bodyReader)
 	if err != nil {
 		return err
 	}
 
-	// 复制常用请求头，Azure 使用 api-key 头，其他使用 Authorization Bearer
+	// 复制常用请求头，Azure 使用 api-key 头，自定义提供商按其鉴权方式，其他使用 Authorization Bearer
 	if providerName == gaia.ProviderAzure {
 		httpReq.Header.Set("api-key", creds.APIKey)
 	} else {
-		httpReq.Header.Set("Authorization", "Bearer "+creds.APIKey)
+		setUpstreamAuth(httpReq.Header, creds)
+	}
+	// 原生协议鉴权：Anthropic 使用 x-api-key + anthropic-version，Gemini 使用 x-goog-api-key
+	switch providerName {
+	case gaia.ProviderAnthropic:
+		httpReq.Header.Set("x-api-key", creds.APIKey)
+		httpReq.Header.Set("anthropic-version", gaia.AnthropicAPIVersion)
+		if v := reqHeader.Get("anthropic-version"); v != "" {
+			httpReq.Header.Set("anthropic-version", v)
+		}
+		if beta := reqHeader.Get("anthropic-beta"); beta != "" {
+			httpReq.Header.Set("anthropic-beta", beta)
+		}
+	case gaia.ProviderGoogle:
+		httpReq.Header.Set("x-goog-api-key", creds.APIKey)
 	}
 	if ct := reqHeader.Get("Content-Type"); ct != "" {
 		httpReq.Header.Set("Content-Type", ct)
@@ -1242,66 +1425,93 @@ func (s *ModelProviderService) ProxyRequest(
 		// 不强制覆盖，上游可能根据 body 的 stream 返回 SSE
 	}
 
-	client := &http.Client{Timeout: 5 * time.Minute}
-	resp, err := client.Do(httpReq)
+	startTime := time.Now()
+	resp, err := doUpstream(att, creds, httpReq)
 	if err != nil {
+		if att.clientCancelled() {
+			s.logProxyAttempt(att, creds, modelFromRequest(path, body), "cancelled", err.Error(), startTime, gaia.TokenUsage{})
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: providerName, Err: err}
+		}
 		return err
 	}
 	defer resp.Body.Close()
+	att.trackFirstByte(resp)
+
+	// 可转移的上游失败：尚未写回任何字节，丢弃响应交由下一个提供商处理（不记日志、不计费）
+	if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+		reportCredentialStatus(providerName, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return &upstreamRetryableError{Provider: providerName, Status: resp.StatusCode}
+	}
 
 	// 记录代理日志（用于计费时可区分 openai_api_base）
-	startTime := time.Now()
 	modelOrPath := path
-	if len(body) > 0 {
-		var obj map[string]interface{}
-		if json.Unmarshal(body, &obj) == nil {
-			if m, _ := obj["model"].(string); m != "" {
-				modelOrPath = m
-			}
-		}
+	if m := modelFromGeminiPath(path); m != "" && usage.format != usageFormatOpenAI {
+		// Gemini 原生接口与 Vertex AI（generateContent / rawPredict）的模型在路径中
+		modelOrPath = m
+	}
+	if m := parseEndpointRequest(body).Model; m != "" {
+		modelOrPath = m
 	}
 	var logStatus, logError string
-	var promptTokens, completionTokens int
+	var tokens gaia.TokenUsage
+	var respBody []byte   // 非流式响应体，供按接口计量（embeddings / 图片 / 语音）
+	var responseID string // Responses API 创建的响应 ID，成功后记录归属
 	defer func() {
 		if logStatus == "" {
 			logStatus = "success"
 		}
-		global.GVA_DB.Create(&gaia.ModelProxyLog{
-			UserId:         userID,
-			ProviderName:   providerName,
-			ModelName:      modelOrPath,
-			RequestTokens:  promptTokens,
-			ResponseTokens: completionTokens,
-			Status:         logStatus,
-			ErrorMessage:   logError,
-			CreatedAt:      startTime,
-		})
-
-		// 计费：仅成功时扣费
-		if logStatus == "success" {
-			if promptTokens > 0 || completionTokens > 0 {
-				// LLM 类型：按 token 计费
-				pricing, _ := s.fetchModelPricingFromDify(modelOrPath)
-				delta := calcQuotaDelta(pricing, modelOrPath, promptTokens, completionTokens)
-				deductAccountQuota(userID, delta)
-			} else if isImageOrPerRequestPath(path) {
-				// 图片生成等无 usage 的接口：按请求次数计费，默认单价见 gaia.DefaultImageGenerationPriceUSD
-				pricing, _ := s.fetchModelPricingFromDify(modelOrPath)
-				var delta float64
-				if pricing != nil && pricing.Input > 0 {
-					// 若定价表有配置，input 字段用作每次请求单价
-					delta = pricing.Input
-				} else {
-					delta = gaia.DefaultImageGenerationPriceUSD
+		// 计费：成功时扣费；客户端中途断开时仅按已写回的内容扣费
+		switch logStatus {
+		case "success":
+			// LLM 按 token 类别（输入/缓存/输出/推理）计费；embeddings 按 total_tokens，图片 / 语音按张数、字符、时长计费
+			att.cost = s.chargeEndpoint(caller, providerName, path, modelOrPath, body, respBody, &tokens)
+			if usage.format == usageFormatResponses {
+				if responseID == "" {
+					responseID = responsesObjectID(respBody)
 				}
-				deductAccountQuota(userID, delta)
+				s.recordResponseOwner(att, body, responseID)
+			}
+		case "cancelled":
+			if tokens = att.cancelledUsage(tokens); hasTokens(tokens) {
+				att.cost = s.chargeUsage(caller, providerName, modelOrPath, tokens)
 			}
 		}
+		log := &gaia.ModelProxyLog{
+			UserId:           userID,
+			ProviderName:     providerName,
+			ModelName:        modelOrPath,
+			CredentialId:     creds.CredentialID,
+			FailoverChain:    att.failoverChain(),
+			RequestTokens:    tokens.PromptTokens,
+			ResponseTokens:   tokens.CompletionTokens,
+			CacheReadTokens:  tokens.CacheReadTokens,
+			CacheWriteTokens: tokens.CacheWriteTokens,
+			ReasoningTokens:  tokens.ReasoningTokens,
+			DurationMs:       time.Since(startTime).Milliseconds(),
+			TtfbMs:           elapsedMs(startTime, att.firstByteAt),
+			UpstreamStatus:   att.upstreamStatus,
+			CostUsd:          att.cost,
+			RequestId:        caller.RequestId,
+			Stream:           att.stream,
+			ClientIp:         caller.ClientIP,
+			Status:           logStatus,
+			ErrorMessage:     logError,
+			CreatedAt:        startTime,
+		}
+		global.GVA_DB.Create(log)
+		observeProxyLog(log)
 	}()
 
 	// 写回状态码与响应头（流式由上游 Content-Type 决定）
 	if w, ok := writer.(http.ResponseWriter); ok {
 		for k, v := range resp.Header {
+			if gatewayRateLimitHeaderSet(w.Header(), k) {
+				continue
+			}
 			for _, vv := range v {
 				w.Header().Add(k, vv)
 			}
@@ -1309,42 +1519,46 @@ func (s *ModelProviderService) ProxyRequest(
 		w.WriteHeader(resp.StatusCode)
 	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		reportCredentialStatus(providerName, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		// 上游失败不计费（按次计费接口也不扣）
+		logStatus, logError = "error", fmt.Sprintf("upstream %d", resp.StatusCode)
 		_, _ = io.Copy(writer, resp.Body)
 		return nil
 	}
 
-	// extractUsage 从 OpenAI 格式的 JSON 对象中提取 usage 字段
+	// extractUsage 按提供商原生格式（OpenAI / Anthropic / Gemini）提取 usage
 	extractUsage := func(data []byte) {
-		var obj gaia.ModelUsageResponse
-		if json.Unmarshal(data, &obj) == nil && obj.Usage != nil {
-			if obj.Usage.PromptTokens > 0 {
-				promptTokens = obj.Usage.PromptTokens
-			}
-			if obj.Usage.CompletionTokens > 0 {
-				completionTokens = obj.Usage.CompletionTokens
-			}
-		}
+		usage.feed(data)
+		tokens = usage.tokens
 	}
 
 	// 流式响应：按行扫描，顺带从最后一条含 usage 的 data 行中提取 token 数
 	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		att.stream = true // Gemini streamGenerateContent 等由路径决定流式
 		if flusher, ok := writer.(http.Flusher); ok {
 			scanner := bufio.NewScanner(resp.Body)
 			for scanner.Scan() {
 				line := scanner.Text()
 				if _, err = writer.Write([]byte(line + "\n")); err != nil {
-					logStatus, logError = "error", err.Error()
+					logStatus, logError = att.failureStatus(), err.Error()
 					return err
 				}
 				flusher.Flush()
-				// 解析 SSE data 行中的 usage（stream_options.include_usage=true 时上游会附带）
-				if strings.HasPrefix(line, "data:") && strings.Contains(line, `"usage"`) {
+				// 解析 SSE data 行：累计已写回的输出内容，并提取 usage（OpenAI 在 include_usage 末尾行，Anthropic 在 message_start/message_delta，
+				// Gemini 为 usageMetadata，Responses API 在 response.completed）
+				if strings.HasPrefix(line, "data:") {
 					payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-					extractUsage([]byte(payload))
+					att.trackStreamed(usage.format, []byte(payload))
+					if usage.format == usageFormatResponses && responseID == "" {
+						responseID = responsesObjectID([]byte(payload))
+					}
+					if strings.Contains(payload, `"usage"`) || strings.Contains(payload, `"usageMetadata"`) {
+						extractUsage([]byte(payload))
+					}
 				}
 			}
 			if err = scanner.Err(); err != nil {
-				logStatus, logError = "error", err.Error()
+				logStatus, logError = att.failureStatus(), err.Error()
 				return err
 			}
 			return nil
@@ -1356,15 +1570,48 @@ func (s *ModelProviderService) ProxyRequest(
 	tee := io.TeeReader(resp.Body, &buf)
 	_, err = io.Copy(writer, tee)
 	if err != nil {
-		logStatus, logError = "error", err.Error()
+		logStatus, logError = att.failureStatus(), err.Error()
 	} else {
-		extractUsage(buf.Bytes())
+		respBody = buf.Bytes()
+		extractUsage(respBody)
 	}
 	return err
 }
 
-// GetProxyLogs 分页查询代理日志（model_proxy_log 表）。
-func (s *ModelProviderService) GetProxyLogs(info gaiaRequest.GetProxyLogsReq) (list []map[string]interface{}, total int64, err error) {
+// upstreamRequestURL 按提供商拼接上游请求地址：Azure 区分新版 v1 API 与传统 API（需 api-version），其余为 {base}/{path}。
+func upstreamRequestURL(providerName, base, path string, creds *gaiaResponse.ProviderCredentials) string {
+	if providerName != gaia.ProviderAzure {
+		return base + "/" + path
+	}
+	// Azure OpenAI 有两种 API 格式：
+	// 1. 新版 v1 API（2025年8月后）：/openai/v1/... 不需要 api-version 参数
+	// 2. 传统 API：/openai/deployments/{deployment}/... 需要 api-version 参数
+	// 参考：https://learn.microsoft.com/en-us/azure/ai-foundry/openai/api-version-lifecycle
+	//
+	// 当请求路径以 "v1/" 开头时，使用新版 v1 API，不添加 api-version；Responses API 仅有 v1 版本，统一映射到 v1
+	path = azureResponsesPath(path)
+	if strings.HasPrefix(path, "v1/") {
+		// 新版 v1 API：/openai/v1/chat/completions（不需要 api-version）
+		return base + "/openai/" + path
+	}
+	if strings.HasPrefix(path, "openai/v1/") {
+		// 已经包含完整的 openai/v1 前缀
+		return base + "/" + path
+	}
+	requestURL := base + "/openai/" + path
+	if strings.HasPrefix(path, "openai/") {
+		// 其他 openai 路径（如 openai/deployments/...），可能需要 api-version
+		requestURL = base + "/" + path
+	}
+	// 其他路径添加 openai 前缀并使用传统 API
+	if creds.APIVersion != "" {
+		requestURL += "?api-version=" + creds.APIVersion
+	}
+	return requestURL
+}
+
+// GetProxyLogs 按筛选条件分页查询代理日志（model_proxy_log_extend 表）。
+func (s *ModelProviderService) GetProxyLogs(info gaiaRequest.GetProxyLogsReq) (list []gaia.ModelProxyLog, total int64, err error) {
 	page, pageSize := info.Page, info.PageSize
 	if page < 1 {
 		page = 1
@@ -1372,7 +1619,10 @@ func (s *ModelProviderService) GetProxyLogs(info gaiaRequest.GetProxyLogsReq) (l
 	if pageSize < 1 || pageSize > 100 {
 		pageSize = 20
 	}
-	db := global.GVA_DB.Table("model_proxy_log")
+	db, err := applyProxyLogFilter(global.GVA_DB.Model(&gaia.ModelProxyLog{}), info.ProxyLogFilter)
+	if err != nil {
+		return nil, 0, err
+	}
 	if err = db.Count(&total).Error; err != nil {
 		err = fmt.Errorf("查询日志总数失败：%w", err)
 		return
@@ -1394,21 +1644,11 @@ func (s *ModelProviderService) isProviderEnabled(providerName string) bool {
 	return true
 }
 
-// isImageOrPerRequestPath 判断请求路径是否为按次计费的接口（图片生成、语音合成等无 usage 字段的接口）。
+// isImageOrPerRequestPath 判断请求路径是否为不按 token 计量的接口（图片生成、语音合成、语音识别等无 usage 字段的接口）。
 func isImageOrPerRequestPath(path string) bool {
-	perRequestPaths := []string{
-		"images/generations",
-		"images/edits",
-		"images/variations",
-		"audio/speech",
-		"audio/transcriptions",
-		"audio/translations",
-	}
-	lpath := strings.ToLower(path)
-	for _, p := range perRequestPaths {
-		if strings.Contains(lpath, p) {
-			return true
-		}
+	switch detectEndpointMeter(path) {
+	case meterImages, meterSpeech, meterTranscription:
+		return true
 	}
 	return false
 }
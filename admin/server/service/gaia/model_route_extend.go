@@ -0,0 +1,305 @@
+package gaia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// compiledModelRoute 已解析的模型路由（providers / provider_models 已反序列化，regex 已编译）
+type compiledModelRoute struct {
+	gaia.ModelRoute
+	providers      []string
+	providerModels map[string]string
+	re             *regexp.Regexp
+}
+
+// matches 判断模型名是否命中该路由（exact / alias / prefix 不区分大小写）。
+func (r *compiledModelRoute) matches(model string) bool {
+	switch r.MatchType {
+	case gaia.ModelRouteMatchExact, gaia.ModelRouteMatchAlias:
+		return strings.EqualFold(r.Pattern, model)
+	case gaia.ModelRouteMatchPrefix:
+		return strings.HasPrefix(strings.ToLower(model), strings.ToLower(r.Pattern))
+	case gaia.ModelRouteMatchRegex:
+		return r.re != nil && r.re.MatchString(model)
+	}
+	return false
+}
+
+// upstreamModelFor 返回转发到指定提供商时应使用的上游模型 ID：provider_models > upstream_model > 原请求模型。
+func (r *compiledModelRoute) upstreamModelFor(provider, requested string) string {
+	if m := r.providerModels[provider]; m != "" {
+		return m
+	}
+	if r.UpstreamModel != "" {
+		return r.UpstreamModel
+	}
+	return requested
+}
+
+// upstreamModels 返回该路由可能改写成的全部上游模型（去重），用于校验别名背后的真实模型是否有权使用。
+func (r *compiledModelRoute) upstreamModels(requested string) []string {
+	var models []string
+	add := func(m string) {
+		if m != "" && !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	if len(r.providers) == 0 {
+		// 未限定提供商：按模型解析出的任一提供商都可能未配置 provider_models
+		add(r.upstreamModelFor("", requested))
+	}
+	for _, p := range r.providers {
+		add(r.upstreamModelFor(p, requested))
+	}
+	for _, m := range r.providerModels {
+		add(m)
+	}
+	return models
+}
+
+// compileModelRoute 解析单条路由记录；regex 非法时返回错误。
+func compileModelRoute(record gaia.ModelRoute) (*compiledModelRoute, error) {
+	route := &compiledModelRoute{ModelRoute: record}
+	if record.Providers != "" {
+		if err := json.Unmarshal([]byte(record.Providers), &route.providers); err != nil {
+			return nil, fmt.Errorf("providers 格式错误：%w", err)
+		}
+	}
+	if record.ProviderModels != "" {
+		if err := json.Unmarshal([]byte(record.ProviderModels), &route.providerModels); err != nil {
+			return nil, fmt.Errorf("provider_models 格式错误：%w", err)
+		}
+	}
+	if record.MatchType == gaia.ModelRouteMatchRegex {
+		re, err := regexp.Compile(record.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("正则 %s 非法：%w", record.Pattern, err)
+		}
+		route.re = re
+	}
+	return route, nil
+}
+
+// matchModelRoute 按顺序返回第一条命中的路由（调用方保证已按 priority DESC, id ASC 排序）。
+func matchModelRoute(routes []*compiledModelRoute, model string) *compiledModelRoute {
+	if model == "" {
+		return nil
+	}
+	for _, r := range routes {
+		if r.matches(model) {
+			return r
+		}
+	}
+	return nil
+}
+
+// modelRouteCache 进程内路由缓存：管理端增删改时立即失效，多实例部署下依赖 TTL 收敛
+type modelRouteCache struct {
+	mu       sync.RWMutex
+	routes   []*compiledModelRoute
+	loadedAt time.Time
+}
+
+var globalModelRouteCache = &modelRouteCache{}
+
+// invalidate 清空缓存，下次查询时重新加载。
+func (c *modelRouteCache) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// loadModelRoutes 返回已启用的路由（带缓存）；查询失败时返回旧缓存，保证代理可用。
+func (s *ModelProviderService) loadModelRoutes() []*compiledModelRoute {
+	c := globalModelRouteCache
+	c.mu.RLock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < gaia.ModelRouteCacheTTL {
+		routes := c.routes
+		c.mu.RUnlock()
+		return routes
+	}
+	c.mu.RUnlock()
+
+	var records []gaia.ModelRoute
+	if err := global.GVA_DB.Where("enabled = ?", true).Order("priority DESC, id ASC").Find(&records).Error; err != nil {
+		global.GVA_LOG.Warn("加载模型路由失败", zap.Error(err))
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.routes
+	}
+	routes := make([]*compiledModelRoute, 0, len(records))
+	for _, record := range records {
+		route, err := compileModelRoute(record)
+		if err != nil {
+			global.GVA_LOG.Warn("跳过非法模型路由", zap.Uint("id", record.Id), zap.Error(err))
+			continue
+		}
+		routes = append(routes, route)
+	}
+	c.mu.Lock()
+	c.routes, c.loadedAt = routes, time.Now()
+	c.mu.Unlock()
+	return routes
+}
+
+// lookupModelRoute 查找模型命中的路由，未命中返回 nil（回落到内置名称规则）。
+func (s *ModelProviderService) lookupModelRoute(model string) *compiledModelRoute {
+	return matchModelRoute(s.loadModelRoutes(), model)
+}
+
+// buildModelRoute 校验请求并转换为表记录。
+func buildModelRoute(req gaiaRequest.ModelRouteReq) (*gaia.ModelRoute, error) {
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if req.Pattern == "" {
+		return nil, errors.New("pattern 不能为空")
+	}
+	if req.MatchType == gaia.ModelRouteMatchAlias && strings.TrimSpace(req.UpstreamModel) == "" {
+		return nil, errors.New("别名路由必须指定 upstream_model")
+	}
+	providers := make([]string, 0, len(req.Providers))
+	for _, p := range req.Providers {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if !isSupportedProvider(p) {
+			return nil, fmt.Errorf("不支持的提供商：%s", p)
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("至少需要一个提供商")
+	}
+	providersJSON, _ := json.Marshal(providers)
+	providerModels := ""
+	if len(req.ProviderModels) > 0 {
+		b, _ := json.Marshal(req.ProviderModels)
+		providerModels = string(b)
+	}
+	record := &gaia.ModelRoute{
+		Name:           req.Name,
+		MatchType:      req.MatchType,
+		Pattern:        req.Pattern,
+		Providers:      string(providersJSON),
+		UpstreamModel:  strings.TrimSpace(req.UpstreamModel),
+		ProviderModels: providerModels,
+		Priority:       req.Priority,
+		Enabled:        req.Enabled,
+		Remark:         req.Remark,
+	}
+	// 提前编译，避免保存非法正则
+	if _, err := compileModelRoute(*record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// isSupportedProvider 判断提供商短名是否为内置提供商或已登记的自定义提供商。
+func isSupportedProvider(provider string) bool {
+	return isBuiltinProvider(provider) || lookupCustomProvider(provider) != nil
+}
+
+// isBuiltinProvider 判断提供商短名是否在内置支持列表内。
+func isBuiltinProvider(provider string) bool {
+	for _, p := range gaia.SupportedProviders {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// GetModelRoutes 分页查询模型路由。
+func (s *ModelProviderService) GetModelRoutes(info gaiaRequest.GetModelRoutesReq) (
+	list []gaia.ModelRoute, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.ModelRoute{})
+	if kw := strings.TrimSpace(info.Keyword); kw != "" {
+		db = db.Where("name LIKE ? OR pattern LIKE ?", "%"+kw+"%", "%"+kw+"%")
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询路由总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("priority DESC, id ASC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询路由列表失败：%w", err)
+	}
+	return list, total, nil
+}
+
+// CreateModelRoute 创建模型路由。
+func (s *ModelProviderService) CreateModelRoute(req gaiaRequest.ModelRouteReq) (*gaia.ModelRoute, error) {
+	record, err := buildModelRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = global.GVA_DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	globalModelRouteCache.invalidate()
+	return record, nil
+}
+
+// UpdateModelRoute 更新模型路由。
+func (s *ModelProviderService) UpdateModelRoute(id uint, req gaiaRequest.ModelRouteReq) error {
+	var existing gaia.ModelRoute
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("路由不存在")
+		}
+		return err
+	}
+	record, err := buildModelRoute(req)
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Model(&existing).Updates(map[string]interface{}{
+		"name":            record.Name,
+		"match_type":      record.MatchType,
+		"pattern":         record.Pattern,
+		"providers":       record.Providers,
+		"upstream_model":  record.UpstreamModel,
+		"provider_models": record.ProviderModels,
+		"priority":        record.Priority,
+		"enabled":         record.Enabled,
+		"remark":          record.Remark,
+	}).Error; err != nil {
+		return err
+	}
+	globalModelRouteCache.invalidate()
+	return nil
+}
+
+// DeleteModelRoute 删除模型路由。
+func (s *ModelProviderService) DeleteModelRoute(id uint) error {
+	if err := global.GVA_DB.Delete(&gaia.ModelRoute{}, id).Error; err != nil {
+		return err
+	}
+	globalModelRouteCache.invalidate()
+	return nil
+}
+
+// rewriteBodyModel 将 JSON body 中的 model 字段改写为上游模型 ID；body 非 JSON 时原样返回。
+func rewriteBodyModel(body []byte, model string) []byte {
+	var obj map[string]interface{}
+	if json.Unmarshal(body, &obj) != nil {
+		return body
+	}
+	obj["model"] = model
+	if rewritten, err := json.Marshal(obj); err == nil {
+		return rewritten
+	}
+	return body
+}
@@ -0,0 +1,79 @@
+package gaia
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestMatchModelRoute 测试 exact / alias / prefix / regex 匹配及顺序优先
+func TestMatchModelRoute(t *testing.T) {
+	var routes []*compiledModelRoute
+	for _, record := range []gaia.ModelRoute{
+		{Id: 1, MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast", Providers: `["tongyi"]`, UpstreamModel: "qwen3.5-turbo"},
+		{Id: 2, MatchType: gaia.ModelRouteMatchExact, Pattern: "deepseek-v3", Providers: `["tongyi"]`},
+		{Id: 3, MatchType: gaia.ModelRouteMatchPrefix, Pattern: "claude", Providers: `["aws","anthropic"]`,
+			ProviderModels: `{"aws":"anthropic.claude-sonnet-4-6-v1:0"}`},
+		{Id: 4, MatchType: gaia.ModelRouteMatchRegex, Pattern: `^doubao-.*-pro$`, Providers: `["openai"]`},
+	} {
+		route, err := compileModelRoute(record)
+		if err != nil {
+			t.Fatalf("compileModelRoute(%d) 失败: %v", record.Id, err)
+		}
+		routes = append(routes, route)
+	}
+
+	cases := []struct {
+		model  string
+		wantId uint
+	}{
+		{"FAST", 1},
+		{"deepseek-v3", 2},
+		{"deepseek-v3.1", 0},
+		{"claude-sonnet-4-6", 3},
+		{"doubao-1.5-pro", 4},
+		{"doubao-1.5-lite", 0},
+		{"", 0},
+	}
+	for _, c := range cases {
+		var got uint
+		if r := matchModelRoute(routes, c.model); r != nil {
+			got = r.Id
+		}
+		if got != c.wantId {
+			t.Errorf("matchModelRoute(%q) 期望路由 %d，got: %d", c.model, c.wantId, got)
+		}
+	}
+
+	if m := routes[0].upstreamModelFor("tongyi", "fast"); m != "qwen3.5-turbo" {
+		t.Errorf("别名应改写为 qwen3.5-turbo，got: %s", m)
+	}
+	if m := routes[2].upstreamModelFor("aws", "claude-sonnet-4-6"); m != "anthropic.claude-sonnet-4-6-v1:0" {
+		t.Errorf("aws 应使用 provider_models 中的模型 ID，got: %s", m)
+	}
+	if m := routes[2].upstreamModelFor("anthropic", "claude-sonnet-4-6"); m != "claude-sonnet-4-6" {
+		t.Errorf("未配置上游模型时应保持原模型名，got: %s", m)
+	}
+
+	if _, err := compileModelRoute(gaia.ModelRoute{MatchType: gaia.ModelRouteMatchRegex, Pattern: "(", Providers: `["openai"]`}); err == nil {
+		t.Error("非法正则应返回错误")
+	}
+}
+
+// TestModelRouteUpstreamModels 测试别名可能改写成的全部上游模型（用于访问策略校验与模型列表过滤）
+func TestModelRouteUpstreamModels(t *testing.T) {
+	route, err := compileModelRoute(gaia.ModelRoute{MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast",
+		UpstreamModel: "gpt-4o-mini", Providers: `["openai","azure"]`, ProviderModels: `{"azure":"gpt-4o"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(route.upstreamModels("fast"), ","); got != "gpt-4o-mini,gpt-4o" {
+		t.Errorf("上游模型错误：%s", got)
+	}
+	// 未配置上游模型的别名按原模型名转发
+	route, _ = compileModelRoute(gaia.ModelRoute{MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast"})
+	if got := route.upstreamModels("fast"); len(got) != 1 || got[0] != "fast" {
+		t.Errorf("上游模型错误：%v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// chatCompletionsURL 返回提供商 OpenAI 兼容 chat/completions 的完整地址：
+// 配置了 openai_api_base 时按 base 拼接（Azure 走新版 v1 API），否则使用 DefaultChatCompletionsEndpoints。
+func (s *ModelProviderService) chatCompletionsURL(providerName string, creds *gaiaResponse.ProviderCredentials) string {
+	if creds != nil && strings.TrimSpace(creds.Endpoint) != "" {
+		base := strings.TrimSuffix(strings.TrimSpace(creds.Endpoint), "/")
+		if providerName == gaia.ProviderAzure {
+			return base + "/openai/v1/chat/completions"
+		}
+		return base + "/v1/chat/completions"
+	}
+	return s.getUpstreamEndpoint(providerName)
+}
+
+// proxyMessagesViaOpenAI 将 Anthropic Messages 请求转换为 OpenAI chat/completions，转发到 OpenAI 兼容提供商（qwen / glm / gpt 等），
+// 再把响应（含 tool_calls 与流式事件序列）转换回 Anthropic 格式写给客户端；计费走 calcUsageCost。
+func (s *ModelProviderService) proxyMessagesViaOpenAI(
+	att *proxyAttempt, body []byte, writer io.Writer, creds *gaiaResponse.ProviderCredentials) error {
+	oreq, err := anthropicToOpenAIRequest(body)
+	if err != nil {
+		return err
+	}
+	modelID, streaming := oreq.Model, oreq.Stream
+	if modelID == "" {
+		return fmt.Errorf("messages 请求缺少 model 字段")
+	}
+	clientModel := att.model
+	if clientModel == "" {
+		clientModel = modelID
+	}
+	requestURL := s.chatCompletionsURL(att.provider, creds)
+	if requestURL == "" {
+		return fmt.Errorf("提供商 %s 无可用上游地址", att.provider)
+	}
+	payload, err := json.Marshal(oreq)
+	if err != nil {
+		return fmt.Errorf("构建 chat/completions 请求失败：%w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(att.ctx, http.MethodPost, requestURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if att.provider == gaia.ProviderAzure {
+		httpReq.Header.Set("api-key", creds.APIKey)
+	} else {
+		setUpstreamAuth(httpReq.Header, creds)
+	}
+	if streaming {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	att.stream = streaming
+	startTime := time.Now()
+	resp, err := doUpstream(att, creds, httpReq)
+	if err != nil {
+		if att.clientCancelled() {
+			s.logCancelledAttempt(att, creds, modelID, startTime, gaia.TokenUsage{}, err)
+			return err
+		}
+		if att.allowRetry {
+			return &upstreamRetryableError{Provider: att.provider, Err: err}
+		}
+		s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	att.trackFirstByte(resp)
+
+	w, _ := writer.(http.ResponseWriter)
+	if resp.StatusCode != http.StatusOK {
+		reportCredentialStatus(att.provider, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+		if att.allowRetry && isRetryableStatus(resp.StatusCode) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return &upstreamRetryableError{Provider: att.provider, Status: resp.StatusCode}
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+		}
+		_, _ = writer.Write(openAIErrorToAnthropic(raw))
+		s.logProxyAttempt(att, creds, modelID, "error",
+			fmt.Sprintf("%s %d: %s", att.provider, resp.StatusCode, string(raw)), startTime, gaia.TokenUsage{})
+		return nil
+	}
+
+	var usage gaia.TokenUsage
+	if streaming {
+		if w != nil {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher, _ := writer.(http.Flusher)
+		translator := newOpenAIStreamTranslator(clientModel)
+		writeEvents := func(events []sseEvent) error {
+			for _, ev := range events {
+				if _, e := writer.Write([]byte("event: " + ev.Name + "\ndata: " + string(ev.Data) + "\n\n")); e != nil {
+					return e
+				}
+			}
+			if flusher != nil && len(events) > 0 {
+				flusher.Flush()
+			}
+			return nil
+		}
+		err = readSSEData(resp.Body, func(chunk []byte) error {
+			att.trackStreamed(usageFormatOpenAI, chunk)
+			return writeEvents(translator.translate(chunk))
+		})
+		if err == nil {
+			err = writeEvents(translator.finish())
+		}
+		usage = translator.usage
+		if err != nil {
+			if att.clientCancelled() {
+				s.logCancelledAttempt(att, creds, modelID, startTime, usage, err)
+				return err
+			}
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	} else {
+		raw, e := io.ReadAll(resp.Body)
+		if e != nil {
+			s.logProxyAttempt(att, creds, modelID, att.failureStatus(), e.Error(), startTime, gaia.TokenUsage{})
+			return e
+		}
+		var converted []byte
+		if converted, usage, err = openAIToAnthropicResponse(raw, clientModel); err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, gaia.TokenUsage{})
+			return err
+		}
+		if w != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		}
+		if _, err = writer.Write(converted); err != nil {
+			s.logProxyAttempt(att, creds, modelID, "error", err.Error(), startTime, usage)
+			return err
+		}
+	}
+
+	att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	s.logProxyAttempt(att, creds, modelID, "success", "", startTime, usage)
+	return nil
+}
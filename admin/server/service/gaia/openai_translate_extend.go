@@ -0,0 +1,445 @@
+package gaia
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// Anthropic Messages ⇄ OpenAI Chat Completions 协议转换（/v1/messages 调用非 Claude 模型）。
+// 请求：Anthropic Messages body → OpenAI chat/completions body（system/messages/tool_use/tool_result/images/tools）
+// 响应：OpenAI chat.completion 或 chunk → Anthropic Messages JSON / SSE 事件序列
+
+// anthropicMessagesRequest Anthropic Messages 请求中参与转换的字段（content / system 可能是字符串或 block 数组）
+type anthropicMessagesRequest struct {
+	Model         string            `json:"model"`
+	System        json.RawMessage   `json:"system"`
+	Messages      []anthropicRawMsg `json:"messages"`
+	MaxTokens     int               `json:"max_tokens"`
+	Temperature   *float64          `json:"temperature"`
+	TopP          *float64          `json:"top_p"`
+	StopSequences []string          `json:"stop_sequences"`
+	Stream        bool              `json:"stream"`
+	Tools         []anthropicTool   `json:"tools"`
+	ToolChoice    *struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"tool_choice"`
+	Metadata *struct {
+		UserID string `json:"user_id"`
+	} `json:"metadata"`
+}
+
+type anthropicRawMsg struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// openAIChatRequestOut 转换后发往 OpenAI 兼容上游的请求
+type openAIChatRequestOut struct {
+	Model         string                 `json:"model"`
+	Messages      []openAIChatMsgOut     `json:"messages"`
+	MaxTokens     int                    `json:"max_tokens,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	Stop          []string               `json:"stop,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	StreamOptions map[string]interface{} `json:"stream_options,omitempty"`
+	Tools         []openAITool           `json:"tools,omitempty"`
+	ToolChoice    interface{}            `json:"tool_choice,omitempty"`
+	User          string                 `json:"user,omitempty"`
+}
+
+type openAIChatMsgOut struct {
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"` // string / []map / nil
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// isAnthropicMessagesPath 判断是否为 Anthropic Messages 接口路径（不含 count_tokens 等子路径）。
+func isAnthropicMessagesPath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(strings.ToLower(path), "/"), "v1/messages")
+}
+
+// parseAnthropicBlocks content 为字符串时视为单个 text block。
+func parseAnthropicBlocks(raw json.RawMessage) []anthropicBlock {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []anthropicBlock{{Type: "text", Text: text}}
+	}
+	var blocks []anthropicBlock
+	_ = json.Unmarshal(raw, &blocks)
+	return blocks
+}
+
+// anthropicBlocksText 拼接 blocks 中的文本（tool_result.content 与 system 使用）。
+func anthropicBlocksText(raw json.RawMessage) string {
+	var sb strings.Builder
+	for _, b := range parseAnthropicBlocks(raw) {
+		if b.Type == "text" {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(b.Text)
+		}
+	}
+	return sb.String()
+}
+
+// anthropicImageToOpenAI base64 source → data URL，url source 原样。
+func anthropicImageToOpenAI(img *anthropicImage) string {
+	if img == nil {
+		return ""
+	}
+	if img.Type == "base64" {
+		return "data:" + img.MediaType + ";base64," + img.Data
+	}
+	return img.URL
+}
+
+// anthropicToOpenAIRequest 将 Anthropic Messages 请求体转换为 OpenAI chat/completions 请求。
+func anthropicToOpenAIRequest(body []byte) (*openAIChatRequestOut, error) {
+	var req anthropicMessagesRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("解析 messages 请求失败：%w", err)
+	}
+	out := &openAIChatRequestOut{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.StopSequences,
+		Stream:      req.Stream,
+	}
+	if req.Stream {
+		// 要求上游在流末尾返回 usage，用于 message_delta 与计费
+		out.StreamOptions = map[string]interface{}{"include_usage": true}
+	}
+	if req.Metadata != nil {
+		out.User = req.Metadata.UserID
+	}
+	if system := anthropicBlocksText(req.System); system != "" {
+		out.Messages = append(out.Messages, openAIChatMsgOut{Role: "system", Content: system})
+	}
+
+	for _, m := range req.Messages {
+		blocks := parseAnthropicBlocks(m.Content)
+		if m.Role == "assistant" {
+			msg := openAIChatMsgOut{Role: "assistant"}
+			var text strings.Builder
+			for _, b := range blocks {
+				switch b.Type {
+				case "text":
+					text.WriteString(b.Text)
+				case "tool_use":
+					input := string(b.Input)
+					if input == "" {
+						input = "{}"
+					}
+					tc := openAIToolCall{ID: b.ID, Type: "function"}
+					tc.Function.Name, tc.Function.Arguments = b.Name, input
+					msg.ToolCalls = append(msg.ToolCalls, tc)
+				}
+			}
+			if text.Len() > 0 || len(msg.ToolCalls) == 0 {
+				msg.Content = text.String()
+			}
+			out.Messages = append(out.Messages, msg)
+			continue
+		}
+
+		// user：tool_result 拆为独立的 tool 消息（需紧跟 assistant 的 tool_calls），其余内容合并为一条 user 消息
+		var parts []map[string]interface{}
+		for _, b := range blocks {
+			switch b.Type {
+			case "tool_result":
+				out.Messages = append(out.Messages, openAIChatMsgOut{
+					Role: "tool", ToolCallID: b.ToolUseID, Content: anthropicBlocksText(b.Content),
+				})
+			case "text":
+				parts = append(parts, map[string]interface{}{"type": "text", "text": b.Text})
+			case "image":
+				if url := anthropicImageToOpenAI(b.Source); url != "" {
+					parts = append(parts, map[string]interface{}{"type": "image_url", "image_url": map[string]string{"url": url}})
+				}
+			}
+		}
+		if len(parts) == 1 && parts[0]["type"] == "text" {
+			out.Messages = append(out.Messages, openAIChatMsgOut{Role: "user", Content: parts[0]["text"]})
+		} else if len(parts) > 0 {
+			out.Messages = append(out.Messages, openAIChatMsgOut{Role: "user", Content: parts})
+		}
+	}
+
+	for _, t := range req.Tools {
+		var tool openAITool
+		tool.Type = "function"
+		tool.Function.Name, tool.Function.Description, tool.Function.Parameters = t.Name, t.Description, t.InputSchema
+		out.Tools = append(out.Tools, tool)
+	}
+	if req.ToolChoice != nil && len(out.Tools) > 0 {
+		switch req.ToolChoice.Type {
+		case "auto":
+			out.ToolChoice = "auto"
+		case "any":
+			out.ToolChoice = "required"
+		case "none":
+			out.ToolChoice = "none"
+		case "tool":
+			out.ToolChoice = map[string]interface{}{"type": "function", "function": map[string]string{"name": req.ToolChoice.Name}}
+		}
+	}
+	return out, nil
+}
+
+// openAIFinishToAnthropic 映射 finish_reason → stop_reason。
+func openAIFinishToAnthropic(reason string) string {
+	switch reason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// openAIToolArgs 将 tool_calls.arguments 转为合法 JSON 对象（上游偶有空串或截断）。
+func openAIToolArgs(args string) json.RawMessage {
+	if args = strings.TrimSpace(args); args != "" && json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	return json.RawMessage("{}")
+}
+
+// openAIChatCompletion 非流式 chat.completion 中参与转换的字段
+type openAIChatCompletion struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Message struct {
+			Content          string           `json:"content"`
+			ReasoningContent string           `json:"reasoning_content"`
+			ToolCalls        []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content          string           `json:"content"`
+			ReasoningContent string           `json:"reasoning_content"`
+			ToolCalls        []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *gaia.ModelUsage `json:"usage"`
+}
+
+// anthropicMessageOut Anthropic Messages 响应（非流式与 message_start 共用）
+type anthropicMessageOut struct {
+	ID           string           `json:"id"`
+	Type         string           `json:"type"`
+	Role         string           `json:"role"`
+	Model        string           `json:"model"`
+	Content      []anthropicBlock `json:"content"`
+	StopReason   *string          `json:"stop_reason"`
+	StopSequence *string          `json:"stop_sequence"`
+	Usage        anthropicUsage   `json:"usage"`
+}
+
+// openAIToAnthropicResponse 将非流式 OpenAI chat.completion 转换为 Anthropic Messages 响应，并返回 token 用量。
+func openAIToAnthropicResponse(data []byte, model string) ([]byte, gaia.TokenUsage, error) {
+	var resp openAIChatCompletion
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, gaia.TokenUsage{}, fmt.Errorf("解析 chat.completion 响应失败：%w", err)
+	}
+	out := anthropicMessageOut{ID: resp.ID, Type: "message", Role: "assistant", Model: model, Content: []anthropicBlock{}}
+	stop := "end_turn"
+	if len(resp.Choices) > 0 {
+		c := resp.Choices[0]
+		if c.Message.ReasoningContent != "" {
+			out.Content = append(out.Content, anthropicBlock{Type: "thinking", Thinking: c.Message.ReasoningContent})
+		}
+		if c.Message.Content != "" {
+			out.Content = append(out.Content, anthropicBlock{Type: "text", Text: c.Message.Content})
+		}
+		for _, tc := range c.Message.ToolCalls {
+			out.Content = append(out.Content, anthropicBlock{
+				Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: openAIToolArgs(tc.Function.Arguments),
+			})
+		}
+		stop = openAIFinishToAnthropic(c.FinishReason)
+	}
+	out.StopReason = &stop
+	usage := openAIUsageTokens(resp.Usage)
+	out.Usage = anthropicUsageFrom(usage)
+	b, err := json.Marshal(out)
+	return b, usage, err
+}
+
+// openAIErrorToAnthropic 将 OpenAI 错误体 {"error":{...}} 转为 Anthropic 错误格式；无法识别时原样返回。
+func openAIErrorToAnthropic(data []byte) []byte {
+	var e struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &e) != nil || e.Error.Message == "" {
+		return data
+	}
+	typ := e.Error.Type
+	if typ == "" {
+		typ = "api_error"
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"type": "error", "error": map[string]string{"type": typ, "message": e.Error.Message},
+	})
+	return b
+}
+
+// sseEvent 一条待写回的 SSE 事件
+type sseEvent struct {
+	Name string
+	Data []byte
+}
+
+// openAIStreamTranslator 将 OpenAI chat.completion.chunk 逐个转换为 Anthropic Messages SSE 事件序列：
+// message_start → content_block_start/delta/stop … → message_delta(usage) → message_stop
+type openAIStreamTranslator struct {
+	model      string
+	started    bool
+	finished   bool
+	blockIndex int         // 下一个 content block 的 index
+	openBlock  string      // 当前未关闭的 block 类型（text / thinking / tool_use），空为无
+	toolBlocks map[int]int // OpenAI tool_calls index → Anthropic content block index
+	stopReason string
+	usage      gaia.TokenUsage
+}
+
+func newOpenAIStreamTranslator(model string) *openAIStreamTranslator {
+	return &openAIStreamTranslator{model: model, toolBlocks: map[int]int{}}
+}
+
+func (t *openAIStreamTranslator) event(name string, v interface{}) sseEvent {
+	b, _ := json.Marshal(v)
+	return sseEvent{Name: name, Data: b}
+}
+
+// closeBlock 关闭当前 block。
+func (t *openAIStreamTranslator) closeBlock() []sseEvent {
+	if t.openBlock == "" {
+		return nil
+	}
+	t.openBlock = ""
+	return []sseEvent{t.event("content_block_stop", map[string]interface{}{
+		"type": "content_block_stop", "index": t.blockIndex - 1,
+	})}
+}
+
+// ensureBlock 确保当前打开的是指定类型的 block（text / thinking），否则关闭旧 block 并开启新 block。
+func (t *openAIStreamTranslator) ensureBlock(kind string) []sseEvent {
+	if t.openBlock == kind {
+		return nil
+	}
+	events := t.closeBlock()
+	block := map[string]interface{}{"type": kind}
+	if kind == "text" {
+		block["text"] = ""
+	} else {
+		block["thinking"] = ""
+	}
+	events = append(events, t.event("content_block_start", map[string]interface{}{
+		"type": "content_block_start", "index": t.blockIndex, "content_block": block,
+	}))
+	t.openBlock = kind
+	t.blockIndex++
+	return events
+}
+
+// translate 转换一个 OpenAI chunk JSON，返回需写给客户端的 Anthropic 事件。
+func (t *openAIStreamTranslator) translate(chunk []byte) []sseEvent {
+	var c openAIChatCompletion
+	if json.Unmarshal(chunk, &c) != nil {
+		return nil
+	}
+	var events []sseEvent
+	if !t.started {
+		t.started = true
+		events = append(events, t.event("message_start", map[string]interface{}{
+			"type": "message_start",
+			"message": anthropicMessageOut{
+				ID: c.ID, Type: "message", Role: "assistant", Model: t.model, Content: []anthropicBlock{},
+			},
+		}))
+	}
+	mergeTokenUsage(&t.usage, openAIUsageTokens(c.Usage))
+	if len(c.Choices) == 0 {
+		return events
+	}
+	delta := c.Choices[0].Delta
+	if delta.ReasoningContent != "" {
+		events = append(events, t.ensureBlock("thinking")...)
+		events = append(events, t.event("content_block_delta", map[string]interface{}{
+			"type": "content_block_delta", "index": t.blockIndex - 1,
+			"delta": map[string]string{"type": "thinking_delta", "thinking": delta.ReasoningContent},
+		}))
+	}
+	if delta.Content != "" {
+		events = append(events, t.ensureBlock("text")...)
+		events = append(events, t.event("content_block_delta", map[string]interface{}{
+			"type": "content_block_delta", "index": t.blockIndex - 1,
+			"delta": map[string]string{"type": "text_delta", "text": delta.Content},
+		}))
+	}
+	for i, tc := range delta.ToolCalls {
+		idx := i
+		if tc.Index != nil {
+			idx = *tc.Index
+		}
+		blockIdx, ok := t.toolBlocks[idx]
+		if !ok {
+			events = append(events, t.closeBlock()...)
+			blockIdx = t.blockIndex
+			t.toolBlocks[idx] = blockIdx
+			events = append(events, t.event("content_block_start", map[string]interface{}{
+				"type": "content_block_start", "index": blockIdx,
+				"content_block": map[string]interface{}{
+					"type": "tool_use", "id": tc.ID, "name": tc.Function.Name, "input": map[string]interface{}{},
+				},
+			}))
+			t.openBlock = "tool_use"
+			t.blockIndex++
+		}
+		if tc.Function.Arguments != "" {
+			events = append(events, t.event("content_block_delta", map[string]interface{}{
+				"type": "content_block_delta", "index": blockIdx,
+				"delta": map[string]string{"type": "input_json_delta", "partial_json": tc.Function.Arguments},
+			}))
+		}
+	}
+	if fr := c.Choices[0].FinishReason; fr != "" {
+		t.stopReason = openAIFinishToAnthropic(fr)
+	}
+	return events
+}
+
+// finish 在上游流结束后补齐 content_block_stop / message_delta（含 usage）/ message_stop。
+func (t *openAIStreamTranslator) finish() []sseEvent {
+	if t.finished || !t.started {
+		return nil
+	}
+	t.finished = true
+	events := t.closeBlock()
+	if t.stopReason == "" {
+		t.stopReason = "end_turn"
+	}
+	events = append(events, t.event("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": t.stopReason, "stop_sequence": nil},
+		"usage": anthropicUsageFrom(t.usage),
+	}))
+	events = append(events, t.event("message_stop", map[string]string{"type": "message_stop"}))
+	return events
+}
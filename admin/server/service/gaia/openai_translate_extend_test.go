@@ -0,0 +1,97 @@
+package gaia
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestAnthropicToOpenAIRequest 测试 system / tool_use / tool_result / 图片 / tools 的转换
+func TestAnthropicToOpenAIRequest(t *testing.T) {
+	body := `{
+		"model": "qwen3-coder-plus",
+		"max_tokens": 1024,
+		"stream": true,
+		"system": [{"type": "text", "text": "你是编程助手"}],
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "看图"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "我来读取文件"},
+				{"type": "tool_use", "id": "toolu_1", "name": "read_file", "input": {"path": "main.go"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "package main"}]},
+				{"type": "text", "text": "继续"}
+			]}
+		],
+		"tools": [{"name": "read_file", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "tool", "name": "read_file"}
+	}`
+	req, err := anthropicToOpenAIRequest([]byte(body))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	roles := make([]string, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		roles = append(roles, m.Role)
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,tool,user" {
+		t.Fatalf("消息顺序错误，got: %s", got)
+	}
+	if req.StreamOptions["include_usage"] != true {
+		t.Error("流式请求应注入 stream_options.include_usage")
+	}
+	if tc := req.Messages[2].ToolCalls; len(tc) != 1 || tc[0].ID != "toolu_1" || tc[0].Function.Arguments != `{"path": "main.go"}` {
+		t.Errorf("tool_use 转换错误: %+v", tc)
+	}
+	if m := req.Messages[3]; m.ToolCallID != "toolu_1" || m.Content != "package main" {
+		t.Errorf("tool_result 转换错误: %+v", m)
+	}
+	raw, _ := json.Marshal(req)
+	for _, want := range []string{`"url":"data:image/png;base64,AAAA"`, `"tool_choice":{"function":{"name":"read_file"},"type":"function"}`} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("输出缺少 %s: %s", want, raw)
+		}
+	}
+}
+
+// TestOpenAIStreamTranslator 测试 OpenAI chunk 转为 Anthropic SSE 事件序列
+func TestOpenAIStreamTranslator(t *testing.T) {
+	tr := newOpenAIStreamTranslator("glm-4.6")
+	chunks := []string{
+		`{"id":"c1","choices":[{"delta":{"role":"assistant","content":"好的"}}]}`,
+		`{"id":"c1","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_file","arguments":""}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":1}"}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"id":"c1","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":8}}`,
+	}
+	var names []string
+	var all []string
+	for _, c := range chunks {
+		for _, ev := range tr.translate([]byte(c)) {
+			names = append(names, ev.Name)
+			all = append(all, string(ev.Data))
+		}
+	}
+	for _, ev := range tr.finish() {
+		names = append(names, ev.Name)
+		all = append(all, string(ev.Data))
+	}
+	want := "message_start,content_block_start,content_block_delta,content_block_stop,content_block_start," +
+		"content_block_delta,content_block_stop,message_delta,message_stop"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("事件序列错误\n期望: %s\n实际: %s", want, got)
+	}
+	joined := strings.Join(all, "\n")
+	for _, s := range []string{`"stop_reason":"tool_use"`, `"input_tokens":20`, `"output_tokens":8`, `"partial_json":"{\"path\":1}"`} {
+		if !strings.Contains(joined, s) {
+			t.Errorf("输出缺少 %s", s)
+		}
+	}
+	if len(tr.finish()) != 0 {
+		t.Error("finish 重复调用不应再输出事件")
+	}
+}
@@ -0,0 +1,336 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/gofrs/uuid/v5"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// firstByteReader 包装上游响应体，记录首次读到数据的时间（TTFB）
+type firstByteReader struct {
+	io.ReadCloser
+	at *time.Time
+}
+
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && r.at.IsZero() {
+		*r.at = time.Now()
+	}
+	return n, err
+}
+
+// trackFirstByte 记录上游状态码并包装响应体以统计首字节耗时。
+func (a *proxyAttempt) trackFirstByte(resp *http.Response) {
+	a.upstreamStatus = resp.StatusCode
+	resp.Body = &firstByteReader{ReadCloser: resp.Body, at: &a.firstByteAt}
+}
+
+// elapsedMs 返回 since 至 t 的毫秒数，t 为零值时返回 0。
+func elapsedMs(since, t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Sub(since).Milliseconds()
+}
+
+// proxyLogSummaryKey 汇总统计（不分组）时的分组表达式
+const proxyLogSummaryKey = "''"
+
+// proxyLogGroupColumns 统计分组维度对应的 SQL 表达式
+var proxyLogGroupColumns = map[string]string{
+	"user":     "user_id::text",
+	"model":    "model_name",
+	"provider": "provider_name",
+	"day":      "to_char(created_at, 'YYYY-MM-DD')",
+}
+
+// parseProxyLogTime 解析筛选时间，支持日期、日期时间与 RFC3339；dateOnly 表示只给了日期。
+func parseProxyLogTime(value string) (t time.Time, dateOnly bool, err error) {
+	value = strings.TrimSpace(value)
+	if t, err = time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("时间格式错误：%s", value)
+}
+
+// applyProxyLogFilter 按筛选条件追加查询条件；结束时间只给日期时包含当天。
+func applyProxyLogFilter(db *gorm.DB, f gaiaRequest.ProxyLogFilter) (*gorm.DB, error) {
+	if f.UserId != "" {
+		if _, err := uuid.FromString(f.UserId); err != nil {
+			return nil, fmt.Errorf("账号 ID 格式错误：%s", f.UserId)
+		}
+		db = db.Where("user_id = ?::uuid", f.UserId)
+	}
+	if f.Provider != "" {
+		db = db.Where("provider_name = ?", f.Provider)
+	}
+	if m := strings.TrimSpace(f.Model); m != "" {
+		db = db.Where("model_name LIKE ?", "%"+m+"%")
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if kw := strings.TrimSpace(f.Keyword); kw != "" {
+		db = db.Where("error_message LIKE ?", "%"+kw+"%")
+	}
+	if f.StartTime != "" {
+		start, _, err := parseProxyLogTime(f.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("created_at >= ?", start)
+	}
+	if f.EndTime != "" {
+		end, dateOnly, err := parseProxyLogTime(f.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		if dateOnly {
+			db = db.Where("created_at < ?", end.AddDate(0, 0, 1))
+		} else {
+			db = db.Where("created_at <= ?", end)
+		}
+	}
+	return db, nil
+}
+
+// proxyLogStatsSelect 统计字段：请求数、失败数、缓存命中数、token 合计与耗时分位（仅统计有耗时记录的请求）
+func proxyLogStatsSelect(keyExpr string) string {
+	return keyExpr + ` AS group_key,
+		COUNT(*) AS requests,
+		COUNT(*) FILTER (WHERE status <> 'success') AS errors,
+		COUNT(*) FILTER (WHERE cache_hit) AS cache_hits,
+		COALESCE(SUM(request_tokens), 0) AS prompt_tokens,
+		COALESCE(SUM(response_tokens), 0) AS completion_tokens,
+		COALESCE(SUM(cost_usd), 0) AS cost_usd,
+		COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE duration_ms > 0), 0) AS p50_ms,
+		COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE duration_ms > 0), 0) AS p95_ms`
+}
+
+// proxyLogCostRow 按分组与模型汇总的 token 用量，用于估算花费
+type proxyLogCostRow struct {
+	GroupKey         string `gorm:"column:group_key"`
+	ProviderName     string `gorm:"column:provider_name"`
+	ModelName        string `gorm:"column:model_name"`
+	CacheHit         bool   `gorm:"column:cache_hit"`
+	PromptTokens     int    `gorm:"column:prompt_tokens"`
+	CompletionTokens int    `gorm:"column:completion_tokens"`
+	CacheReadTokens  int    `gorm:"column:cache_read_tokens"`
+	CacheWriteTokens int    `gorm:"column:cache_write_tokens"`
+	ReasoningTokens  int    `gorm:"column:reasoning_tokens"`
+}
+
+// estimateProxyLogCost 对未记录 cost_usd 的历史日志按当前定价估算各分组花费（仅成功请求；命中缓存的按 hit-discount 折算），返回分组值 → USD。
+func (s *ModelProviderService) estimateProxyLogCost(db *gorm.DB, keyExpr string) (map[string]float64, error) {
+	groupBy := "group_key, provider_name, model_name, cache_hit"
+	if keyExpr == proxyLogSummaryKey {
+		groupBy = "provider_name, model_name, cache_hit"
+	}
+	var rows []proxyLogCostRow
+	if err := db.Select(keyExpr+` AS group_key, provider_name, model_name, cache_hit,
+		COALESCE(SUM(request_tokens), 0) AS prompt_tokens,
+		COALESCE(SUM(response_tokens), 0) AS completion_tokens,
+		COALESCE(SUM(cache_read_tokens), 0) AS cache_read_tokens,
+		COALESCE(SUM(cache_write_tokens), 0) AS cache_write_tokens,
+		COALESCE(SUM(reasoning_tokens), 0) AS reasoning_tokens`).
+		Where("status = ? AND cost_usd = 0", "success").
+		Group(groupBy).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	pricingCache := make(map[string]*gaia.ModelPricing)
+	costs := make(map[string]float64)
+	for _, row := range rows {
+		usage := gaia.TokenUsage{
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			CacheReadTokens:  row.CacheReadTokens,
+			CacheWriteTokens: row.CacheWriteTokens,
+			ReasoningTokens:  row.ReasoningTokens,
+		}
+		if !hasTokens(usage) {
+			continue
+		}
+		pricingKey := row.ProviderName + "/" + row.ModelName
+		pricing, ok := pricingCache[pricingKey]
+		if !ok {
+			pricing, _ = s.fetchModelPricingFromDify(row.ProviderName, row.ModelName)
+			pricingCache[pricingKey] = pricing
+		}
+		cost := calcUsageCost(pricing, row.ModelName, usage)
+		if row.CacheHit {
+			cost *= global.GVA_CONFIG.Gaia.Gateway.Cache.HitDiscount
+		}
+		costs[row.GroupKey] += cost
+	}
+	return costs, nil
+}
+
+// GetProxyLogStats 按筛选条件统计代理日志：汇总及按账号 / 模型 / 提供商 / 日期分组的请求数、token、花费与耗时分位。
+func (s *ModelProviderService) GetProxyLogStats(info gaiaRequest.GetProxyLogStatsReq) (stats gaiaResponse.ProxyLogStats, err error) {
+	newQuery := func() (*gorm.DB, error) {
+		return applyProxyLogFilter(global.GVA_DB.Model(&gaia.ModelProxyLog{}), info.ProxyLogFilter)
+	}
+	db, err := newQuery()
+	if err != nil {
+		return stats, err
+	}
+	if err = db.Select(proxyLogStatsSelect(proxyLogSummaryKey)).Scan(&stats.Summary).Error; err != nil {
+		return stats, fmt.Errorf("统计代理日志失败：%w", err)
+	}
+	db, _ = newQuery()
+	summaryCost, err := s.estimateProxyLogCost(db, proxyLogSummaryKey)
+	if err != nil {
+		return stats, fmt.Errorf("统计代理日志花费失败：%w", err)
+	}
+	stats.Summary.CostUSD += summaryCost[""]
+
+	keyExpr, ok := proxyLogGroupColumns[info.GroupBy]
+	if !ok {
+		stats.Groups = []gaiaResponse.ProxyLogStatsItem{}
+		return stats, nil
+	}
+	order := "requests DESC"
+	if info.GroupBy == "day" {
+		order = "group_key ASC"
+	}
+	db, _ = newQuery()
+	if err = db.Select(proxyLogStatsSelect(keyExpr)).Group("group_key").Order(order).
+		Scan(&stats.Groups).Error; err != nil {
+		return stats, fmt.Errorf("分组统计代理日志失败：%w", err)
+	}
+	db, _ = newQuery()
+	groupCost, err := s.estimateProxyLogCost(db, keyExpr)
+	if err != nil {
+		return stats, fmt.Errorf("统计代理日志花费失败：%w", err)
+	}
+	for i := range stats.Groups {
+		stats.Groups[i].CostUSD += groupCost[stats.Groups[i].Key]
+	}
+	return stats, nil
+}
+
+// proxyLogExportHeader 导出列
+var proxyLogExportHeader = []string{
+	"时间", "账号ID", "提供商", "模型", "凭证ID", "状态", "请求token", "响应token",
+	"缓存命中token", "缓存写入token", "推理token", "命中响应缓存", "流式", "耗时(ms)", "首字节(ms)",
+	"上游状态码", "花费(USD)", "请求ID", "客户端IP", "转移链路", "错误信息",
+}
+
+// proxyLogExportRow 将日志转换为导出行
+func proxyLogExportRow(l gaia.ModelProxyLog) []string {
+	return []string{
+		l.CreatedAt.Format(time.DateTime),
+		l.UserId,
+		l.ProviderName,
+		l.ModelName,
+		l.CredentialId,
+		l.Status,
+		strconv.Itoa(l.RequestTokens),
+		strconv.Itoa(l.ResponseTokens),
+		strconv.Itoa(l.CacheReadTokens),
+		strconv.Itoa(l.CacheWriteTokens),
+		strconv.Itoa(l.ReasoningTokens),
+		strconv.FormatBool(l.CacheHit),
+		strconv.FormatBool(l.Stream),
+		strconv.FormatInt(l.DurationMs, 10),
+		strconv.FormatInt(l.TtfbMs, 10),
+		strconv.Itoa(l.UpstreamStatus),
+		strconv.FormatFloat(l.CostUsd, 'f', 6, 64),
+		l.RequestId,
+		l.ClientIp,
+		l.FailoverChain,
+		l.ErrorMessage,
+	}
+}
+
+// ExportProxyLogs 按筛选条件导出代理日志（最多 gaia.ProxyLogExportMaxRows 条，按时间倒序），format 为 csv 或 xlsx。
+func (s *ModelProviderService) ExportProxyLogs(info gaiaRequest.ExportProxyLogsReq) (file *bytes.Buffer, name string, err error) {
+	db, err := applyProxyLogFilter(global.GVA_DB.Model(&gaia.ModelProxyLog{}), info.ProxyLogFilter)
+	if err != nil {
+		return nil, "", err
+	}
+	var logs []gaia.ModelProxyLog
+	if err = db.Order("created_at DESC").Limit(gaia.ProxyLogExportMaxRows).Find(&logs).Error; err != nil {
+		return nil, "", fmt.Errorf("查询代理日志失败：%w", err)
+	}
+	rows := make([][]string, 0, len(logs)+1)
+	rows = append(rows, proxyLogExportHeader)
+	for _, l := range logs {
+		rows = append(rows, proxyLogExportRow(l))
+	}
+
+	name = "proxy_logs_" + time.Now().Format("20060102150405")
+	if info.Format == "csv" {
+		file, err = writeProxyLogCSV(rows)
+		return file, name + ".csv", err
+	}
+	file, err = writeProxyLogXLSX(rows)
+	return file, name + ".xlsx", err
+}
+
+// csvFormulaEscape 单元格以 = + - @ 制表符或回车开头时加 ' 前缀，防止模型名、上游错误信息等在 Excel 中被当作公式执行
+func csvFormulaEscape(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// writeProxyLogCSV 生成带 UTF-8 BOM 的 CSV，确保在 Excel 中正确显示中文
+func writeProxyLogCSV(rows [][]string) (*bytes.Buffer, error) {
+	buf := bytes.NewBuffer([]byte{0xEF, 0xBB, 0xBF})
+	w := csv.NewWriter(buf)
+	escaped := make([][]string, len(rows))
+	for i, row := range rows {
+		escaped[i] = make([]string, len(row))
+		for j, v := range row {
+			escaped[i][j] = csvFormulaEscape(v)
+		}
+	}
+	if err := w.WriteAll(escaped); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeProxyLogXLSX 生成 xlsx
+func writeProxyLogXLSX(rows [][]string) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer func() { _ = f.Close() }()
+	sheet := f.GetSheetName(0)
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = v
+		}
+		if err = f.SetSheetRow(sheet, cell, &values); err != nil {
+			return nil, err
+		}
+	}
+	return f.WriteToBuffer()
+}
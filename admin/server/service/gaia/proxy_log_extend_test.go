@@ -0,0 +1,106 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestParseProxyLogTime 测试筛选时间支持的格式与仅日期标记
+func TestParseProxyLogTime(t *testing.T) {
+	cases := []struct {
+		value    string
+		want     time.Time
+		dateOnly bool
+	}{
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), true},
+		{"2025-03-01 08:30:00", time.Date(2025, 3, 1, 8, 30, 0, 0, time.Local), false},
+		{"2025-03-01T08:30:00Z", time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC), false},
+	}
+	for _, c := range cases {
+		got, dateOnly, err := parseProxyLogTime(c.value)
+		if err != nil || !got.Equal(c.want) || dateOnly != c.dateOnly {
+			t.Errorf("%s：got=%v dateOnly=%v err=%v", c.value, got, dateOnly, err)
+		}
+	}
+	if _, _, err := parseProxyLogTime("03/01/2025"); err == nil {
+		t.Error("非法格式应返回错误")
+	}
+}
+
+// TestWriteProxyLogExport 测试 CSV（带 BOM）与 xlsx 导出内容
+func TestWriteProxyLogExport(t *testing.T) {
+	rows := [][]string{proxyLogExportHeader, {"2025-03-01 08:30:00", "acc-1", "openai", "gpt-5", "", "error", "10", "0"},
+		{"2025-03-01 08:31:00", "acc-1", "openai", `=HYPERLINK("http://evil.example","x")`, "", "error", "@SUM(A1)", "-1+2"}}
+
+	buf, err := writeProxyLogCSV(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0xEF, 0xBB, 0xBF}) {
+		t.Error("CSV 应以 UTF-8 BOM 开头")
+	}
+	reader := csv.NewReader(bytes.NewReader(buf.Bytes()[3:]))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil || len(records) != 3 || records[1][3] != "gpt-5" {
+		t.Fatalf("CSV 内容错误：%v %v", records, err)
+	}
+	// 客户端传入的模型名与上游错误信息以公式字符开头时加 ' 前缀
+	if records[2][3] != `'=HYPERLINK("http://evil.example","x")` || records[2][6] != "'@SUM(A1)" || records[2][7] != "'-1+2" {
+		t.Errorf("CSV 公式注入未转义：%q", records[2])
+	}
+
+	buf, err = writeProxyLogXLSX(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if v, _ := f.GetCellValue(f.GetSheetName(0), "D2"); v != "gpt-5" {
+		t.Errorf("xlsx D2 期望 gpt-5，实际 %q", v)
+	}
+	if v, _ := f.GetCellValue(f.GetSheetName(0), "A1"); v != "时间" {
+		t.Errorf("xlsx 表头错误：%q", v)
+	}
+}
+
+// TestTrackFirstByte 测试上游状态码与首字节时间的记录
+func TestTrackFirstByte(t *testing.T) {
+	att := &proxyAttempt{}
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("data"))}
+	start := time.Now()
+	att.trackFirstByte(resp)
+	if att.upstreamStatus != http.StatusOK || !att.firstByteAt.IsZero() {
+		t.Fatalf("读取前不应记录首字节：%+v", att)
+	}
+	if elapsedMs(start, att.firstByteAt) != 0 {
+		t.Error("未读到数据时 TTFB 应为 0")
+	}
+	_, _ = io.ReadAll(resp.Body)
+	if att.firstByteAt.IsZero() || att.firstByteAt.Before(start) {
+		t.Error("读到数据后应记录首字节时间")
+	}
+}
+
+// TestApplyProxyLogFilterUserId 测试非法账号 ID 直接报错，不拼入 ::uuid 查询
+func TestApplyProxyLogFilterUserId(t *testing.T) {
+	setupTestDB(t)
+	if _, err := applyProxyLogFilter(global.GVA_DB, gaiaRequest.ProxyLogFilter{UserId: "not-a-uuid"}); err == nil {
+		t.Error("非法账号 ID 应返回错误")
+	}
+	if _, err := applyProxyLogFilter(global.GVA_DB, gaiaRequest.ProxyLogFilter{UserId: "6f1c2f3e-6f0a-4a8e-9a59-2f7f3c1d2b4a"}); err != nil {
+		t.Errorf("合法账号 ID 不应报错：%v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package gaia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// QuotaInsufficientError 可用余额（扣除在途预占后）不足以覆盖本次请求的预估花费
+type QuotaInsufficientError struct {
+	Message string
+}
+
+func (e *QuotaInsufficientError) Error() string {
+	return e.Message
+}
+
+// estimateRequestTokens 从请求体估算输入 token（约 4 字节/token）与最大输出 token：
+// 依次取 max_tokens / max_completion_tokens / max_output_tokens / generationConfig.maxOutputTokens，均未设置时按默认值。
+func estimateRequestTokens(body []byte) (prompt, maxOutput int) {
+	prompt = len(body)/4 + 1
+	var obj struct {
+		MaxTokens           int `json:"max_tokens"`
+		MaxCompletionTokens int `json:"max_completion_tokens"`
+		MaxOutputTokens     int `json:"max_output_tokens"`
+		GenerationConfig    struct {
+			MaxOutputTokens int `json:"maxOutputTokens"`
+		} `json:"generationConfig"`
+	}
+	_ = json.Unmarshal(body, &obj)
+	for _, v := range []int{obj.MaxTokens, obj.MaxCompletionTokens, obj.MaxOutputTokens, obj.GenerationConfig.MaxOutputTokens} {
+		if v > 0 {
+			return prompt, v
+		}
+	}
+	return prompt, gaia.QuotaHoldDefaultMaxTokens
+}
+
+// perRequestPrice 无法按张数 / 字符 / 时长计量时的每次请求单价：定价表配置了 input 时用作单价，否则为 gaia.DefaultImageGenerationPriceUSD。
+func (s *ModelProviderService) perRequestPrice(provider, modelName string) float64 {
+	if pricing, _ := s.fetchModelPricingFromDify(provider, modelName); pricing != nil && pricing.Input > 0 {
+		return pricing.Input
+	}
+	return gaia.DefaultImageGenerationPriceUSD
+}
+
+// estimateMaxCost 估算本次请求的最大花费（USD），用于额度预占：上游模型与提供商未定，
+// 按路由可能改写成的全部上游模型、内置定价与登记了该模型的自定义提供商定价中最贵的估算。
+func (s *ModelProviderService) estimateMaxCost(path, modelName string, body []byte) float64 {
+	customProviders := loadCustomProviders()
+	var amount float64
+	for _, model := range s.quotaEstimateModels(modelName) {
+		declared, _ := customProviderCandidates(customProviders, model)
+		for _, provider := range append([]string{""}, declared...) {
+			amount = max(amount, s.estimateProviderCost(path, provider, model, body))
+		}
+	}
+	return amount
+}
+
+// quotaEstimateModels 额度预估使用的模型：命中模型路由时为全部可能的上游模型（别名本身没有定价），否则为请求模型。
+func (s *ModelProviderService) quotaEstimateModels(model string) []string {
+	if route := s.lookupModelRoute(model); route != nil {
+		return route.upstreamModels(model)
+	}
+	return []string{model}
+}
+
+// estimateProviderCost 按提供商 provider 的定价估算本次请求的最大花费（USD）。
+func (s *ModelProviderService) estimateProviderCost(path, provider, modelName string, body []byte) float64 {
+	if isImageOrPerRequestPath(path) {
+		// 图片按张数与尺寸、语音合成按输入字符估算；语音识别仅 WAV 可从请求估算时长，否则按次
+		return s.endpointCost(detectEndpointMeter(path), provider, modelName, parseEndpointRequest(body), nil)
+	}
+	prompt, maxOutput := estimateRequestTokens(body)
+	pricing, _ := s.fetchModelPricingFromDify(provider, modelName)
+	if resolvePricing(pricing, modelName) == nil {
+		return float64(prompt+maxOutput) * gaia.DefaultQuotaFallbackUSDPerToken
+	}
+	return calcUsageCost(pricing, modelName, gaia.TokenUsage{PromptTokens: prompt, CompletionTokens: maxOutput})
+}
+
+// ReserveQuota 请求上游前按最大可能花费预占额度：在事务内锁定账号（及网关 Key）额度行，
+// 校验「总额 - 已用 - 未过期预占」足以覆盖本次预估后写入预占记录，并发请求因此无法合计超支。
+// 成功时 caller.HoldId 指向预占记录，chargeCaller 扣费时一并结算；调用方须在请求结束后调用 ReleaseQuotaHold 兜底释放。
+// 余额不足返回 *QuotaInsufficientError；数据库异常时记录日志并放行，与余额检查缺失账号记录时的行为一致。
+func (s *ModelProviderService) ReserveQuota(caller *gaiaRequest.ProxyCaller, path string, body []byte) error {
+	if isBatchAPIPath(path) {
+		// Files / Batches 请求本身不计费（上传文件体积与花费无关），Batch 完成后由定时任务按实际用量结算
+		return nil
+	}
+	modelName := modelFromRequest(path, body)
+	amount := s.estimateMaxCost(path, modelName, body)
+	now := time.Now()
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var account gaiaResponse.CheckAccountQuotaRow
+		accountLimited := false
+		if e := tx.Raw(`SELECT total_quota, used_quota FROM account_money_extend WHERE account_id = ?::uuid FOR UPDATE`,
+			caller.AccountId).Scan(&account).Error; e != nil {
+			return e
+		}
+		// total_quota = 0（或账号记录不存在）表示不限额
+		if account.TotalQuota > 0 {
+			accountLimited = true
+			if e := tx.Where("account_id = ?::uuid AND expires_at <= ?", caller.AccountId, now).
+				Delete(&gaia.QuotaHold{}).Error; e != nil {
+				return e
+			}
+			held, e := sumQuotaHolds(tx, "account_id = ?::uuid", caller.AccountId, now)
+			if e != nil {
+				return e
+			}
+			if available := account.TotalQuota - account.UsedQuota - held; available < amount {
+				return &QuotaInsufficientError{Message: fmt.Sprintf(
+					"余额不足，可用 %.6f USD（总额 %.6f / 已用 %.6f / 进行中请求预占 %.6f），本次预估 %.6f USD，请联系管理员充值",
+					available, account.TotalQuota, account.UsedQuota, held, amount)}
+			}
+		}
+
+		keyLimited := false
+		if caller.KeyId != 0 {
+			var key gaia.GatewayKey
+			if e := tx.Raw(`SELECT quota_limit, used_quota FROM gateway_key_extend WHERE id = ? FOR UPDATE`,
+				caller.KeyId).Scan(&key).Error; e != nil {
+				return e
+			}
+			if key.QuotaLimit > 0 {
+				keyLimited = true
+				held, e := sumQuotaHolds(tx, "key_id = ?", caller.KeyId, now)
+				if e != nil {
+					return e
+				}
+				if available := key.QuotaLimit - key.UsedQuota - held; available < amount {
+					return &QuotaInsufficientError{Message: fmt.Sprintf(
+						"API Key 预算不足，可用 %.6f USD（上限 %.6f / 已用 %.6f / 进行中请求预占 %.6f），本次预估 %.6f USD",
+						available, key.QuotaLimit, key.UsedQuota, held, amount)}
+				}
+			}
+		}
+
+		if !accountLimited && !keyLimited {
+			return nil
+		}
+		hold := gaia.QuotaHold{
+			AccountId: caller.AccountId,
+			KeyId:     caller.KeyId,
+			Amount:    amount,
+			ModelName: modelName,
+			ExpiresAt: now.Add(gaia.QuotaHoldTTL),
+			CreatedAt: now,
+		}
+		if e := tx.Create(&hold).Error; e != nil {
+			return e
+		}
+		caller.HoldId = hold.Id
+		return nil
+	})
+
+	var insufficient *QuotaInsufficientError
+	if errors.As(err, &insufficient) {
+		return err
+	}
+	if err != nil {
+		global.GVA_LOG.Warn("ReserveQuota 预占额度失败，放行请求",
+			zap.String("account_id", caller.AccountId), zap.Float64("amount", amount), zap.Error(err))
+	}
+	return nil
+}
+
+// sumQuotaHolds 汇总满足条件的未过期预占金额
+func sumQuotaHolds(tx *gorm.DB, where string, arg interface{}, now time.Time) (float64, error) {
+	var held float64
+	err := tx.Model(&gaia.QuotaHold{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where(where+" AND expires_at > ?", arg, now).
+		Scan(&held).Error
+	return held, err
+}
+
+// ReleaseQuotaHold 释放调用方的额度预占（上游失败、无用量等未扣费的情况）；已结算或未预占时为空操作。
+func (s *ModelProviderService) ReleaseQuotaHold(caller gaiaRequest.ProxyCaller) {
+	releaseQuotaHold(caller.HoldId)
+}
+
+func releaseQuotaHold(holdID uint) {
+	if holdID == 0 {
+		return
+	}
+	if err := global.GVA_DB.Delete(&gaia.QuotaHold{}, holdID).Error; err != nil {
+		global.GVA_LOG.Warn("releaseQuotaHold 失败", zap.Uint("hold_id", holdID), zap.Error(err))
+	}
+}
+
+// CheckBalance 校验调用方当前可用余额（总额 - 已用 - 未过期预占）是否仍大于 0，供无法预估单次花费的长连接会话
+// （Realtime WebSocket）在开始时与进行中定期检查；不限额或查询失败时放行。
+func (s *ModelProviderService) CheckBalance(caller gaiaRequest.ProxyCaller) error {
+	now := time.Now()
+	var account gaiaResponse.CheckAccountQuotaRow
+	if err := global.GVA_DB.Raw(`SELECT total_quota, used_quota FROM account_money_extend WHERE account_id = ?::uuid`,
+		caller.AccountId).Scan(&account).Error; err != nil {
+		global.GVA_LOG.Warn("CheckBalance 查询账号额度失败，放行", zap.String("account_id", caller.AccountId), zap.Error(err))
+		return nil
+	}
+	if account.TotalQuota > 0 {
+		held, err := sumQuotaHolds(global.GVA_DB, "account_id = ?::uuid", caller.AccountId, now)
+		if err == nil && account.TotalQuota-account.UsedQuota-held <= 0 {
+			return &QuotaInsufficientError{Message: fmt.Sprintf(
+				"余额不足（总额 %.6f / 已用 %.6f / 进行中请求预占 %.6f USD），请联系管理员充值",
+				account.TotalQuota, account.UsedQuota, held)}
+		}
+	}
+	if caller.KeyId != 0 {
+		var key gaia.GatewayKey
+		if err := global.GVA_DB.Raw(`SELECT quota_limit, used_quota FROM gateway_key_extend WHERE id = ?`,
+			caller.KeyId).Scan(&key).Error; err == nil && key.QuotaLimit > 0 {
+			held, err := sumQuotaHolds(global.GVA_DB, "key_id = ?", caller.KeyId, now)
+			if err == nil && key.QuotaLimit-key.UsedQuota-held <= 0 {
+				return &QuotaInsufficientError{Message: fmt.Sprintf(
+					"API Key 预算不足（上限 %.6f / 已用 %.6f / 进行中请求预占 %.6f USD）", key.QuotaLimit, key.UsedQuota, held)}
+			}
+		}
+	}
+	return nil
+}
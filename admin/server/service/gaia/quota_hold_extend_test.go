@@ -0,0 +1,51 @@
+package gaia
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestEstimateRequestTokens 测试预占额度时对输入/最大输出 token 的估算
+func TestEstimateRequestTokens(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		maxOutput int
+	}{
+		{"openai max_tokens", `{"model":"gpt-4o","max_tokens":512}`, 512},
+		{"openai max_completion_tokens", `{"model":"o3","max_completion_tokens":2048}`, 2048},
+		{"responses max_output_tokens", `{"model":"gpt-5","max_output_tokens":100}`, 100},
+		{"gemini generationConfig", `{"generationConfig":{"maxOutputTokens":300}}`, 300},
+		{"未指定时取默认值", `{"model":"claude-sonnet-4-6"}`, gaia.QuotaHoldDefaultMaxTokens},
+		{"非 JSON 请求体", `--multipart--`, gaia.QuotaHoldDefaultMaxTokens},
+	}
+	for _, c := range cases {
+		prompt, maxOutput := estimateRequestTokens([]byte(c.body))
+		if maxOutput != c.maxOutput {
+			t.Errorf("%s: 最大输出期望 %d，got: %d", c.name, c.maxOutput, maxOutput)
+		}
+		if prompt != len(c.body)/4+1 {
+			t.Errorf("%s: 输入估算错误，got: %d", c.name, prompt)
+		}
+	}
+}
+
+// TestQuotaEstimateModels 测试额度预估按别名路由的全部上游模型估算，未命中路由时为请求模型
+func TestQuotaEstimateModels(t *testing.T) {
+	setupTestDB(t, &gaia.ModelRoute{})
+	globalModelRouteCache.invalidate()
+	t.Cleanup(globalModelRouteCache.invalidate)
+	global.GVA_DB.Create(&gaia.ModelRoute{MatchType: gaia.ModelRouteMatchAlias, Pattern: "fast", UpstreamModel: "claude-sonnet-4-5",
+		Providers: `["anthropic","aws"]`, ProviderModels: `{"aws":"claude-opus-4-7"}`, Enabled: true})
+	s := &ModelProviderService{}
+
+	if got := strings.Join(s.quotaEstimateModels("fast"), ","); got != "claude-sonnet-4-5,claude-opus-4-7" {
+		t.Errorf("别名路由的上游模型错误：%s", got)
+	}
+	if got := s.quotaEstimateModels("gpt-4o"); len(got) != 1 || got[0] != "gpt-4o" {
+		t.Errorf("未命中路由时应为请求模型：%v", got)
+	}
+}
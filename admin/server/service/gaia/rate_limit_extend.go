@@ -0,0 +1,383 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 限流计数类型，与 OpenAI x-ratelimit-*-requests / x-ratelimit-*-tokens 对应
+const (
+	rateLimitRequests = "requests"
+	rateLimitTokens   = "tokens"
+)
+
+// rateLimitScript 滑动窗口计数（当前桶 + 上一桶按剩余比例加权），一次性检查全部计数器，全部未超限才累加：
+// KEYS 为每个计数器的 (当前桶, 上一桶)；ARGV[1] 上一桶权重，ARGV[2] 过期毫秒数，之后每个计数器依次为 (上限, 本次累加值)。
+// 返回 {被拒绝的计数器序号(0 为放行), 各计数器的已用量...}。
+var rateLimitScript = redis.NewScript(`
+local weight = tonumber(ARGV[1])
+local n = #KEYS / 2
+local res = {0}
+for i = 1, n do
+  local curr = tonumber(redis.call('GET', KEYS[2*i-1]) or '0')
+  local prev = tonumber(redis.call('GET', KEYS[2*i]) or '0')
+  res[i+1] = math.floor(prev * weight) + curr
+  if res[1] == 0 and res[i+1] >= tonumber(ARGV[1+2*i]) then
+    res[1] = i
+  end
+end
+if res[1] ~= 0 then
+  return res
+end
+for i = 1, n do
+  local cost = tonumber(ARGV[2+2*i])
+  if cost > 0 then
+    redis.call('INCRBY', KEYS[2*i-1], cost)
+    redis.call('PEXPIRE', KEYS[2*i-1], ARGV[2])
+    res[i+1] = res[i+1] + cost
+  end
+end
+return res
+`)
+
+// compiledRateLimitRule 已解析 models 的限流规则
+type compiledRateLimitRule struct {
+	gaia.RateLimitRule
+	models []string
+}
+
+// subjectFor 返回调用方在该规则下的计数主体（账号 ID / 角色 ID），规则不适用时返回空。
+// models 为请求模型及其路由可能改写成的上游模型，任一命中规则的 models 即适用。
+func (r *compiledRateLimitRule) subjectFor(caller gaiaRequest.ProxyCaller, models []string) string {
+	if len(r.models) > 0 && !slices.ContainsFunc(models, func(m string) bool { return m != "" && matchModelPattern(r.models, m) }) {
+		return ""
+	}
+	var subject string
+	switch r.Scope {
+	case gaia.RateLimitScopeAccount:
+		subject = caller.AccountId
+	case gaia.RateLimitScopeAuthority:
+		if caller.AuthorityId != 0 {
+			subject = strconv.FormatUint(uint64(caller.AuthorityId), 10)
+		}
+	}
+	if subject == "" || (r.Subject != "" && r.Subject != subject) {
+		return ""
+	}
+	return subject
+}
+
+// rateLimitCounter 一次检查中的单个计数器
+type rateLimitCounter struct {
+	kind  string
+	base  string // 计数 key 前缀（不含时间桶）
+	limit int
+	cost  int
+}
+
+// rateLimitBuckets 返回计数 key 当前桶与上一桶的完整 key、上一桶在滑动窗口内的剩余权重，以及当前桶结束前的剩余时间。
+func rateLimitBuckets(base string, now time.Time) (curr, prev string, weight float64, reset time.Duration) {
+	window := int64(gaia.RateLimitWindow)
+	bucket := now.UnixNano() / window
+	elapsed := time.Duration(now.UnixNano() - bucket*window)
+	weight = 1 - float64(elapsed)/float64(gaia.RateLimitWindow)
+	reset = gaia.RateLimitWindow - elapsed
+	return base + ":" + strconv.FormatInt(bucket, 10), base + ":" + strconv.FormatInt(bucket-1, 10), weight, reset
+}
+
+// rateLimitCache 进程内限流规则缓存：管理端增删改时立即失效，多实例部署下依赖 TTL 收敛
+type rateLimitCache struct {
+	mu       sync.RWMutex
+	rules    []*compiledRateLimitRule
+	loadedAt time.Time
+}
+
+var globalRateLimitCache = &rateLimitCache{}
+
+// invalidate 清空缓存，下次查询时重新加载。
+func (c *rateLimitCache) invalidate() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// loadRateLimitRules 返回已启用的限流规则（带缓存）；查询失败时返回旧缓存。
+func (s *ModelProviderService) loadRateLimitRules() []*compiledRateLimitRule {
+	c := globalRateLimitCache
+	c.mu.RLock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < gaia.RateLimitRuleCacheTTL {
+		rules := c.rules
+		c.mu.RUnlock()
+		return rules
+	}
+	c.mu.RUnlock()
+
+	var records []gaia.RateLimitRule
+	if err := global.GVA_DB.Where("enabled = ?", true).Order("id ASC").Find(&records).Error; err != nil {
+		global.GVA_LOG.Warn("加载限流规则失败", zap.Error(err))
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.rules
+	}
+	rules := make([]*compiledRateLimitRule, 0, len(records))
+	for _, record := range records {
+		rule := &compiledRateLimitRule{RateLimitRule: record}
+		if record.Models != "" {
+			_ = json.Unmarshal([]byte(record.Models), &rule.models)
+		}
+		rules = append(rules, rule)
+	}
+	c.mu.Lock()
+	c.rules, c.loadedAt = rules, time.Now()
+	c.mu.Unlock()
+	return rules
+}
+
+// accountAuthorityID 查询账号在 sys_users 中的角色 ID，未找到返回 0。
+func accountAuthorityID(accountID string) uint {
+	var authorityID uint
+	if err := global.GVA_DB.Table("sys_users").Select("authority_id").
+		Where("uuid = ? AND deleted_at IS NULL", accountID).Limit(1).Scan(&authorityID).Error; err != nil {
+		global.GVA_LOG.Warn("查询账号角色失败", zap.String("account_id", accountID), zap.Error(err))
+	}
+	return authorityID
+}
+
+// CheckRateLimit 按账号 / 角色 / 模型的 RPM、TPM 规则做滑动窗口限流：RPM 在放行时计数，TPM 以已用 token 判断，
+// 实际 token 在响应结束后由 recordRateLimitTokens 累加（命中的 TPM 计数器写入 caller.TPMCounters）。
+// 带模型过滤的规则同时按路由改写后的上游模型匹配（别名背后的真实模型同样受限）。未启用 Redis 或 Redis 异常时放行。
+func (s *ModelProviderService) CheckRateLimit(caller *gaiaRequest.ProxyCaller, model string) *gaiaResponse.RateLimitResult {
+	result := &gaiaResponse.RateLimitResult{Allowed: true}
+	if global.GVA_REDIS == nil {
+		return result
+	}
+	rules := s.loadRateLimitRules()
+	if len(rules) == 0 {
+		return result
+	}
+	if caller.AuthorityId == 0 {
+		for _, r := range rules {
+			if r.Scope == gaia.RateLimitScopeAuthority {
+				caller.AuthorityId = accountAuthorityID(caller.AccountId)
+				break
+			}
+		}
+	}
+
+	models := []string{model}
+	if route := s.lookupModelRoute(model); route != nil {
+		models = append(models, route.upstreamModels(model)...)
+	}
+	var counters []rateLimitCounter
+	for _, r := range rules {
+		subject := r.subjectFor(*caller, models)
+		if subject == "" {
+			continue
+		}
+		base := fmt.Sprintf("%s%d:%s:", gaia.RedisKeyGaiaRateLimitPrefix, r.Id, subject)
+		if r.Rpm > 0 {
+			counters = append(counters, rateLimitCounter{kind: rateLimitRequests, base: base + "rpm", limit: r.Rpm, cost: 1})
+		}
+		if r.Tpm > 0 {
+			counters = append(counters, rateLimitCounter{kind: rateLimitTokens, base: base + "tpm", limit: r.Tpm})
+		}
+	}
+	if len(counters) == 0 {
+		return result
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(counters)*2)
+	args := []interface{}{0.0, (2 * gaia.RateLimitWindow).Milliseconds()}
+	var reset time.Duration
+	for _, c := range counters {
+		curr, prev, weight, r := rateLimitBuckets(c.base, now)
+		keys = append(keys, curr, prev)
+		args[0], reset = weight, r
+		args = append(args, c.limit, c.cost)
+	}
+	res, err := rateLimitScript.Run(context.Background(), global.GVA_REDIS, keys, args...).Int64Slice()
+	if err != nil || len(res) != len(counters)+1 {
+		global.GVA_LOG.Warn("网关限流检查失败，放行请求", zap.String("account_id", caller.AccountId), zap.Error(err))
+		return result
+	}
+
+	result.Headers = rateLimitHeaders(counters, res[1:], reset)
+	if idx := res[0]; idx > 0 {
+		c := counters[idx-1]
+		result.Allowed = false
+		result.Type = c.kind
+		result.RetryAfter = int(math.Ceil(reset.Seconds()))
+		result.Message = fmt.Sprintf("触发限流（%s）：每分钟上限 %d，已用 %d，请 %d 秒后重试",
+			c.kind, c.limit, res[idx], result.RetryAfter)
+		return result
+	}
+	for _, c := range counters {
+		if c.kind == rateLimitTokens {
+			caller.TPMCounters = append(caller.TPMCounters, c.base)
+		}
+	}
+	return result
+}
+
+// rateLimitHeaders 生成 OpenAI 风格的 x-ratelimit-* 响应头，requests / tokens 各取剩余最少的计数器。
+func rateLimitHeaders(counters []rateLimitCounter, used []int64, reset time.Duration) map[string]string {
+	headers := map[string]string{}
+	remaining := map[string]int64{}
+	for i, c := range counters {
+		left := max(int64(c.limit)-used[i], 0)
+		if old, ok := remaining[c.kind]; ok && old <= left {
+			continue
+		}
+		remaining[c.kind] = left
+		headers["x-ratelimit-limit-"+c.kind] = strconv.Itoa(c.limit)
+		headers["x-ratelimit-remaining-"+c.kind] = strconv.FormatInt(left, 10)
+		headers["x-ratelimit-reset-"+c.kind] = fmt.Sprintf("%ds", int(math.Ceil(reset.Seconds())))
+	}
+	return headers
+}
+
+// gatewayRateLimitHeaderSet 网关已写入同名 x-ratelimit-* 头时不再透传上游的值（上游额度属于共享凭证，不应暴露给调用方）。
+func gatewayRateLimitHeaderSet(dst http.Header, key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), "x-ratelimit-") && dst.Get(key) != ""
+}
+
+// recordRateLimitTokens 将本次实际消耗的 token 计入命中的 TPM 计数器。
+func recordRateLimitTokens(caller gaiaRequest.ProxyCaller, tokens int) {
+	if global.GVA_REDIS == nil || len(caller.TPMCounters) == 0 || tokens <= 0 {
+		return
+	}
+	ctx := context.Background()
+	now := time.Now()
+	pipe := global.GVA_REDIS.TxPipeline()
+	for _, base := range caller.TPMCounters {
+		curr, _, _, _ := rateLimitBuckets(base, now)
+		pipe.IncrBy(ctx, curr, int64(tokens))
+		pipe.PExpire(ctx, curr, 2*gaia.RateLimitWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		global.GVA_LOG.Warn("记录 TPM 用量失败", zap.String("account_id", caller.AccountId), zap.Error(err))
+	}
+}
+
+// buildRateLimitRule 校验请求并转换为表记录。
+func buildRateLimitRule(req gaiaRequest.RateLimitRuleReq) (*gaia.RateLimitRule, error) {
+	if req.Rpm <= 0 && req.Tpm <= 0 {
+		return nil, errors.New("rpm 与 tpm 至少设置一项")
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Scope == gaia.RateLimitScopeAuthority && req.Subject != "" {
+		if _, err := strconv.ParseUint(req.Subject, 10, 64); err != nil {
+			return nil, fmt.Errorf("角色 ID 非法：%s", req.Subject)
+		}
+	}
+	models := make([]string, 0, len(req.Models))
+	for _, m := range req.Models {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	modelsJSON := ""
+	if len(models) > 0 {
+		b, _ := json.Marshal(models)
+		modelsJSON = string(b)
+	}
+	return &gaia.RateLimitRule{
+		Name:    req.Name,
+		Scope:   req.Scope,
+		Subject: req.Subject,
+		Models:  modelsJSON,
+		Rpm:     req.Rpm,
+		Tpm:     req.Tpm,
+		Enabled: req.Enabled,
+		Remark:  req.Remark,
+	}, nil
+}
+
+// GetRateLimitRules 分页查询限流规则。
+func (s *ModelProviderService) GetRateLimitRules(info gaiaRequest.GetRateLimitRulesReq) (
+	list []gaia.RateLimitRule, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.RateLimitRule{})
+	if info.Scope != "" {
+		db = db.Where("scope = ?", info.Scope)
+	}
+	if kw := strings.TrimSpace(info.Keyword); kw != "" {
+		db = db.Where("name LIKE ? OR subject LIKE ?", "%"+kw+"%", "%"+kw+"%")
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询限流规则总数失败：%w", err)
+	}
+	offset := (info.Page - 1) * info.PageSize
+	if err = db.Order("id ASC").Limit(info.PageSize).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询限流规则列表失败：%w", err)
+	}
+	return list, total, nil
+}
+
+// CreateRateLimitRule 创建限流规则。
+func (s *ModelProviderService) CreateRateLimitRule(req gaiaRequest.RateLimitRuleReq) (*gaia.RateLimitRule, error) {
+	record, err := buildRateLimitRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = global.GVA_DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	globalRateLimitCache.invalidate()
+	return record, nil
+}
+
+// UpdateRateLimitRule 更新限流规则。
+func (s *ModelProviderService) UpdateRateLimitRule(id uint, req gaiaRequest.RateLimitRuleReq) error {
+	var existing gaia.RateLimitRule
+	if err := global.GVA_DB.First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("限流规则不存在")
+		}
+		return err
+	}
+	record, err := buildRateLimitRule(req)
+	if err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Model(&existing).Updates(map[string]interface{}{
+		"name":    record.Name,
+		"scope":   record.Scope,
+		"subject": record.Subject,
+		"models":  record.Models,
+		"rpm":     record.Rpm,
+		"tpm":     record.Tpm,
+		"enabled": record.Enabled,
+		"remark":  record.Remark,
+	}).Error; err != nil {
+		return err
+	}
+	globalRateLimitCache.invalidate()
+	return nil
+}
+
+// DeleteRateLimitRule 删除限流规则。
+func (s *ModelProviderService) DeleteRateLimitRule(id uint) error {
+	if err := global.GVA_DB.Delete(&gaia.RateLimitRule{}, id).Error; err != nil {
+		return err
+	}
+	globalRateLimitCache.invalidate()
+	return nil
+}
@@ -0,0 +1,126 @@
+package gaia
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB 以内存 SQLite 替换 global.GVA_DB 并迁移给定的表，测试结束后恢复
+func setupTestDB(t *testing.T, models ...interface{}) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	old := global.GVA_DB
+	global.GVA_DB = db
+	t.Cleanup(func() { global.GVA_DB = old })
+}
+
+// TestRateLimitBuckets 测试滑动窗口的时间桶、上一桶权重与重置时间
+func TestRateLimitBuckets(t *testing.T) {
+	now := time.Unix(600+15, 0) // 第 10 个一分钟桶内第 15 秒
+	curr, prev, weight, reset := rateLimitBuckets("gaia:ratelimit:1:a:rpm", now)
+	if curr != "gaia:ratelimit:1:a:rpm:10" || prev != "gaia:ratelimit:1:a:rpm:9" {
+		t.Errorf("桶 key 错误：%s / %s", curr, prev)
+	}
+	if weight != 0.75 || reset != 45*time.Second {
+		t.Errorf("权重或重置时间错误：%v / %v", weight, reset)
+	}
+}
+
+// TestRateLimitRuleSubject 测试规则按范围、subject 与模型匹配调用方
+func TestRateLimitRuleSubject(t *testing.T) {
+	caller := gaiaRequest.ProxyCaller{AccountId: "acc-1", AuthorityId: 888}
+	cases := []struct {
+		name   string
+		rule   gaia.RateLimitRule
+		models []string
+		want   string
+	}{
+		{"账号默认规则", gaia.RateLimitRule{Scope: gaia.RateLimitScopeAccount}, nil, "acc-1"},
+		{"指定其他账号", gaia.RateLimitRule{Scope: gaia.RateLimitScopeAccount, Subject: "acc-2"}, nil, ""},
+		{"角色规则", gaia.RateLimitRule{Scope: gaia.RateLimitScopeAuthority, Subject: "888"}, nil, "888"},
+		{"模型命中", gaia.RateLimitRule{Scope: gaia.RateLimitScopeAccount}, []string{"gpt-5*"}, "acc-1"},
+		{"模型不命中", gaia.RateLimitRule{Scope: gaia.RateLimitScopeAccount}, []string{"claude-*"}, ""},
+	}
+	for _, c := range cases {
+		rule := &compiledRateLimitRule{RateLimitRule: c.rule, models: c.models}
+		if got := rule.subjectFor(caller, []string{"gpt-5-mini"}); got != c.want {
+			t.Errorf("%s: 期望 %q，got: %q", c.name, c.want, got)
+		}
+	}
+}
+
+// TestRateLimitRuleSubjectRouted 测试带模型过滤的规则按路由改写后的上游模型匹配，模型未知时不适用
+func TestRateLimitRuleSubjectRouted(t *testing.T) {
+	caller := gaiaRequest.ProxyCaller{AccountId: "acc-1"}
+	rule := &compiledRateLimitRule{RateLimitRule: gaia.RateLimitRule{Scope: gaia.RateLimitScopeAccount}, models: []string{"claude-opus*"}}
+	if got := rule.subjectFor(caller, []string{"fast", "claude-opus-4-7"}); got != "acc-1" {
+		t.Errorf("别名改写到的上游模型命中规则时应适用，got: %q", got)
+	}
+	if got := rule.subjectFor(caller, []string{"fast", "claude-haiku-4-5"}); got != "" {
+		t.Errorf("上游模型不命中时不应适用，got: %q", got)
+	}
+	if got := rule.subjectFor(caller, []string{""}); got != "" {
+		t.Errorf("模型未知时带模型过滤的规则不应适用，got: %q", got)
+	}
+}
+
+// TestRateLimitHeaders 测试 x-ratelimit-* 头取剩余最少的计数器
+func TestRateLimitHeaders(t *testing.T) {
+	counters := []rateLimitCounter{
+		{kind: rateLimitRequests, limit: 60},
+		{kind: rateLimitRequests, limit: 10},
+		{kind: rateLimitTokens, limit: 1000},
+	}
+	h := rateLimitHeaders(counters, []int64{5, 9, 1200}, 30*time.Second)
+	if h["x-ratelimit-limit-requests"] != "10" || h["x-ratelimit-remaining-requests"] != "1" {
+		t.Errorf("requests 头错误：%v", h)
+	}
+	if h["x-ratelimit-remaining-tokens"] != "0" || h["x-ratelimit-reset-tokens"] != "30s" {
+		t.Errorf("tokens 头错误：%v", h)
+	}
+}
+
+// TestBuildRateLimitRule 测试限流规则校验
+func TestBuildRateLimitRule(t *testing.T) {
+	if _, err := buildRateLimitRule(gaiaRequest.RateLimitRuleReq{Scope: gaia.RateLimitScopeAccount}); err == nil {
+		t.Error("rpm/tpm 均为 0 应报错")
+	}
+	if _, err := buildRateLimitRule(gaiaRequest.RateLimitRuleReq{Scope: gaia.RateLimitScopeAuthority, Subject: "admin", Rpm: 10}); err == nil {
+		t.Error("非数字角色 ID 应报错")
+	}
+	rule, err := buildRateLimitRule(gaiaRequest.RateLimitRuleReq{
+		Scope: gaia.RateLimitScopeAccount, Models: []string{" gpt-5* ", ""}, Tpm: 100000})
+	if err != nil || rule.Models != `["gpt-5*"]` {
+		t.Errorf("规则构建错误：%v %+v", err, rule)
+	}
+}
+
+// TestCreateRateLimitRuleDisabled 测试创建时 enabled=false 原样落库（不被列默认值覆盖为启用）
+func TestCreateRateLimitRuleDisabled(t *testing.T) {
+	setupTestDB(t, &gaia.RateLimitRule{})
+	rule, err := (&ModelProviderService{}).CreateRateLimitRule(gaiaRequest.RateLimitRuleReq{
+		Name: "暂不启用", Scope: gaia.RateLimitScopeAccount, Rpm: 10, Enabled: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved gaia.RateLimitRule
+	if err = global.GVA_DB.First(&saved, rule.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Enabled {
+		t.Errorf("禁用的限流规则被保存为启用")
+	}
+}
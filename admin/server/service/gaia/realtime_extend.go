@@ -0,0 +1,528 @@
+package gaia
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// isRealtimePath 判断是否为 Realtime WebSocket 接口（v1/realtime，Azure 客户端也可能使用 openai/realtime）。
+func isRealtimePath(path string) bool {
+	switch strings.Trim(path, "/") {
+	case "v1/realtime", "openai/realtime", "openai/v1/realtime":
+		return true
+	}
+	return false
+}
+
+// isRealtimeProvider 判断提供商是否支持 Realtime WebSocket（OpenAI 与 Azure OpenAI）。
+func isRealtimeProvider(provider string) bool {
+	return provider == gaia.ProviderOpenai || provider == gaia.ProviderAzure
+}
+
+// realtimeModel 取 Realtime 请求的模型名：OpenAI 为 query model，Azure 客户端可能只传 deployment。
+func realtimeModel(query url.Values) string {
+	if m := strings.TrimSpace(query.Get("model")); m != "" {
+		return m
+	}
+	return strings.TrimSpace(query.Get("deployment"))
+}
+
+// realtimeUpstreamURL 构建上游 WebSocket 地址：OpenAI 为 {base}/v1/realtime?model=，
+// Azure 为 {base}/openai/realtime?api-version=&deployment=（api-version 取客户端 query，未指定时用 gaia.AzureRealtimeAPIVersion）。
+func realtimeUpstreamURL(providerName, base, model string, query url.Values) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("提供商 %s 上游地址非法：%s", providerName, base)
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	q := url.Values{}
+	if providerName == gaia.ProviderAzure {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/openai/realtime"
+		version := query.Get("api-version")
+		if version == "" {
+			version = gaia.AzureRealtimeAPIVersion
+		}
+		q.Set("api-version", version)
+		q.Set("deployment", model)
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/realtime"
+		q.Set("model", model)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// realtimeUsage Realtime response.done 事件中的 usage（文本与音频 token 分别统计）
+type realtimeUsage struct {
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	InputTokenDetails struct {
+		CachedTokens        int `json:"cached_tokens"`
+		AudioTokens         int `json:"audio_tokens"`
+		CachedTokensDetails struct {
+			AudioTokens int `json:"audio_tokens"`
+		} `json:"cached_tokens_details"`
+	} `json:"input_token_details"`
+	OutputTokenDetails struct {
+		AudioTokens int `json:"audio_tokens"`
+	} `json:"output_token_details"`
+}
+
+// realtimeServerEvent 网关关心的 Realtime 服务端事件：session.created / session.updated 取实际模型，response.done 取 usage
+type realtimeServerEvent struct {
+	Type    string `json:"type"`
+	Session *struct {
+		Model string `json:"model"`
+	} `json:"session"`
+	Response *struct {
+		Status string         `json:"status"`
+		Usage  *realtimeUsage `json:"usage"`
+	} `json:"response"`
+}
+
+// parseRealtimeEvent 解析上游文本帧；音频增量等无关事件（不含关心的事件类型）直接跳过，避免反序列化大段 base64。
+func parseRealtimeEvent(data []byte) (model string, usage gaia.TokenUsage, ok bool) {
+	if !bytes.Contains(data, []byte(`"response.done"`)) && !bytes.Contains(data, []byte(`"session.`)) {
+		return "", usage, false
+	}
+	var ev realtimeServerEvent
+	if json.Unmarshal(data, &ev) != nil {
+		return "", usage, false
+	}
+	switch ev.Type {
+	case "session.created", "session.updated":
+		if ev.Session != nil {
+			model = ev.Session.Model
+		}
+	case "response.done":
+		if ev.Response != nil && ev.Response.Usage != nil {
+			u := ev.Response.Usage
+			usage = gaia.TokenUsage{
+				PromptTokens:      u.InputTokens,
+				CompletionTokens:  u.OutputTokens,
+				CacheReadTokens:   u.InputTokenDetails.CachedTokens,
+				AudioInputTokens:  max(u.InputTokenDetails.AudioTokens-u.InputTokenDetails.CachedTokensDetails.AudioTokens, 0),
+				AudioOutputTokens: u.OutputTokenDetails.AudioTokens,
+			}
+			return "", usage, true
+		}
+	}
+	return model, usage, model != ""
+}
+
+// realtimeErrorEvent 构建写给客户端的 Realtime error 事件（与 OpenAI 服务端 error 事件格式一致）。
+func realtimeErrorEvent(code, message string) []byte {
+	b, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    "invalid_request_error",
+			"code":    code,
+			"message": message,
+		},
+	})
+	return b
+}
+
+// realtimeCloseFrame 将一端的关闭原因转换为转发给另一端的 close 帧（1005 / 1006 等不可发送的保留码按正常关闭 / 离开处理）。
+func realtimeCloseFrame(err error) []byte {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		switch ce.Code {
+		case websocket.CloseNoStatusReceived:
+			return websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		case websocket.CloseAbnormalClosure, websocket.CloseTLSHandshake:
+			return websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+		}
+		return websocket.FormatCloseMessage(ce.Code, ce.Text)
+	}
+	return websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+}
+
+// isNormalClose 判断是否为正常关闭（1000 / 1001 / 未带状态码）。
+func isNormalClose(err error) bool {
+	return websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived)
+}
+
+// realtimeEnd 会话结束原因：fromClient 表示由客户端一侧读写失败 / 关闭引起
+type realtimeEnd struct {
+	fromClient bool
+	err        error
+	status     string // 非空时直接作为日志状态（余额耗尽、超时）
+}
+
+// realtimeSession 一次 Realtime 会话：upstream → client 方向的 goroutine 独占计费字段，client 写入由 clientMu 串行化
+type realtimeSession struct {
+	s        *ModelProviderService
+	att      *proxyAttempt
+	client   *websocket.Conn
+	upstream *websocket.Conn
+	clientMu sync.Mutex
+
+	billingModel string             // 计费模型：session 事件中的实际模型优先，其次上游模型 ID
+	usage        gaia.TokenUsage    // 会话累计用量
+	dlpRules     []*compiledDLPRule // 对本会话生效的 DLP 规则，客户端帧转发前检查
+}
+
+func (rs *realtimeSession) writeClient(messageType int, data []byte) error {
+	rs.clientMu.Lock()
+	defer rs.clientMu.Unlock()
+	return rs.client.WriteMessage(messageType, data)
+}
+
+// charge 按单次 response.done 的 usage 扣费并累计，扣费后检查余额，耗尽时返回 *QuotaInsufficientError。
+func (rs *realtimeSession) charge(usage gaia.TokenUsage) error {
+	rs.att.cost += rs.s.chargeUsage(rs.att.caller, rs.att.provider, rs.billingModel, usage)
+	addTokenUsage(&rs.usage, usage)
+	return rs.s.CheckBalance(rs.att.caller)
+}
+
+// pumpClient 客户端 → 上游：按 DLP 规则检查后转发帧，被拒绝的帧不转发，向客户端回写 error 事件后会话继续。
+func (rs *realtimeSession) pumpClient(done chan<- realtimeEnd) {
+	for {
+		mt, data, err := rs.client.ReadMessage()
+		if err != nil {
+			done <- realtimeEnd{fromClient: true, err: err}
+			return
+		}
+		if data, err = rs.applyPromptPolicy(mt, data); err != nil {
+			_ = rs.writeClient(websocket.TextMessage, realtimeErrorEvent("content_policy_violation", err.Error()))
+			continue
+		}
+		if err = rs.upstream.WriteMessage(mt, data); err != nil {
+			done <- realtimeEnd{err: err}
+			return
+		}
+	}
+}
+
+// applyPromptPolicy 按 DLP 规则检查客户端帧：文本帧按 JSON 事件检查（input_audio_buffer.append 等音频事件记为无法检查），二进制帧无法检查。
+func (rs *realtimeSession) applyPromptPolicy(messageType int, data []byte) ([]byte, error) {
+	if len(rs.dlpRules) == 0 {
+		return data, nil
+	}
+	var result dlpScanResult
+	if messageType == websocket.TextMessage {
+		result = scanDLP(rs.dlpRules, "application/json", data)
+	} else {
+		result = dlpScanResult{body: data, hits: map[*compiledDLPRule][]string{}, unscannable: true}
+	}
+	return rs.s.enforceDLP(rs.att.caller, []string{rs.att.provider}, rs.att.model, "v1/realtime", rs.dlpRules, result)
+}
+
+// pumpUpstream 上游 → 客户端：转发帧，记录首帧时间与实际模型，response.done 时扣费并检查余额。
+func (rs *realtimeSession) pumpUpstream(done chan<- realtimeEnd) {
+	for {
+		mt, data, err := rs.upstream.ReadMessage()
+		if err != nil {
+			done <- realtimeEnd{err: err}
+			return
+		}
+		if rs.att.firstByteAt.IsZero() {
+			rs.att.firstByteAt = time.Now()
+		}
+		var quotaErr error
+		if mt == websocket.TextMessage {
+			if model, usage, ok := parseRealtimeEvent(data); ok {
+				if model != "" {
+					rs.billingModel = model
+				} else {
+					quotaErr = rs.charge(usage)
+				}
+			}
+		}
+		if err = rs.writeClient(mt, data); err != nil {
+			done <- realtimeEnd{fromClient: true, err: err}
+			return
+		}
+		if quotaErr != nil {
+			done <- realtimeEnd{fromClient: true, err: quotaErr, status: "error"}
+			return
+		}
+	}
+}
+
+// run 双向转发直到任一端关闭、余额耗尽或超过最长时长，返回日志状态与错误信息。
+func (rs *realtimeSession) run() (status, errMsg string) {
+	cfg := global.GVA_CONFIG.Gaia.Gateway.Realtime
+	interval := time.Duration(cfg.BalanceCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = gaia.RealtimeDefaultBalanceCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var deadline <-chan time.Time
+	if cfg.MaxDuration > 0 {
+		timer := time.NewTimer(time.Duration(cfg.MaxDuration) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	done := make(chan realtimeEnd, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); rs.pumpClient(done) }()
+	go func() { defer wg.Done(); rs.pumpUpstream(done) }()
+
+	var end realtimeEnd
+wait:
+	for {
+		select {
+		case end = <-done:
+			break wait
+		case <-ticker.C:
+			if err := rs.s.CheckBalance(rs.att.caller); err != nil {
+				end = realtimeEnd{fromClient: true, err: err, status: "error"}
+				break wait
+			}
+		case <-deadline:
+			end = realtimeEnd{fromClient: true, err: fmt.Errorf("会话超过最长时长 %d 秒", cfg.MaxDuration), status: "error"}
+			break wait
+		}
+	}
+
+	// 余额耗尽 / 超时：先告知客户端原因，再关闭两端；一端关闭时把关闭原因转发给另一端
+	closeDeadline := time.Now().Add(time.Second)
+	var quotaErr *QuotaInsufficientError
+	switch {
+	case errors.As(end.err, &quotaErr):
+		_ = rs.writeClient(websocket.TextMessage, realtimeErrorEvent("insufficient_quota", end.err.Error()))
+		_ = rs.client.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "insufficient_quota"), closeDeadline)
+		_ = rs.upstream.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), closeDeadline)
+	case end.status != "":
+		_ = rs.writeClient(websocket.TextMessage, realtimeErrorEvent("session_expired", end.err.Error()))
+		_ = rs.client.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), closeDeadline)
+		_ = rs.upstream.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), closeDeadline)
+	case end.fromClient:
+		_ = rs.upstream.WriteControl(websocket.CloseMessage, realtimeCloseFrame(end.err), closeDeadline)
+	default:
+		_ = rs.client.WriteControl(websocket.CloseMessage, realtimeCloseFrame(end.err), closeDeadline)
+	}
+	_ = rs.client.Close()
+	_ = rs.upstream.Close()
+	wg.Wait()
+
+	switch {
+	case end.status != "":
+		return end.status, end.err.Error()
+	case isNormalClose(end.err) || errors.Is(end.err, io.EOF):
+		return "success", ""
+	case end.fromClient:
+		return "cancelled", end.err.Error()
+	}
+	return "error", end.err.Error()
+}
+
+// dialRealtime 选取凭证并与上游完成 WebSocket 握手；连接失败与 5xx / 429 以 *upstreamRetryableError 返回，供转移到下一个提供商。
+func (s *ModelProviderService) dialRealtime(att *proxyAttempt, query url.Values, reqHeader http.Header) (
+	conn *websocket.Conn, creds *gaiaResponse.ProviderCredentials, release func(), err error) {
+	if !s.isProviderEnabled(att.provider) {
+		return nil, nil, nil, fmt.Errorf("提供商 %s 未开启", att.provider)
+	}
+	if creds, release, err = s.AcquireProviderCredential(att.provider); err != nil {
+		return nil, nil, nil, err
+	}
+	base := s.getUpstreamBase(att.provider, creds)
+	if base == "" {
+		release()
+		return nil, nil, nil, fmt.Errorf("提供商 %s 无可用上游地址", att.provider)
+	}
+	target, err := realtimeUpstreamURL(att.provider, base, att.upstream, query)
+	if err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+
+	header := http.Header{}
+	if att.provider == gaia.ProviderAzure {
+		header.Set("api-key", creds.APIKey)
+	} else {
+		setUpstreamAuth(header, creds)
+	}
+	if beta := reqHeader.Get("OpenAI-Beta"); beta != "" {
+		header.Set("OpenAI-Beta", beta)
+	} else if strings.Contains(reqHeader.Get("Sec-WebSocket-Protocol"), "openai-beta.realtime-v1") {
+		// 浏览器客户端以子协议 openai-beta.realtime-v1 声明 Beta 版本
+		header.Set("OpenAI-Beta", "realtime=v1")
+	}
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: gaia.RealtimeHandshakeTimeout}
+	conn, resp, err := dialer.DialContext(att.ctx, target, header)
+	if resp != nil {
+		att.upstreamStatus = resp.StatusCode
+		globalCircuitBreaker.report(att.provider, creds, resp, nil)
+	} else {
+		globalCircuitBreaker.report(att.provider, creds, nil, err)
+	}
+	if err == nil {
+		return conn, creds, release, nil
+	}
+	release()
+	if resp == nil {
+		return nil, nil, nil, &upstreamRetryableError{Provider: att.provider, Err: err}
+	}
+	reportCredentialStatus(att.provider, creds, resp.StatusCode, resp.Header.Get("Retry-After"))
+	if isRetryableStatus(resp.StatusCode) {
+		return nil, nil, nil, &upstreamRetryableError{Provider: att.provider, Status: resp.StatusCode}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return nil, nil, nil, fmt.Errorf("上游 %s Realtime 握手失败 %d：%s", att.provider, resp.StatusCode, string(body))
+}
+
+// ProxyRealtime 代理 OpenAI Realtime WebSocket 会话（v1/realtime?model=...）：先与上游（OpenAI / Azure）完成握手，
+// 再升级客户端连接并双向转发帧；按 response.done 事件的 usage（文本 / 音频 token）逐次扣费，会话中定期检查余额，
+// 余额耗尽或超过最长时长（gaia.gateway.realtime）时向客户端发送 error 事件后断开，整个会话记一条代理日志。
+// 升级前的失败（模型无权限、余额不足、上游握手失败等）以 error 返回，由调用方写回 HTTP 错误。
+func (s *ModelProviderService) ProxyRealtime(w http.ResponseWriter, r *http.Request,
+	caller gaiaRequest.ProxyCaller, path string, reqHeader http.Header) (err error) {
+	if path = strings.TrimPrefix(path, "/"); !isRealtimePath(path) {
+		return fmt.Errorf("WebSocket 仅支持 v1/realtime 接口，当前路径：%s", path)
+	}
+	query := r.URL.Query()
+	model := realtimeModel(query)
+	if model == "" {
+		return errors.New("Realtime 请求缺少 model 查询参数（Azure 可使用 deployment）")
+	}
+	if err = s.checkModelAccess(caller, model); err != nil {
+		return err
+	}
+	if err = s.CheckBalance(caller); err != nil {
+		return err
+	}
+
+	var providers []string
+	if p := strings.TrimSpace(strings.ToLower(reqHeader.Get("X-Gaia-Provider"))); p != "" {
+		// 显式指定提供商时不做转移
+		providers = []string{p}
+	} else {
+		resolved, resolveErr := s.resolveProvidersByModel(model)
+		if resolveErr != nil {
+			return resolveErr
+		}
+		for _, p := range resolved {
+			if isRealtimeProvider(p) {
+				providers = append(providers, p)
+			}
+		}
+	}
+	if len(providers) == 0 || !isRealtimeProvider(providers[0]) {
+		return fmt.Errorf("模型 %s 没有支持 Realtime 的已启用提供商（仅支持 openai / azure）", model)
+	}
+
+	route := s.lookupModelRoute(model)
+	var key *gaia.GatewayKey
+	if route != nil {
+		if key, err = callerGatewayKey(caller); err != nil {
+			return err
+		}
+	}
+	attempts := failoverAttempts(len(providers))
+	var failover []string
+	tried := 0
+	for i := 0; i < len(providers) && tried < attempts; i++ {
+		if ok, wait := globalCircuitBreaker.allow(providers[i], "", time.Now()); !ok {
+			openErr := &CircuitOpenError{Provider: providers[i], RetryAfter: wait}
+			failover = append(failover, openErr.String())
+			err = openErr
+			continue
+		}
+		att := &proxyAttempt{
+			ctx:        r.Context(),
+			caller:     caller,
+			provider:   providers[i],
+			model:      model,
+			upstream:   model,
+			failover:   append(append([]string{}, failover...), providers[i]),
+			allowRetry: tried < attempts-1 && i < len(providers)-1,
+			stream:     true,
+		}
+		if route != nil {
+			att.upstream = route.upstreamModelFor(att.provider, model)
+			// 路由改写后的上游模型同样受 Key 作用域与访问策略约束：无权使用时跳过该提供商
+			if att.upstream != model {
+				if denyErr := s.checkUpstreamModel(caller, key, att.upstream); denyErr != nil {
+					err = denyErr
+					continue
+				}
+			}
+		}
+		conn, creds, release, dialErr := s.dialRealtime(att, query, reqHeader)
+		if dialErr != nil {
+			err = dialErr
+			tried++
+			var retryErr *upstreamRetryableError
+			if !errors.As(err, &retryErr) || !att.allowRetry {
+				return err
+			}
+			global.GVA_LOG.Warn("ProxyRealtime 上游握手失败，转移到下一个提供商",
+				zap.String("provider", att.provider), zap.Int("attempt", tried), zap.Error(err))
+			failover = append(failover, retryErr.String())
+			continue
+		}
+		defer release()
+		return s.relayRealtime(w, r, att, creds, conn)
+	}
+	return err
+}
+
+// realtimeCheckOrigin 校验 WebSocket 升级请求的来源：无 Origin（SDK 等非浏览器客户端）、与管理端同源或在跨域白名单中时放行。
+func realtimeCheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, w := range global.GVA_CONFIG.Cors.Whitelist {
+		if strings.EqualFold(strings.TrimRight(w.AllowOrigin, "/"), strings.TrimRight(origin, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// relayRealtime 升级客户端连接并转发整个会话，结束后按会话累计用量记录代理日志（扣费已在 response.done 时逐次完成）。
+func (s *ModelProviderService) relayRealtime(w http.ResponseWriter, r *http.Request,
+	att *proxyAttempt, creds *gaiaResponse.ProviderCredentials, upstream *websocket.Conn) error {
+	upgrader := websocket.Upgrader{
+		// 管理端 JWT 可从 x-token cookie 读取，跨站页面发起的升级会带上 cookie，须校验来源防止跨站 WebSocket 劫持
+		CheckOrigin:  realtimeCheckOrigin,
+		Subprotocols: []string{"realtime"},
+	}
+	startTime := time.Now()
+	client, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		_ = upstream.Close()
+		return err
+	}
+	rs := &realtimeSession{s: s, att: att, client: client, upstream: upstream, billingModel: att.upstream}
+	caller := att.caller
+	rs.dlpRules = s.dlpRulesFor(&caller, []string{att.provider})
+	status, errMsg := rs.run()
+	s.logProxyAttempt(att, creds, rs.billingModel, status, errMsg, startTime, rs.usage)
+	return nil
+}
@@ -0,0 +1,102 @@
+package gaia
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/gorilla/websocket"
+)
+
+// TestRealtimeUpstreamURL 测试 OpenAI / Azure Realtime 上游地址构建（scheme 转换、api-version 默认值）
+func TestRealtimeUpstreamURL(t *testing.T) {
+	got, err := realtimeUpstreamURL(gaia.ProviderOpenai, "https://api.openai.com/", "gpt-realtime", nil)
+	if err != nil || got != "wss://api.openai.com/v1/realtime?model=gpt-realtime" {
+		t.Errorf("OpenAI 地址错误：%s %v", got, err)
+	}
+	got, _ = realtimeUpstreamURL(gaia.ProviderAzure, "https://res.openai.azure.com", "rt", url.Values{})
+	if got != "wss://res.openai.azure.com/openai/realtime?api-version="+gaia.AzureRealtimeAPIVersion+"&deployment=rt" {
+		t.Errorf("Azure 地址错误：%s", got)
+	}
+	got, _ = realtimeUpstreamURL(gaia.ProviderAzure, "http://127.0.0.1:8080", "rt", url.Values{"api-version": {"2025-04-01-preview"}})
+	if got != "ws://127.0.0.1:8080/openai/realtime?api-version=2025-04-01-preview&deployment=rt" {
+		t.Errorf("Azure 应沿用客户端 api-version：%s", got)
+	}
+	if _, err = realtimeUpstreamURL(gaia.ProviderOpenai, "not a url", "m", nil); err == nil {
+		t.Error("非法上游地址应报错")
+	}
+	if !isRealtimePath("/v1/realtime") || isRealtimePath("v1/chat/completions") {
+		t.Error("Realtime 路径识别错误")
+	}
+}
+
+// TestParseRealtimeEvent 测试 session 事件取模型、response.done 取文本 / 音频 usage，其余事件跳过
+func TestParseRealtimeEvent(t *testing.T) {
+	model, _, ok := parseRealtimeEvent([]byte(`{"type":"session.created","session":{"model":"gpt-realtime-2025-08-28"}}`))
+	if !ok || model != "gpt-realtime-2025-08-28" {
+		t.Errorf("session 模型解析错误：%q %v", model, ok)
+	}
+	done := `{"type":"response.done","response":{"status":"completed","usage":{"total_tokens":380,"input_tokens":300,"output_tokens":80,
+		"input_token_details":{"text_tokens":100,"audio_tokens":200,"cached_tokens":64,"cached_tokens_details":{"text_tokens":0,"audio_tokens":64}},
+		"output_token_details":{"text_tokens":20,"audio_tokens":60}}}}`
+	model, usage, ok := parseRealtimeEvent([]byte(done))
+	want := gaia.TokenUsage{PromptTokens: 300, CompletionTokens: 80, CacheReadTokens: 64, AudioInputTokens: 136, AudioOutputTokens: 60}
+	if !ok || model != "" || usage != want {
+		t.Errorf("response.done 用量解析错误：%+v", usage)
+	}
+	if _, _, ok = parseRealtimeEvent([]byte(`{"type":"response.audio.delta","delta":"AAAA"}`)); ok {
+		t.Error("无关事件应跳过")
+	}
+
+	// gpt-realtime：文本输入 $4/M、音频输入 $32/M、缓存命中 $0.4/M、文本输出 $16/M、音频输出 $64/M
+	wantCost := (100*4.0 + 136*32.0 + 64*0.4 + 20*16.0 + 60*64.0) / 1e6
+	if got := calcUsageCost(nil, "gpt-realtime", usage); math.Abs(got-wantCost) > 1e-9 {
+		t.Errorf("音频计费错误，期望 %v，got: %v", wantCost, got)
+	}
+}
+
+// TestRealtimeCloseFrame 测试关闭原因转发：保留码转换为可发送的关闭码，其余原样转发
+func TestRealtimeCloseFrame(t *testing.T) {
+	cases := []struct {
+		err  error
+		want []byte
+	}{
+		{&websocket.CloseError{Code: websocket.CloseNoStatusReceived}, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")},
+		{&websocket.CloseError{Code: websocket.CloseAbnormalClosure}, websocket.FormatCloseMessage(websocket.CloseGoingAway, "")},
+		{&websocket.CloseError{Code: 4000, Text: "bye"}, websocket.FormatCloseMessage(4000, "bye")},
+	}
+	for _, c := range cases {
+		if got := realtimeCloseFrame(c.err); string(got) != string(c.want) {
+			t.Errorf("%v 转发错误：%v", c.err, got)
+		}
+	}
+}
+
+// TestRealtimeCheckOrigin 测试 WebSocket 来源校验：非浏览器客户端与同源放行，跨站页面拒绝，白名单来源放行
+func TestRealtimeCheckOrigin(t *testing.T) {
+	origWhitelist := global.GVA_CONFIG.Cors.Whitelist
+	t.Cleanup(func() { global.GVA_CONFIG.Cors.Whitelist = origWhitelist })
+	global.GVA_CONFIG.Cors.Whitelist = []config.CORSWhitelist{{AllowOrigin: "https://console.example.com"}}
+
+	cases := map[string]bool{
+		"":                            true,
+		"https://admin.example.com":   true,
+		"https://console.example.com": true,
+		"https://evil.example.net":    false,
+		"null":                        false,
+	}
+	for origin, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "http://admin.example.com/gaia/proxy/v1/realtime", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := realtimeCheckOrigin(r); got != want {
+			t.Errorf("Origin %q 校验结果 %v，期望 %v", origin, got, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+)
+
+// responseCacheHeader 请求头控制是否走缓存（true/1/on 启用，false/0/off/bypass 跳过），响应头回写 HIT / MISS
+const responseCacheHeader = "X-Gaia-Cache"
+
+// cachedResponse Redis 中缓存的上游响应
+type cachedResponse struct {
+	ContentType string          `json:"content_type"`
+	Body        []byte          `json:"body"`
+	Provider    string          `json:"provider"`
+	Model       string          `json:"model"` // 实际服务请求的上游模型（路由改写后），命中时按其定价计费
+	Tokens      gaia.TokenUsage `json:"tokens"`
+}
+
+// isResponseCacheablePath 仅缓存结果可复用的接口：chat/completions、Anthropic Messages、embeddings。
+func isResponseCacheablePath(path string) bool {
+	lpath := strings.TrimSuffix(strings.ToLower(path), "/")
+	return isChatCompletionsPath(lpath) || isAnthropicMessagesPath(lpath) || strings.HasSuffix(lpath, "embeddings")
+}
+
+// responseCacheEnabled 判断本次请求是否使用响应缓存：仅非流式 POST；请求头显式开关优先，否则按配置的模型列表。
+func responseCacheEnabled(method, path string, reqHeader http.Header, body []byte, model string) bool {
+	cfg := global.GVA_CONFIG.Gaia.Gateway.Cache
+	if cfg.TTL <= 0 || method != http.MethodPost || !isResponseCacheablePath(path) || len(body) == 0 {
+		return false
+	}
+	var obj struct {
+		Stream bool `json:"stream"`
+	}
+	if json.Unmarshal(body, &obj) != nil || obj.Stream {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(reqHeader.Get(responseCacheHeader))) {
+	case "true", "1", "on", "yes":
+		return true
+	case "false", "0", "off", "no", "bypass":
+		return false
+	}
+	return model != "" && matchModelPattern(cfg.Models, model)
+}
+
+// responseCacheKeyHeaders 会改变响应内容的请求头，参与缓存 Key 计算
+var responseCacheKeyHeaders = []string{"anthropic-version", "anthropic-beta"}
+
+// responseCacheKey 由 provider、path、影响响应的请求头与规范化后的 body（JSON 重新序列化，字段顺序、空白不影响命中）计算缓存 Key。
+func responseCacheKey(provider, path string, reqHeader http.Header, body []byte) string {
+	normalized := body
+	var obj interface{}
+	if json.Unmarshal(body, &obj) == nil {
+		if b, err := json.Marshal(obj); err == nil {
+			normalized = b
+		}
+	}
+	h := sha256.New()
+	h.Write([]byte(strings.ToLower(provider)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Trim(strings.ToLower(path), "/")))
+	h.Write([]byte{0})
+	for _, name := range responseCacheKeyHeaders {
+		h.Write([]byte(strings.Join(reqHeader.Values(name), ",")))
+		h.Write([]byte{0})
+	}
+	h.Write(normalized)
+	return gaia.RedisKeyGaiaResponseCachePrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCacheUsageFormat 缓存的是返回给客户端的响应，usage 格式由客户端协议（路径）决定。
+func responseCacheUsageFormat(path string) usageFormat {
+	if isAnthropicMessagesPath(path) {
+		return usageFormatAnthropic
+	}
+	return detectUsageFormat("", path)
+}
+
+// loadCachedResponse 读取缓存，未命中或 Redis 异常时返回 nil。
+func loadCachedResponse(key string) *cachedResponse {
+	raw, err := global.GVA_REDIS.Get(context.Background(), key).Bytes()
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err = json.Unmarshal(raw, &cached); err != nil || len(cached.Body) == 0 {
+		return nil
+	}
+	return &cached
+}
+
+// serveCachedResponse 将缓存响应写回客户端，记录 cache_hit 日志并按 hit-discount 折扣计费。
+func (s *ModelProviderService) serveCachedResponse(
+	caller gaiaRequest.ProxyCaller, cached *cachedResponse, writer io.Writer) error {
+	startTime := time.Now()
+	if w, ok := writer.(http.ResponseWriter); ok {
+		if cached.ContentType != "" {
+			w.Header().Set("Content-Type", cached.ContentType)
+		}
+		w.Header().Set(responseCacheHeader, "HIT")
+		w.WriteHeader(http.StatusOK)
+	}
+	if _, err := writer.Write(cached.Body); err != nil {
+		return err
+	}
+
+	// 命中缓存不消耗上游 token，不计入 TPM；按配置比例计费，0 为免费（额度预占由调用方释放）
+	var cost float64
+	if discount := global.GVA_CONFIG.Gaia.Gateway.Cache.HitDiscount; discount > 0 && hasTokens(cached.Tokens) {
+		pricing, _ := s.fetchModelPricingFromDify(cached.Provider, cached.Model)
+		cost = discount * calcUsageCost(pricing, cached.Model, cached.Tokens)
+		chargeCaller(caller, cost)
+	}
+
+	log := &gaia.ModelProxyLog{
+		UserId:           caller.AccountId,
+		ProviderName:     cached.Provider,
+		ModelName:        cached.Model,
+		RequestTokens:    cached.Tokens.PromptTokens,
+		ResponseTokens:   cached.Tokens.CompletionTokens,
+		CacheReadTokens:  cached.Tokens.CacheReadTokens,
+		CacheWriteTokens: cached.Tokens.CacheWriteTokens,
+		ReasoningTokens:  cached.Tokens.ReasoningTokens,
+		CacheHit:         true,
+		DurationMs:       time.Since(startTime).Milliseconds(),
+		CostUsd:          cost,
+		RequestId:        caller.RequestId,
+		ClientIp:         caller.ClientIP,
+		Status:           "success",
+		CreatedAt:        startTime,
+	}
+	if err := global.GVA_DB.Create(log).Error; err != nil {
+		global.GVA_LOG.Warn("serveCachedResponse 写日志失败", zap.Error(err))
+	}
+	observeProxyLog(log)
+	return nil
+}
+
+// storeCachedResponse 将成功且未超出大小限制的响应写入缓存；解析不到 usage 的响应不缓存，避免命中时无法计费。
+func storeCachedResponse(key, path string, capture *responseCaptureWriter, provider, model string) {
+	if capture.overflow || capture.status != http.StatusOK || capture.buf.Len() == 0 {
+		return
+	}
+	usage := &proxyUsage{format: responseCacheUsageFormat(path)}
+	usage.feed(capture.buf.Bytes())
+	if !hasTokens(usage.tokens) {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{
+		ContentType: capture.Header().Get("Content-Type"),
+		Body:        capture.buf.Bytes(),
+		Provider:    provider,
+		Model:       model,
+		Tokens:      usage.tokens,
+	})
+	if err != nil {
+		return
+	}
+	ttl := time.Duration(global.GVA_CONFIG.Gaia.Gateway.Cache.TTL) * time.Second
+	if err = global.GVA_REDIS.Set(context.Background(), key, data, ttl).Err(); err != nil {
+		global.GVA_LOG.Warn("storeCachedResponse 写入缓存失败", zap.Error(err))
+	}
+}
+
+// responseCaptureWriter 透传响应给客户端的同时保留一份副本用于写入缓存，超过 limit 后停止保留
+type responseCaptureWriter struct {
+	http.ResponseWriter
+	buf      bytes.Buffer
+	limit    int
+	status   int
+	overflow bool
+}
+
+func newResponseCaptureWriter(w http.ResponseWriter, limit int) *responseCaptureWriter {
+	return &responseCaptureWriter{ResponseWriter: w, limit: limit}
+}
+
+func (w *responseCaptureWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseCaptureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.overflow {
+		if w.limit > 0 && w.buf.Len()+len(p) > w.limit {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseCaptureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+package gaia
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// TestResponseCacheKey 测试缓存 Key 忽略 JSON 字段顺序与空白，并区分 provider、path 与 anthropic-version / anthropic-beta 请求头
+func TestResponseCacheKey(t *testing.T) {
+	a := responseCacheKey("openai", "v1/chat/completions", nil, []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}]}`))
+	b := responseCacheKey("OpenAI", "/v1/chat/completions", http.Header{"Authorization": {"Bearer x"}}, []byte("{ \"messages\": [{\"content\":\"hi\",\"role\":\"user\"}],\n \"model\": \"gpt-5\" }"))
+	if a != b {
+		t.Errorf("规范化后 Key 应一致：%s / %s", a, b)
+	}
+	if a == responseCacheKey("azure_openai", "v1/chat/completions", nil, []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}]}`)) {
+		t.Error("不同 provider 的 Key 不应相同")
+	}
+	if a == responseCacheKey("openai", "v1/embeddings", nil, []byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}]}`)) {
+		t.Error("不同 path 的 Key 不应相同")
+	}
+	msg := []byte(`{"model":"claude-sonnet-4-6","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	plain := responseCacheKey("anthropic", "v1/messages", http.Header{"Anthropic-Version": {"2023-06-01"}}, msg)
+	beta := responseCacheKey("anthropic", "v1/messages",
+		http.Header{"Anthropic-Version": {"2023-06-01"}, "Anthropic-Beta": {"context-1m-2025-08-07"}}, msg)
+	if plain == beta {
+		t.Error("anthropic-beta 不同的 Key 不应相同")
+	}
+}
+
+// TestResponseCacheEnabled 测试请求头开关、模型列表与流式、路径等限制
+func TestResponseCacheEnabled(t *testing.T) {
+	old := global.GVA_CONFIG.Gaia.Gateway.Cache
+	defer func() { global.GVA_CONFIG.Gaia.Gateway.Cache = old }()
+	global.GVA_CONFIG.Gaia.Gateway.Cache.TTL = 60
+	global.GVA_CONFIG.Gaia.Gateway.Cache.Models = []string{"text-embedding-*"}
+
+	on := http.Header{responseCacheHeader: []string{"true"}}
+	off := http.Header{responseCacheHeader: []string{"bypass"}}
+	body := []byte(`{"model":"gpt-5","messages":[]}`)
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		body   []byte
+		model  string
+		want   bool
+	}{
+		{"请求头启用", http.MethodPost, "v1/chat/completions", on, body, "gpt-5", true},
+		{"未启用", http.MethodPost, "v1/chat/completions", http.Header{}, body, "gpt-5", false},
+		{"模型列表启用", http.MethodPost, "v1/embeddings", http.Header{}, []byte(`{"model":"text-embedding-3-small"}`), "text-embedding-3-small", true},
+		{"请求头跳过", http.MethodPost, "v1/embeddings", off, []byte(`{"model":"text-embedding-3-small"}`), "text-embedding-3-small", false},
+		{"流式不缓存", http.MethodPost, "v1/chat/completions", on, []byte(`{"model":"gpt-5","stream":true}`), "gpt-5", false},
+		{"非缓存接口", http.MethodPost, "v1/images/generations", on, body, "gpt-5", false},
+		{"GET 不缓存", http.MethodGet, "v1/chat/completions", on, body, "gpt-5", false},
+	}
+	for _, c := range cases {
+		if got := responseCacheEnabled(c.method, c.path, c.header, c.body, c.model); got != c.want {
+			t.Errorf("%s：期望 %v，实际 %v", c.name, c.want, got)
+		}
+	}
+
+	global.GVA_CONFIG.Gaia.Gateway.Cache.TTL = 0
+	if responseCacheEnabled(http.MethodPost, "v1/chat/completions", on, body, "gpt-5") {
+		t.Error("TTL 为 0 时应关闭缓存")
+	}
+}
+
+// TestResponseCaptureWriter 测试副本超过大小限制后放弃缓存但不影响透传
+func TestResponseCaptureWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newResponseCaptureWriter(rec, 8)
+	_, _ = w.Write([]byte("12345"))
+	if w.status != http.StatusOK || w.buf.String() != "12345" || w.overflow {
+		t.Fatalf("未超限时应保留副本：status=%d buf=%q", w.status, w.buf.String())
+	}
+	_, _ = w.Write([]byte("6789"))
+	if !w.overflow || w.buf.Len() != 0 {
+		t.Error("超限后应放弃副本")
+	}
+	if rec.Body.String() != "123456789" {
+		t.Errorf("透传内容错误：%q", rec.Body.String())
+	}
+}
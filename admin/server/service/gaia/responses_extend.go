@@ -0,0 +1,199 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// isResponsesPath 判断是否为 OpenAI Responses API 创建接口（v1/responses、Azure 的 openai/v1/responses / openai/responses）。
+// 查询 / 取消 / 删除已有响应（v1/responses/{id}...）不在此列：其返回体同样带 usage，但已在创建时计费。
+func isResponsesPath(path string) bool {
+	lpath := strings.Trim(strings.ToLower(path), "/")
+	return lpath == "responses" || strings.HasSuffix(lpath, "/responses")
+}
+
+// azureResponsesPath Azure OpenAI 的 Responses API 只在 v1 接口（/openai/v1/responses...，无需 api-version）提供，
+// 将 responses... / openai/responses... 映射为 v1/responses...；其他路径原样返回。
+func azureResponsesPath(path string) string {
+	for _, prefix := range []string{"responses", "openai/responses"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "v1/responses" + strings.TrimPrefix(path, prefix)
+		}
+	}
+	return path
+}
+
+// responsesUsage Responses API 的 usage（cached_tokens 含于 input_tokens，reasoning_tokens 含于 output_tokens）
+type responsesUsage struct {
+	InputTokens        int `json:"input_tokens"`
+	OutputTokens       int `json:"output_tokens"`
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+func (u *responsesUsage) tokens() gaia.TokenUsage {
+	if u == nil {
+		return gaia.TokenUsage{}
+	}
+	return gaia.TokenUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		CacheReadTokens:  u.InputTokensDetails.CachedTokens,
+		ReasoningTokens:  u.OutputTokensDetails.ReasoningTokens,
+	}
+}
+
+// parseResponsesUsage 非流式响应的 usage 在顶层；流式的 response.completed / response.incomplete / response.failed 事件在 response.usage。
+// 使用 previous_response_id 续接的对话，上游返回的 input_tokens 已包含历史上下文，按其计费即可。
+func parseResponsesUsage(data []byte) gaia.TokenUsage {
+	var obj struct {
+		Usage    *responsesUsage `json:"usage"`
+		Response *struct {
+			Usage *responsesUsage `json:"usage"`
+		} `json:"response"`
+	}
+	if json.Unmarshal(data, &obj) != nil {
+		return gaia.TokenUsage{}
+	}
+	if obj.Response != nil && obj.Response.Usage != nil {
+		return obj.Response.Usage.tokens()
+	}
+	return obj.Usage.tokens()
+}
+
+// responsesStreamedText 提取 Responses 流式事件中的输出文本（output_text / reasoning_summary_text / function_call_arguments 等 *.delta），
+// 音频增量为 base64，不计入。
+func responsesStreamedText(data []byte) string {
+	var ev struct {
+		Type  string          `json:"type"`
+		Delta json.RawMessage `json:"delta"`
+	}
+	if json.Unmarshal(data, &ev) != nil || !strings.HasSuffix(ev.Type, ".delta") || strings.Contains(ev.Type, "audio") {
+		return ""
+	}
+	var text string
+	_ = json.Unmarshal(ev.Delta, &text)
+	return text
+}
+
+// isResponsesBackground 请求是否为 background 模式：创建时只返回 queued 状态、不含 usage，结果需另行轮询，网关无法计费。
+func isResponsesBackground(body []byte) bool {
+	var obj struct {
+		Background bool `json:"background"`
+	}
+	return json.Unmarshal(body, &obj) == nil && obj.Background
+}
+
+// storedResponseID 解析查询 / 删除 / 取消已有响应的路径（v1/responses/{id}[/cancel|/input_items]，兼容 Azure 的 openai/ 前缀），返回响应 ID。
+func storedResponseID(path string) (string, bool) {
+	p := strings.Trim(path, "/")
+	p = strings.TrimPrefix(p, "openai/")
+	p = strings.TrimPrefix(p, "v1/")
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "responses" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// previousResponseID 创建请求体中的 previous_response_id（续接已有响应的对话）。
+func previousResponseID(body []byte) string {
+	var obj struct {
+		PreviousResponseID string `json:"previous_response_id"`
+	}
+	_ = json.Unmarshal(body, &obj)
+	return obj.PreviousResponseID
+}
+
+// responsesObjectID 提取非流式响应体或流式事件（response.created 等）中的响应 ID。
+func responsesObjectID(data []byte) string {
+	var obj struct {
+		ID       string `json:"id"`
+		Object   string `json:"object"`
+		Response *struct {
+			ID string `json:"id"`
+		} `json:"response"`
+	}
+	if json.Unmarshal(data, &obj) != nil {
+		return ""
+	}
+	if obj.Response != nil {
+		return obj.Response.ID
+	}
+	if obj.Object == "response" {
+		return obj.ID
+	}
+	return ""
+}
+
+// responseOwner 校验响应归属，返回创建时的记录；未经网关创建或属于其他账号时返回 404。
+func (s *ModelProviderService) responseOwner(caller gaiaRequest.ProxyCaller, responseID string) (*gaia.ModelResponse, error) {
+	var record gaia.ModelResponse
+	err := global.GVA_DB.Where("response_id = ? AND account_id = ?", responseID, caller.AccountId).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &BatchRequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("响应 %s 不存在或无权访问", responseID)}
+	}
+	return &record, err
+}
+
+// recordResponseOwner 记录上游保存的响应归属（请求体 store=false 时上游不保存，无需记录）。
+func (s *ModelProviderService) recordResponseOwner(att *proxyAttempt, body []byte, responseID string) {
+	var obj struct {
+		Store *bool `json:"store"`
+	}
+	if responseID == "" || (json.Unmarshal(body, &obj) == nil && obj.Store != nil && !*obj.Store) {
+		return
+	}
+	record := &gaia.ModelResponse{
+		ResponseId: responseID, ProviderName: att.provider, CredentialId: att.credential,
+		AccountId: att.caller.AccountId, KeyId: att.caller.KeyId,
+	}
+	if err := global.GVA_DB.Create(record).Error; err != nil {
+		global.GVA_LOG.Error("记录 Responses 响应归属失败", zap.String("provider", att.provider),
+			zap.String("response_id", responseID), zap.Error(err))
+	}
+}
+
+// proxyStoredResponse 代理查询 / 删除 / 取消已有响应：须为创建时的同一账号，并固定到创建时的提供商与凭证；删除成功后移除归属记录。
+func (s *ModelProviderService) proxyStoredResponse(ctx context.Context, caller gaiaRequest.ProxyCaller, responseID,
+	path, method string, reqHeader http.Header, body []byte, writer io.Writer) error {
+	record, err := s.responseOwner(caller, responseID)
+	if err != nil {
+		return err
+	}
+	att := &proxyAttempt{ctx: ctx, caller: caller, provider: record.ProviderName, credential: record.CredentialId}
+	if ok, wait := globalCircuitBreaker.allow(att.provider, "", time.Now()); !ok {
+		return &CircuitOpenError{Provider: att.provider, RetryAfter: wait}
+	}
+	w, ok := writer.(http.ResponseWriter)
+	if method != http.MethodDelete || !ok {
+		return s.proxyToProvider(att, path, method, reqHeader, body, writer)
+	}
+	// 删除只需上游状态码，不保留响应体
+	capture := newResponseCaptureWriter(w, 1)
+	if err = s.proxyToProvider(att, path, method, reqHeader, body, capture); err != nil {
+		return err
+	}
+	if capture.status == http.StatusOK {
+		if err = global.GVA_DB.Delete(record).Error; err != nil {
+			global.GVA_LOG.Error("删除 Responses 响应归属失败", zap.String("response_id", responseID), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package gaia
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+)
+
+// TestResponsesUsage 测试 Responses API 非流式与 response.completed 事件的 usage 解析（缓存 / 推理 token 归一化），查询接口不计费
+func TestResponsesUsage(t *testing.T) {
+	if detectUsageFormat(gaia.ProviderOpenai, "v1/responses") != usageFormatResponses ||
+		detectUsageFormat(gaia.ProviderAzure, "openai/v1/responses") != usageFormatResponses {
+		t.Error("Responses 创建接口应识别为 Responses 格式")
+	}
+	if detectUsageFormat(gaia.ProviderOpenai, "v1/responses/resp_123") != usageFormatOpenAI {
+		t.Error("查询已有响应不应按 Responses 格式计费")
+	}
+
+	want := gaia.TokenUsage{PromptTokens: 1200, CompletionTokens: 300, CacheReadTokens: 1024, ReasoningTokens: 256}
+	u := &proxyUsage{format: usageFormatResponses}
+	u.feed([]byte(`{"id":"resp_1","object":"response","status":"completed","usage":{"input_tokens":1200,
+		"input_tokens_details":{"cached_tokens":1024},"output_tokens":300,"output_tokens_details":{"reasoning_tokens":256},"total_tokens":1500}}`))
+	if u.tokens != want {
+		t.Errorf("非流式 usage 解析错误：%+v", u.tokens)
+	}
+
+	u = &proxyUsage{format: usageFormatResponses}
+	u.feed([]byte(`{"type":"response.created","response":{"id":"resp_1","status":"in_progress","usage":null}}`))
+	u.feed([]byte(`{"type":"response.completed","response":{"id":"resp_1","status":"completed","usage":{"input_tokens":1200,
+		"input_tokens_details":{"cached_tokens":1024},"output_tokens":300,"output_tokens_details":{"reasoning_tokens":256}}}}`))
+	if u.tokens != want {
+		t.Errorf("流式 usage 解析错误：%+v", u.tokens)
+	}
+}
+
+// TestResponsesStreamedText 测试 Responses 流式增量文本提取（音频增量不计入）
+func TestResponsesStreamedText(t *testing.T) {
+	cases := map[string]string{
+		`{"type":"response.output_text.delta","item_id":"msg_1","delta":"你好"}`:  "你好",
+		`{"type":"response.reasoning_summary_text.delta","delta":"think"}`:      "think",
+		`{"type":"response.function_call_arguments.delta","delta":"{\"a\":1}"}`: `{"a":1}`,
+		`{"type":"response.audio.delta","delta":"UklGRg=="}`:                    "",
+		`{"type":"response.output_text.done","text":"你好"}`:                      "",
+	}
+	for in, want := range cases {
+		if got := streamedText(usageFormatResponses, []byte(in)); got != want {
+			t.Errorf("%s 提取错误：%q", in, got)
+		}
+	}
+}
+
+// TestAzureResponsesPath 测试 Azure Responses API 统一映射到 v1 接口，其余路径不变
+func TestAzureResponsesPath(t *testing.T) {
+	cases := map[string]string{
+		"responses":                    "v1/responses",
+		"openai/responses":             "v1/responses",
+		"responses/resp_1/input_items": "v1/responses/resp_1/input_items",
+		"v1/responses":                 "v1/responses",
+		"openai/v1/responses":          "openai/v1/responses",
+		"chat/completions":             "chat/completions",
+		"responses_archive":            "responses_archive",
+	}
+	for in, want := range cases {
+		if got := azureResponsesPath(in); got != want {
+			t.Errorf("%s 映射错误：%s", in, got)
+		}
+	}
+	if !isResponsesBackground([]byte(`{"model":"o3","background":true}`)) || isResponsesBackground([]byte(`{"model":"o3"}`)) {
+		t.Error("background 模式识别错误")
+	}
+}
+
+// TestStoredResponseID 测试查询 / 删除 / 取消已有响应的路径解析，创建接口与其他路径不匹配
+func TestStoredResponseID(t *testing.T) {
+	cases := map[string]string{
+		"v1/responses/resp_1":                   "resp_1",
+		"/v1/responses/resp_1/input_items":      "resp_1",
+		"openai/v1/responses/resp_1/cancel":     "resp_1",
+		"responses/resp_1":                      "resp_1",
+		"v1/responses":                          "",
+		"v1/responses/":                         "",
+		"v1/chat/completions":                   "",
+		"v1/responses/resp_1/input_items/extra": "",
+	}
+	for in, want := range cases {
+		if got, ok := storedResponseID(in); got != want || ok != (want != "") {
+			t.Errorf("%s 解析错误：%q %v", in, got, ok)
+		}
+	}
+	if previousResponseID([]byte(`{"model":"o3","previous_response_id":"resp_1"}`)) != "resp_1" ||
+		previousResponseID([]byte(`{"model":"o3"}`)) != "" {
+		t.Error("previous_response_id 解析错误")
+	}
+	if responsesObjectID([]byte(`{"id":"resp_1","object":"response","status":"completed"}`)) != "resp_1" ||
+		responsesObjectID([]byte(`{"type":"response.created","response":{"id":"resp_2"}}`)) != "resp_2" ||
+		responsesObjectID([]byte(`{"type":"response.output_text.delta","delta":"hi"}`)) != "" {
+		t.Error("响应 ID 提取错误")
+	}
+}
+
+// TestResponseOwner 测试响应归属：创建账号可访问并固定到创建时的凭证，其他账号与 store=false 的响应返回 404
+func TestResponseOwner(t *testing.T) {
+	setupTestDB(t, &gaia.ModelResponse{})
+	s := &ModelProviderService{}
+	owner := gaiaRequest.ProxyCaller{AccountId: "11111111-1111-1111-1111-111111111111", KeyId: 3}
+	other := gaiaRequest.ProxyCaller{AccountId: "22222222-2222-2222-2222-222222222222"}
+	att := &proxyAttempt{caller: owner, provider: gaia.ProviderOpenai, credential: "cred-2"}
+	s.recordResponseOwner(att, []byte(`{"model":"o3"}`), "resp_1")
+	s.recordResponseOwner(att, []byte(`{"model":"o3","store":false}`), "resp_2")
+
+	record, err := s.responseOwner(owner, "resp_1")
+	if err != nil || record.ProviderName != gaia.ProviderOpenai || record.CredentialId != "cred-2" || record.KeyId != 3 {
+		t.Fatalf("创建账号应可访问：%+v %v", record, err)
+	}
+	var reqErr *BatchRequestError
+	if _, err = s.responseOwner(other, "resp_1"); !errors.As(err, &reqErr) || reqErr.Status != http.StatusNotFound {
+		t.Errorf("其他账号应返回 404：%v", err)
+	}
+	if _, err = s.responseOwner(owner, "resp_2"); !errors.As(err, &reqErr) || reqErr.Status != http.StatusNotFound {
+		t.Errorf("store=false 的响应不应记录归属：%v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package gaia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+)
+
+// upstreamTransport 所有上游模型请求共用的连接池，避免每次请求重新建立 TCP / TLS 连接
+var upstreamTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          512,
+	MaxIdleConnsPerHost:   64,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// upstreamClient 上游模型请求客户端：单次请求（含流式读取）最长 5 分钟，客户端断开时随请求 context 提前取消
+var upstreamClient = &http.Client{Transport: upstreamTransport, Timeout: 5 * time.Minute}
+
+// doUpstream 发起上游请求并将结果上报熔断器；请求须以 att.ctx 构建，客户端断开时上游随之中止。
+func doUpstream(att *proxyAttempt, creds *gaiaResponse.ProviderCredentials, req *http.Request) (*http.Response, error) {
+	resp, err := upstreamClient.Do(req)
+	globalCircuitBreaker.report(att.provider, creds, resp, err)
+	return resp, err
+}
+
+// clientCancelled 判断本次转发是否因客户端断开（请求 context 取消）而中止。
+func (a *proxyAttempt) clientCancelled() bool {
+	return a.ctx != nil && errors.Is(a.ctx.Err(), context.Canceled)
+}
+
+// failureStatus 转发中途失败时的日志状态：客户端断开为 cancelled，其余为 error。
+func (a *proxyAttempt) failureStatus() string {
+	if a.clientCancelled() {
+		return "cancelled"
+	}
+	return "error"
+}
+
+// trackStreamed 累计流式事件中已写回客户端的输出 token（按文本估算），data 为 format 协议的单个事件 JSON。
+func (a *proxyAttempt) trackStreamed(format usageFormat, data []byte) {
+	a.streamedTokens += estimateTextTokens(streamedText(format, data))
+}
+
+// cancelledUsage 客户端中途断开时的计费用量：上游已返回的 usage 优先，缺失的输入 / 输出按请求体与已写回内容估算；
+// 尚未写回任何内容时不计费。
+func (a *proxyAttempt) cancelledUsage(tokens gaia.TokenUsage) gaia.TokenUsage {
+	if a.streamedTokens == 0 && tokens.CompletionTokens == 0 {
+		return gaia.TokenUsage{}
+	}
+	if tokens.PromptTokens == 0 {
+		tokens.PromptTokens = a.promptTokens
+	}
+	if tokens.CompletionTokens < a.streamedTokens {
+		tokens.CompletionTokens = a.streamedTokens
+	}
+	return tokens
+}
+
+// logCancelledAttempt 客户端中途断开：按已写回的内容计费并记录 cancelled 日志（协议转换 / Bedrock 路径使用）。
+func (s *ModelProviderService) logCancelledAttempt(att *proxyAttempt, creds *gaiaResponse.ProviderCredentials,
+	modelID string, startTime time.Time, usage gaia.TokenUsage, err error) {
+	usage = att.cancelledUsage(usage)
+	if hasTokens(usage) {
+		att.cost = s.chargeUsage(att.caller, att.provider, modelID, usage)
+	}
+	s.logProxyAttempt(att, creds, modelID, "cancelled", err.Error(), startTime, usage)
+}
+
+// streamedText 提取流式事件中的输出文本：OpenAI choices[].delta，Anthropic delta（text / thinking / partial_json），
+// Gemini candidates[].content.parts[].text，Responses API 的 *.delta 事件。
+func streamedText(format usageFormat, data []byte) string {
+	var text string
+	switch format {
+	case usageFormatResponses:
+		text = responsesStreamedText(data)
+	case usageFormatAnthropic:
+		var ev struct {
+			Delta struct {
+				Text        string `json:"text"`
+				Thinking    string `json:"thinking"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+		}
+		if json.Unmarshal(data, &ev) == nil {
+			text = ev.Delta.Text + ev.Delta.Thinking + ev.Delta.PartialJSON
+		}
+	case usageFormatGemini:
+		var ev struct {
+			Candidates []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"candidates"`
+		}
+		if json.Unmarshal(data, &ev) == nil {
+			for _, c := range ev.Candidates {
+				for _, p := range c.Content.Parts {
+					text += p.Text
+				}
+			}
+		}
+	default:
+		var ev struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+					ToolCalls        []struct {
+						Function struct {
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if json.Unmarshal(data, &ev) == nil {
+			for _, c := range ev.Choices {
+				text += c.Delta.Content + c.Delta.ReasoningContent
+				for _, tc := range c.Delta.ToolCalls {
+					text += tc.Function.Arguments
+				}
+			}
+		}
+	}
+	return text
+}
+
+// estimateTextTokens 粗略估算文本 token 数：ASCII 约 4 字符 / token，中文等非 ASCII 字符约 1 字符 / token。
+func estimateTextTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	ascii, others := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			others++
+		}
+	}
+	return (ascii+3)/4 + others
+}
@@ -0,0 +1,67 @@
+package gaia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestStreamedText 测试从各协议流式事件中提取输出文本
+func TestStreamedText(t *testing.T) {
+	cases := []struct {
+		format usageFormat
+		data   string
+		want   string
+	}{
+		{usageFormatOpenAI, `{"choices":[{"delta":{"content":"你好"}}]}`, "你好"},
+		{usageFormatOpenAI, `{"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"{\"a\":1}"}}]}}]}`, `{"a":1}`},
+		{usageFormatOpenAI, `{"choices":[],"usage":{"prompt_tokens":3}}`, ""},
+		{usageFormatAnthropic, `{"type":"content_block_delta","delta":{"type":"text_delta","text":"hello"}}`, "hello"},
+		{usageFormatAnthropic, `{"type":"message_start","message":{"usage":{"input_tokens":10}}}`, ""},
+		{usageFormatGemini, `{"candidates":[{"content":{"parts":[{"text":"a"},{"text":"b"}]}}]}`, "ab"},
+	}
+	for _, c := range cases {
+		if got := streamedText(c.format, []byte(c.data)); got != c.want {
+			t.Errorf("%s：期望 %q，实际 %q", c.data, c.want, got)
+		}
+	}
+}
+
+// TestEstimateTextTokens 测试 ASCII 按 4 字符、非 ASCII 按 1 字符估算
+func TestEstimateTextTokens(t *testing.T) {
+	if n := estimateTextTokens(""); n != 0 {
+		t.Errorf("空文本应为 0，实际 %d", n)
+	}
+	if n := estimateTextTokens("hello world"); n != 3 {
+		t.Errorf("英文估算错误：%d", n)
+	}
+	if n := estimateTextTokens("你好ab"); n != 3 {
+		t.Errorf("中英混合估算错误：%d", n)
+	}
+}
+
+// TestCancelledUsage 测试客户端断开时的计费用量：未写回内容不计费，缺失的 usage 按估算补齐
+func TestCancelledUsage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	att := &proxyAttempt{ctx: ctx, promptTokens: 100}
+	if att.clientCancelled() || att.failureStatus() != "error" {
+		t.Fatal("未取消时不应判定为客户端断开")
+	}
+	cancel()
+	if !att.clientCancelled() || att.failureStatus() != "cancelled" {
+		t.Fatal("context 取消后应判定为客户端断开")
+	}
+
+	if u := att.cancelledUsage(gaia.TokenUsage{PromptTokens: 80}); hasTokens(u) {
+		t.Errorf("尚未写回内容时不应计费：%+v", u)
+	}
+	att.trackStreamed(usageFormatOpenAI, []byte(`{"choices":[{"delta":{"content":"12345678"}}]}`))
+	if u := att.cancelledUsage(gaia.TokenUsage{}); u.PromptTokens != 100 || u.CompletionTokens != 2 {
+		t.Errorf("上游无 usage 时应按估算计费：%+v", u)
+	}
+	// Anthropic message_start 已给出输入 token 与初始输出 token
+	if u := att.cancelledUsage(gaia.TokenUsage{PromptTokens: 80, CompletionTokens: 1}); u.PromptTokens != 80 || u.CompletionTokens != 2 {
+		t.Errorf("应优先使用上游输入 token、输出取较大值：%+v", u)
+	}
+}
@@ -0,0 +1,231 @@
+package gaia
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaRequest "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+)
+
+// usageFormat 上游响应中 usage 的格式
+type usageFormat int
+
+const (
+	usageFormatOpenAI    usageFormat = iota // usage.prompt_tokens / completion_tokens
+	usageFormatAnthropic                    // usage.input_tokens / output_tokens，流式在 message_start / message_delta
+	usageFormatGemini                       // usageMetadata.promptTokenCount / candidatesTokenCount
+	usageFormatResponses                    // usage.input_tokens / output_tokens，流式在 response.completed 的 response.usage
+)
+
+// detectUsageFormat 按提供商与路径判断响应中 usage 的格式。
+func detectUsageFormat(providerName, path string) usageFormat {
+	lpath := strings.ToLower(path)
+	if isAnthropicMessagesPath(lpath) && (providerName == gaia.ProviderAnthropic || providerName == gaia.ProviderAWS) {
+		return usageFormatAnthropic
+	}
+	if strings.Contains(lpath, "rawpredict") {
+		// Vertex AI 上的 Claude（rawPredict / streamRawPredict）返回 Anthropic Messages 原生格式
+		return usageFormatAnthropic
+	}
+	if isResponsesPath(lpath) {
+		return usageFormatResponses
+	}
+	if strings.Contains(lpath, "generatecontent") {
+		return usageFormatGemini
+	}
+	return usageFormatOpenAI
+}
+
+// proxyUsage 从非流式响应体或流式 data 行中累计 token 用量
+type proxyUsage struct {
+	format usageFormat
+	tokens gaia.TokenUsage
+}
+
+// feed 解析一段 JSON（完整响应体或单个流式事件），有值时覆盖已记录的用量（各格式的流式 usage 均为累计值）。
+func (u *proxyUsage) feed(data []byte) {
+	switch u.format {
+	case usageFormatAnthropic:
+		u.feedAnthropic(data)
+	case usageFormatGemini:
+		u.feedGemini(data)
+	case usageFormatResponses:
+		mergeTokenUsage(&u.tokens, parseResponsesUsage(data))
+	default:
+		var obj gaia.ModelUsageResponse
+		if json.Unmarshal(data, &obj) == nil {
+			mergeTokenUsage(&u.tokens, openAIUsageTokens(obj.Usage))
+		}
+	}
+}
+
+// feedAnthropic 非流式响应与 message_delta 的 usage 在顶层，message_start 的在 message.usage。
+func (u *proxyUsage) feedAnthropic(data []byte) {
+	mergeTokenUsage(&u.tokens, parseAnthropicUsage(data))
+	var ev struct {
+		Message struct {
+			Usage anthropicUsage `json:"usage"`
+		} `json:"message"`
+	}
+	if json.Unmarshal(data, &ev) == nil {
+		mergeTokenUsage(&u.tokens, ev.Message.Usage.tokens())
+	}
+}
+
+// geminiUsageMetadata Gemini generateContent 响应中的 usageMetadata
+type geminiUsageMetadata struct {
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// feedGemini 支持单个响应对象，以及未使用 alt=sse 时 streamGenerateContent 返回的 JSON 数组（取最后一个带 usageMetadata 的元素）。
+// promptTokenCount 已包含 cachedContentTokenCount；思考 token（thoughtsTokenCount）计入输出并按推理单价计费。
+func (u *proxyUsage) feedGemini(data []byte) {
+	var items []geminiUsageMetadata
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		_ = json.Unmarshal(data, &items)
+	} else {
+		var one geminiUsageMetadata
+		if json.Unmarshal(data, &one) == nil {
+			items = append(items, one)
+		}
+	}
+	for _, item := range items {
+		if m := item.UsageMetadata; m != nil {
+			mergeTokenUsage(&u.tokens, gaia.TokenUsage{
+				PromptTokens:     m.PromptTokenCount,
+				CompletionTokens: m.CandidatesTokenCount + m.ThoughtsTokenCount,
+				CacheReadTokens:  m.CachedContentTokenCount,
+				ReasoningTokens:  m.ThoughtsTokenCount,
+			})
+		}
+	}
+}
+
+// mergeTokenUsage 用 src 中大于 0 的字段覆盖 dst（流式事件的 usage 为累计值，且可能只携带部分字段）。
+func mergeTokenUsage(dst *gaia.TokenUsage, src gaia.TokenUsage) {
+	for _, f := range []struct {
+		dst *int
+		src int
+	}{
+		{&dst.PromptTokens, src.PromptTokens},
+		{&dst.CompletionTokens, src.CompletionTokens},
+		{&dst.CacheReadTokens, src.CacheReadTokens},
+		{&dst.CacheWriteTokens, src.CacheWriteTokens},
+		{&dst.ReasoningTokens, src.ReasoningTokens},
+	} {
+		if f.src > 0 {
+			*f.dst = f.src
+		}
+	}
+}
+
+// addTokenUsage 将 src 的各类 token 累加到 dst（多次计费的会话、Batch 逐行用量汇总）。
+func addTokenUsage(dst *gaia.TokenUsage, src gaia.TokenUsage) {
+	dst.PromptTokens += src.PromptTokens
+	dst.CompletionTokens += src.CompletionTokens
+	dst.CacheReadTokens += src.CacheReadTokens
+	dst.CacheWriteTokens += src.CacheWriteTokens
+	dst.ReasoningTokens += src.ReasoningTokens
+	dst.AudioInputTokens += src.AudioInputTokens
+	dst.AudioOutputTokens += src.AudioOutputTokens
+}
+
+// openAIUsageTokens 将 OpenAI usage（cached_tokens 含于 prompt_tokens，reasoning_tokens 含于 completion_tokens）归一化。
+func openAIUsageTokens(u *gaia.ModelUsage) gaia.TokenUsage {
+	if u == nil {
+		return gaia.TokenUsage{}
+	}
+	t := gaia.TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+	if u.PromptTokensDetails != nil {
+		t.CacheReadTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		t.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return t
+}
+
+// openAIUsageFrom 将归一化用量转换回 OpenAI usage（有缓存/推理 token 时带上 details）。
+func openAIUsageFrom(t gaia.TokenUsage) *gaia.ModelUsage {
+	u := &gaia.ModelUsage{PromptTokens: t.PromptTokens, CompletionTokens: t.CompletionTokens}
+	if t.CacheReadTokens > 0 {
+		u.PromptTokensDetails = &gaia.PromptTokensDetails{CachedTokens: t.CacheReadTokens}
+	}
+	if t.ReasoningTokens > 0 {
+		u.CompletionTokensDetails = &gaia.CompletionTokensDetails{ReasoningTokens: t.ReasoningTokens}
+	}
+	return u
+}
+
+// tokens 将 Anthropic usage 归一化：输入总量 = input_tokens + 缓存命中 + 缓存写入。
+func (u anthropicUsage) tokens() gaia.TokenUsage {
+	return gaia.TokenUsage{
+		PromptTokens:     u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens,
+		CompletionTokens: u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// anthropicUsageFrom 将归一化用量转换回 Anthropic usage（input_tokens 扣除缓存部分）。
+func anthropicUsageFrom(t gaia.TokenUsage) anthropicUsage {
+	return anthropicUsage{
+		InputTokens:              max(t.PromptTokens-t.CacheReadTokens-t.CacheWriteTokens, 0),
+		OutputTokens:             t.CompletionTokens,
+		CacheReadInputTokens:     t.CacheReadTokens,
+		CacheCreationInputTokens: t.CacheWriteTokens,
+	}
+}
+
+// hasTokens 是否有可计费的 token 用量
+func hasTokens(t gaia.TokenUsage) bool {
+	return t.PromptTokens > 0 || t.CompletionTokens > 0
+}
+
+// chargeUsage 按模型定价（provider 为实际服务请求的提供商）对本次 token 用量计费并扣减调用方额度，同时计入命中的 TPM 限流计数；返回扣费金额（USD）。
+func (s *ModelProviderService) chargeUsage(caller gaiaRequest.ProxyCaller, provider, modelID string, usage gaia.TokenUsage) float64 {
+	if !hasTokens(usage) {
+		return 0
+	}
+	pricing, _ := s.fetchModelPricingFromDify(provider, modelID)
+	cost := calcUsageCost(pricing, modelID, usage)
+	chargeCaller(caller, cost)
+	recordRateLimitTokens(caller, usage.PromptTokens+usage.CompletionTokens)
+	return cost
+}
+
+// modelFromRequest 取请求的模型名：优先 body 的 model 字段（JSON 或 multipart 表单，如语音识别），
+// 其次 Bedrock Converse 路径与 Gemini 原生路径中的模型。
+func modelFromRequest(path string, body []byte) string {
+	if m := parseEndpointRequest(body).Model; m != "" {
+		return m
+	}
+	if m, _, ok := bedrockConversePath(path); ok {
+		return m
+	}
+	return modelFromGeminiPath(path)
+}
+
+// RequestModel 取请求的模型名（body 的 model 字段，其次 Bedrock Converse / Gemini 原生路径中的模型），供鉴权、限流使用。
+func (s *ModelProviderService) RequestModel(path string, body []byte) string {
+	return modelFromRequest(path, body)
+}
+
+// modelFromGeminiPath 从 Gemini 原生路径（如 v1beta/models/gemini-2.5-pro:streamGenerateContent）中取模型名，用于日志与计费。
+func modelFromGeminiPath(path string) string {
+	idx := strings.Index(path, "models/")
+	if idx < 0 {
+		return ""
+	}
+	model := path[idx+len("models/"):]
+	if i := strings.IndexAny(model, ":/?"); i >= 0 {
+		model = model[:i]
+	}
+	return model
+}
@@ -0,0 +1,129 @@
+package gaia
+
+import (
+	"math"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TestProxyUsage 测试 OpenAI / Anthropic / Gemini 三种 usage 格式（含流式事件）的解析
+func TestProxyUsage(t *testing.T) {
+	cases := []struct {
+		name             string
+		provider, path   string
+		chunks           []string
+		prompt, complete int
+	}{
+		{"openai 流式末尾行", gaia.ProviderOpenai, "v1/chat/completions",
+			[]string{`{"choices":[{"delta":{"content":"hi"}}]}`, `{"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":3}}`}, 9, 3},
+		{"anthropic 非流式", gaia.ProviderAnthropic, "v1/messages",
+			[]string{`{"id":"msg_1","usage":{"input_tokens":11,"output_tokens":4}}`}, 11, 4},
+		{"anthropic 流式", gaia.ProviderAnthropic, "v1/messages",
+			[]string{`{"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":15}}`}, 25, 15},
+		{"gemini 流式 SSE", gaia.ProviderGoogle, "v1beta/models/gemini-2.5-pro:streamGenerateContent",
+			[]string{`{"candidates":[],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":2}}`,
+				`{"candidates":[],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":5,"thoughtsTokenCount":10}}`}, 7, 15},
+		{"gemini JSON 数组", gaia.ProviderGoogle, "v1beta/models/gemini-2.5-flash:streamGenerateContent",
+			[]string{`[{"candidates":[]},{"candidates":[],"usageMetadata":{"promptTokenCount":6,"candidatesTokenCount":8}}]`}, 6, 8},
+	}
+	for _, c := range cases {
+		u := &proxyUsage{format: detectUsageFormat(c.provider, c.path)}
+		for _, chunk := range c.chunks {
+			u.feed([]byte(chunk))
+		}
+		if u.tokens.PromptTokens != c.prompt || u.tokens.CompletionTokens != c.complete {
+			t.Errorf("%s: 期望 %d/%d，got: %d/%d", c.name, c.prompt, c.complete, u.tokens.PromptTokens, u.tokens.CompletionTokens)
+		}
+	}
+
+	if m := modelFromGeminiPath("v1beta/models/gemini-2.5-pro:generateContent"); m != "gemini-2.5-pro" {
+		t.Errorf("modelFromGeminiPath 错误，got: %s", m)
+	}
+}
+
+// TestProxyUsageDetails 测试缓存命中/缓存写入/推理 token 的解析与归一化
+func TestProxyUsageDetails(t *testing.T) {
+	cases := []struct {
+		name           string
+		provider, path string
+		chunks         []string
+		want           gaia.TokenUsage
+	}{
+		{"openai details", gaia.ProviderOpenai, "v1/chat/completions",
+			[]string{`{"usage":{"prompt_tokens":100,"completion_tokens":50,"prompt_tokens_details":{"cached_tokens":80},"completion_tokens_details":{"reasoning_tokens":30}}}`},
+			gaia.TokenUsage{PromptTokens: 100, CompletionTokens: 50, CacheReadTokens: 80, ReasoningTokens: 30}},
+		{"anthropic 流式缓存", gaia.ProviderAnthropic, "v1/messages",
+			[]string{`{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":1000,"cache_creation_input_tokens":200,"output_tokens":1}}}`,
+				`{"type":"message_delta","usage":{"output_tokens":40}}`},
+			gaia.TokenUsage{PromptTokens: 1210, CompletionTokens: 40, CacheReadTokens: 1000, CacheWriteTokens: 200}},
+		{"gemini 缓存与思考", gaia.ProviderGoogle, "v1beta/models/gemini-2.5-pro:generateContent",
+			[]string{`{"usageMetadata":{"promptTokenCount":500,"cachedContentTokenCount":400,"candidatesTokenCount":20,"thoughtsTokenCount":60}}`},
+			gaia.TokenUsage{PromptTokens: 500, CompletionTokens: 80, CacheReadTokens: 400, ReasoningTokens: 60}},
+	}
+	for _, c := range cases {
+		u := &proxyUsage{format: detectUsageFormat(c.provider, c.path)}
+		for _, chunk := range c.chunks {
+			u.feed([]byte(chunk))
+		}
+		if u.tokens != c.want {
+			t.Errorf("%s: 期望 %+v，got: %+v", c.name, c.want, u.tokens)
+		}
+	}
+
+	// Anthropic ↔ 归一化用量往返不丢缓存信息
+	want := anthropicUsage{InputTokens: 10, OutputTokens: 40, CacheReadInputTokens: 1000, CacheCreationInputTokens: 200}
+	if got := anthropicUsageFrom(want.tokens()); got != want {
+		t.Errorf("anthropicUsage 往返错误，got: %+v", got)
+	}
+}
+
+// TestCalcUsageCost 测试按 token 类别分别计费及单价回退
+func TestCalcUsageCost(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	// claude-sonnet-4-6：输入 $3/M，缓存命中 $0.3/M，缓存写入 $3.75/M，输出 $15/M
+	usage := gaia.TokenUsage{PromptTokens: 1210, CompletionTokens: 40, CacheReadTokens: 1000, CacheWriteTokens: 200}
+	want := (10*3.0 + 1000*0.3 + 200*3.75 + 40*15.0) / 1e6
+	if got := calcUsageCost(nil, "claude-sonnet-4-6", usage); !near(got, want) {
+		t.Errorf("claude 缓存计费错误，期望 %v，got: %v", want, got)
+	}
+
+	// 未配置缓存/推理单价：缓存按输入价、推理按输出价，与不区分类别时一致
+	p := &gaia.ModelPricing{Input: 1, Output: 2, Unit: 0.001, Currency: "USD"}
+	detailed := gaia.TokenUsage{PromptTokens: 100, CompletionTokens: 50, CacheReadTokens: 80, ReasoningTokens: 30}
+	plain := gaia.TokenUsage{PromptTokens: 100, CompletionTokens: 50}
+	if a, b := calcUsageCost(p, "custom", detailed), calcUsageCost(p, "custom", plain); !near(a, b) {
+		t.Errorf("单价回退错误：%v != %v", a, b)
+	}
+
+	// 配置了推理单价时推理 token 单独计价
+	p.Reasoning = 4
+	want = (100*1 + 20*2 + 30*4) * 0.001
+	if got := calcUsageCost(p, "custom", detailed); !near(got, want) {
+		t.Errorf("推理计费错误，期望 %v，got: %v", want, got)
+	}
+}
+
+// TestResolvePricingUnitScale 测试 Dify 定价与内置表 unit 不同时，沿用的缓存单价按 unit 换算；前缀匹配取最长前缀
+func TestResolvePricingUnitScale(t *testing.T) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+
+	// Dify 按每 token 计价（unit=0.000001）：$3/M → input=3；内置表按每千 token 计价（unit=0.001）
+	dify := &gaia.ModelPricing{Input: 3, Output: 15, Unit: 0.000001, Currency: "USD"}
+	p := resolvePricing(dify, "claude-sonnet-4-6")
+	if !near(p.CacheRead, 0.3) || !near(p.CacheWrite, 3.75) {
+		t.Errorf("缓存单价未按 unit 换算：%+v", p)
+	}
+	usage := gaia.TokenUsage{PromptTokens: 1210, CompletionTokens: 40, CacheReadTokens: 1000, CacheWriteTokens: 200}
+	if a, b := calcUsageCost(dify, "claude-sonnet-4-6", usage), calcUsageCost(nil, "claude-sonnet-4-6", usage); !near(a, b) {
+		t.Errorf("不同 unit 的定价计费不一致：%v != %v", a, b)
+	}
+
+	for i := 0; i < 20; i++ {
+		if got := builtinPricing("tts-1-hd-1106"); got == nil || got.PerKChars != 0.03 {
+			t.Fatalf("应匹配最长前缀 tts-1-hd：%+v", got)
+		}
+	}
+}
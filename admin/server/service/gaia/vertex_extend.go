@@ -0,0 +1,286 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaResponse "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Google Vertex AI 渠道：
+// 鉴权：服务账号 JSON key 签发 RS256 JWT，向 token_uri 换取 OAuth access token（进程内缓存至过期前 5 分钟）
+// 地址：https://{location}-aiplatform.googleapis.com/v1/projects/{project}/locations/{location}/publishers/{publisher}/models/{model}:{method}
+//   - Gemini：v1beta/models/{model}:generateContent → publishers/google/models/{model}:generateContent（流式追加 alt=sse）
+//   - Claude：v1/messages → publishers/anthropic/models/{model}:rawPredict / streamRawPredict，
+//     body 去掉 model 并注入 anthropic_version=vertex-2023-10-16，响应为 Anthropic Messages 原生格式
+// 计费与日志走通用转发（model 取自路径）；OpenAI chat/completions 调用 Claude 时由 proxyChatViaAnthropic 转换。
+
+// vertexServiceAccount 服务账号 JSON key 中参与鉴权的字段
+type vertexServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// vertexToken 已换取的 access token
+type vertexToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// vertexTokenCache 进程内 access token 缓存，按服务账号（client_email + private_key_id）区分
+var vertexTokenCache = struct {
+	mutex  sync.Mutex
+	tokens map[string]vertexToken
+}{tokens: make(map[string]vertexToken)}
+
+// isVertexAnthropicModel 判断 Vertex 模型 ID 是否为 Claude（如 claude-sonnet-4-5@20250929）。
+func isVertexAnthropicModel(modelID string) bool {
+	return strings.Contains(strings.ToLower(modelID), "claude")
+}
+
+// parseVertexServiceAccount 解析服务账号 JSON key，兼容 Dify 插件的 base64 编码与 JSON 原文两种存储。
+func parseVertexServiceAccount(raw string) (*vertexServiceAccount, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("Vertex AI 凭证缺失（需要 vertex_service_account_key）")
+	}
+	data := []byte(raw)
+	if !strings.HasPrefix(raw, "{") {
+		decoded, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Vertex AI 服务账号 key 不是 JSON 或 base64：%w", err)
+		}
+		data = decoded
+	}
+	var sa vertexServiceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("解析 Vertex AI 服务账号 key 失败：%w", err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("Vertex AI 服务账号 key 缺少 client_email 或 private_key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = gaia.VertexDefaultTokenURI
+	}
+	return &sa, nil
+}
+
+// signVertexAssertion 用服务账号私钥签发换取 access token 的 JWT（有效期 1 小时）。
+func signVertexAssertion(sa *vertexServiceAccount, now time.Time) (string, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("解析 Vertex AI 服务账号私钥失败：%w", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   sa.ClientEmail,
+		"scope": gaia.VertexOAuthScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if sa.PrivateKeyID != "" {
+		token.Header["kid"] = sa.PrivateKeyID
+	}
+	return token.SignedString(key)
+}
+
+// vertexAccessToken 返回服务账号的 access token：缓存未过期（距过期超过 VertexTokenRefreshBefore）时直接使用，否则重新换取。
+func vertexAccessToken(ctx context.Context, sa *vertexServiceAccount) (string, error) {
+	cacheKey := sa.ClientEmail + "|" + sa.PrivateKeyID
+	now := time.Now()
+	vertexTokenCache.mutex.Lock()
+	cached, ok := vertexTokenCache.tokens[cacheKey]
+	vertexTokenCache.mutex.Unlock()
+	if ok && now.Add(gaia.VertexTokenRefreshBefore).Before(cached.expiresAt) {
+		return cached.accessToken, nil
+	}
+
+	assertion, err := signVertexAssertion(sa, now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Vertex AI 换取 access token 失败：%w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vertex AI 换取 access token 失败（%d）：%s", resp.StatusCode, string(raw))
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.Unmarshal(raw, &out); err != nil || out.AccessToken == "" {
+		return "", fmt.Errorf("Vertex AI access token 响应无效：%s", string(raw))
+	}
+	if out.ExpiresIn <= 0 {
+		out.ExpiresIn = 3600
+	}
+	vertexTokenCache.mutex.Lock()
+	vertexTokenCache.tokens[cacheKey] = vertexToken{
+		accessToken: out.AccessToken,
+		expiresAt:   now.Add(time.Duration(out.ExpiresIn) * time.Second),
+	}
+	vertexTokenCache.mutex.Unlock()
+	return out.AccessToken, nil
+}
+
+// vertexTarget 解析 Vertex 凭证：服务账号、项目（未配置时取 key 中的 project_id）与区域（默认 us-central1）。
+func vertexTarget(creds *gaiaResponse.ProviderCredentials) (sa *vertexServiceAccount, project, location string, err error) {
+	if creds == nil {
+		return nil, "", "", fmt.Errorf("Vertex AI 凭证缺失（需要 vertex_service_account_key）")
+	}
+	if sa, err = parseVertexServiceAccount(creds.VertexServiceAccountKey); err != nil {
+		return nil, "", "", err
+	}
+	if project = creds.VertexProjectID; project == "" {
+		project = sa.ProjectID
+	}
+	if project == "" {
+		return nil, "", "", fmt.Errorf("Vertex AI 凭证缺少项目 ID（vertex_project_id）")
+	}
+	if location = creds.VertexLocation; location == "" {
+		location = gaia.VertexDefaultLocation
+	}
+	return sa, project, location, nil
+}
+
+// vertexBaseURL 返回区域的 API 根地址，global 区域无区域前缀。
+func vertexBaseURL(location string) string {
+	if location == "global" {
+		return "https://aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+}
+
+// vertexModelPath 返回发布方模型的调用路径（不含前导 /）。
+func vertexModelPath(project, location, publisher, modelID, method string) string {
+	return fmt.Sprintf("v1/projects/%s/locations/%s/publishers/%s/models/%s:%s", project, location, publisher, modelID, method)
+}
+
+// rewriteVertexAnthropicBody 去掉 model（Vertex 走 URL 路径），未指定时注入 anthropic_version，保留 stream。
+func rewriteVertexAnthropicBody(body []byte) (modelID string, streaming bool, rewritten []byte, err error) {
+	var bodyObj map[string]interface{}
+	if err = json.Unmarshal(body, &bodyObj); err != nil {
+		return "", false, nil, fmt.Errorf("解析 Vertex AI 请求 body 失败：%w", err)
+	}
+	modelID, _ = bodyObj["model"].(string)
+	streaming, _ = bodyObj["stream"].(bool)
+	delete(bodyObj, "model")
+	if _, ok := bodyObj["anthropic_version"]; !ok {
+		bodyObj["anthropic_version"] = gaia.VertexAnthropicVersion
+	}
+	rewritten, err = json.Marshal(bodyObj)
+	return modelID, streaming, rewritten, err
+}
+
+// vertexUpstreamPath 将客户端路径与 body 改写为 Vertex 路径与 body：
+// Anthropic Messages（Claude）→ rawPredict / streamRawPredict；Gemini 原生接口 → publishers/google/models/{model}:{method}。
+func vertexUpstreamPath(path string, body []byte, project, location string) (string, []byte, error) {
+	if isAnthropicMessagesPath(path) {
+		modelID, streaming, rewritten, err := rewriteVertexAnthropicBody(body)
+		if err != nil {
+			return "", nil, err
+		}
+		if !isVertexAnthropicModel(modelID) {
+			return "", nil, fmt.Errorf("Vertex AI 的 Messages 接口仅支持 Claude 模型，当前为 %q", modelID)
+		}
+		method := "rawPredict"
+		if streaming {
+			method = "streamRawPredict"
+		}
+		return vertexModelPath(project, location, "anthropic", modelID, method), rewritten, nil
+	}
+	if modelID := modelFromGeminiPath(path); modelID != "" {
+		idx := strings.LastIndex(path, ":")
+		if idx < 0 {
+			return "", nil, fmt.Errorf("Vertex AI 路径缺少调用方法，如 models/%s:generateContent", modelID)
+		}
+		method := path[idx+1:]
+		if i := strings.IndexAny(method, "?/"); i >= 0 {
+			method = method[:i]
+		}
+		upstreamPath := vertexModelPath(project, location, "google", modelID, method)
+		if method == "streamGenerateContent" {
+			upstreamPath += "?alt=sse"
+		}
+		return upstreamPath, body, nil
+	}
+	return "", nil, fmt.Errorf("Vertex AI 渠道仅支持 Gemini generateContent 与 Claude Messages 接口，当前路径 %s", path)
+}
+
+// prepareVertexRequest 为通用转发准备 Vertex 请求：返回 API 根地址、改写后的路径与 body，
+// 以及 APIKey 替换为 access token 的凭证副本（通用转发以 Authorization: Bearer 发送）。
+func (s *ModelProviderService) prepareVertexRequest(ctx context.Context, path string, body []byte,
+	creds *gaiaResponse.ProviderCredentials) (string, string, []byte, *gaiaResponse.ProviderCredentials, error) {
+	sa, project, location, err := vertexTarget(creds)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	upstreamPath, upstreamBody, err := vertexUpstreamPath(path, body, project, location)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	token, err := vertexAccessToken(ctx, sa)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	authed := *creds
+	authed.APIKey = token
+	return vertexBaseURL(location), upstreamPath, upstreamBody, &authed, nil
+}
+
+// newVertexAnthropicRequest 构建 Vertex AI 上 Claude 的 rawPredict（流式为 streamRawPredict）请求。
+// payload 为已去掉 model、带 anthropic_version 的 Anthropic Messages body。
+func (s *ModelProviderService) newVertexAnthropicRequest(ctx context.Context,
+	creds *gaiaResponse.ProviderCredentials, modelID string, payload []byte, streaming bool) (*http.Request, error) {
+	sa, project, location, err := vertexTarget(creds)
+	if err != nil {
+		return nil, err
+	}
+	token, err := vertexAccessToken(ctx, sa)
+	if err != nil {
+		return nil, err
+	}
+	method := "rawPredict"
+	if streaming {
+		method = "streamRawPredict"
+	}
+	requestURL := vertexBaseURL(location) + "/" + vertexModelPath(project, location, "anthropic", modelID, method)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	if streaming {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	return httpReq, nil
+}
@@ -0,0 +1,108 @@
+package gaia
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// testVertexServiceAccount 生成测试用服务账号 key，token_uri 指向 tokenURI
+func testVertexServiceAccount(t *testing.T, tokenURI string) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	raw, _ := json.Marshal(vertexServiceAccount{
+		Type: "service_account", ProjectID: "demo-project", PrivateKeyID: "kid-1",
+		PrivateKey: string(pemKey), ClientEmail: "gw@demo-project.iam.gserviceaccount.com", TokenURI: tokenURI,
+	})
+	return key, base64.StdEncoding.EncodeToString(raw)
+}
+
+// TestVertexAccessToken 测试服务账号 JWT 换取 access token，且在过期前复用缓存
+func TestVertexAccessToken(t *testing.T) {
+	var key *rsa.PrivateKey
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type 错误：%s", r.Form.Get("grant_type"))
+		}
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil || !token.Valid || token.Header["kid"] != "kid-1" {
+			t.Errorf("JWT 校验失败：%v", err)
+		}
+		if claims["iss"] != "gw@demo-project.iam.gserviceaccount.com" || claims["aud"] != "http://"+r.Host+"/token" {
+			t.Errorf("JWT claims 错误：%v", claims)
+		}
+		_, _ = w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer srv.Close()
+
+	var encoded string
+	key, encoded = testVertexServiceAccount(t, srv.URL+"/token")
+	sa, err := parseVertexServiceAccount(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		token, err := vertexAccessToken(context.Background(), sa)
+		if err != nil || token != "ya29.test" {
+			t.Fatalf("换取 access token 失败：%q %v", token, err)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("未过期的 token 应复用缓存，实际请求 %d 次", hits)
+	}
+}
+
+// TestVertexUpstreamPath 测试 Claude Messages 与 Gemini 原生路径改写为 Vertex 路径
+func TestVertexUpstreamPath(t *testing.T) {
+	path, body, err := vertexUpstreamPath("v1/messages",
+		[]byte(`{"model":"claude-sonnet-4-5@20250929","stream":true,"max_tokens":10,"messages":[]}`), "p1", "us-east5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "v1/projects/p1/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:streamRawPredict" {
+		t.Errorf("Claude 路径错误：%s", path)
+	}
+	var obj map[string]interface{}
+	_ = json.Unmarshal(body, &obj)
+	if _, ok := obj["model"]; ok || obj["anthropic_version"] != "vertex-2023-10-16" || obj["stream"] != true {
+		t.Errorf("Claude body 改写错误：%s", body)
+	}
+	if detectUsageFormat("vertex", path) != usageFormatAnthropic || modelFromGeminiPath(path) != "claude-sonnet-4-5@20250929" {
+		t.Error("rawPredict 路径应按 Anthropic 格式解析 usage，并从路径取模型名")
+	}
+
+	path, _, err = vertexUpstreamPath("v1beta/models/gemini-2.5-pro:streamGenerateContent", []byte(`{}`), "p1", "global")
+	if err != nil || path != "v1/projects/p1/locations/global/publishers/google/models/gemini-2.5-pro:streamGenerateContent?alt=sse" {
+		t.Errorf("Gemini 路径错误：%s %v", path, err)
+	}
+	if vertexBaseURL("global") != "https://aiplatform.googleapis.com" || vertexBaseURL("us-east5") != "https://us-east5-aiplatform.googleapis.com" {
+		t.Error("区域地址错误")
+	}
+
+	if _, _, err = vertexUpstreamPath("v1/messages", []byte(`{"model":"gemini-2.5-pro"}`), "p1", "us-east5"); err == nil {
+		t.Error("Messages 接口调用非 Claude 模型应报错")
+	}
+	if _, _, err = vertexUpstreamPath("v1/embeddings", []byte(`{}`), "p1", "us-east5"); err == nil ||
+		!strings.Contains(err.Error(), "仅支持") {
+		t.Errorf("不支持的路径应报错：%v", err)
+	}
+}
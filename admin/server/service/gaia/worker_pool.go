@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/metrics"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,10 +23,10 @@ var globalWorkerPool *WorkerPool
 type WorkerPool struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
-	totalWorkers   int                                      // 总工作器数量
-	userWorkers    map[uint]*gaia.UserWorkerAllocation      // 每个用户的工作器分配
-	userTaskChan   map[uint]chan *gaia.BatchWorkflowTask    // 每个用户的任务队列
-	runningWorkers map[uint]int                             // 每个用户当前运行的worker数量
+	totalWorkers   int                                   // 总工作器数量
+	userWorkers    map[uint]*gaia.UserWorkerAllocation   // 每个用户的工作器分配
+	userTaskChan   map[uint]chan *gaia.BatchWorkflowTask // 每个用户的任务队列
+	runningWorkers map[uint]int                          // 每个用户当前运行的worker数量
 	wg             sync.WaitGroup
 	batchService   *BatchWorkflowService
 	running        bool
@@ -693,6 +694,7 @@ func (wp *WorkerPool) processTask(task *gaia.BatchWorkflowTask) {
 			global.GVA_LOG.Error(fmt.Sprintf("更新空值任务状态失败: %s", err.Error()))
 			return
 		}
+		metrics.BatchTaskOutcomes.WithLabelValues("skipped").Inc()
 
 		// 更新批量处理的已处理行数
 		global.GVA_DB.Exec("UPDATE batch_workflows_extend SET processed_rows = processed_rows + 1, updated_at = ? WHERE id = ?",
@@ -773,6 +775,7 @@ func (wp *WorkerPool) processTask(task *gaia.BatchWorkflowTask) {
 		global.GVA_LOG.Error(fmt.Sprintf("更新任务结果失败: %s", err.Error()))
 		return
 	}
+	metrics.BatchTaskOutcomes.WithLabelValues("completed").Inc()
 
 	// 更新批量处理的已处理行数
 	global.GVA_DB.Exec(
@@ -849,6 +852,7 @@ func (wp *WorkerPool) updateTaskError(task *gaia.BatchWorkflowTask, errorMsg str
 			global.GVA_LOG.Error(fmt.Sprintf("更新任务最终失败状态失败: %s", err.Error()))
 		}
 		global.GVA_LOG.Warn(fmt.Sprintf("任务 %s 重试次数已达上限(%d次)，标记为最终失败", task.ID, gaia.MaxTaskRetryCount))
+		metrics.BatchTaskOutcomes.WithLabelValues("failed").Inc()
 	} else {
 		// 未超过重试次数，重置为pending状态以便重试
 		if err := global.GVA_DB.Model(task).Updates(map[string]interface{}{
@@ -860,6 +864,7 @@ func (wp *WorkerPool) updateTaskError(task *gaia.BatchWorkflowTask, errorMsg str
 			global.GVA_LOG.Error(fmt.Sprintf("更新任务重试状态失败: %s", err.Error()))
 		}
 		global.GVA_LOG.Info(fmt.Sprintf("任务 %s 第%d次失败，重置为pending状态准备重试", task.ID, newErrorCount))
+		metrics.BatchTaskOutcomes.WithLabelValues("retry").Inc()
 	}
 
 	// 检查批量工作流状态
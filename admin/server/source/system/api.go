@@ -240,6 +240,36 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/available-models", Description: "获取可用模型"},
 		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/test-credentials", Description: "测试提供商凭证"},
 		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/logs", Description: "获取代理日志"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/keys", Description: "网关Key列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/keys", Description: "创建网关Key"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/keys/:id", Description: "更新网关Key"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/keys/:id", Description: "删除网关Key"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/credentials", Description: "凭证负载均衡状态"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/credentials/balance", Description: "设置凭证负载均衡策略"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/routes", Description: "获取模型路由列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/routes", Description: "创建模型路由"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/routes/:id", Description: "更新模型路由"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/routes/:id", Description: "删除模型路由"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/rate-limits", Description: "限流规则列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/rate-limits", Description: "创建限流规则"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/rate-limits/:id", Description: "更新限流规则"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/rate-limits/:id", Description: "删除限流规则"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/access-rules", Description: "模型访问规则列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/access-rules", Description: "创建模型访问规则"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/access-rules/:id", Description: "更新模型访问规则"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/access-rules/:id", Description: "删除模型访问规则"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/logs/stats", Description: "代理日志统计"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/logs/export", Description: "导出代理日志"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/circuit-breakers", Description: "上游熔断器状态"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/custom-providers", Description: "自定义提供商列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/custom-providers", Description: "创建自定义提供商"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/custom-providers/:id", Description: "更新自定义提供商"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/custom-providers/:id", Description: "删除自定义提供商"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/dlp-rules", Description: "DLP规则列表"},
+		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/model-provider/dlp-rules", Description: "创建DLP规则"},
+		{ApiGroup: "模型管理", Method: "PUT", Path: "/gaia/model-provider/dlp-rules/:id", Description: "更新DLP规则"},
+		{ApiGroup: "模型管理", Method: "DELETE", Path: "/gaia/model-provider/dlp-rules/:id", Description: "删除DLP规则"},
+		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/model-provider/dlp-audits", Description: "DLP命中审计列表"},
 		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/models", Description: "获取开启的模型列表(第三方)"},
 		{ApiGroup: "模型管理", Method: "GET", Path: "/gaia/proxy/*", Description: "中转API(第三方)-GET"},
 		{ApiGroup: "模型管理", Method: "POST", Path: "/gaia/proxy/*", Description: "中转API(第三方)-POST"},
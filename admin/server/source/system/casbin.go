@@ -388,6 +388,36 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/available-models", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/test-credentials", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/logs", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/keys", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/keys", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/keys/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/keys/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/credentials", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/credentials/balance", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/routes", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/routes", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/routes/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/routes/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/rate-limits", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/rate-limits", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/rate-limits/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/rate-limits/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/access-rules", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/access-rules", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/access-rules/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/access-rules/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/logs/stats", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/logs/export", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/circuit-breakers", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/custom-providers", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/custom-providers", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/custom-providers/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/custom-providers/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/dlp-rules", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/dlp-rules", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/dlp-rules/:id", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/dlp-rules/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/gaia/model-provider/dlp-audits", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/models", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/proxy/*", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/proxy/*", V2: "POST"},
@@ -399,6 +429,36 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/available-models", V2: "GET"},
 		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/test-credentials", V2: "GET"},
 		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/logs", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/keys", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/keys", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/keys/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/keys/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/credentials", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/credentials/balance", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/routes", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/routes", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/routes/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/routes/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/rate-limits", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/rate-limits", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/rate-limits/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/rate-limits/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/access-rules", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/access-rules", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/access-rules/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/access-rules/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/logs/stats", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/logs/export", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/circuit-breakers", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/custom-providers", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/custom-providers", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/custom-providers/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/custom-providers/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/dlp-rules", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/dlp-rules", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/dlp-rules/:id", V2: "PUT"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/dlp-rules/:id", V2: "DELETE"},
+		{Ptype: "p", V0: "8881", V1: "/gaia/model-provider/dlp-audits", V2: "GET"},
 		{Ptype: "p", V0: "8881", V1: "/gaia/models", V2: "GET"},
 		{Ptype: "p", V0: "8881", V1: "/gaia/proxy/*", V2: "GET"},
 		{Ptype: "p", V0: "8881", V1: "/gaia/proxy/*", V2: "POST"},
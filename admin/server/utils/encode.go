@@ -19,7 +19,14 @@ func pkcs7Padding(data []byte, blockSize int) []byte {
 // 去除PKCS#7填充
 func pkcs7UnPadding(data []byte) []byte {
 	length := len(data)
+	if length == 0 {
+		return data
+	}
 	unpadding := int(data[length-1])
+	if unpadding == 0 || unpadding > length {
+		// 密钥不匹配时填充非法，原样返回避免越界
+		return data
+	}
 	return data[:(length - unpadding)]
 }
 
@@ -0,0 +1,95 @@
+// Package metrics 管理端 Prometheus 指标：模型网关、批量工作流工作池与定时任务，经 /metrics 暴露。
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gaia"
+
+// Registry 独立注册表，附带 Go 运行时与进程指标
+var Registry = prometheus.NewRegistry()
+
+// 模型网关指标：status 为代理日志状态（success / error / cancelled），命中响应缓存的请求 status 为 cache_hit
+var (
+	GatewayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "gateway", Name: "requests_total",
+		Help: "网关转发请求数",
+	}, []string{"provider", "model", "status"})
+
+	GatewayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "gateway", Name: "request_duration_seconds",
+		Help:    "网关转发请求耗时",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model", "status"})
+
+	GatewayTTFB = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "gateway", Name: "ttfb_seconds",
+		Help:    "上游响应首字节耗时",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	}, []string{"provider", "model"})
+
+	GatewayTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "gateway", Name: "tokens_total",
+		Help: "网关计费 token 数（type：prompt / completion / cache_read / cache_write / reasoning）",
+	}, []string{"provider", "model", "type"})
+
+	GatewayCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "gateway", Name: "cost_usd_total",
+		Help: "网关扣费金额（USD）",
+	}, []string{"provider", "model"})
+)
+
+// 批量工作流工作池指标：工作器与队列深度为 Gauge，由 service/gaia 在采集时注册的 Collector 提供
+var BatchTaskOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace, Subsystem: "batch", Name: "task_outcomes_total",
+	Help: "批量工作流任务处理结果（completed / skipped / retry / failed）",
+}, []string{"outcome"})
+
+// 定时任务指标
+var (
+	CronLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "cron", Name: "last_run_timestamp_seconds",
+		Help: "定时任务最近一次开始执行的时间",
+	}, []string{"job"})
+
+	CronLastDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "cron", Name: "last_duration_seconds",
+		Help: "定时任务最近一次执行耗时",
+	}, []string{"job"})
+
+	CronRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "cron", Name: "runs_total",
+		Help: "定时任务执行次数",
+	}, []string{"job"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GatewayRequests, GatewayRequestDuration, GatewayTTFB, GatewayTokens, GatewayCost,
+		BatchTaskOutcomes,
+		CronLastRun, CronLastDuration, CronRuns,
+	)
+}
+
+// Handler 返回 /metrics 的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveCron 记录定时任务开始执行，返回的函数在任务结束时调用以记录耗时，用法：defer metrics.ObserveCron("job")()
+func ObserveCron(job string) func() {
+	start := time.Now()
+	CronLastRun.WithLabelValues(job).Set(float64(start.Unix()))
+	CronRuns.WithLabelValues(job).Inc()
+	return func() {
+		CronLastDuration.WithLabelValues(job).Set(time.Since(start).Seconds())
+	}
+}